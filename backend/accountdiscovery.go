@@ -0,0 +1,260 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// maxDiscoveredAccounts limits the number of account indices scanned per coin, to stop eventually
+// in case a blockchain server reports a history for every address.
+const maxDiscoveredAccounts = 50
+
+// purposes maps the script types to the BIP44 purpose field of their keypath.
+var purposes = map[signing.ScriptType]uint32{
+	signing.ScriptTypeP2PKH:      44,
+	signing.ScriptTypeP2WPKHP2SH: 49,
+	signing.ScriptTypeP2WPKH:     84,
+	signing.ScriptTypeP2TR:       86,
+}
+
+// discoveryCoin describes the accounts of a coin scanned during account discovery. The account
+// with index 0 uses the code and name of the default account added by `initDefaultAccounts()`, so
+// that it is not added twice.
+type discoveryCoin struct {
+	code        coinpkg.Code
+	accountCode string
+	name        string
+	bip44Coin   uint32
+	scriptTypes []signing.ScriptType
+}
+
+func (backend *Backend) discoveryCoins() []discoveryCoin {
+	if backend.arguments.Testing() {
+		if backend.arguments.Regtest() {
+			return []discoveryCoin{
+				{coinpkg.CodeRBTC, "rbtc", "Bitcoin Regtest", 1, []signing.ScriptType{
+					signing.ScriptTypeP2WPKHP2SH, signing.ScriptTypeP2PKH}},
+			}
+		}
+		return []discoveryCoin{
+			{coinpkg.CodeTBTC, "tbtc", "Bitcoin Testnet", 1, []signing.ScriptType{
+				signing.ScriptTypeP2WPKH, signing.ScriptTypeP2WPKHP2SH, signing.ScriptTypeP2PKH,
+				signing.ScriptTypeP2TR}},
+			{coinpkg.CodeTLTC, "tltc", "Litecoin Testnet", 1, []signing.ScriptType{
+				signing.ScriptTypeP2WPKH, signing.ScriptTypeP2WPKHP2SH}},
+		}
+	}
+	return []discoveryCoin{
+		{coinpkg.CodeBTC, "btc", "Bitcoin", 0, []signing.ScriptType{
+			signing.ScriptTypeP2WPKH, signing.ScriptTypeP2WPKHP2SH, signing.ScriptTypeP2PKH,
+			signing.ScriptTypeP2TR}},
+		{coinpkg.CodeLTC, "ltc", "Litecoin", 2, []signing.ScriptType{
+			signing.ScriptTypeP2WPKH, signing.ScriptTypeP2WPKHP2SH}},
+	}
+}
+
+// emitAccountDiscoveryEvent informs the frontend about the progress of the account discovery.
+func (backend *Backend) emitAccountDiscoveryEvent(data string, meta map[string]interface{}) {
+	backend.events <- backendEvent{Type: "accountDiscovery", Data: data, Meta: meta}
+}
+
+// DiscoverAccounts scans the accounts of the registered keystore as described in the account
+// discovery section of BIP44, e.g. after restoring a seed. For each active bitcoin-like coin, the
+// account indices are scanned until an account without any transaction history in any of the
+// script types is found. All used accounts and the first unused account are persisted and added.
+// The first account is only added if it is used, as the user might have removed the default
+// account. Progress is reported with "accountDiscovery" events.
+//
+// Ethereum accounts are not discovered: an Ethereum account is a single address, and the
+// keystores only derive the addresses of the default account keypath (m/44'/60'/0'/0/<index>), which
+// does not have account indices to scan.
+func (backend *Backend) DiscoverAccounts() error {
+	unlock := backend.accountDiscoveryRunningLock.Lock()
+	if backend.accountDiscoveryRunning {
+		unlock()
+		return errp.New("Account discovery is already running")
+	}
+	backend.accountDiscoveryRunning = true
+	unlock()
+	defer func() {
+		defer backend.accountDiscoveryRunningLock.Lock()()
+		backend.accountDiscoveryRunning = false
+	}()
+
+	if backend.keystores.Count() != 1 {
+		return errp.New("Account discovery requires exactly one keystore")
+	}
+	keystore := backend.keystores.Keystores()[0]
+	backend.emitAccountDiscoveryEvent("started", nil)
+	for _, discoveryCoin := range backend.discoveryCoins() {
+		if err := backend.discoverBTCAccounts(keystore, discoveryCoin); err != nil {
+			backend.emitAccountDiscoveryEvent("failed", map[string]interface{}{
+				"coinCode": discoveryCoin.code,
+			})
+			return err
+		}
+	}
+	backend.emitAccountDiscoveryEvent("done", nil)
+	return nil
+}
+
+// discoverBTCAccounts scans and persists the accounts of one coin, see `DiscoverAccounts()`.
+func (backend *Backend) discoverBTCAccounts(keystore keystore.Keystore, discoveryCoin discoveryCoin) error {
+	log := backend.log.WithField("code", discoveryCoin.code)
+	if !backend.config.AppConfig().Backend.CoinActive(discoveryCoin.code) {
+		log.Info("skipping account discovery of inactive coin")
+		return nil
+	}
+	coin, err := backend.Coin(discoveryCoin.code)
+	if err != nil {
+		return err
+	}
+	btcCoin, ok := coin.(*btc.Coin)
+	if !ok {
+		return errp.Newf("Account discovery is not supported for %s", discoveryCoin.code)
+	}
+	var scriptTypes []signing.ScriptType
+	for _, scriptType := range discoveryCoin.scriptTypes {
+		if keystore.SupportsAccount(coin, false, scriptType) {
+			scriptTypes = append(scriptTypes, scriptType)
+		}
+	}
+	if len(scriptTypes) == 0 {
+		return nil
+	}
+	splitAccounts := backend.config.AppConfig().Backend.SplitAccounts ||
+		!keystore.SupportsUnifiedAccounts()
+	rootFingerprint, err := keystore.RootFingerprint()
+	if err != nil {
+		return err
+	}
+
+	return discoverUsedAccounts(func(accountIndex uint32) (bool, error) {
+		backend.emitAccountDiscoveryEvent("progress", map[string]interface{}{
+			"coinCode":     discoveryCoin.code,
+			"accountIndex": accountIndex,
+		})
+		configurations := signing.Configurations{}
+		used := false
+		for _, scriptType := range scriptTypes {
+			keypath, err := signing.NewAbsoluteKeypath(fmt.Sprintf("m/%d'/%d'/%d'",
+				purposes[scriptType], discoveryCoin.bip44Coin, accountIndex))
+			if err != nil {
+				return false, err
+			}
+			extendedPublicKey, err := keystore.ExtendedPublicKey(coin, keypath)
+			if err != nil {
+				return false, err
+			}
+			configuration := signing.NewSinglesigConfiguration(scriptType, keypath, extendedPublicKey)
+			hasHistory, err := btc.HasHistory(btcCoin, configuration)
+			if err != nil {
+				return false, err
+			}
+			used = used || hasHistory
+			configurations = append(configurations, configuration)
+		}
+		log.WithField("accountIndex", accountIndex).WithField("used", used).Info("scanned account")
+		if accountIndex > 0 || used {
+			if err := backend.persistDiscoveredAccount(keystore, coin, discoveryCoin, accountIndex,
+				rootFingerprint, configurations, splitAccounts); err != nil {
+				return false, err
+			}
+		}
+		return used, nil
+	})
+}
+
+// discoverUsedAccounts calls scan with the account indices 0, 1, ... until scan reports an unused
+// account, i.e. the account gap limit is one (BIP44). At most maxDiscoveredAccounts are scanned.
+func discoverUsedAccounts(scan func(accountIndex uint32) (bool, error)) error {
+	for accountIndex := uint32(0); accountIndex < maxDiscoveredAccounts; accountIndex++ {
+		used, err := scan(accountIndex)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return nil
+		}
+	}
+	return nil
+}
+
+// persistDiscoveredAccount persists and adds the account with the given index as a keystore
+// account. Accounts which were already added before are skipped.
+func (backend *Backend) persistDiscoveredAccount(
+	keystore keystore.Keystore,
+	coin coinpkg.Coin,
+	discoveryCoin discoveryCoin,
+	accountIndex uint32,
+	rootFingerprint []byte,
+	configurations signing.Configurations,
+	splitAccounts bool,
+) error {
+	code := keystoreAccountCode(rootFingerprint, fmt.Sprintf("%s-%d", discoveryCoin.accountCode, accountIndex))
+	name := fmt.Sprintf("%s %d", discoveryCoin.name, accountIndex+1)
+	if accountIndex == 0 {
+		code = keystoreAccountCode(rootFingerprint, discoveryCoin.accountCode)
+		name = discoveryCoin.name
+	}
+	configurations = withRootFingerprint(configurations, rootFingerprint, keystore.CosignerIndex())
+	accountConfigurations := []signing.Configurations{configurations}
+	if splitAccounts {
+		accountConfigurations = nil
+		for _, configuration := range configurations {
+			accountConfigurations = append(accountConfigurations, signing.Configurations{configuration})
+		}
+	}
+	for _, configurations := range accountConfigurations {
+		configurations := configurations
+		accountCode := code
+		if splitAccounts {
+			accountCode = fmt.Sprintf("%s-%s", code, configurations[0].ScriptType())
+		}
+		err := backend.persistAccount(coin, config.Account{
+			CoinCode:        coin.Code(),
+			Code:            accountCode,
+			Name:            name,
+			Configurations:  configurations,
+			RootFingerprint: hex.EncodeToString(rootFingerprint),
+		})
+		if errp.Cause(err) == ErrAccountAlreadyExists {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = backend.CreateAndAddAccount(
+			coin,
+			accountCode,
+			name,
+			func() (signing.Configurations, error) { return configurations, nil },
+			false, true,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
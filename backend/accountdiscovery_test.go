@@ -0,0 +1,54 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverUsedAccounts(t *testing.T) {
+	scanned := func(usedAccounts uint32, scanErr error) ([]uint32, error) {
+		var indices []uint32
+		err := discoverUsedAccounts(func(accountIndex uint32) (bool, error) {
+			indices = append(indices, accountIndex)
+			if accountIndex == 1 && scanErr != nil {
+				return false, scanErr
+			}
+			return accountIndex < usedAccounts, nil
+		})
+		return indices, err
+	}
+
+	// The scan stops at the first unused account, which is scanned too.
+	indices, err := scanned(0, nil)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0}, indices)
+	indices, err = scanned(3, nil)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0, 1, 2, 3}, indices)
+
+	// The scan stops eventually if every account is used.
+	indices, err = scanned(1000, nil)
+	require.NoError(t, err)
+	require.Len(t, indices, maxDiscoveredAccounts)
+
+	scanErr := errors.New("scan failed")
+	indices, err = scanned(3, scanErr)
+	require.Equal(t, scanErr, err)
+	require.Equal(t, []uint32{0, 1}, indices)
+}
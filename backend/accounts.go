@@ -0,0 +1,674 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// ErrAccountNotPersisted is returned when modifying an account which is not in the accounts
+// config, e.g. a default account of a keystore.
+var ErrAccountNotPersisted = errors.New("account not persisted")
+
+// ErrAccountNotSupported is returned when adding an account which the keystore does not support.
+var ErrAccountNotSupported = errors.New("account not supported")
+
+// CreateAndAddKeystoreAccount persists and adds an account of the registered keystore at the given
+// keypath, e.g. to access funds of a further account index or of a different wallet using other
+// keypaths. The script type only applies to bitcoin-like coins. Returns the account code.
+func (backend *Backend) CreateAndAddKeystoreAccount(
+	coin coin.Coin,
+	name string,
+	scriptType signing.ScriptType,
+	keypath signing.AbsoluteKeypath,
+) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errp.New("The account name cannot be empty")
+	}
+	if backend.keystores.Count() != 1 {
+		return "", errp.New("Adding an account requires exactly one keystore")
+	}
+	keystore := backend.keystores.Keystores()[0]
+	var meta interface{}
+	if _, ok := coin.(*btc.Coin); ok {
+		meta = scriptType
+	} else {
+		scriptType = signing.ScriptTypeP2PKH // TODO: meaningless in Ethereum
+	}
+	if !keystore.SupportsAccount(coin, false, meta) {
+		return "", errp.WithStack(ErrAccountNotSupported)
+	}
+	extendedPublicKey, err := keystore.ExtendedPublicKey(coin, keypath)
+	if err != nil {
+		return "", err
+	}
+	rootFingerprint, err := keystore.RootFingerprint()
+	if err != nil {
+		return "", err
+	}
+	configurations := withRootFingerprint(signing.Configurations{
+		signing.NewSinglesigConfiguration(scriptType, keypath, extendedPublicKey),
+	}, rootFingerprint, keystore.CosignerIndex())
+	code := keystoreAccountCode(rootFingerprint, fmt.Sprintf("%s-%s", configurations.Hash(), coin.Code()))
+	// Accounts added by the user are assumed to be new.
+	err = backend.persistAccount(coin, config.Account{
+		CoinCode:        coin.Code(),
+		Code:            code,
+		Name:            name,
+		Configurations:  configurations,
+		RootFingerprint: hex.EncodeToString(rootFingerprint),
+		BirthHeight:     backend.newAccountBirthHeight(coin),
+	})
+	if err != nil {
+		return "", err
+	}
+	getSigningConfigurations := func() (signing.Configurations, error) {
+		return configurations, nil
+	}
+	if err := backend.CreateAndAddAccount(coin, code, name, getSigningConfigurations, false, true); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// newAccountBirthHeight returns the birth height of a new account of the given coin: the current
+// block for Ethereum-like coins, so that the node transactions source does not scan the blocks
+// before it, and zero otherwise or if the current block is not known. If the account was used
+// before, e.g. in another wallet, the user can lower the birth height.
+func (backend *Backend) newAccountBirthHeight(coin coin.Coin) uint64 {
+	ethCoin, ok := coin.(*eth.Coin)
+	if !ok {
+		return 0
+	}
+	blockNumber, err := ethCoin.BlockNumber()
+	if err != nil {
+		backend.log.WithError(err).Warning("Could not get the birth height of the account")
+		return 0
+	}
+	return blockNumber.Uint64()
+}
+
+// erc20CoinCodePrefix is the prefix of the coin codes of ERC20 tokens.
+const erc20CoinCodePrefix = "eth-erc20-"
+
+// registeredRootFingerprint returns the root fingerprint of the registered keystore, or nil if
+// there is not exactly one keystore registered.
+func (backend *Backend) registeredRootFingerprint() ([]byte, error) {
+	if backend.keystores.Count() != 1 {
+		return nil, nil
+	}
+	return backend.keystores.Keystores()[0].RootFingerprint()
+}
+
+// keystoreAccountCode prefixes the account code with the root fingerprint, so that the accounts of
+// different keystores have different codes.
+func keystoreAccountCode(rootFingerprint []byte, code string) string {
+	return fmt.Sprintf("%x-%s", rootFingerprint, code)
+}
+
+// knownKeystore returns the keystore with the given root fingerprint for which default accounts
+// were already created, or nil if there is none.
+func (backend *Backend) knownKeystore(rootFingerprint []byte) *config.Keystore {
+	encoded := hex.EncodeToString(rootFingerprint)
+	for _, keystore := range backend.config.AccountsConfig().Keystores {
+		if keystore.RootFingerprint == encoded && keystore.Testing == backend.Testing() {
+			keystore := keystore
+			return &keystore
+		}
+	}
+	return nil
+}
+
+// keystoreKnown returns true if the default accounts of the keystore with the given root
+// fingerprint were already created.
+func (backend *Backend) keystoreKnown(rootFingerprint []byte) bool {
+	return backend.knownKeystore(rootFingerprint) != nil
+}
+
+// keystoreCoins returns the coins whose default accounts were created for the known keystore. For
+// keystores registered before the coins were recorded, these are the coins of the persisted
+// accounts of the keystore.
+func (backend *Backend) keystoreCoins(keystore *config.Keystore) map[coin.Code]struct{} {
+	result := map[coin.Code]struct{}{}
+	if keystore.Coins != nil {
+		for _, code := range keystore.Coins {
+			result[code] = struct{}{}
+		}
+		return result
+	}
+	for _, account := range backend.config.AccountsConfig().Accounts {
+		if account.RootFingerprint == keystore.RootFingerprint {
+			result[account.CoinCode] = struct{}{}
+		}
+	}
+	return result
+}
+
+// addKnownKeystore records that the default accounts of the given coins were created for the
+// keystore with the given root fingerprint.
+func (backend *Backend) addKnownKeystore(rootFingerprint []byte, coins []coin.Code) error {
+	accountsConfig := backend.config.AccountsConfig()
+	encoded := hex.EncodeToString(rootFingerprint)
+	keystores := []config.Keystore{}
+	var known *config.Keystore
+	for _, keystore := range accountsConfig.Keystores {
+		if keystore.RootFingerprint == encoded && keystore.Testing == backend.Testing() {
+			keystore := keystore
+			known = &keystore
+			continue
+		}
+		keystores = append(keystores, keystore)
+	}
+	keystoreCoins := map[coin.Code]struct{}{}
+	if known != nil {
+		keystoreCoins = backend.keystoreCoins(known)
+	}
+	for _, code := range coins {
+		keystoreCoins[code] = struct{}{}
+	}
+	codes := []coin.Code{}
+	for code := range keystoreCoins {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	accountsConfig.Keystores = append(keystores, config.Keystore{
+		RootFingerprint: encoded,
+		Testing:         backend.Testing(),
+		Coins:           codes,
+	})
+	return backend.config.SetAccountsConfig(accountsConfig)
+}
+
+// removeKeystoresCoin forgets that the default accounts of the given coin were created for the
+// known keystores, so that they are created again if the coin is added back, e.g. a custom ERC20
+// token.
+func (backend *Backend) removeKeystoresCoin(code coin.Code) error {
+	accountsConfig := backend.config.AccountsConfig()
+	keystores := make([]config.Keystore, len(accountsConfig.Keystores))
+	for index, keystore := range accountsConfig.Keystores {
+		keystores[index] = keystore
+		if keystore.Coins == nil {
+			continue
+		}
+		keystores[index].Coins = []coin.Code{}
+		for _, keystoreCoin := range keystore.Coins {
+			if keystoreCoin != code {
+				keystores[index].Coins = append(keystores[index].Coins, keystoreCoin)
+			}
+		}
+	}
+	accountsConfig.Keystores = keystores
+	return backend.config.SetAccountsConfig(accountsConfig)
+}
+
+// coinActive returns true if the coin or ERC20 token is enabled in the settings.
+func (backend *Backend) coinActive(code coin.Code) bool {
+	backendConfig := backend.config.AppConfig().Backend
+	if strings.HasPrefix(string(code), erc20CoinCodePrefix) {
+		return backendConfig.EthereumActive && backendConfig.ETH.ERC20TokenActive(
+			strings.TrimPrefix(string(code), erc20CoinCodePrefix))
+	}
+	return backendConfig.CoinActive(code)
+}
+
+// persistKeystoreAccount persists an account of the keystore with the given root fingerprint and
+// birth height, see config.Account.BirthHeight. The root fingerprint is also stored in the signing
+// configurations. Accounts which were already persisted are skipped.
+func (backend *Backend) persistKeystoreAccount(
+	keystore keystore.Keystore,
+	rootFingerprint []byte,
+	coin coin.Coin,
+	code string,
+	name string,
+	configurations signing.Configurations,
+	birthHeight uint64,
+) error {
+	err := backend.persistAccount(coin, config.Account{
+		CoinCode:        coin.Code(),
+		Code:            code,
+		Name:            name,
+		Configurations:  withRootFingerprint(configurations, rootFingerprint, keystore.CosignerIndex()),
+		RootFingerprint: hex.EncodeToString(rootFingerprint),
+		BirthHeight:     birthHeight,
+	})
+	if errp.Cause(err) == ErrAccountAlreadyExists {
+		return nil
+	}
+	return err
+}
+
+// withRootFingerprint returns a copy of the configurations in which the extended public key of the
+// keystore has the given root fingerprint. In multisig configurations, the keystore's extended
+// public key is the one at its cosigner index, and the fingerprints of the other cosigners are kept.
+func withRootFingerprint(
+	configurations signing.Configurations,
+	rootFingerprint []byte,
+	cosignerIndex int,
+) signing.Configurations {
+	result := make(signing.Configurations, len(configurations))
+	for i, configuration := range configurations {
+		result[i] = configuration
+		index := 0
+		if configuration.Multisig() {
+			index = cosignerIndex
+		}
+		numberOfKeys := len(configuration.ExtendedPublicKeys())
+		if index < 0 || index >= numberOfKeys {
+			continue
+		}
+		rootFingerprints := make([]uint32, numberOfKeys)
+		for keyIndex := range rootFingerprints {
+			rootFingerprints[keyIndex] = configuration.RootFingerprint(keyIndex)
+		}
+		rootFingerprints[index] = binary.BigEndian.Uint32(rootFingerprint)
+		result[i] = configuration.WithRootFingerprints(rootFingerprints)
+	}
+	return result
+}
+
+// withMissingRootFingerprint returns a copy of the configurations of a keystore account in which
+// the singlesig configurations without a root fingerprint get the given one, e.g. for accounts
+// persisted before the fingerprints were stored in the configurations.
+func withMissingRootFingerprint(
+	configurations signing.Configurations, rootFingerprint []byte) signing.Configurations {
+	result := make(signing.Configurations, len(configurations))
+	for i, configuration := range configurations {
+		result[i] = configuration
+		if configuration.Singlesig() && configuration.RootFingerprint(0) == 0 {
+			result[i] = configuration.WithRootFingerprints(
+				[]uint32{binary.BigEndian.Uint32(rootFingerprint)})
+		}
+	}
+	return result
+}
+
+// accountKeystoreConnected returns true if a connected keystore can sign for an account with the
+// given keystores and signing configurations, i.e. if the root fingerprint of one of the account's
+// extended public keys is one of the connected root fingerprints. Accounts without known root
+// fingerprints, e.g. the unpersisted default accounts of a keystore whose root fingerprint is not
+// available, are assumed to belong to the keystores they were loaded with.
+func accountKeystoreConnected(
+	accountKeystores *keystore.Keystores,
+	configurations signing.Configurations,
+	connectedRootFingerprints map[uint32]struct{},
+) bool {
+	if accountKeystores == nil || accountKeystores.Count() == 0 {
+		return false
+	}
+	rootFingerprintKnown := false
+	for _, configuration := range configurations {
+		for index := range configuration.ExtendedPublicKeys() {
+			rootFingerprint := configuration.RootFingerprint(index)
+			if rootFingerprint == 0 {
+				continue
+			}
+			rootFingerprintKnown = true
+			if _, ok := connectedRootFingerprints[rootFingerprint]; ok {
+				return true
+			}
+		}
+	}
+	return !rootFingerprintKnown
+}
+
+// KeystoreConnectedAccounts returns for the code of each loaded account whether a keystore which
+// can sign for the account is connected.
+func (backend *Backend) KeystoreConnectedAccounts() map[string]bool {
+	connectedRootFingerprints := map[uint32]struct{}{}
+	for _, keystore := range backend.keystores.Keystores() {
+		rootFingerprint, err := keystore.RootFingerprint()
+		if err != nil {
+			backend.log.WithError(err).Error("Could not get the root fingerprint of the keystore")
+			continue
+		}
+		connectedRootFingerprints[binary.BigEndian.Uint32(rootFingerprint)] = struct{}{}
+	}
+	result := map[string]bool{}
+	for _, account := range backend.Accounts() {
+		configurations, err := account.Config().GetSigningConfigurations()
+		if err != nil {
+			backend.log.WithError(err).Error("Could not get the signing configurations of the account")
+			continue
+		}
+		result[account.Config().Code] = accountKeystoreConnected(
+			account.Config().Keystores, configurations, connectedRootFingerprints)
+	}
+	return result
+}
+
+// updatePersistedAccount applies the change to the persisted account with the given code and
+// persists the accounts config. The updated account is returned.
+func (backend *Backend) updatePersistedAccount(
+	code string, update func(*config.Account)) (*config.Account, error) {
+	accountsConfig := backend.config.AccountsConfig()
+	accounts := append([]config.Account{}, accountsConfig.Accounts...)
+	var updated *config.Account
+	for index := range accounts {
+		if accounts[index].Code == code {
+			update(&accounts[index])
+			updated = &accounts[index]
+			break
+		}
+	}
+	if updated == nil {
+		return nil, errp.WithStack(ErrAccountNotPersisted)
+	}
+	accountsConfig.Accounts = accounts
+	if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// reloadAccount closes the loaded account with the code of the given persisted account, if any,
+// and loads it again from the persisted account.
+func (backend *Backend) reloadAccount(account *config.Account) {
+	backend.unloadAccounts(map[string]struct{}{account.Code: {}})
+	backend.loadPersistedAccount(*account, backend.registeredRootFingerprintHex())
+	backend.emitAccountsStatusChanged()
+}
+
+// RenameAccount sets the name of a persisted account.
+func (backend *Backend) RenameAccount(code string, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errp.New("The account name cannot be empty")
+	}
+	_, err := backend.updatePersistedAccount(code, func(account *config.Account) {
+		account.Name = name
+	})
+	if err != nil {
+		return err
+	}
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, account := range backend.accounts {
+			if account.Config().Code == code {
+				account.Config().Name = name
+			}
+		}
+	}()
+	backend.emitAccountsStatusChanged()
+	return nil
+}
+
+// SetAccountHidden hides or shows a persisted account. Hidden accounts are not loaded.
+func (backend *Backend) SetAccountHidden(code string, hidden bool) error {
+	account, err := backend.updatePersistedAccount(code, func(account *config.Account) {
+		account.Hidden = hidden
+	})
+	if err != nil {
+		return err
+	}
+	backend.reloadAccount(account)
+	return nil
+}
+
+// SetAccountBirthHeight sets the block height before which the node transactions source does not
+// scan for transactions of an Ethereum account. Lowering it rescans the account.
+func (backend *Backend) SetAccountBirthHeight(code string, birthHeight uint64) error {
+	account, err := backend.updatePersistedAccount(code, func(account *config.Account) {
+		account.BirthHeight = birthHeight
+	})
+	if errp.Cause(err) == ErrAccountNotPersisted {
+		return backend.setUnpersistedAccountBirthHeight(code, birthHeight)
+	}
+	if err != nil {
+		return err
+	}
+	backend.reloadAccount(account)
+	return nil
+}
+
+// setUnpersistedAccountBirthHeight sets the birth height of a loaded account which is not
+// persisted, see config.AccountsConfig.BirthHeights.
+func (backend *Backend) setUnpersistedAccountBirthHeight(code string, birthHeight uint64) error {
+	var account accounts.Interface
+	func() {
+		defer backend.accountsLock.RLock()()
+		for _, loadedAccount := range backend.accounts {
+			if loadedAccount.Config().Code == code {
+				account = loadedAccount
+			}
+		}
+	}()
+	if account == nil {
+		return errp.WithStack(ErrAccountNotPersisted)
+	}
+	identifier, err := unpersistedAccountIdentifier(
+		account.Coin(), code, account.Config().GetSigningConfigurations)
+	if err != nil {
+		return err
+	}
+	accountsConfig := backend.config.AccountsConfig()
+	birthHeights := map[string]uint64{}
+	for accountIdentifier, accountBirthHeight := range accountsConfig.BirthHeights {
+		birthHeights[accountIdentifier] = accountBirthHeight
+	}
+	if birthHeight == 0 {
+		delete(birthHeights, identifier)
+	} else {
+		birthHeights[identifier] = birthHeight
+	}
+	accountsConfig.BirthHeights = birthHeights
+	if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
+		return err
+	}
+	// Unpersisted accounts are created together with the other default accounts of the keystore,
+	// so all accounts are reloaded.
+	backend.ReinitializeAccounts()
+	return nil
+}
+
+// accountBirthHeight returns the birth height of the account with the given code, which is stored
+// in the persisted account, or by account identifier for accounts which are not persisted.
+func (backend *Backend) accountBirthHeight(
+	coin coin.Coin,
+	code string,
+	getSigningConfigurations func() (signing.Configurations, error),
+) uint64 {
+	accountsConfig := backend.config.AccountsConfig()
+	for _, persistedAccount := range accountsConfig.Accounts {
+		if persistedAccount.Code == code {
+			return persistedAccount.BirthHeight
+		}
+	}
+	if len(accountsConfig.BirthHeights) == 0 {
+		return 0
+	}
+	identifier, err := unpersistedAccountIdentifier(coin, code, getSigningConfigurations)
+	if err != nil {
+		backend.log.WithError(err).Error("Could not get the identifier of the account")
+		return 0
+	}
+	return accountsConfig.BirthHeights[identifier]
+}
+
+// ReorderAccounts moves the persisted accounts with the given codes to the front, in the given
+// order. The remaining persisted accounts keep their relative order.
+func (backend *Backend) ReorderAccounts(codes []string) error {
+	accountsConfig := backend.config.AccountsConfig()
+	remaining := append([]config.Account{}, accountsConfig.Accounts...)
+	accounts := []config.Account{}
+	for _, code := range codes {
+		found := false
+		for index, account := range remaining {
+			if account.Code == code {
+				accounts = append(accounts, account)
+				remaining = append(remaining[:index], remaining[index+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errp.WithStack(ErrAccountNotPersisted)
+		}
+	}
+	accountsConfig.Accounts = append(accounts, remaining...)
+	if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
+		return err
+	}
+	// The accounts are listed in the order of the accounts config, so they don't need reloading.
+	backend.emitAccountsStatusChanged()
+	return nil
+}
+
+// RemoveAccount removes a persisted account and deletes its cached files, like the transactions
+// database.
+func (backend *Backend) RemoveAccount(code string) error {
+	return backend.removeAccounts(func(account *config.Account) bool {
+		return account.Code == code
+	})
+}
+
+// unpersistedAccountIdentifier returns the identifier the files of the account which is not
+// persisted are named after, see persistedAccountIdentifier().
+func unpersistedAccountIdentifier(
+	coin coin.Coin,
+	code string,
+	getSigningConfigurations func() (signing.Configurations, error),
+) (string, error) {
+	configurations, err := getSigningConfigurations()
+	if err != nil {
+		return "", err
+	}
+	return persistedAccountIdentifier(&config.Account{
+		CoinCode:       coin.Code(),
+		Code:           code,
+		Configurations: configurations,
+	})
+}
+
+// persistedAccountIdentifier returns the identifier the files of the persisted account are named
+// after, see Initialize() of the accounts.
+func persistedAccountIdentifier(account *config.Account) (string, error) {
+	switch account.CoinCode {
+	case coin.CodeBTC, coin.CodeTBTC, coin.CodeRBTC, coin.CodeLTC, coin.CodeTLTC:
+		return btc.AccountIdentifier(account.Code, account.Configurations), nil
+	default:
+		// Ethereum and ERC20 token accounts.
+		return eth.AccountIdentifier(account.Code, account.Configurations)
+	}
+}
+
+// accountFiles returns the files folder, the transactions database and the notes of the account
+// with the given identifier.
+func accountFiles(dbFolder string, notesFolder string, accountIdentifier string) []string {
+	return []string{
+		path.Join(dbFolder, accountIdentifier),
+		path.Join(dbFolder, accountIdentifier+".db"),
+		path.Join(notesFolder, accountIdentifier+".json"),
+	}
+}
+
+// removeAccountFiles deletes the transactions database and the notes of the persisted account.
+func removeAccountFiles(dbFolder string, notesFolder string, account *config.Account) error {
+	accountIdentifier, err := persistedAccountIdentifier(account)
+	if err != nil {
+		return err
+	}
+	for _, file := range accountFiles(dbFolder, notesFolder, accountIdentifier) {
+		if err := os.RemoveAll(file); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// moveAccountFiles moves the transactions database and the notes of the account `from` to the
+// account `to`, e.g. when the code of an account changes. Files which already exist for `to` are
+// not overwritten.
+func moveAccountFiles(dbFolder string, notesFolder string, from, to *config.Account) error {
+	fromIdentifier, err := persistedAccountIdentifier(from)
+	if err != nil {
+		return err
+	}
+	toIdentifier, err := persistedAccountIdentifier(to)
+	if err != nil {
+		return err
+	}
+	toFiles := accountFiles(dbFolder, notesFolder, toIdentifier)
+	for index, fromFile := range accountFiles(dbFolder, notesFolder, fromIdentifier) {
+		if _, err := os.Stat(fromFile); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(toFiles[index]); err == nil {
+			continue
+		}
+		if err := os.Rename(fromFile, toFiles[index]); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// removeAccounts removes the persisted accounts matching the filter, unloads them and deletes their
+// cached files and notes, also of hidden accounts. ErrAccountNotPersisted is returned if no
+// account matches.
+func (backend *Backend) removeAccounts(filter func(*config.Account) bool) error {
+	accountsConfig := backend.config.AccountsConfig()
+	accounts := []config.Account{}
+	removedAccounts := []config.Account{}
+	removedCodes := map[string]struct{}{}
+	for _, account := range accountsConfig.Accounts {
+		account := account
+		if filter(&account) {
+			removedAccounts = append(removedAccounts, account)
+			removedCodes[account.Code] = struct{}{}
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	if len(removedAccounts) == 0 {
+		return errp.WithStack(ErrAccountNotPersisted)
+	}
+
+	accountsConfig.Accounts = accounts
+	if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
+		return err
+	}
+	// The accounts must be closed before their databases are deleted.
+	backend.unloadAccounts(removedCodes)
+	backend.emitAccountsStatusChanged()
+
+	for index := range removedAccounts {
+		err := removeAccountFiles(
+			backend.arguments.CacheDirectoryPath(),
+			backend.arguments.NotesDirectoryPath(),
+			&removedAccounts[index],
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
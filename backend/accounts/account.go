@@ -29,14 +29,36 @@ import (
 // AddressList is a list of addresses.
 type AddressList []Address
 
+// Recipient is an output of a tx proposal.
+type Recipient struct {
+	Address string
+	Amount  coin.SendAmount
+}
+
 // TxProposalArgs are the arguments needed when creating a tx proposal.
 type TxProposalArgs struct {
 	RecipientAddress string
 	Amount           coin.SendAmount
-	FeeTargetCode    FeeTargetCode
+	// Recipients, if not empty, are the outputs of a batch payment, replacing RecipientAddress and
+	// Amount. At most one of them can send all remaining funds. Only supported by UTXO based coins.
+	Recipients    []Recipient
+	FeeTargetCode FeeTargetCode
+	// CustomFee is the user provided fee rate in sat/vB if FeeTargetCode is FeeTargetCodeCustom. It
+	// is user input and validated when creating the proposal.
+	CustomFee string
+	// AllowHighFeeRate is true if the user confirmed a custom fee rate far above the fee estimates,
+	// which is otherwise rejected with errors.ErrFeeRateTooHigh.
+	AllowHighFeeRate bool
 	SelectedUTXOs    map[wire.OutPoint]struct{}
-	Data             []byte
-	Note             string
+	// CoinSelection is the strategy to select coins among the (selected) UTXOs. The zero value
+	// means the default strategy. It is ignored if a recipient receives all remaining funds, as all
+	// (selected) UTXOs are spent then.
+	CoinSelection CoinSelectionStrategy
+	// LockTime is the absolute locktime of the tx, a block height or a unix timestamp (see BIP65).
+	// The zero value means no locktime. Only supported by UTXO based coins.
+	LockTime uint32
+	Data     []byte
+	Note     string
 }
 
 // Interface is the API of a Account.
@@ -85,6 +107,8 @@ type Interface interface {
 	ProposeTxNote(string)
 	// SetTxNote sets a tx note and refreshes the account.
 	SetTxNote(txID string, note string) error
+	// SetAddressLabel sets an address label and refreshes the account.
+	SetAddressLabel(address string, label string) error
 
 	// ExportCSV exports the given transaction in CSV format (comma-separated).
 	ExportCSV(w io.Writer, transactions []*TransactionData) error
@@ -93,4 +117,7 @@ type Interface interface {
 // Info holds account information.
 type Info struct {
 	SigningConfigurations []*signing.Configuration `json:"signingConfigurations"`
+	// Descriptors are the output script descriptors (BIP380) of the signing configurations, if
+	// the coin supports them. Keys whose root fingerprint is unknown have no key origin.
+	Descriptors []string `json:"descriptors,omitempty"`
 }
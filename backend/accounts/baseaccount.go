@@ -49,6 +49,9 @@ type AccountConfig struct {
 	RateUpdater              *rates.RateUpdater
 	GetSigningConfigurations func() (signing.Configurations, error)
 	GetNotifier              func(signing.Configurations) Notifier
+	// BirthHeight is the block height before which no transactions are expected. Only used by
+	// Ethereum accounts.
+	BirthHeight uint64
 }
 
 // BaseAccount is an account struct with common functionality to all coin accounts.
@@ -179,6 +182,16 @@ func (account *BaseAccount) SetTxNote(txID string, note string) error {
 	return nil
 }
 
+// SetAddressLabel implements accounts.Account.
+func (account *BaseAccount) SetAddressLabel(address string, label string) error {
+	if err := account.notes.SetAddressLabel(address, label); err != nil {
+		return err
+	}
+	// Prompt refresh.
+	account.config.OnEvent(EventStatusChanged)
+	return nil
+}
+
 // ExportCSV implements accounts.Account.
 func (account *BaseAccount) ExportCSV(w io.Writer, transactions []*TransactionData) error {
 	writer := csv.NewWriter(w)
@@ -0,0 +1,56 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// CoinSelectionStrategy models how the coins spent by a transaction are selected. See the constants
+// below. Only relevant for UTXO based coins.
+type CoinSelectionStrategy string
+
+// NewCoinSelectionStrategy checks if the code is valid and returns a CoinSelectionStrategy in that
+// case.
+func NewCoinSelectionStrategy(code string) (CoinSelectionStrategy, error) {
+	switch code {
+	case "":
+		return DefaultCoinSelectionStrategy, nil
+	case string(CoinSelectionLargestFirst):
+	case string(CoinSelectionBranchAndBound):
+	case string(CoinSelectionSingleRandomDraw):
+	case string(CoinSelectionMinimizeLinkage):
+	default:
+		return "", errp.WithStack(errp.Newf("Unrecognized coin selection strategy %s", code))
+	}
+	return CoinSelectionStrategy(code), nil
+}
+
+const (
+	// CoinSelectionLargestFirst selects the largest coins first.
+	CoinSelectionLargestFirst CoinSelectionStrategy = "largestFirst"
+
+	// CoinSelectionBranchAndBound looks for a selection that does not need a change output.
+	CoinSelectionBranchAndBound CoinSelectionStrategy = "branchAndBound"
+
+	// CoinSelectionSingleRandomDraw selects coins in random order.
+	CoinSelectionSingleRandomDraw CoinSelectionStrategy = "singleRandomDraw"
+
+	// CoinSelectionMinimizeLinkage prefers coins from as few addresses as possible.
+	CoinSelectionMinimizeLinkage CoinSelectionStrategy = "minimizeLinkage"
+
+	// DefaultCoinSelectionStrategy is the default coin selection strategy.
+	DefaultCoinSelectionStrategy = CoinSelectionLargestFirst
+)
@@ -32,4 +32,18 @@ var (
 	// ErrInsufficientFunds is returned when there are not enough funds to cover the target amount
 	// and fee.
 	ErrInsufficientFunds = TxValidationError("insufficientFunds")
+	// ErrInvalidFeeRate is used when the user entered fee rate is malformatted or not positive.
+	ErrInvalidFeeRate = TxValidationError("invalidFeeRate")
+	// ErrFeeRateTooLow is used when the user entered fee rate is below the minimum relay fee rate,
+	// so the transaction would not be relayed by the network.
+	ErrFeeRateTooLow = TxValidationError("feeRateTooLow")
+	// ErrFeeRateTooHigh is used when the user entered fee rate is far above the highest fee
+	// estimate, which is most likely a mistake. It is a warning: the user can confirm the fee rate,
+	// see TxProposalArgs.AllowHighFeeRate.
+	ErrFeeRateTooHigh = TxValidationError("feeRateTooHigh")
+	// ErrCustomFeeNotSupported is used when a custom fee rate is requested where only the estimated
+	// fee targets are supported.
+	ErrCustomFeeNotSupported = TxValidationError("customFeeNotSupported")
+	// ErrInvalidLockTime is used when the user entered locktime is not in the future.
+	ErrInvalidLockTime = TxValidationError("invalidLockTime")
 )
@@ -34,4 +34,8 @@ const (
 
 	// EventFeeTargetsChanged is fired when the fee targets change.
 	EventFeeTargetsChanged Event = "feeTargetsChanged"
+
+	// EventScheduledTxsChanged is fired when a timelocked transaction is scheduled, broadcast or
+	// fails to be broadcast.
+	EventScheduledTxsChanged Event = "scheduledTxsChanged"
 )
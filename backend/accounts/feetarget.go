@@ -36,6 +36,7 @@ func NewFeeTargetCode(code string) (FeeTargetCode, error) {
 	case string(FeeTargetCodeEconomy):
 	case string(FeeTargetCodeNormal):
 	case string(FeeTargetCodeHigh):
+	case string(FeeTargetCodeCustom):
 	default:
 		return "", errp.WithStack(errp.Newf("Unrecognized fee target code %s", code))
 	}
@@ -55,6 +56,10 @@ const (
 	// FeeTargetCodeHigh is the high priority fee target.
 	FeeTargetCodeHigh FeeTargetCode = "high"
 
+	// FeeTargetCodeCustom means that the fee rate is provided by the user, see
+	// TxProposalArgs.CustomFee.
+	FeeTargetCodeCustom FeeTargetCode = "custom"
+
 	// DefaultFeeTarget is the default fee target
 	DefaultFeeTarget = FeeTargetCodeNormal
 )
@@ -12,7 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package notes provides functionality to retrieve and store account transaction notes.
+// Package notes provides functionality to retrieve and store account transaction notes and address
+// labels.
 package notes
 
 import (
@@ -28,10 +29,12 @@ const maxNoteLen = 1024
 
 // NotesData is the notes JSON data serialized to disk.
 type notesData struct {
-	// More fields to be added when we can label more stuff, e.g. receive addresses, utxos, etc.
+	// More fields to be added when we can label more stuff.
 
 	// a map of transaction ID to transaction note.
 	TransactionNotes map[string]string `json:"transactions"`
+	// a map of address to address label.
+	AddressLabels map[string]string `json:"addresses"`
 }
 
 // read deserializes the json files into notes. If the file does not exist yet, no error is
@@ -65,7 +68,8 @@ func write(data *notesData, filename string) error {
 	return nil
 }
 
-// Notes is a high level helper for notes, allowing you to read and set notes for transactions.
+// Notes is a high level helper for notes, allowing you to read and set notes for transactions and
+// labels for addresses.
 type Notes struct {
 	filename string
 	data     *notesData
@@ -85,10 +89,9 @@ func LoadNotes(filename string) (*Notes, error) {
 	}, nil
 }
 
-// SetTxNote stores a note for a transaction. An empty note will result in the entry being deleted
-// (or not written if it didn't exist), since `TxNote()` returns an empty string anyway if there is
-// no note.
-func (notes *Notes) SetTxNote(txID string, note string) error {
+// set stores a note in the given map, allocating it if needed, and persists all notes. An empty
+// note will result in the entry being deleted (or not written if it didn't exist).
+func (notes *Notes) set(entries *map[string]string, key string, note string) error {
 	notes.dataMu.Lock()
 	defer notes.dataMu.Unlock()
 
@@ -96,19 +99,26 @@ func (notes *Notes) SetTxNote(txID string, note string) error {
 		return errp.Newf("Length of note must be smaller than %d. Got %d", maxNoteLen, len(note))
 	}
 
-	if notes.data.TransactionNotes == nil {
-		notes.data.TransactionNotes = map[string]string{}
+	if *entries == nil {
+		*entries = map[string]string{}
 	}
 	if note == "" {
 		// Since not existing entries are returned as `""` anyway, there no need to actually store
 		// them in the JSON file.
-		delete(notes.data.TransactionNotes, txID)
+		delete(*entries, key)
 	} else {
-		notes.data.TransactionNotes[txID] = note
+		(*entries)[key] = note
 	}
 	return write(notes.data, notes.filename)
 }
 
+// SetTxNote stores a note for a transaction. An empty note will result in the entry being deleted
+// (or not written if it didn't exist), since `TxNote()` returns an empty string anyway if there is
+// no note.
+func (notes *Notes) SetTxNote(txID string, note string) error {
+	return notes.set(&notes.data.TransactionNotes, txID, note)
+}
+
 // TxNote fetches a note for a transcation. Returns the empty string if no note was found.
 func (notes *Notes) TxNote(txID string) string {
 	notes.dataMu.RLock()
@@ -116,3 +126,17 @@ func (notes *Notes) TxNote(txID string) string {
 
 	return notes.data.TransactionNotes[txID]
 }
+
+// SetAddressLabel stores a label for an address, e.g. to remember the purpose of a receive address
+// before it is used. An empty label deletes the entry.
+func (notes *Notes) SetAddressLabel(address string, label string) error {
+	return notes.set(&notes.data.AddressLabels, address, label)
+}
+
+// AddressLabel fetches the label of an address. Returns the empty string if no label was found.
+func (notes *Notes) AddressLabel(address string) string {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+
+	return notes.data.AddressLabels[address]
+}
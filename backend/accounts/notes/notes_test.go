@@ -12,7 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package notes provides functionality to retrieve and store account transaction notes.
+// Package notes provides functionality to retrieve and store account transaction notes and address
+// labels.
 package notes
 
 import (
@@ -41,6 +42,27 @@ func TestNotes(t *testing.T) {
 	require.Equal(t, "note for tx-id-2", notes.TxNote("tx-id-2"))
 }
 
+func TestAddressLabels(t *testing.T) {
+	filename := test.TstTempFile("account-notes")
+	notes, err := LoadNotes(filename)
+	require.NoError(t, err)
+
+	require.Equal(t, "", notes.AddressLabel("address-1"))
+	require.NoError(t, notes.SetAddressLabel("address-1", "invoice 1043"))
+	require.NoError(t, notes.SetTxNote("address-1", "a tx note"))
+	require.Equal(t, "invoice 1043", notes.AddressLabel("address-1"))
+	require.Equal(t, "a tx note", notes.TxNote("address-1"))
+
+	// Reload notes.
+	notes, err = LoadNotes(filename)
+	require.NoError(t, err)
+	require.Equal(t, "invoice 1043", notes.AddressLabel("address-1"))
+
+	require.NoError(t, notes.SetAddressLabel("address-1", ""))
+	require.Equal(t, "", notes.AddressLabel("address-1"))
+	require.Error(t, notes.SetAddressLabel("address-1", strings.Repeat("x", 1025)))
+}
+
 // TestNotesPersisted checks that notes are persisted.
 func TestNotesPersisted(t *testing.T) {
 	filename := test.TstTempFile("account-notes")
@@ -0,0 +1,71 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// sendAllCSVAmount is the amount in a recipients CSV file that sends all remaining funds.
+const sendAllCSVAmount = "all"
+
+// ParseRecipientsCSV parses a list of recipients for a batch payment. Each row has two columns, the
+// address and the amount in the default unit of the coin (e.g. BTC). The amount of at most one row
+// can be "all", sending all remaining funds. An optional header row with the column names
+// "address" and "amount" is skipped. The addresses and amounts are not validated.
+func ParseRecipientsCSV(reader io.Reader) ([]Recipient, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+	recipients := []Recipient{}
+	sendAll := false
+	for row := 0; ; row++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		address := strings.TrimSpace(record[0])
+		amount := strings.TrimSpace(record[1])
+		if row == 0 && strings.EqualFold(address, "address") && strings.EqualFold(amount, "amount") {
+			continue
+		}
+		if address == "" {
+			return nil, errp.Newf("Missing address in row %d", row+1)
+		}
+		recipient := Recipient{Address: address}
+		if strings.EqualFold(amount, sendAllCSVAmount) {
+			if sendAll {
+				return nil, errp.Newf("Only one recipient can receive all remaining funds (row %d)", row+1)
+			}
+			sendAll = true
+			recipient.Amount = coin.NewSendAmountAll()
+		} else {
+			recipient.Amount = coin.NewSendAmount(amount)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) == 0 {
+		return nil, errp.New("No recipients found")
+	}
+	return recipients, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecipientsCSV(t *testing.T) {
+	recipients, err := ParseRecipientsCSV(strings.NewReader(
+		"Address,Amount\n" +
+			"tb1qxyz, 0.1\n" +
+			"\n" +
+			"mabc,2\n" +
+			"n123,all\n"))
+	require.NoError(t, err)
+	require.Equal(t, []Recipient{
+		{Address: "tb1qxyz", Amount: coin.NewSendAmount("0.1")},
+		{Address: "mabc", Amount: coin.NewSendAmount("2")},
+		{Address: "n123", Amount: coin.NewSendAmountAll()},
+	}, recipients)
+
+	// Without header.
+	recipients, err = ParseRecipientsCSV(strings.NewReader("tb1qxyz,0.1"))
+	require.NoError(t, err)
+	require.Equal(t, []Recipient{{Address: "tb1qxyz", Amount: coin.NewSendAmount("0.1")}}, recipients)
+
+	for _, invalid := range []string{
+		"",
+		"address,amount\n",
+		"tb1qxyz\n",
+		"tb1qxyz,0.1,extra\n",
+		",0.1\n",
+		"tb1qxyz,all\nmabc,ALL\n",
+	} {
+		_, err := ParseRecipientsCSV(strings.NewReader(invalid))
+		require.Error(t, err, invalid)
+	}
+}
@@ -53,6 +53,8 @@ type AddressAndAmount struct {
 	Amount coin.Amount
 	// Ours is true if the address is one of our receive addresses.
 	Ours bool
+	// Label is the user defined label of the address, if any.
+	Label string
 }
 
 // TransactionData holds transaction data to be shown to the user. It is as coin-agnostic as
@@ -102,4 +104,7 @@ type TransactionData struct {
 	// --- Fields only used for ETH follow
 
 	Gas uint64
+	// ReplacesTxID is the ID of the pending transaction which was replaced by this transaction to
+	// speed it up or to cancel it. Empty if it is not a replacement.
+	ReplacesTxID string
 }
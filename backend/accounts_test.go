@@ -0,0 +1,606 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/arguments"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/usb"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestXPub(t *testing.T, seed byte) *hdkeychain.ExtendedKey {
+	t.Helper()
+	master, err := hdkeychain.NewMaster(append(make([]byte, 31), seed), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	xpub, err := master.Neuter()
+	require.NoError(t, err)
+	return xpub
+}
+
+type testEnvironment struct{}
+
+func (testEnvironment) NotifyUser(string)             {}
+func (testEnvironment) DeviceInfos() []usb.DeviceInfo { return nil }
+func (testEnvironment) SystemOpen(string) error       { return nil }
+func (testEnvironment) UsingMobileData() bool         { return false }
+func (testEnvironment) NativeLocale() string          { return "" }
+
+// newTestBackend returns a testnet backend with a registered software keystore. The backend has to
+// be closed and its main directory removed by the caller.
+func newTestBackend(t *testing.T) (*Backend, string) {
+	t.Helper()
+	mainDirectoryPath, err := ioutil.TempDir("", "backend")
+	require.NoError(t, err)
+	backend, err := NewBackend(arguments.NewArguments(
+		mainDirectoryPath,
+		true,  // testing
+		false, // regtest
+		false, // devmode
+		false, // devservers
+		nil,   // gap limits
+	), testEnvironment{})
+	require.NoError(t, err)
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+	master, err := hdkeychain.NewMaster(make([]byte, 32), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.NoError(t, backend.keystores.Add(software.NewKeystore(0, master)))
+	return backend, mainDirectoryPath
+}
+
+func TestWithMissingRootFingerprint(t *testing.T) {
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/0'")
+	require.NoError(t, err)
+	withoutFingerprint := signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKH, keypath, newTestXPub(t, 1))
+	withFingerprint := signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2TR, keypath, newTestXPub(t, 2)).WithRootFingerprints([]uint32{0x11223344})
+	configurations := withMissingRootFingerprint(
+		signing.Configurations{withoutFingerprint, withFingerprint}, []byte{0xd3, 0x4d, 0xb3, 0x3f})
+	require.Equal(t, uint32(0xd34db33f), configurations[0].RootFingerprint(0))
+	require.Equal(t, uint32(0x11223344), configurations[1].RootFingerprint(0))
+	require.Equal(t, withoutFingerprint.Hash(), configurations[0].Hash())
+	// The input is not modified.
+	require.Equal(t, uint32(0), withoutFingerprint.RootFingerprint(0))
+}
+
+func TestRemoveAccountFiles(t *testing.T) {
+	dbFolder, err := ioutil.TempDir("", "db")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dbFolder) }()
+	notesFolder, err := ioutil.TempDir("", "notes")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(notesFolder) }()
+
+	btcKeypath, err := signing.NewAbsoluteKeypath("m/84'/1'/0'")
+	require.NoError(t, err)
+	ethKeypath, err := signing.NewAbsoluteKeypath("m/44'/60'/0'/0")
+	require.NoError(t, err)
+	accounts := []*config.Account{
+		{
+			CoinCode: coin.CodeTBTC,
+			Code:     "v0-d34db33f-tbtc-0",
+			Configurations: signing.Configurations{signing.NewSinglesigConfiguration(
+				signing.ScriptTypeP2WPKH, btcKeypath, newTestXPub(t, 1))},
+		},
+		{
+			CoinCode: coin.CodeTETH,
+			Code:     "v0-d34db33f-teth-0",
+			Configurations: signing.Configurations{signing.NewSinglesigConfiguration(
+				signing.ScriptTypeP2WPKH, ethKeypath, newTestXPub(t, 2))},
+		},
+	}
+	// Another account's files are kept.
+	otherFile := path.Join(dbFolder, "account-other.db")
+	require.NoError(t, ioutil.WriteFile(otherFile, nil, 0600))
+
+	for _, account := range accounts {
+		accountIdentifier, err := persistedAccountIdentifier(account)
+		require.NoError(t, err)
+		filesFolder := path.Join(dbFolder, accountIdentifier)
+		require.NoError(t, os.MkdirAll(filesFolder, 0700))
+		files := []string{
+			path.Join(filesFolder, "headers.db"),
+			filesFolder + ".db",
+			path.Join(notesFolder, accountIdentifier+".json"),
+		}
+		for _, file := range files {
+			require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+		}
+
+		require.NoError(t, removeAccountFiles(dbFolder, notesFolder, account))
+		for _, file := range append(files, filesFolder) {
+			_, err := os.Stat(file)
+			require.True(t, os.IsNotExist(err), file)
+		}
+		// Removing the files of an account without files does not fail.
+		require.NoError(t, removeAccountFiles(dbFolder, notesFolder, account))
+	}
+	_, err = os.Stat(otherFile)
+	require.NoError(t, err)
+}
+
+func TestCreateAndAddKeystoreAccount(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	tbtc, err := backend.Coin(coin.CodeTBTC)
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/1'")
+	require.NoError(t, err)
+
+	for _, name := range []string{"", "  \t "} {
+		_, err := backend.CreateAndAddKeystoreAccount(tbtc, name, signing.ScriptTypeP2WPKH, keypath)
+		require.Error(t, err)
+	}
+	require.Empty(t, backend.config.AccountsConfig().Accounts)
+	require.Empty(t, backend.Accounts())
+
+	code, err := backend.CreateAndAddKeystoreAccount(
+		tbtc, " Savings ", signing.ScriptTypeP2WPKH, keypath)
+	require.NoError(t, err)
+	rootFingerprint, err := backend.registeredRootFingerprint()
+	require.NoError(t, err)
+	persistedAccounts := backend.config.AccountsConfig().Accounts
+	require.Len(t, persistedAccounts, 1)
+	require.Equal(t, code, persistedAccounts[0].Code)
+	require.Equal(t, "Savings", persistedAccounts[0].Name)
+	require.Equal(t, hex.EncodeToString(rootFingerprint), persistedAccounts[0].RootFingerprint)
+	require.Len(t, backend.Accounts(), 1)
+	require.Equal(t, code, backend.Accounts()[0].Config().Code)
+	require.Equal(t, "Savings", backend.Accounts()[0].Config().Name)
+
+	// The same account can't be added twice.
+	_, err = backend.CreateAndAddKeystoreAccount(tbtc, "Savings", signing.ScriptTypeP2WPKH, keypath)
+	require.Equal(t, ErrAccountAlreadyExists, errp.Cause(err))
+
+	// Ethereum accounts are not supported by the software keystore.
+	teth, err := backend.Coin(coin.CodeTETH)
+	require.NoError(t, err)
+	_, err = backend.CreateAndAddKeystoreAccount(teth, "Ethereum", signing.ScriptTypeP2WPKH, keypath)
+	require.Equal(t, ErrAccountNotSupported, errp.Cause(err))
+}
+
+func TestWithRootFingerprint(t *testing.T) {
+	keypath, err := signing.NewAbsoluteKeypath("m/48'/1'/0'/2'")
+	require.NoError(t, err)
+	singlesig := signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKH, keypath, newTestXPub(t, 1))
+	multisig := signing.NewConfiguration(
+		signing.ScriptTypeP2WSH,
+		keypath,
+		[]*hdkeychain.ExtendedKey{newTestXPub(t, 1), newTestXPub(t, 2), newTestXPub(t, 3)},
+		"",
+		2,
+	).WithRootFingerprints([]uint32{0x11111111, 0, 0x33333333})
+
+	configurations := withRootFingerprint(
+		signing.Configurations{singlesig, multisig}, []byte{0xd3, 0x4d, 0xb3, 0x3f}, 1)
+	require.Equal(t, uint32(0xd34db33f), configurations[0].RootFingerprint(0))
+	require.Equal(t, uint32(0x11111111), configurations[1].RootFingerprint(0))
+	require.Equal(t, uint32(0xd34db33f), configurations[1].RootFingerprint(1))
+	require.Equal(t, uint32(0x33333333), configurations[1].RootFingerprint(2))
+	require.Equal(t, multisig.Hash(), configurations[1].Hash())
+	// The input is not modified.
+	require.Equal(t, uint32(0), singlesig.RootFingerprint(0))
+	require.Equal(t, uint32(0), multisig.RootFingerprint(1))
+
+	// A cosigner index out of range leaves the multisig configuration as is.
+	configurations = withRootFingerprint(
+		signing.Configurations{multisig}, []byte{0xd3, 0x4d, 0xb3, 0x3f}, 3)
+	require.Equal(t, multisig, configurations[0])
+}
+
+func TestMoveAccountFiles(t *testing.T) {
+	dbFolder, err := ioutil.TempDir("", "db")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dbFolder) }()
+	notesFolder, err := ioutil.TempDir("", "notes")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(notesFolder) }()
+
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/0'")
+	require.NoError(t, err)
+	configurations := signing.Configurations{signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKH, keypath, newTestXPub(t, 1))}
+	from := &config.Account{CoinCode: coin.CodeTBTC, Code: "tbtc", Configurations: configurations}
+	to := &config.Account{
+		CoinCode: coin.CodeTBTC, Code: "d34db33f-tbtc", Configurations: configurations}
+	fromIdentifier, err := persistedAccountIdentifier(from)
+	require.NoError(t, err)
+	toIdentifier, err := persistedAccountIdentifier(to)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(path.Join(dbFolder, fromIdentifier), 0700))
+	require.NoError(t, ioutil.WriteFile(path.Join(dbFolder, fromIdentifier+".db"), []byte("db"), 0600))
+	require.NoError(t, ioutil.WriteFile(
+		path.Join(notesFolder, fromIdentifier+".json"), []byte("from"), 0600))
+	// Existing files are not overwritten.
+	require.NoError(t, ioutil.WriteFile(
+		path.Join(notesFolder, toIdentifier+".json"), []byte("to"), 0600))
+
+	require.NoError(t, moveAccountFiles(dbFolder, notesFolder, from, to))
+	info, err := os.Stat(path.Join(dbFolder, toIdentifier))
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+	db, err := ioutil.ReadFile(path.Join(dbFolder, toIdentifier+".db"))
+	require.NoError(t, err)
+	require.Equal(t, "db", string(db))
+	notes, err := ioutil.ReadFile(path.Join(notesFolder, toIdentifier+".json"))
+	require.NoError(t, err)
+	require.Equal(t, "to", string(notes))
+	_, err = os.Stat(path.Join(dbFolder, fromIdentifier+".db"))
+	require.True(t, os.IsNotExist(err))
+
+	// Moving the files of an account without files does not fail.
+	require.NoError(t, moveAccountFiles(dbFolder, notesFolder, to, from))
+	require.NoError(t, moveAccountFiles(dbFolder, notesFolder, from, to))
+}
+
+func TestInitDefaultAccountsMigratesFiles(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	keystore := backend.keystores.Keystores()[0]
+	accounts, err := backend.defaultAccounts(keystore, nil)
+	require.NoError(t, err)
+	require.Equal(t, "tbtc", accounts[0].code)
+	require.Len(t, accounts[0].configurations, 4)
+	rootFingerprint, err := keystore.RootFingerprint()
+	require.NoError(t, err)
+
+	// The account as created by previous versions, without taproot and the root fingerprint.
+	unpersisted := &config.Account{
+		CoinCode:       coin.CodeTBTC,
+		Code:           "tbtc",
+		Configurations: accounts[0].configurations[:3],
+	}
+	unpersistedIdentifier, err := persistedAccountIdentifier(unpersisted)
+	require.NoError(t, err)
+	notesFile := path.Join(backend.arguments.NotesDirectoryPath(), unpersistedIdentifier+".json")
+	require.NoError(t, os.MkdirAll(backend.arguments.NotesDirectoryPath(), 0700))
+	require.NoError(t, ioutil.WriteFile(notesFile, []byte("{}"), 0600))
+
+	backend.initDefaultAccounts()
+	require.True(t, backend.keystoreKnown(rootFingerprint))
+	persistedAccounts := backend.config.AccountsConfig().Accounts
+	require.Equal(t, keystoreAccountCode(rootFingerprint, "tbtc"), persistedAccounts[0].Code)
+	identifier, err := persistedAccountIdentifier(&persistedAccounts[0])
+	require.NoError(t, err)
+	_, err = os.Stat(notesFile)
+	require.True(t, os.IsNotExist(err))
+	notes, err := ioutil.ReadFile(
+		path.Join(backend.arguments.NotesDirectoryPath(), identifier+".json"))
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(notes))
+}
+
+func TestInitDefaultAccountsOfNewCoins(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	rootFingerprint, err := backend.keystores.Keystores()[0].RootFingerprint()
+	require.NoError(t, err)
+	accountCoins := func() []coin.Code {
+		codes := []coin.Code{}
+		for _, account := range backend.config.AccountsConfig().Accounts {
+			codes = append(codes, account.CoinCode)
+		}
+		return codes
+	}
+	// setAccountsConfig simulates a keystore registered when only the default accounts of the
+	// given coins were created, with the given persisted accounts.
+	setAccountsConfig := func(coins []coin.Code, accountCoins ...coin.Code) {
+		accountsConfig := backend.config.AccountsConfig()
+		accounts := []config.Account{}
+		for _, account := range accountsConfig.Accounts {
+			for _, accountCoin := range accountCoins {
+				if account.CoinCode == accountCoin {
+					accounts = append(accounts, account)
+				}
+			}
+		}
+		accountsConfig.Accounts = accounts
+		accountsConfig.Keystores[0].Coins = coins
+		require.NoError(t, backend.config.SetAccountsConfig(accountsConfig))
+	}
+
+	backend.initDefaultAccounts()
+	require.Equal(t, []coin.Code{coin.CodeTBTC, coin.CodeTLTC}, accountCoins())
+	require.Len(t, backend.config.AccountsConfig().Keystores, 1)
+	require.Equal(t, []coin.Code{coin.CodeTBTC, coin.CodeTLTC},
+		backend.config.AccountsConfig().Keystores[0].Coins)
+
+	// The default accounts of coins added later are created.
+	setAccountsConfig([]coin.Code{coin.CodeTBTC}, coin.CodeTBTC)
+	backend.initDefaultAccounts()
+	require.Equal(t, []coin.Code{coin.CodeTBTC, coin.CodeTLTC}, accountCoins())
+	require.Equal(t, []coin.Code{coin.CodeTBTC, coin.CodeTLTC},
+		backend.config.AccountsConfig().Keystores[0].Coins)
+
+	// Removed default accounts are not created again.
+	setAccountsConfig([]coin.Code{coin.CodeTBTC, coin.CodeTLTC}, coin.CodeTLTC)
+	backend.initDefaultAccounts()
+	require.Equal(t, []coin.Code{coin.CodeTLTC}, accountCoins())
+
+	// For keystores registered before the coins were recorded, the coins of the persisted accounts
+	// are assumed to be created.
+	setAccountsConfig(nil, coin.CodeTBTC)
+	backend.initDefaultAccounts()
+	require.Equal(t, []coin.Code{coin.CodeTBTC, coin.CodeTLTC}, accountCoins())
+	require.Len(t, backend.config.AccountsConfig().Keystores, 1)
+	require.True(t, backend.keystoreKnown(rootFingerprint))
+	require.Equal(t, []coin.Code{coin.CodeTBTC, coin.CodeTLTC},
+		backend.config.AccountsConfig().Keystores[0].Coins)
+
+	// Removed custom tokens are forgotten, so that their accounts are created again if they are
+	// added back.
+	require.NoError(t, backend.removeKeystoresCoin(coin.CodeTLTC))
+	require.Equal(t, []coin.Code{coin.CodeTBTC}, backend.config.AccountsConfig().Keystores[0].Coins)
+}
+
+// ethKeystore is a software keystore which also supports Ethereum accounts.
+type ethKeystore struct {
+	*software.Keystore
+}
+
+func (keystore ethKeystore) SupportsAccount(coin coin.Coin, multisig bool, meta interface{}) bool {
+	if _, ok := coin.(*eth.Coin); ok {
+		return !multisig
+	}
+	return keystore.Keystore.SupportsAccount(coin, multisig, meta)
+}
+
+func TestInitDefaultAccountsBirthHeight(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	softwareKeystore := backend.keystores.Keystores()[0]
+	require.NoError(t, backend.keystores.Remove(softwareKeystore))
+	require.NoError(t, backend.keystores.Add(ethKeystore{softwareKeystore.(*software.Keystore)}))
+
+	// An Ethereum node at block 1234.
+	var blockNumberCalls int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "eth_getBlockByNumber", request.Method)
+		atomic.AddInt32(&blockNumberCalls, 1)
+		header := &types.Header{Number: big.NewInt(1234), Difficulty: big.NewInt(0)}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": request.ID, "result": header}))
+	}))
+	defer node.Close()
+	appConfig := backend.config.AppConfig()
+	appConfig.Backend.TETH.NodeURL = node.URL
+	appConfig.Backend.RETH.NodeURL = node.URL
+	require.NoError(t, backend.config.SetAppConfig(appConfig))
+
+	birthHeights := func() map[coin.Code]uint64 {
+		result := map[coin.Code]uint64{}
+		for _, account := range backend.config.AccountsConfig().Accounts {
+			result[account.CoinCode] = account.BirthHeight
+		}
+		return result
+	}
+
+	// The Ethereum accounts of a keystore new to the app start at the current block, which is
+	// fetched once per chain.
+	backend.initDefaultAccounts()
+	require.Equal(t, map[coin.Code]uint64{
+		coin.CodeTBTC:      0,
+		coin.CodeTLTC:      0,
+		coin.CodeTETH:      1234,
+		coin.CodeRETH:      1234,
+		coin.CodeERC20TEST: 1234,
+	}, birthHeights())
+	require.Equal(t, int32(2), atomic.LoadInt32(&blockNumberCalls))
+
+	// The accounts of coins added later for a known keystore might have been used before.
+	accountsConfig := backend.config.AccountsConfig()
+	accountsConfig.Accounts = accountsConfig.Accounts[:2]
+	accountsConfig.Keystores[0].Coins = []coin.Code{coin.CodeTBTC, coin.CodeTLTC}
+	require.NoError(t, backend.config.SetAccountsConfig(accountsConfig))
+	backend.initDefaultAccounts()
+	require.Equal(t, uint64(0), birthHeights()[coin.CodeTETH])
+	require.Equal(t, int32(2), atomic.LoadInt32(&blockNumberCalls))
+}
+
+// keystoreWithoutRootFingerprint is a keystore which can't return its root fingerprint, like a
+// BitBox02 with an old firmware.
+type keystoreWithoutRootFingerprint struct {
+	*software.Keystore
+}
+
+func (keystoreWithoutRootFingerprint) RootFingerprint() ([]byte, error) {
+	return nil, errp.New("unsupported")
+}
+
+func TestInitDefaultAccountsWithoutRootFingerprint(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	softwareKeystore := backend.keystores.Keystores()[0]
+	require.NoError(t, backend.keystores.Remove(softwareKeystore))
+	require.NoError(t, backend.keystores.Add(
+		keystoreWithoutRootFingerprint{softwareKeystore.(*software.Keystore)}))
+
+	backend.initDefaultAccounts()
+	require.Empty(t, backend.config.AccountsConfig().Accounts)
+	require.Empty(t, backend.config.AccountsConfig().Keystores)
+	codes := []string{}
+	for _, account := range backend.Accounts() {
+		codes = append(codes, account.Config().Code)
+	}
+	require.Equal(t, []string{"tbtc", "tltc"}, codes)
+}
+
+func TestSetAccountBirthHeight(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	loadedBirthHeight := func(code string) uint64 {
+		for _, account := range backend.Accounts() {
+			if account.Config().Code == code {
+				return account.Config().BirthHeight
+			}
+		}
+		require.FailNow(t, "account not loaded", code)
+		return 0
+	}
+
+	tbtc, err := backend.Coin(coin.CodeTBTC)
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/1'")
+	require.NoError(t, err)
+	code, err := backend.CreateAndAddKeystoreAccount(
+		tbtc, "Savings", signing.ScriptTypeP2WPKH, keypath)
+	require.NoError(t, err)
+	require.NoError(t, backend.SetAccountBirthHeight(code, 100))
+	require.Equal(t, uint64(100), backend.config.AccountsConfig().Accounts[0].BirthHeight)
+	require.Equal(t, uint64(100), loadedBirthHeight(code))
+	require.Empty(t, backend.config.AccountsConfig().BirthHeights)
+
+	// The default accounts of a keystore without root fingerprint are not persisted.
+	softwareKeystore := backend.keystores.Keystores()[0]
+	require.NoError(t, backend.keystores.Remove(softwareKeystore))
+	require.NoError(t, backend.keystores.Add(
+		keystoreWithoutRootFingerprint{softwareKeystore.(*software.Keystore)}))
+	backend.ReinitializeAccounts()
+	require.NoError(t, backend.SetAccountBirthHeight("tbtc", 200))
+	require.Equal(t, uint64(200), loadedBirthHeight("tbtc"))
+	require.Equal(t, uint64(0), loadedBirthHeight("tltc"))
+	require.Len(t, backend.config.AccountsConfig().BirthHeights, 1)
+	// The birth height is kept when the accounts are loaded again.
+	backend.ReinitializeAccounts()
+	require.Equal(t, uint64(200), loadedBirthHeight("tbtc"))
+
+	// The default accounts of another keystore without root fingerprint have the same codes, but not
+	// the same birth height.
+	require.NoError(t, backend.keystores.Remove(backend.keystores.Keystores()[0]))
+	master, err := hdkeychain.NewMaster(append(make([]byte, 31), 1), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.NoError(t, backend.keystores.Add(
+		keystoreWithoutRootFingerprint{software.NewKeystore(0, master)}))
+	backend.ReinitializeAccounts()
+	require.Equal(t, uint64(0), loadedBirthHeight("tbtc"))
+
+	require.NoError(t, backend.keystores.Remove(backend.keystores.Keystores()[0]))
+	require.NoError(t, backend.keystores.Add(
+		keystoreWithoutRootFingerprint{softwareKeystore.(*software.Keystore)}))
+	backend.ReinitializeAccounts()
+	require.NoError(t, backend.SetAccountBirthHeight("tbtc", 0))
+	require.Equal(t, uint64(0), loadedBirthHeight("tbtc"))
+	require.Empty(t, backend.config.AccountsConfig().BirthHeights)
+
+	err = backend.SetAccountBirthHeight("unknown", 100)
+	require.Equal(t, ErrAccountNotPersisted, errp.Cause(err))
+}
+
+func TestAccountKeystoreConnected(t *testing.T) {
+	keypath, err := signing.NewAbsoluteKeypath("m/48'/1'/0'/2'")
+	require.NoError(t, err)
+	singlesig := signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKH, keypath, newTestXPub(t, 1))
+	multisig := signing.NewConfiguration(
+		signing.ScriptTypeP2WSH,
+		keypath,
+		[]*hdkeychain.ExtendedKey{newTestXPub(t, 1), newTestXPub(t, 2)},
+		"",
+		2,
+	).WithRootFingerprints([]uint32{0x11111111, 0x22222222})
+	keystores := keystore.NewKeystores()
+	master, err := hdkeychain.NewMaster(make([]byte, 32), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	require.NoError(t, keystores.Add(software.NewKeystore(0, master)))
+	connected := map[uint32]struct{}{0x22222222: {}}
+
+	tests := []struct {
+		keystores      *keystore.Keystores
+		configurations signing.Configurations
+		expected       bool
+	}{
+		{nil, signing.Configurations{multisig}, false},
+		{keystore.NewKeystores(), signing.Configurations{multisig}, false},
+		{keystores, signing.Configurations{multisig}, true},
+		{keystores, signing.Configurations{
+			singlesig.WithRootFingerprints([]uint32{0x11111111})}, false},
+		{keystores, signing.Configurations{
+			singlesig.WithRootFingerprints([]uint32{0x22222222})}, true},
+		// Without known root fingerprints, the account's keystores are assumed to be its own.
+		{keystores, signing.Configurations{singlesig}, true},
+		{keystore.NewKeystores(), signing.Configurations{singlesig}, false},
+	}
+	for index, test := range tests {
+		require.Equal(t, test.expected,
+			accountKeystoreConnected(test.keystores, test.configurations, connected), index)
+	}
+}
+
+func TestKeystoreConnectedAccounts(t *testing.T) {
+	backend, mainDirectoryPath := newTestBackend(t)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	defer func() { _ = backend.Close() }()
+
+	tbtc, err := backend.Coin(coin.CodeTBTC)
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/1'")
+	require.NoError(t, err)
+	code, err := backend.CreateAndAddKeystoreAccount(
+		tbtc, "Savings", signing.ScriptTypeP2WPKH, keypath)
+	require.NoError(t, err)
+	// An account of another keystore, loaded with the connected keystores.
+	otherConfigurations := signing.Configurations{signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKH, keypath, newTestXPub(t, 1),
+	).WithRootFingerprints([]uint32{0x11223344})}
+	err = backend.CreateAndAddAccount(tbtc, "other", "Other",
+		func() (signing.Configurations, error) { return otherConfigurations, nil }, true, false)
+	require.NoError(t, err)
+
+	require.Equal(t,
+		map[string]bool{code: true, "other": false},
+		backend.KeystoreConnectedAccounts())
+}
@@ -16,6 +16,7 @@
 package backend
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -133,6 +134,10 @@ type Backend struct {
 	accounts     []accounts.Interface
 	accountsLock locker.Locker
 
+	// accountDiscoveryRunning is true while DiscoverAccounts() is scanning for used accounts.
+	accountDiscoveryRunning     bool
+	accountDiscoveryRunningLock locker.Locker
+
 	baseManager *mdns.Manager
 
 	log *logrus.Entry
@@ -226,6 +231,26 @@ func (backend *Backend) emitAccountsStatusChanged() {
 	})
 }
 
+// persistAccount adds the account to the accounts configuration. Returns ErrAccountAlreadyExists
+// if an account with the same signing configurations already exists.
+func (backend *Backend) persistAccount(coin coin.Coin, account config.Account) error {
+	if len(account.Configurations) == 0 {
+		return errp.New("An account needs at least one signing configuration")
+	}
+	if _, ok := coin.(*btc.Coin); !ok && len(account.Configurations) != 1 {
+		return errp.New("Only bitcoin-like accounts support mixed inputs")
+	}
+	accountsConfig := backend.config.AccountsConfig()
+	for _, existingAccount := range accountsConfig.Accounts {
+		if existingAccount.Configurations.Hash() == account.Configurations.Hash() &&
+			existingAccount.CoinCode == account.CoinCode {
+			return errp.WithStack(ErrAccountAlreadyExists)
+		}
+	}
+	accountsConfig.Accounts = append(accountsConfig.Accounts, account)
+	return backend.config.SetAccountsConfig(accountsConfig)
+}
+
 // CreateAndAddAccount creates an account with the given parameters and adds it to the backend. If
 // persist is true, the configuration is fetched and saved in the accounts configuration.
 func (backend *Backend) CreateAndAddAccount(
@@ -235,30 +260,33 @@ func (backend *Backend) CreateAndAddAccount(
 	getSigningConfigurations func() (signing.Configurations, error),
 	persist bool,
 	emitEvent bool,
+) error {
+	return backend.createAndAddAccount(
+		coin, code, name, getSigningConfigurations, backend.keystores, persist, emitEvent)
+}
+
+// createAndAddAccount is like CreateAndAddAccount, but the account signs with the given keystores.
+func (backend *Backend) createAndAddAccount(
+	coin coin.Coin,
+	code string,
+	name string,
+	getSigningConfigurations func() (signing.Configurations, error),
+	keystores *keystore.Keystores,
+	persist bool,
+	emitEvent bool,
 ) error {
 	if persist {
 		configurations, err := getSigningConfigurations()
 		if err != nil {
 			return err
 		}
-		if len(configurations) != 1 {
-			// TODO: unified-accounts
-			return errp.New("Watch-only accounts don't support mixed inputs yet")
-		}
-		configuration := configurations[0]
-		accountsConfig := backend.config.AccountsConfig()
-		for _, account := range accountsConfig.Accounts {
-			if account.Configuration.Hash() == configuration.Hash() && account.CoinCode == coin.Code() {
-				return errp.WithStack(ErrAccountAlreadyExists)
-			}
-		}
-		accountsConfig.Accounts = append(accountsConfig.Accounts, config.Account{
-			CoinCode:      coin.Code(),
-			Code:          code,
-			Name:          name,
-			Configuration: configuration,
+		err = backend.persistAccount(coin, config.Account{
+			CoinCode:       coin.Code(),
+			Code:           code,
+			Name:           name,
+			Configurations: configurations,
 		})
-		if err := backend.config.SetAccountsConfig(accountsConfig); err != nil {
+		if err != nil {
 			return err
 		}
 	}
@@ -269,7 +297,7 @@ func (backend *Backend) CreateAndAddAccount(
 		Name:        name,
 		DBFolder:    backend.arguments.CacheDirectoryPath(),
 		NotesFolder: backend.arguments.NotesDirectoryPath(),
-		Keystores:   backend.keystores,
+		Keystores:   keystores,
 		OnEvent: func(event accounts.Event) {
 			backend.events <- AccountEvent{Type: "account", Code: code, Data: string(event)}
 			if account != nil && event == accounts.EventSyncDone {
@@ -281,6 +309,7 @@ func (backend *Backend) CreateAndAddAccount(
 		GetNotifier: func(configurations signing.Configurations) accounts.Notifier {
 			return backend.notifier.ForAccount(fmt.Sprintf("%s-%s", configurations.Hash(), code))
 		},
+		BirthHeight: backend.accountBirthHeight(coin, code, getSigningConfigurations),
 	}
 
 	accountAdded := false
@@ -323,21 +352,26 @@ func newScriptTypeWithKeypath(scriptType signing.ScriptType, keypath string) scr
 	}
 }
 
-// adds a combined BTC account with the given script types. If the keystore requires split accounts
-// (bitbox01) or the user configure split accounts in the settings, one account per script type is
-// added instead of a combined account.
-func (backend *Backend) createAndAddBTCAccount(
+// defaultAccount is a default account of a keystore. Its code does not contain the root
+// fingerprint of the keystore, see keystoreAccountCode().
+type defaultAccount struct {
+	coin           coin.Coin
+	code           string
+	name           string
+	configurations signing.Configurations
+}
+
+// defaultBTCAccounts returns a combined BTC account of the keystore with the given script types.
+// If the keystore requires split accounts (bitbox01) or the user configured split accounts in the
+// settings, one account per script type is returned instead of a combined account.
+func (backend *Backend) defaultBTCAccounts(
 	keystore keystore.Keystore,
 	coin coin.Coin,
 	code string,
 	name string,
 	configs []scriptTypeWithKeypath,
-) {
+) ([]defaultAccount, error) {
 	log := backend.log.WithField("code", code).WithField("name", name)
-	if !backend.config.AppConfig().Backend.CoinActive(coin.Code()) {
-		log.Info("skipping inactive account")
-		return
-	}
 	var supportedConfigs []scriptTypeWithKeypath
 	for _, cfg := range configs {
 		if keystore.SupportsAccount(coin, false, cfg.scriptType) {
@@ -346,9 +380,8 @@ func (backend *Backend) createAndAddBTCAccount(
 	}
 	if len(supportedConfigs) == 0 {
 		log.Info("skipping unsupported account")
-		return
+		return nil, nil
 	}
-	log.Info("init account")
 
 	getSigningConfiguration := func(cfg scriptTypeWithKeypath) (*signing.Configuration, error) {
 		extendedPublicKey, err := keystore.ExtendedPublicKey(coin, cfg.keypath)
@@ -366,14 +399,11 @@ func (backend *Backend) createAndAddBTCAccount(
 	splitAccounts := backend.config.AppConfig().Backend.SplitAccounts ||
 		!keystore.SupportsUnifiedAccounts()
 	if splitAccounts {
+		var accounts []defaultAccount
 		for _, cfg := range supportedConfigs {
-			cfg := cfg
-			getSigningConfigurations := func() (signing.Configurations, error) {
-				signingConfiguration, err := getSigningConfiguration(cfg)
-				if err != nil {
-					return nil, err
-				}
-				return signing.Configurations{signingConfiguration}, nil
+			signingConfiguration, err := getSigningConfiguration(cfg)
+			if err != nil {
+				return nil, err
 			}
 			suffixedName := name
 			switch cfg.scriptType {
@@ -381,83 +411,126 @@ func (backend *Backend) createAndAddBTCAccount(
 				suffixedName += ": legacy"
 			case signing.ScriptTypeP2WPKH:
 				suffixedName += ": bech32"
+			case signing.ScriptTypeP2TR:
+				suffixedName += ": taproot"
 			}
-			err := backend.CreateAndAddAccount(
-				coin,
-				fmt.Sprintf("%s-%s", code, cfg.scriptType),
-				suffixedName,
-				getSigningConfigurations,
-				false, false,
-			)
-			if err != nil {
-				panic(err)
-			}
-		}
-	} else {
-		getSigningConfigurations := func() (signing.Configurations, error) {
-			var result signing.Configurations
-			for _, cfg := range supportedConfigs {
-				signingConfiguration, err := getSigningConfiguration(cfg)
-				if err != nil {
-					return nil, err
-				}
-				result = append(result, signingConfiguration)
-			}
-			return result, nil
+			accounts = append(accounts, defaultAccount{
+				coin:           coin,
+				code:           fmt.Sprintf("%s-%s", code, cfg.scriptType),
+				name:           suffixedName,
+				configurations: signing.Configurations{signingConfiguration},
+			})
 		}
-		err := backend.CreateAndAddAccount(coin, code, name, getSigningConfigurations, false, false)
+		return accounts, nil
+	}
+	var signingConfigurations signing.Configurations
+	for _, cfg := range supportedConfigs {
+		signingConfiguration, err := getSigningConfiguration(cfg)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
+		signingConfigurations = append(signingConfigurations, signingConfiguration)
 	}
+	return []defaultAccount{{
+		coin:           coin,
+		code:           code,
+		name:           name,
+		configurations: signingConfigurations,
+	}}, nil
 }
 
-func (backend *Backend) createAndAddETHAccount(
+// defaultETHAccounts returns the ETH or ERC20 account of the keystore, or no account if the
+// keystore does not support it.
+func (backend *Backend) defaultETHAccounts(
 	keystore keystore.Keystore,
 	coin coin.Coin,
 	code string,
 	name string,
 	keypath string,
-) {
+) ([]defaultAccount, error) {
 	log := backend.log.WithField("code", code).WithField("name", name)
-	prefix := "eth-erc20-"
-	if strings.HasPrefix(code, prefix) {
-		if !backend.config.AppConfig().Backend.ETH.ERC20TokenActive(code[len(prefix):]) {
-			log.Info("skipping inactive erc20 token")
-			return
-		}
-	} else if !backend.config.AppConfig().Backend.CoinActive(coin.Code()) {
-		log.Info("skipping inactive account")
-		return
-	}
-
 	if !keystore.SupportsAccount(coin, false, nil) {
 		log.Info("skipping unsupported account")
-		return
+		return nil, nil
 	}
 
-	log.Info("init account")
 	absoluteKeypath, err := signing.NewAbsoluteKeypath(keypath)
 	if err != nil {
 		panic(err)
 	}
-	getSigningConfigurations := func() (signing.Configurations, error) {
-		extendedPublicKey, err := keystore.ExtendedPublicKey(coin, absoluteKeypath)
-		if err != nil {
-			return nil, err
-		}
-
-		return signing.Configurations{
+	extendedPublicKey, err := keystore.ExtendedPublicKey(coin, absoluteKeypath)
+	if err != nil {
+		return nil, err
+	}
+	return []defaultAccount{{
+		coin: coin,
+		code: code,
+		name: name,
+		configurations: signing.Configurations{
 			signing.NewSinglesigConfiguration(
 				signing.ScriptTypeP2PKH, // TODO: meaningless in Ethereum
 				absoluteKeypath,
 				extendedPublicKey,
 			),
-		}, nil
+		},
+	}}, nil
+}
+
+// persistDefaultAccount persists the default account of the keystore with the given root
+// fingerprint and birth height. The files of the account created by previous versions of the app,
+// when the default accounts were not persisted and their codes did not contain the root
+// fingerprint, are migrated.
+func (backend *Backend) persistDefaultAccount(
+	keystore keystore.Keystore,
+	rootFingerprint []byte,
+	account defaultAccount,
+	birthHeight uint64,
+) error {
+	backend.log.WithField("code", account.code).WithField("name", account.name).Info("persist account")
+	code := keystoreAccountCode(rootFingerprint, account.code)
+	backend.migrateUnpersistedDefaultAccount(account, code)
+	return backend.persistKeystoreAccount(
+		keystore, rootFingerprint, account.coin, code, account.name, account.configurations,
+		birthHeight)
+}
+
+// migrateUnpersistedDefaultAccount moves the transactions database, the notes and the
+// notifications of the default account, as created by previous versions of the app under the code
+// without the root fingerprint, to the account with the given code. Back then, the bitcoin
+// accounts did not include taproot. Failures are only logged, as the database is a cache.
+func (backend *Backend) migrateUnpersistedDefaultAccount(account defaultAccount, code string) {
+	unpersistedConfigurations := signing.Configurations{}
+	for _, configuration := range account.configurations {
+		if configuration.ScriptType() != signing.ScriptTypeP2TR {
+			unpersistedConfigurations = append(unpersistedConfigurations, configuration)
+		}
+	}
+	if len(unpersistedConfigurations) == 0 {
+		return
 	}
-	err = backend.CreateAndAddAccount(coin, code, name, getSigningConfigurations, false, false)
+	from := &config.Account{
+		CoinCode:       account.coin.Code(),
+		Code:           account.code,
+		Configurations: unpersistedConfigurations,
+	}
+	to := &config.Account{
+		CoinCode:       account.coin.Code(),
+		Code:           code,
+		Configurations: account.configurations,
+	}
+	log := backend.log.WithField("code", code)
+	err := moveAccountFiles(
+		backend.arguments.CacheDirectoryPath(), backend.arguments.NotesDirectoryPath(), from, to)
 	if err != nil {
-		panic(err)
+		log.WithError(err).Error("Could not migrate the files of the account")
+	}
+	// See GetNotifier in createAndAddAccount().
+	err = backend.notifier.MoveAccount(
+		fmt.Sprintf("%s-%s", from.Configurations.Hash(), from.Code),
+		fmt.Sprintf("%s-%s", to.Configurations.Hash(), to.Code),
+	)
+	if err != nil {
+		log.WithError(err).Error("Could not migrate the notifications of the account")
 	}
 }
 
@@ -562,7 +635,7 @@ func (backend *Backend) Coin(code coinpkg.Code) (coin.Coin, error) {
 	dbFolder := backend.arguments.CacheDirectoryPath()
 
 	// ethMakeTransactionsSource selects between the provided transactions sources based on the coin
-	// config. Currently we can only switch between None and EtherScan.
+	// config.
 	ethMakeTransactionsSource := func(
 		source config.ETHTransactionsSource,
 		etherScan eth.TransactionsSourceMaker) eth.TransactionsSourceMaker {
@@ -571,11 +644,13 @@ func (backend *Backend) Coin(code coinpkg.Code) (coin.Coin, error) {
 			return eth.TransactionsSourceNone
 		case config.ETHTransactionsSourceEtherScan:
 			return etherScan
+		case config.ETHTransactionsSourceNode:
+			return eth.TransactionsSourceNode
 		default:
 			panic(fmt.Sprintf("unknown eth transactions source: %s", source))
 		}
 	}
-	erc20Token := erc20TokenByCode(code)
+	erc20Token := backend.erc20TokenByCode(code)
 	switch {
 	case code == coinpkg.CodeRBTC:
 		servers := backend.defaultElectrumXServers(code)
@@ -663,109 +738,278 @@ func (backend *Backend) Coin(code coinpkg.Code) (coin.Coin, error) {
 	return coin, nil
 }
 
+// initPersistedAccounts loads the persisted accounts. Accounts of a keystore which is not
+// registered are loaded in watch-only mode using the stored extended public keys: they sync and
+// show addresses, but cannot sign or verify addresses until the keystore is connected.
 func (backend *Backend) initPersistedAccounts() {
+	registeredRootFingerprint := backend.registeredRootFingerprintHex()
 	for _, account := range backend.config.AccountsConfig().Accounts {
-		account := account
-		if _, isTestnet := coinpkg.TestnetCoins[account.CoinCode]; isTestnet != backend.Testing() {
-			// Don't load testnet accounts when running normally, nor mainnet accounts when running
-			// in testing mode
-			continue
-		}
-		coin, err := backend.Coin(account.CoinCode)
-		if err != nil {
-			backend.log.Errorf("skipping persisted account %s/%s, could not find coin",
-				account.CoinCode, account.Code)
-			continue
-		}
-		getSigningConfigurations := func() (signing.Configurations, error) {
-			return signing.Configurations{account.Configuration}, nil
-		}
-		err = backend.CreateAndAddAccount(coin, account.Code, account.Name, getSigningConfigurations, false, false)
-		if err != nil {
-			panic(err)
-		}
+		backend.loadPersistedAccount(account, registeredRootFingerprint)
 	}
 }
 
-// initDefaultAccounts creates a bunch of default accounts for a set of keystores (not manually
-// user-added). Currently the first bip44 account for all supported and active account types.
-func (backend *Backend) initDefaultAccounts() {
-	if backend.keystores.Count() == 0 {
+// registeredRootFingerprintHex returns the hex encoded root fingerprint of the registered keystore,
+// or an empty string if no keystore is registered or the fingerprint is not available.
+func (backend *Backend) registeredRootFingerprintHex() string {
+	rootFingerprint, err := backend.registeredRootFingerprint()
+	if err != nil {
+		backend.log.WithError(err).Error("Could not get the root fingerprint of the keystore")
+		return ""
+	}
+	if rootFingerprint == nil {
+		return ""
+	}
+	return hex.EncodeToString(rootFingerprint)
+}
+
+// loadPersistedAccount creates and adds the persisted account, unless it is hidden, its coin is not
+// active or it belongs to the other network. Accounts of keystores other than the one with the given
+// root fingerprint are loaded watch-only.
+func (backend *Backend) loadPersistedAccount(account config.Account, registeredRootFingerprint string) {
+	if account.Hidden {
 		return
 	}
-	if backend.keystores.Count() > 1 {
-		// If needed, insert multisig account initialization here based on multiple connected
-		// keystores.
+	keystores := backend.keystores
+	if account.RootFingerprint != "" {
+		if !backend.coinActive(account.CoinCode) {
+			return
+		}
+		if account.RootFingerprint != registeredRootFingerprint {
+			keystores = keystore.NewKeystores()
+		}
+	}
+	if _, isTestnet := coinpkg.TestnetCoins[account.CoinCode]; isTestnet != backend.Testing() {
+		// Don't load testnet accounts when running normally, nor mainnet accounts when running
+		// in testing mode
 		return
 	}
-	keystore := backend.keystores.Keystores()[0]
+	coin, err := backend.Coin(account.CoinCode)
+	if err != nil {
+		backend.log.Errorf("skipping persisted account %s/%s, could not find coin",
+			account.CoinCode, account.Code)
+		return
+	}
+	configurations := account.Configurations
+	if rootFingerprint, err := hex.DecodeString(account.RootFingerprint); err == nil &&
+		len(rootFingerprint) == 4 {
+		// The fingerprint is needed to export descriptors and PSBTs of the account.
+		configurations = withMissingRootFingerprint(configurations, rootFingerprint)
+	}
+	getSigningConfigurations := func() (signing.Configurations, error) {
+		return configurations, nil
+	}
+	err = backend.createAndAddAccount(
+		coin, account.Code, account.Name, getSigningConfigurations, keystores, false, false)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// defaultAccounts returns the default accounts of the keystore, currently the first bip44 account
+// of all supported account types. If include is not nil, only the accounts of the coins for which
+// it returns true are derived. All accounts are attempted, so that one failure does not prevent
+// the others from being returned. The first error is returned alongside.
+func (backend *Backend) defaultAccounts(
+	keystore keystore.Keystore, include func(coinpkg.Code) bool) ([]defaultAccount, error) {
+	var result []defaultAccount
+	var firstErr error
+	add := func(code coinpkg.Code, getAccounts func() ([]defaultAccount, error)) {
+		if include != nil && !include(code) {
+			return
+		}
+		accounts, err := getAccounts()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		result = append(result, accounts...)
+	}
 	if backend.arguments.Testing() {
 		if backend.arguments.Regtest() {
-			RBTC, _ := backend.Coin(coinpkg.CodeRBTC)
-			backend.createAndAddBTCAccount(keystore, RBTC,
-				"rbtc", "Bitcoin Regtest",
-				[]scriptTypeWithKeypath{
-					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/1'/0'"),
-					newScriptTypeWithKeypath(signing.ScriptTypeP2PKH, "m/44'/1'/0'"),
-				},
-			)
+			add(coinpkg.CodeRBTC, func() ([]defaultAccount, error) {
+				RBTC, _ := backend.Coin(coinpkg.CodeRBTC)
+				return backend.defaultBTCAccounts(keystore, RBTC,
+					"rbtc", "Bitcoin Regtest",
+					[]scriptTypeWithKeypath{
+						newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/1'/0'"),
+						newScriptTypeWithKeypath(signing.ScriptTypeP2PKH, "m/44'/1'/0'"),
+					},
+				)
+			})
 		} else {
-			TBTC, _ := backend.Coin(coinpkg.CodeTBTC)
-			backend.createAndAddBTCAccount(keystore, TBTC,
-				"tbtc", "Bitcoin Testnet",
+			add(coinpkg.CodeTBTC, func() ([]defaultAccount, error) {
+				TBTC, _ := backend.Coin(coinpkg.CodeTBTC)
+				return backend.defaultBTCAccounts(keystore, TBTC,
+					"tbtc", "Bitcoin Testnet",
+					[]scriptTypeWithKeypath{
+						newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/1'/0'"),
+						newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/1'/0'"),
+						newScriptTypeWithKeypath(signing.ScriptTypeP2PKH, "m/44'/1'/0'"),
+						newScriptTypeWithKeypath(signing.ScriptTypeP2TR, "m/86'/1'/0'"),
+					},
+				)
+			})
+
+			add(coinpkg.CodeTLTC, func() ([]defaultAccount, error) {
+				TLTC, _ := backend.Coin(coinpkg.CodeTLTC)
+				return backend.defaultBTCAccounts(keystore, TLTC,
+					"tltc", "Litecoin Testnet",
+					[]scriptTypeWithKeypath{
+						newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/1'/0'"),
+						newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/1'/0'"),
+					},
+				)
+			})
+
+			add(coinpkg.CodeTETH, func() ([]defaultAccount, error) {
+				TETH, _ := backend.Coin(coinpkg.CodeTETH)
+				return backend.defaultETHAccounts(keystore, TETH, "teth", "Ethereum Ropsten", "m/44'/1'/0'/0")
+			})
+			add(coinpkg.CodeRETH, func() ([]defaultAccount, error) {
+				RETH, _ := backend.Coin(coinpkg.CodeRETH)
+				return backend.defaultETHAccounts(keystore, RETH, "reth", "Ethereum Rinkeby", "m/44'/1'/0'/0")
+			})
+			add(coinpkg.CodeERC20TEST, func() ([]defaultAccount, error) {
+				erc20TEST, _ := backend.Coin(coinpkg.CodeERC20TEST)
+				return backend.defaultETHAccounts(keystore, erc20TEST, "erc20Test", "ERC20 TEST", "m/44'/1'/0'/0")
+			})
+		}
+	} else {
+		add(coinpkg.CodeBTC, func() ([]defaultAccount, error) {
+			BTC, _ := backend.Coin(coinpkg.CodeBTC)
+			return backend.defaultBTCAccounts(keystore, BTC,
+				"btc", "Bitcoin",
 				[]scriptTypeWithKeypath{
-					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/1'/0'"),
-					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/1'/0'"),
-					newScriptTypeWithKeypath(signing.ScriptTypeP2PKH, "m/44'/1'/0'"),
+					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/0'/0'"),
+					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/0'/0'"),
+					newScriptTypeWithKeypath(signing.ScriptTypeP2PKH, "m/44'/0'/0'"),
+					newScriptTypeWithKeypath(signing.ScriptTypeP2TR, "m/86'/0'/0'"),
 				},
 			)
+		})
 
-			TLTC, _ := backend.Coin(coinpkg.CodeTLTC)
-			backend.createAndAddBTCAccount(keystore, TLTC,
-				"tltc", "Litecoin Testnet",
+		add(coinpkg.CodeLTC, func() ([]defaultAccount, error) {
+			LTC, _ := backend.Coin(coinpkg.CodeLTC)
+			return backend.defaultBTCAccounts(keystore, LTC,
+				"ltc", "Litecoin",
 				[]scriptTypeWithKeypath{
-					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/1'/0'"),
-					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/1'/0'"),
+					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/2'/0'"),
+					newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/2'/0'"),
 				},
 			)
+		})
 
-			TETH, _ := backend.Coin(coinpkg.CodeTETH)
-			backend.createAndAddETHAccount(keystore, TETH, "teth", "Ethereum Ropsten", "m/44'/1'/0'/0")
-			RETH, _ := backend.Coin(coinpkg.CodeRETH)
-			backend.createAndAddETHAccount(keystore, RETH, "reth", "Ethereum Rinkeby", "m/44'/1'/0'/0")
-			erc20TEST, _ := backend.Coin(coinpkg.CodeERC20TEST)
-			backend.createAndAddETHAccount(keystore, erc20TEST, "erc20Test", "ERC20 TEST", "m/44'/1'/0'/0")
-		}
-	} else {
-		BTC, _ := backend.Coin(coinpkg.CodeBTC)
-		backend.createAndAddBTCAccount(keystore, BTC,
-			"btc", "Bitcoin",
-			[]scriptTypeWithKeypath{
-				newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/0'/0'"),
-				newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/0'/0'"),
-				newScriptTypeWithKeypath(signing.ScriptTypeP2PKH, "m/44'/0'/0'"),
-			},
-		)
-
-		LTC, _ := backend.Coin(coinpkg.CodeLTC)
-		backend.createAndAddBTCAccount(keystore, LTC,
-			"ltc", "Litecoin",
-			[]scriptTypeWithKeypath{
-				newScriptTypeWithKeypath(signing.ScriptTypeP2WPKH, "m/84'/2'/0'"),
-				newScriptTypeWithKeypath(signing.ScriptTypeP2WPKHP2SH, "m/49'/2'/0'"),
-			},
-		)
-
-		ETH, _ := backend.Coin(coinpkg.CodeETH)
-		backend.createAndAddETHAccount(keystore, ETH, "eth", "Ethereum", "m/44'/60'/0'/0")
+		add(coinpkg.CodeETH, func() ([]defaultAccount, error) {
+			ETH, _ := backend.Coin(coinpkg.CodeETH)
+			return backend.defaultETHAccounts(keystore, ETH, "eth", "Ethereum", "m/44'/60'/0'/0")
+		})
 
-		if backend.config.AppConfig().Backend.CoinActive(coinpkg.CodeETH) {
-			for _, erc20Token := range erc20Tokens {
+		for _, erc20Token := range backend.allERC20Tokens() {
+			erc20Token := erc20Token
+			add(erc20Token.code, func() ([]defaultAccount, error) {
 				token, _ := backend.Coin(erc20Token.code)
-				backend.createAndAddETHAccount(keystore, token, string(erc20Token.code), erc20Token.name, "m/44'/60'/0'/0")
+				return backend.defaultETHAccounts(keystore, token, string(erc20Token.code), erc20Token.name, erc20Keypath)
+			})
+		}
+	}
+	return result, firstErr
+}
+
+// initDefaultAccounts persists the default accounts of the registered keystore (not manually
+// user-added). This is only done once per coin: the first time a keystore is registered, and for
+// coins added later, e.g. ERC20 tokens, the next time it is registered. Afterwards, the accounts of
+// the keystore are loaded from the accounts config, respecting the changes of the user.
+func (backend *Backend) initDefaultAccounts() {
+	if backend.keystores.Count() == 0 {
+		return
+	}
+	if backend.keystores.Count() > 1 {
+		// If needed, insert multisig account initialization here based on multiple connected
+		// keystores.
+		return
+	}
+	keystore := backend.keystores.Keystores()[0]
+	rootFingerprint, err := keystore.RootFingerprint()
+	if err != nil {
+		backend.log.WithError(err).Error("Could not get the root fingerprint of the keystore")
+		backend.initUnpersistedDefaultAccounts(keystore)
+		return
+	}
+	var include func(coinpkg.Code) bool
+	knownKeystore := backend.knownKeystore(rootFingerprint)
+	if knownKeystore != nil {
+		keystoreCoins := backend.keystoreCoins(knownKeystore)
+		include = func(code coinpkg.Code) bool {
+			_, ok := keystoreCoins[code]
+			return !ok
+		}
+	}
+	accounts, firstErr := backend.defaultAccounts(keystore, include)
+	if knownKeystore != nil && len(accounts) == 0 && firstErr == nil {
+		return
+	}
+	// The default accounts of a keystore new to the app are assumed to be new, like the accounts
+	// added by the user. The birth height is the same for all coins of a chain.
+	birthHeights := map[string]uint64{}
+	birthHeight := func(coin coinpkg.Coin) uint64 {
+		ethCoin, ok := coin.(*eth.Coin)
+		if !ok || knownKeystore != nil {
+			return 0
+		}
+		chainID := ethCoin.Net().ChainID.String()
+		if _, ok := birthHeights[chainID]; !ok {
+			birthHeights[chainID] = backend.newAccountBirthHeight(coin)
+		}
+		return birthHeights[chainID]
+	}
+	failedCoins := map[coinpkg.Code]struct{}{}
+	for _, account := range accounts {
+		err := backend.persistDefaultAccount(
+			keystore, rootFingerprint, account, birthHeight(account.coin))
+		if err != nil {
+			failedCoins[account.coin.Code()] = struct{}{}
+			if firstErr == nil {
+				firstErr = err
 			}
 		}
 	}
+	if firstErr != nil {
+		// The defaults of the failed coins are persisted again the next time.
+		backend.log.WithError(firstErr).Error("Could not persist the default accounts of the keystore")
+	}
+	coins := []coinpkg.Code{}
+	for _, account := range accounts {
+		if _, failed := failedCoins[account.coin.Code()]; !failed {
+			coins = append(coins, account.coin.Code())
+		}
+	}
+	if err := backend.addKnownKeystore(rootFingerprint, coins); err != nil {
+		backend.log.WithError(err).Error("Could not persist the keystore")
+	}
+}
+
+// initUnpersistedDefaultAccounts adds the default accounts of the active coins of the keystore
+// without persisting them. The accounts can't be persisted for a keystore whose root fingerprint is
+// unknown, e.g. a BitBox02 with an old firmware, so they are created on each registration under
+// the codes without the root fingerprint, like before accounts were persisted per keystore.
+func (backend *Backend) initUnpersistedDefaultAccounts(keystore keystore.Keystore) {
+	accounts, err := backend.defaultAccounts(keystore, nil)
+	if err != nil {
+		backend.log.WithError(err).Error("Could not get the default accounts of the keystore")
+	}
+	for _, account := range accounts {
+		if !backend.coinActive(account.coin.Code()) {
+			continue
+		}
+		configurations := account.configurations
+		getSigningConfigurations := func() (signing.Configurations, error) {
+			return configurations, nil
+		}
+		err := backend.createAndAddAccount(account.coin, account.code, account.name,
+			getSigningConfigurations, backend.keystores, false, false)
+		if err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (backend *Backend) initAccounts() {
@@ -935,6 +1179,22 @@ func (backend *Backend) uninitAccounts() {
 	backend.accounts = []accounts.Interface{}
 }
 
+// unloadAccounts closes and removes the loaded accounts with the given codes.
+func (backend *Backend) unloadAccounts(codes map[string]struct{}) {
+	defer backend.accountsLock.Lock()()
+	remaining := []accounts.Interface{}
+	for _, account := range backend.accounts {
+		account := account
+		if _, ok := codes[account.Config().Code]; !ok {
+			remaining = append(remaining, account)
+			continue
+		}
+		backend.onAccountUninit(account)
+		account.Close()
+	}
+	backend.accounts = remaining
+}
+
 // Keystores returns the keystores registered at this backend.
 func (backend *Backend) Keystores() *keystore.Keystores {
 	return backend.keystores
@@ -966,7 +1226,8 @@ func (backend *Backend) DeregisterKeystore() {
 	})
 	backend.uninitAccounts()
 	// TODO: classify accounts by keystore, remove only the ones belonging to the deregistered
-	// keystore. For now we just remove all, then re-add the rest.
+	// keystore. For now we just remove all, then re-add them. The accounts of the deregistered
+	// keystore are re-added in watch-only mode.
 	backend.initPersistedAccounts()
 	backend.emitAccountsStatusChanged()
 }
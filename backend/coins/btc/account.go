@@ -88,6 +88,9 @@ type Account struct {
 	activeTxProposal     *maketx.TxProposal
 	activeTxProposalLock locker.Locker
 
+	// scheduledTxsLock serializes the broadcasting of scheduled (timelocked) transactions.
+	scheduledTxsLock locker.Locker
+
 	feeTargets []*FeeTarget
 
 	// true when initialized (Initialize() was called).
@@ -137,6 +140,12 @@ func (account *Account) String() string {
 	return fmt.Sprintf("%s-%s", account.Coin().Code(), account.Config().Code)
 }
 
+// AccountIdentifier returns the identifier of the account with the given code and signing
+// configurations. The account's database and notes files are named after it.
+func AccountIdentifier(code string, signingConfigurations signing.Configurations) string {
+	return fmt.Sprintf("account-%s-%s", signingConfigurations.Hash(), code)
+}
+
 // FilesFolder implements accounts.Interface.
 func (account *Account) FilesFolder() string {
 	if account.dbSubfolder == "" {
@@ -145,8 +154,8 @@ func (account *Account) FilesFolder() string {
 	return account.dbSubfolder
 }
 
-// defaultGapLimits returns the default gap limits for this account.
-func (account *Account) defaultGapLimits(signingConfiguration *signing.Configuration) types.GapLimits {
+// defaultGapLimits returns the default gap limits for a signing configuration.
+func defaultGapLimits(signingConfiguration *signing.Configuration) types.GapLimits {
 	limits := types.GapLimits{
 		Receive: 20,
 		Change:  6,
@@ -161,12 +170,21 @@ func (account *Account) defaultGapLimits(signingConfiguration *signing.Configura
 		// Usually 20, but BWS used to not have any limit. We put it fairly high to cover most
 		// outliers.
 		limits.Receive = 60
-		account.log.Warning("increased change gap limit to 20 and gap limit to 60 for BWS compatibility")
 	}
 
 	return limits
 }
 
+// defaultGapLimits returns the default gap limits for this account.
+func (account *Account) defaultGapLimits(signingConfiguration *signing.Configuration) types.GapLimits {
+	limits := defaultGapLimits(signingConfiguration)
+	if signingConfiguration.Singlesig() &&
+		signingConfiguration.ScriptType() == signing.ScriptTypeP2PKH {
+		account.log.Warning("increased change gap limit to 20 and gap limit to 60 for BWS compatibility")
+	}
+	return limits
+}
+
 // gapLimits gets the gap limits as stored in the account configuration, and defaults to
 // `defaultGapLimits()` if there is no configuration or the configuration limits are smaller than
 // the default limits.
@@ -255,7 +273,7 @@ func (account *Account) Initialize() error {
 	}
 	account.notifier = account.Config().GetNotifier(signingConfigurations)
 
-	accountIdentifier := fmt.Sprintf("account-%s-%s", signingConfigurations.Hash(), account.Config().Code)
+	accountIdentifier := AccountIdentifier(account.Config().Code, signingConfigurations)
 	account.dbSubfolder = path.Join(account.Config().DBFolder, accountIdentifier)
 	if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
 		return errp.WithStack(err)
@@ -294,6 +312,9 @@ func (account *Account) Initialize() error {
 		if event == headers.EventSynced {
 			account.Config().OnEvent(accounts.EventHeadersSynced)
 		}
+		if event == headers.EventSynced || event == headers.EventNewTip {
+			go account.broadcastScheduledTxs()
+		}
 	})
 	account.transactions = transactions.NewTransactions(
 		account.coin.Net(), account.db, theHeaders, account.Synchronizer,
@@ -358,7 +379,20 @@ func (account *Account) Info() *accounts.Info {
 	// The internal extended key representation always uses the same version bytes (prefix xpub). We
 	// convert it here to the account-specific version (zpub, ypub, tpub, ...).
 	signingConfigurations := make([]*signing.Configuration, len(account.subaccounts))
+	descriptors := []string{}
 	for idx, subacc := range account.subaccounts {
+		if descriptor, err := subacc.signingConfiguration.Descriptor(account.coin.Net()); err == nil {
+			descriptors = append(descriptors, descriptor)
+		}
+		// Multisig xpubs keep the version bytes of the network (xpub, tpub, ...).
+		var scriptType signing.ScriptType
+		hdPublicKeyID := account.coin.Net().HDPublicKeyID
+		if subacc.signingConfiguration.Multisig() {
+			scriptType = subacc.signingConfiguration.MultisigScriptType()
+		} else {
+			scriptType = subacc.signingConfiguration.ScriptType()
+			hdPublicKeyID = XPubVersionForScriptType(account.coin, scriptType)
+		}
 		var xpubs []*hdkeychain.ExtendedKey
 		for _, xpub := range subacc.signingConfiguration.ExtendedPublicKeys() {
 			if xpub.IsPrivate() {
@@ -368,16 +402,11 @@ func (account *Account) Info() *accounts.Info {
 			if err != nil {
 				panic(err)
 			}
-			xpubCopy.SetNet(
-				&chaincfg.Params{
-					HDPublicKeyID: XPubVersionForScriptType(
-						account.coin, subacc.signingConfiguration.ScriptType()),
-				},
-			)
+			xpubCopy.SetNet(&chaincfg.Params{HDPublicKeyID: hdPublicKeyID})
 			xpubs = append(xpubs, xpubCopy)
 		}
 		signingConfigurations[idx] = signing.NewConfiguration(
-			subacc.signingConfiguration.ScriptType(),
+			scriptType,
 			subacc.signingConfiguration.AbsoluteKeypath(),
 			xpubs,
 			subacc.signingConfiguration.Address(),
@@ -386,6 +415,7 @@ func (account *Account) Info() *accounts.Info {
 	}
 	return &accounts.Info{
 		SigningConfigurations: signingConfigurations,
+		Descriptors:           descriptors,
 	}
 }
 
@@ -646,7 +676,7 @@ func (account *Account) Transactions() ([]*accounts.TransactionData, error) {
 	if account.fatalError {
 		return nil, errp.New("can't call Transactions() after a fatal error")
 	}
-	return account.transactions.Transactions(
+	txs := account.transactions.Transactions(
 		func(scriptHashHex blockchain.ScriptHashHex) bool {
 			for _, subacc := range account.subaccounts {
 				if subacc.changeAddresses.LookupByScriptHashHex(scriptHashHex) != nil {
@@ -654,7 +684,54 @@ func (account *Account) Transactions() ([]*accounts.TransactionData, error) {
 				}
 			}
 			return false
-		}), nil
+		})
+	for _, tx := range txs {
+		for index := range tx.Addresses {
+			tx.Addresses[index].Label = account.Notes().AddressLabel(tx.Addresses[index].Address)
+		}
+	}
+	return txs, nil
+}
+
+// AddressInfo describes a derived address of the account and its usage.
+type AddressInfo struct {
+	Address *addresses.AccountAddress
+	// Change is true for change addresses, false for receive addresses.
+	Change bool
+	Label  string
+	Used   bool
+	// Balance is the sum of the unspent outputs on the address, confirmed or not.
+	Balance btcutil.Amount
+	// TxCount is the number of transactions involving the address.
+	TxCount int
+}
+
+// Addresses returns all derived receive and change addresses of all subaccounts.
+func (account *Account) Addresses() []*AddressInfo {
+	account.Synchronizer.WaitSynchronized()
+	addressStats := account.transactions.AddressStats()
+	defer account.RLock()()
+	result := []*AddressInfo{}
+	add := func(addressChain AddressChain, change bool) {
+		for _, address := range addressChain.Addresses() {
+			info := &AddressInfo{
+				Address: address,
+				Change:  change,
+				Label:   account.Notes().AddressLabel(address.EncodeForHumans()),
+				Used:    address.IsUsed(),
+			}
+			if stats, ok := addressStats[address.PubkeyScriptHashHex()]; ok {
+				info.Balance = stats.Balance
+				info.TxCount = stats.TxCount
+			}
+			result = append(result, info)
+		}
+	}
+	for _, subacc := range account.subaccounts {
+		add(subacc.receiveAddresses, false)
+		add(subacc.changeAddresses, true)
+	}
+	return result
 }
 
 // GetUnusedReceiveAddresses returns a number of unused addresses.
@@ -687,15 +764,20 @@ func (account *Account) VerifyAddress(addressID string) (bool, error) {
 	defer account.RLock()()
 	scriptHashHex := blockchain.ScriptHashHex(addressID)
 	var address *addresses.AccountAddress
+	var accountConfiguration *signing.Configuration
 	for _, subacc := range account.subaccounts {
 		if addr := subacc.receiveAddresses.LookupByScriptHashHex(scriptHashHex); addr != nil {
 			address = addr
+			accountConfiguration = subacc.signingConfiguration
 			break
 		}
 	}
 	if address == nil {
 		return false, errp.New("unknown address not found")
 	}
+	if accountConfiguration.Multisig() {
+		return account.verifyMultisigAddress(accountConfiguration, address)
+	}
 	canVerifyAddress, _, err := account.Config().Keystores.CanVerifyAddresses(account.Coin())
 	if err != nil {
 		return false, err
@@ -746,6 +828,12 @@ func (account *Account) SpendableOutputs() []*SpendableOutput {
 	return result
 }
 
+// SetUTXOMetadata freezes/unfreezes and labels an unspent output. Frozen outputs are not spent
+// unless selected explicitly via coin control.
+func (account *Account) SetUTXOMetadata(outPoint wire.OutPoint, metadata transactions.UTXOMetadata) error {
+	return account.transactions.SetUTXOMetadata(outPoint, metadata)
+}
+
 // CanVerifyExtendedPublicKey returns the indices of the keystores that support secure verification.
 func (account *Account) CanVerifyExtendedPublicKey() []int {
 	return account.Config().Keystores.CanVerifyExtendedPublicKeys()
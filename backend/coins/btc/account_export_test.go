@@ -0,0 +1,56 @@
+// Copyright 2020 Shift Devices AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+)
+
+func (account *Account) TstFeeRatePerKb(feeTargetCode accounts.FeeTargetCode) (btcutil.Amount, error) {
+	return account.feeRatePerKb(feeTargetCode)
+}
+
+func (account *Account) TstGetPrevTx(txHash chainhash.Hash) (*wire.MsgTx, error) {
+	return account.getPrevTx(txHash)
+}
+
+func (account *Account) TstSignTransaction(
+	txProposal *maketx.TxProposal,
+	previousOutputs map[wire.OutPoint]*transactions.SpendableOutput,
+) error {
+	return account.signTransaction(txProposal, previousOutputs, account.getPrevTx)
+}
+
+func TstVerifyInputScripts(transaction *wire.MsgTx, previousOutputs map[wire.OutPoint]*transactions.SpendableOutput) error {
+	return verifyInputScripts(transaction, previousOutputs, txscript.NewTxSigHashes(transaction))
+}
+
+func (account *Account) TstLockTimeReached(lockTime uint32) bool {
+	return account.lockTimeReached(lockTime)
+}
+
+func (account *Account) TstScheduleTx(tx *wire.MsgTx) error {
+	return account.scheduleTx(tx)
+}
+
+func (account *Account) TstBroadcastScheduledTxs() {
+	account.broadcastScheduledTxs()
+}
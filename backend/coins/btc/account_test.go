@@ -19,15 +19,21 @@ import (
 	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
@@ -42,14 +48,25 @@ func TestAccount(t *testing.T) {
 	dbFolder := test.TstTempDir("btc-dbfolder")
 	defer func() { _ = os.RemoveAll(dbFolder) }()
 
-	coin := btc.NewCoin(
+	btcCoin := btc.NewCoin(
 		code, unit, net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
 
 	blockchainMock := &blockchainMock.BlockchainMock{}
 	blockchainMock.MockRegisterOnConnectionStatusChangedEvent = func(onConnectionStatusChanged func(blockchain.Status)) {
 	}
 
-	coin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
+	// Fee estimates of 10 sat/vB for all targets, provided asynchronously like the real backend.
+	blockchainMock.MockHeadersSubscribe = func(
+		setupAndTeardown func() func(error), success func(*blockchain.Header) error) {
+		go func() { _ = success(&blockchain.Header{BlockHeight: 100}) }()
+	}
+	blockchainMock.MockEstimateFee = func(
+		blocks int, success func(*btcutil.Amount), cleanup func(error)) {
+		feeRatePerKb := btcutil.Amount(10000)
+		go success(&feeRatePerKb)
+	}
+
+	btcCoin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
 
 	getSigningConfigurations := func() (signing.Configurations, error) {
 		keypath, err := signing.NewAbsoluteKeypath("m/49'/1'/0'")
@@ -76,7 +93,7 @@ func TestAccount(t *testing.T) {
 			GetSigningConfigurations: getSigningConfigurations,
 			GetNotifier:              func(signing.Configurations) accounts.Notifier { return nil },
 		},
-		coin, nil,
+		btcCoin, nil,
 		logging.Get().WithGroup("account_test"),
 	)
 	require.False(t, account.Synced())
@@ -93,4 +110,79 @@ func TestAccount(t *testing.T) {
 	require.Equal(t, []*accounts.TransactionData{}, transactions)
 
 	require.Equal(t, []*btc.SpendableOutput{}, account.SpendableOutputs())
+
+	// Custom fee rates are validated before the coins are selected.
+	blockchainMock.MockRelayFee = func(success func(btcutil.Amount), cleanup func(error)) {
+		success(1000)
+		cleanup(nil)
+	}
+	customFee := func(feeRate string) error {
+		_, _, _, err := account.TxProposal(&accounts.TxProposalArgs{
+			RecipientAddress: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+			Amount:           coin.NewSendAmount("0.001"),
+			FeeTargetCode:    accounts.FeeTargetCodeCustom,
+			CustomFee:        feeRate,
+		})
+		return errp.Cause(err)
+	}
+	require.Equal(t, errors.ErrInvalidFeeRate, customFee(""))
+	require.Equal(t, errors.ErrInvalidFeeRate, customFee("abc"))
+	require.Equal(t, errors.ErrInvalidFeeRate, customFee("-1"))
+	require.Equal(t, errors.ErrFeeRateTooLow, customFee("0.5"))
+	require.Equal(t, errors.ErrInsufficientFunds, customFee("1"))
+	require.Equal(t, errors.ErrInsufficientFunds, customFee("2.5"))
+
+	// Fee rates far above the estimates must be confirmed by the user.
+	require.Eventually(t, func() bool {
+		feeTargets, _ := account.FeeTargets()
+		return len(feeTargets) > 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, errors.ErrInsufficientFunds, customFee("100"))
+	require.Equal(t, errors.ErrFeeRateTooHigh, customFee("101"))
+	_, _, _, err = account.TxProposal(&accounts.TxProposalArgs{
+		RecipientAddress: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+		Amount:           coin.NewSendAmount("0.001"),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "101",
+		AllowHighFeeRate: true,
+	})
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+
+	// Custom fee rates are rejected where only the fee estimates are supported, like when bumping
+	// the fee.
+	_, err = account.TstFeeRatePerKb(accounts.FeeTargetCodeCustom)
+	require.Equal(t, errors.ErrCustomFeeNotSupported, errp.Cause(err))
+	feeRatePerKb, err := account.TstFeeRatePerKb(accounts.FeeTargetCodeNormal)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(10000), feeRatePerKb)
+
+	// Locktimes must lie in the future.
+	_, _, _, err = account.TxProposal(&accounts.TxProposalArgs{
+		RecipientAddress: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+		Amount:           coin.NewSendAmount("0.001"),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "1",
+		LockTime:         500000001,
+	})
+	require.Equal(t, errors.ErrInvalidLockTime, errp.Cause(err))
+	scheduledTxs, err := account.ScheduledTxs()
+	require.NoError(t, err)
+	require.Empty(t, scheduledTxs)
+
+	// Failing to fetch a previous transaction is an error, not a crash.
+	prevTx := wire.NewMsgTx(wire.TxVersion)
+	blockchainMock.MockTransactionGet = func(
+		txHash chainhash.Hash, success func(*wire.MsgTx), cleanup func(error)) {
+		if txHash == prevTx.TxHash() {
+			success(prevTx)
+			cleanup(nil)
+			return
+		}
+		cleanup(errp.New("transaction not found"))
+	}
+	tx, err := account.TstGetPrevTx(prevTx.TxHash())
+	require.NoError(t, err)
+	require.Equal(t, prevTx, tx)
+	_, err = account.TstGetPrevTx(chainhash.Hash{})
+	require.Error(t, err)
 }
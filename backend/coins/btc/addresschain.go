@@ -23,5 +23,6 @@ import (
 type AddressChain interface {
 	GetUnused() []*addresses.AccountAddress
 	EnsureAddresses() []*addresses.AccountAddress
+	Addresses() []*addresses.AccountAddress
 	LookupByScriptHashHex(blockchain.ScriptHashHex) *addresses.AccountAddress
 }
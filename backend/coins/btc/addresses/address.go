@@ -15,6 +15,7 @@
 package addresses
 
 import (
+	"crypto/sha256"
 	"fmt"
 
 	"github.com/btcsuite/btcd/btcec"
@@ -23,6 +24,7 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/sirupsen/logrus"
 )
@@ -43,6 +45,9 @@ type AccountAddress struct {
 	// redeemScript stores the redeem script of a BIP16 P2SH output or nil if address type is P2PKH.
 	redeemScript []byte
 
+	// witnessScript stores the witness script of a P2WSH output (also if wrapped in P2SH), or nil.
+	witnessScript []byte
+
 	log *logrus.Entry
 }
 
@@ -56,6 +61,7 @@ func NewAccountAddress(
 
 	var address btcutil.Address
 	var redeemScript []byte
+	var witnessScript []byte
 	configuration, err := accountConfiguration.Derive(keyPath)
 	if err != nil {
 		log.WithError(err).Panic("Failed to derive the configuration.")
@@ -69,25 +75,53 @@ func NewAccountAddress(
 	switch {
 	case configuration.IsAddressBased():
 		address, err = btcutil.DecodeAddress(configuration.Address(), net)
+		if err != nil {
+			address, err = taproot.DecodeAddress(configuration.Address(), net)
+		}
 		if err != nil {
 			log.WithError(err).Panic("invalid address")
 		}
 	case configuration.Multisig():
-		sortedPublicKeys := configuration.SortedPublicKeys()
-		addresses := make([]*btcutil.AddressPubKey, len(sortedPublicKeys))
-		for index, publicKey := range sortedPublicKeys {
+		scriptPublicKeys := configuration.MultisigPublicKeys()
+		addresses := make([]*btcutil.AddressPubKey, len(scriptPublicKeys))
+		for index, publicKey := range scriptPublicKeys {
 			addresses[index], err = btcutil.NewAddressPubKey(publicKey.SerializeCompressed(), net)
 			if err != nil {
 				log.WithError(err).Panic("Failed to get a P2PK address from a public key.")
 			}
 		}
-		redeemScript, err = txscript.MultiSigScript(addresses, configuration.SigningThreshold())
+		multisigScript, err := txscript.MultiSigScript(addresses, configuration.SigningThreshold())
 		if err != nil {
 			log.WithError(err).Panic("Failed to get the redeem script for multisig.")
 		}
-		address, err = btcutil.NewAddressScriptHash(redeemScript, net)
-		if err != nil {
-			log.WithError(err).Panic("Failed to get a P2SH address for multisig.")
+		switch configuration.MultisigScriptType() {
+		case signing.ScriptTypeP2WSH, signing.ScriptTypeP2WSHP2SH:
+			witnessScript = multisigScript
+			witnessScriptHash := sha256.Sum256(witnessScript)
+			var segwitAddress *btcutil.AddressWitnessScriptHash
+			segwitAddress, err = btcutil.NewAddressWitnessScriptHash(witnessScriptHash[:], net)
+			if err != nil {
+				log.WithError(err).Panic("Failed to get a P2WSH address for multisig.")
+			}
+			address = segwitAddress
+			if configuration.MultisigScriptType() == signing.ScriptTypeP2WSHP2SH {
+				redeemScript, err = txscript.PayToAddrScript(segwitAddress)
+				if err != nil {
+					log.WithError(err).Panic("Failed to get redeem script for segwit multisig.")
+				}
+				address, err = btcutil.NewAddressScriptHash(redeemScript, net)
+				if err != nil {
+					log.WithError(err).Panic("Failed to get a P2SH address for segwit multisig.")
+				}
+			}
+		case signing.ScriptTypeP2PKH, "":
+			redeemScript = multisigScript
+			address, err = btcutil.NewAddressScriptHash(redeemScript, net)
+			if err != nil {
+				log.WithError(err).Panic("Failed to get a P2SH address for multisig.")
+			}
+		default:
+			log.Panic("Unknown multisig script type")
 		}
 	default:
 		publicKeyHash := btcutil.Hash160(configuration.PublicKeys()[0].SerializeCompressed())
@@ -116,6 +150,15 @@ func NewAccountAddress(
 			if err != nil {
 				log.WithError(err).Panic("Failed to get p2wpkh addr. from publ. key hash.")
 			}
+		case signing.ScriptTypeP2TR:
+			outputKey, err := taproot.OutputKey(configuration.PublicKeys()[0])
+			if err != nil {
+				log.WithError(err).Panic("Failed to get the taproot output key.")
+			}
+			address, err = taproot.NewAddressTaproot(outputKey, net)
+			if err != nil {
+				log.WithError(err).Panic("Failed to get p2tr addr. from output key.")
+			}
 		default:
 			log.Panic(fmt.Sprintf("Unrecognized script type: %s", configuration.ScriptType()))
 		}
@@ -125,6 +168,7 @@ func NewAccountAddress(
 		Configuration: configuration,
 		HistoryStatus: "",
 		redeemScript:  redeemScript,
+		witnessScript: witnessScript,
 		log:           log,
 	}
 }
@@ -139,13 +183,14 @@ func (address *AccountAddress) EncodeForHumans() string {
 	return address.EncodeAddress()
 }
 
-func (address *AccountAddress) isUsed() bool {
+// IsUsed returns true if the address has a transaction history.
+func (address *AccountAddress) IsUsed() bool {
 	return address.HistoryStatus != ""
 }
 
 // PubkeyScript returns the pubkey script of this address. Use this in a tx output to receive funds.
 func (address *AccountAddress) PubkeyScript() []byte {
-	script, err := txscript.PayToAddrScript(address.Address)
+	script, err := taproot.PayToAddrScript(address.Address)
 	if err != nil {
 		address.log.WithError(err).Panic("Failed to get the pubkey script for an address.")
 	}
@@ -163,6 +208,9 @@ func (address *AccountAddress) PubkeyScriptHashHex() blockchain.ScriptHashHex {
 // from this address.
 func (address *AccountAddress) ScriptForHashToSign() (bool, []byte) {
 	if address.Configuration.Multisig() {
+		if address.witnessScript != nil {
+			return true, address.witnessScript
+		}
 		return false, address.redeemScript
 	}
 	switch address.Configuration.ScriptType() {
@@ -172,12 +220,35 @@ func (address *AccountAddress) ScriptForHashToSign() (bool, []byte) {
 		return true, address.redeemScript
 	case signing.ScriptTypeP2WPKH:
 		return true, address.PubkeyScript()
+	case signing.ScriptTypeP2TR:
+		// The BIP341 signature hash commits to the pkScripts of all spent outputs, see
+		// taproot.SigHash().
+		return true, address.PubkeyScript()
 	default:
 		address.log.Panic("Unrecognized address type.")
 	}
 	panic("The end of the function cannot be reached.")
 }
 
+// IsTaproot returns true if the address is a taproot (P2TR) address, spent with a BIP340 Schnorr
+// signature.
+func (address *AccountAddress) IsTaproot() bool {
+	return address.Configuration.Singlesig() &&
+		address.Configuration.ScriptType() == signing.ScriptTypeP2TR
+}
+
+// RedeemScript returns the redeem script of a BIP16 P2SH address, or nil if the address is not
+// P2SH.
+func (address *AccountAddress) RedeemScript() []byte {
+	return address.redeemScript
+}
+
+// WitnessScript returns the witness script of a P2WSH address (also if wrapped in P2SH), or nil if
+// the address is not P2WSH.
+func (address *AccountAddress) WitnessScript() []byte {
+	return address.witnessScript
+}
+
 func index(publicKey *btcec.PublicKey, sortedPublicKeys []*btcec.PublicKey) int {
 	for index, sortedPublicKey := range sortedPublicKeys {
 		if sortedPublicKey.IsEqual(publicKey) {
@@ -198,13 +269,32 @@ func (address *AccountAddress) SignatureScript(
 	if address.Configuration.Multisig() {
 		length := address.Configuration.NumberOfSigners()
 		publicKeys := address.Configuration.PublicKeys()
-		sortedPublicKeys := address.Configuration.SortedPublicKeys()
-		sortedSignatures := make([]*btcec.Signature, length)
+		scriptPublicKeys := address.Configuration.MultisigPublicKeys()
+		scriptSignatures := make([]*btcec.Signature, length)
 		for i := 0; i < length; i++ {
-			sortedSignatures[index(publicKeys[i], sortedPublicKeys)] = signatures[i]
+			scriptSignatures[index(publicKeys[i], scriptPublicKeys)] = signatures[i]
+		}
+		if address.witnessScript != nil {
+			// The empty item is consumed by the CHECKMULTISIG off-by-one bug.
+			txWitness := wire.TxWitness{[]byte{}}
+			for _, signature := range scriptSignatures {
+				if signature != nil {
+					txWitness = append(txWitness,
+						append(signature.Serialize(), byte(txscript.SigHashAll)))
+				}
+			}
+			txWitness = append(txWitness, address.witnessScript)
+			if address.redeemScript == nil {
+				return []byte{}, txWitness
+			}
+			signatureScript, err := txscript.NewScriptBuilder().AddData(address.redeemScript).Script()
+			if err != nil {
+				address.log.WithError(err).Panic("Failed to build segwit multisig signature script.")
+			}
+			return signatureScript, txWitness
 		}
 		scriptBuilder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
-		for _, signature := range sortedSignatures {
+		for _, signature := range scriptSignatures {
 			if signature != nil {
 				scriptBuilder.AddData(append(signature.Serialize(), byte(txscript.SigHashAll)))
 			}
@@ -248,6 +338,9 @@ func (address *AccountAddress) SignatureScript(
 			publicKey.SerializeCompressed(),
 		}
 		return []byte{}, txWitness
+	case signing.ScriptTypeP2TR:
+		// A Schnorr signature with the default sighash type, see taproot.SigHash().
+		return []byte{}, wire.TxWitness{taproot.SerializeSignature(signature)}
 	default:
 		address.log.Panic("Unrecognized address type.")
 	}
@@ -15,13 +15,17 @@
 package addresses_test
 
 import (
+	"bytes"
+	"encoding/hex"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses/test"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -61,3 +65,72 @@ func (s *addressTestSuite) TestScriptHashHex() {
 		blockchain.ScriptHashHex("0466d0029406f583feadaccb91c7b5b855eb5d6782316cafa4f390b7c784436b"),
 		s.address.PubkeyScriptHashHex())
 }
+
+func TestUnsortedMultisig(t *testing.T) {
+	xpubs := make([]*hdkeychain.ExtendedKey, 2)
+	for i := range xpubs {
+		master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{byte(i + 1)}, 32), net)
+		require.NoError(t, err)
+		xpubs[i], err = master.Neuter()
+		require.NoError(t, err)
+	}
+	reversed := []*hdkeychain.ExtendedKey{xpubs[1], xpubs[0]}
+	newAddress := func(configuration *signing.Configuration) *addresses.AccountAddress {
+		return addresses.NewAccountAddress(
+			configuration,
+			signing.NewEmptyRelativeKeypath(),
+			net,
+			logging.Get().WithGroup("addresses_test"),
+		)
+	}
+	sorted := newAddress(signing.NewConfiguration(
+		signing.ScriptTypeP2WSH, absoluteKeypath, xpubs, "", 1))
+	sortedReversed := newAddress(signing.NewConfiguration(
+		signing.ScriptTypeP2WSH, absoluteKeypath, reversed, "", 1))
+	unsorted := newAddress(signing.NewConfiguration(
+		signing.ScriptTypeP2WSH, absoluteKeypath, xpubs, "", 1).WithUnsortedMultisig())
+	unsortedReversed := newAddress(signing.NewConfiguration(
+		signing.ScriptTypeP2WSH, absoluteKeypath, reversed, "", 1).WithUnsortedMultisig())
+
+	// The order of the xpubs only matters for unsorted multisig.
+	require.Equal(t, sorted.EncodeAddress(), sortedReversed.EncodeAddress())
+	require.NotEqual(t, unsorted.EncodeAddress(), unsortedReversed.EncodeAddress())
+	// The keys appear in the script in the order of the xpubs.
+	for _, address := range []*addresses.AccountAddress{unsorted, unsortedReversed} {
+		publicKeys := address.Configuration.PublicKeys()
+		script := address.WitnessScript()
+		require.Less(t,
+			bytes.Index(script, publicKeys[0].SerializeCompressed()),
+			bytes.Index(script, publicKeys[1].SerializeCompressed()))
+	}
+	require.Contains(t,
+		[]string{unsorted.EncodeAddress(), unsortedReversed.EncodeAddress()}, sorted.EncodeAddress())
+}
+
+func TestMultisigAddressVectors(t *testing.T) {
+	// Keys of the first BIP67 test vector. The xpubs derive to these keys with the empty keypath.
+	newXPub := func(publicKeyHex string) *hdkeychain.ExtendedKey {
+		publicKey, err := hex.DecodeString(publicKeyHex)
+		require.NoError(t, err)
+		return hdkeychain.NewExtendedKey(
+			chaincfg.MainNetParams.HDPublicKeyID[:], publicKey, make([]byte, 32), []byte{0, 0, 0, 0},
+			0, 0, false)
+	}
+	xpubs := []*hdkeychain.ExtendedKey{
+		newXPub("02ff12471208c14bd580709cb2358d98975247d8765f92bc25eab3b2763ed605f8"),
+		newXPub("02fe6f0a5a297eb38c391581c4413e084773ea23954d93f7753db7dc0adc188b2f"),
+	}
+	for scriptType, expectedAddress := range map[signing.ScriptType]string{
+		signing.ScriptTypeP2PKH:     "39bgKC7RFbpoCRbtD5KEdkYKtNyhpsNa3Z",
+		signing.ScriptTypeP2WSH:     "bc1qknwt9mhqpd7hrjrvpqz57zjqk28xlp2h90te6v22en0m3uctnams3pq5ce",
+		signing.ScriptTypeP2WSHP2SH: "3BBLivaThSP3C31jzmQJiMWBM7BLndaWfh",
+	} {
+		address := addresses.NewAccountAddress(
+			signing.NewConfiguration(scriptType, signing.NewEmptyAbsoluteKeypath(), xpubs, "", 2),
+			signing.NewEmptyRelativeKeypath(),
+			&chaincfg.MainNetParams,
+			logging.Get().WithGroup("addresses_test"),
+		)
+		require.Equal(t, expectedAddress, address.EncodeAddress(), scriptType)
+	}
+}
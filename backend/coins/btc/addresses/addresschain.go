@@ -61,6 +61,11 @@ func (addresses *AddressChain) GetUnused() []*AccountAddress {
 	return addresses.addresses[len(addresses.addresses)-unusedTailCount:]
 }
 
+// Addresses returns all addresses derived so far, in the order of derivation.
+func (addresses *AddressChain) Addresses() []*AccountAddress {
+	return append([]*AccountAddress{}, addresses.addresses...)
+}
+
 // addAddress appends a new address at the end of the chain.
 func (addresses *AddressChain) addAddress() *AccountAddress {
 	addresses.log.Debug("Add new address to chain")
@@ -79,7 +84,7 @@ func (addresses *AddressChain) addAddress() *AccountAddress {
 func (addresses *AddressChain) unusedTailCount() int {
 	count := 0
 	for i := len(addresses.addresses) - 1; i >= 0; i-- {
-		if addresses.addresses[i].isUsed() {
+		if addresses.addresses[i].IsUsed() {
 			break
 		}
 		count++
@@ -80,6 +80,15 @@ func (s *addressChainTestSuite) TestGetUnused() {
 	require.Equal(s.T(), newAddresses[1], s.addresses.GetUnused()[0])
 }
 
+func (s *addressChainTestSuite) TestAddresses() {
+	require.Empty(s.T(), s.addresses.Addresses())
+	newAddresses := s.addresses.EnsureAddresses()
+	require.Equal(s.T(), newAddresses, s.addresses.Addresses())
+	newAddresses[0].HistoryStatus = blockchain.TxHistory{tx1}.Status()
+	moreAddresses := s.addresses.EnsureAddresses()
+	require.Equal(s.T(), append(newAddresses, moreAddresses...), s.addresses.Addresses())
+}
+
 func (s *addressChainTestSuite) TestLookupByScriptHashHex() {
 	newAddresses := s.addresses.EnsureAddresses()
 	for _, address := range newAddresses {
@@ -14,18 +14,37 @@
 
 package addresses
 
-import "github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+)
 
-// SigScriptWitnessSize returns the maximum possible sigscript size for a given address type.
+// multisigScriptSize returns the size of the multisig script, which is the redeem script of a P2SH
+// multisig output or the witness script of a P2WSH multisig output.
+func multisigScriptSize(configuration *signing.Configuration) int {
+	// OP_N (1 byte, signingThreshold)
+	// numberOfSigners*(
+	// OP_DATA_33
+	// 33 bytes of compressed pubkey
+	// )
+	// OP_N (1 byte, numberOfSigners) OP_CHECKMULTISIG (1 byte)
+	return 1 + configuration.NumberOfSigners()*(1+33) + 1 + 1
+}
+
+// SigScriptWitnessSize returns the maximum possible sigscript size for a given address type, and
+// whether the address has a witness. See WitnessSize() for the size of the witness.
 func SigScriptWitnessSize(configuration *signing.Configuration) (int, bool) {
 	if configuration.Multisig() {
-		// OP_N (1 byte, signingThreshold)
-		// numberOfSigners*(
-		// OP_DATA_33
-		// 33 bytes of compressed pubkey
-		// )
-		// OP_N (1 byte, numberOfSigners) OP_CHECKMULTISIG (1 byte)
-		redeemScriptSize := 1 + configuration.NumberOfSigners()*(1+33) + 1 + 1
+		switch configuration.MultisigScriptType() {
+		case signing.ScriptTypeP2WSH:
+			return 0, true
+		case signing.ScriptTypeP2WSHP2SH:
+			// OP_0 (1 byte) OP_32 (1 byte) witnessScriptHash (32 bytes)
+			const redeemScriptSize = 1 + 1 + 32
+			// OP_DATA_34 (1 Byte) redeemScript (34 bytes)
+			return 1 + redeemScriptSize, true
+		}
+		redeemScriptSize := multisigScriptSize(configuration)
 		// OP_0 (1 byte)
 		// numSigs*(
 		// OP_DATA_72
@@ -59,7 +78,42 @@ func SigScriptWitnessSize(configuration *signing.Configuration) (int, bool) {
 		return 1 + redeemScriptSize, true
 	case signing.ScriptTypeP2WPKH:
 		return 0, true // hooray
+	case signing.ScriptTypeP2TR:
+		return 0, true
 	default:
 		panic("unknown address type")
 	}
 }
+
+// WitnessSize returns the maximum possible size of the serialized witness for a given address type,
+// or 0 if the address has no witness.
+func WitnessSize(configuration *signing.Configuration) int {
+	const (
+		// Including SIGHASH op. Assumes signatures follow the low-S requirement.
+		// See https://en.bitcoin.it/wiki/BIP_0062#DER_encoding
+		signatureSize = 72
+		pubkeySize    = 33
+	)
+	if _, hasWitness := SigScriptWitnessSize(configuration); !hasWitness {
+		return 0
+	}
+	if configuration.Multisig() {
+		// <empty> <signatures...> <witnessScript>
+		witnessScriptSize := multisigScriptSize(configuration)
+		numSigs := configuration.SigningThreshold()
+		return wire.VarIntSerializeSize(uint64(numSigs+2)) +
+			wire.VarIntSerializeSize(0) +
+			numSigs*(wire.VarIntSerializeSize(signatureSize)+signatureSize) +
+			wire.VarIntSerializeSize(uint64(witnessScriptSize)) + witnessScriptSize
+	}
+	if configuration.ScriptType() == signing.ScriptTypeP2TR {
+		// <64 bytes Schnorr signature>, using the default sighash type.
+		const schnorrSignatureSize = 64
+		return wire.VarIntSerializeSize(1) +
+			wire.VarIntSerializeSize(schnorrSignatureSize) + schnorrSignatureSize
+	}
+	// <serialized sig> <serialized compressed pubkey>
+	return wire.VarIntSerializeSize(2) +
+		wire.VarIntSerializeSize(signatureSize) + signatureSize +
+		wire.VarIntSerializeSize(pubkeySize) + pubkeySize
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/db/headersdb"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/electrum"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -48,12 +49,13 @@ type Coin struct {
 	net                   *chaincfg.Params
 	dbFolder              string
 	makeBlockchain        func() blockchain.Interface
+	makeHeaders           func() headers.Interface
 	blockExplorerTxPrefix string
 
 	observable.Implementation
 
 	blockchain blockchain.Interface
-	headers    *headers.Headers
+	headers    headers.Interface
 
 	log *logrus.Entry
 }
@@ -84,6 +86,7 @@ func NewCoin(
 		},
 		log: log,
 	}
+	coin.makeHeaders = coin.newHeaders
 	return coin
 }
 
@@ -94,23 +97,7 @@ func (coin *Coin) Initialize() {
 		coin.blockchain = coin.makeBlockchain()
 
 		// Init Headers
-
-		// delete old db version (up to v4.10.0, bbolt was used):
-		oldDBFilename := path.Join(coin.dbFolder, fmt.Sprintf("headers-%s.db", coin.code))
-		if _, err := os.Stat(oldDBFilename); err == nil {
-			_ = os.Remove(oldDBFilename)
-		}
-
-		db, err := headersdb.NewDB(
-			path.Join(coin.dbFolder, fmt.Sprintf("headers-%s.bin", coin.code)))
-		if err != nil {
-			coin.log.WithError(err).Panic("Could not open headers DB")
-		}
-		coin.headers = headers.NewHeaders(
-			coin.net,
-			db,
-			coin.blockchain,
-			coin.log)
+		coin.headers = coin.makeHeaders()
 		coin.headers.Initialize()
 		coin.headers.SubscribeEvent(func(event headers.Event) {
 			if event == headers.EventSyncing || event == headers.EventSynced {
@@ -128,6 +115,27 @@ func (coin *Coin) Initialize() {
 	})
 }
 
+// newHeaders creates the headers of the coin, stored in the coin's db folder and synced from its
+// blockchain backend.
+func (coin *Coin) newHeaders() headers.Interface {
+	// delete old db version (up to v4.10.0, bbolt was used):
+	oldDBFilename := path.Join(coin.dbFolder, fmt.Sprintf("headers-%s.db", coin.code))
+	if _, err := os.Stat(oldDBFilename); err == nil {
+		_ = os.Remove(oldDBFilename)
+	}
+
+	db, err := headersdb.NewDB(
+		path.Join(coin.dbFolder, fmt.Sprintf("headers-%s.bin", coin.code)))
+	if err != nil {
+		coin.log.WithError(err).Panic("Could not open headers DB")
+	}
+	return headers.NewHeaders(
+		coin.net,
+		db,
+		coin.blockchain,
+		coin.log)
+}
+
 // Code implements coin.Coin.
 func (coin *Coin) Code() coin.Code {
 	return coin.code
@@ -167,7 +175,7 @@ func (coin *Coin) Blockchain() blockchain.Interface {
 }
 
 // Headers returns the coin headers.
-func (coin *Coin) Headers() *headers.Headers {
+func (coin *Coin) Headers() headers.Interface {
 	return coin.headers
 }
 
@@ -195,7 +203,12 @@ func (coin *Coin) SmallestUnit() string {
 func (coin *Coin) DecodeAddress(address string) (btcutil.Address, error) {
 	btcAddress, err := btcutil.DecodeAddress(address, coin.Net())
 	if err != nil {
-		return nil, errp.WithStack(errors.ErrInvalidAddress)
+		// btcutil does not support taproot addresses yet.
+		taprootAddress, taprootErr := taproot.DecodeAddress(address, coin.Net())
+		if taprootErr != nil {
+			return nil, errp.WithStack(errors.ErrInvalidAddress)
+		}
+		btcAddress = taprootAddress
 	}
 	if !btcAddress.IsForNet(coin.Net()) {
 		return nil, errp.WithStack(errors.ErrInvalidAddress)
@@ -14,8 +14,15 @@
 
 package btc
 
-import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+import (
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
+)
 
 func (coin *Coin) TstSetMakeBlockchain(f func() blockchain.Interface) {
 	coin.makeBlockchain = f
 }
+
+func (coin *Coin) TstSetMakeHeaders(f func() headers.Interface) {
+	coin.makeHeaders = f
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// ConsolidationArgs are the arguments needed to propose a consolidation of unspent outputs.
+type ConsolidationArgs struct {
+	// Threshold, if not zero, restricts the consolidation to outputs with a smaller value.
+	Threshold btcutil.Amount
+	// SelectedUTXOs, if not empty, restricts the consolidation to these outputs. Frozen outputs can't
+	// be selected.
+	SelectedUTXOs map[wire.OutPoint]struct{}
+	// MaxInputs, if not zero, limits the number of consolidated outputs. The keystores stream the
+	// inputs when signing and impose no limit, but the transaction size is always limited to what
+	// is relayed by the network.
+	MaxInputs int
+	// FeeTargetCode is the fee target of the consolidation. If empty, the economy fee target is
+	// used, as a consolidation is usually not urgent.
+	FeeTargetCode accounts.FeeTargetCode
+	// CustomFee is the fee rate in sat/vB if FeeTargetCode is accounts.FeeTargetCodeCustom.
+	CustomFee string
+	// AllowHighFeeRate is true if the user confirmed a custom fee rate far above the estimates.
+	AllowHighFeeRate bool
+	// FutureFee is the fee rate in sat/vB at which the savings are estimated.
+	FutureFee string
+}
+
+// ConsolidationProposal describes a proposed consolidation transaction.
+type ConsolidationProposal struct {
+	// NumInputs is the number of outputs which are consolidated.
+	NumInputs int
+	// Amount is the value of the resulting output.
+	Amount btcutil.Amount
+	Fee    btcutil.Amount
+	// Savings is the fee saved when spending the consolidated output instead of the outputs it
+	// replaces, at the future fee rate. The fee of the consolidation itself is not deducted.
+	Savings btcutil.Amount
+}
+
+// ConsolidationProposal creates a transaction which merges unspent outputs into one output paying a
+// fresh change address of the account. Frozen outputs and outputs spent by scheduled transactions
+// are never consolidated. The number of outputs is limited by args.MaxInputs and the maximum
+// transaction size relayed by the network. Like TxProposal(), the proposal is stored internally and
+// can be signed and sent with SendTx().
+func (account *Account) ConsolidationProposal(args *ConsolidationArgs) (*ConsolidationProposal, error) {
+	defer account.activeTxProposalLock.Lock()()
+
+	if args.MaxInputs < 0 {
+		return nil, errp.New("The maximum number of inputs cannot be negative")
+	}
+
+	feeTargetCode := args.FeeTargetCode
+	if feeTargetCode == "" {
+		feeTargetCode = accounts.FeeTargetCodeEconomy
+	}
+	feeRatePerKb, err := account.targetFeeRatePerKb(
+		feeTargetCode, args.CustomFee, args.AllowHighFeeRate)
+	if err != nil {
+		return nil, err
+	}
+	futureFeeRatePerKb, err := parseFeeRatePerKb(args.FutureFee)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledOutPoints, err := account.scheduledOutPoints()
+	if err != nil {
+		return nil, err
+	}
+	spendableOutputs := account.transactions.SpendableOutputs()
+	for outPoint := range args.SelectedUTXOs {
+		output, ok := spendableOutputs[outPoint]
+		if !ok {
+			return nil, errp.Newf("Unknown unspent output %s", outPoint)
+		}
+		if output.Frozen {
+			return nil, errp.Newf("The unspent output %s is frozen", outPoint)
+		}
+		if _, ok := scheduledOutPoints[outPoint]; ok {
+			return nil, errp.Newf("The unspent output %s is spent by a scheduled transaction", outPoint)
+		}
+	}
+	utxos := map[wire.OutPoint]maketx.UTXO{}
+	for outPoint, output := range spendableOutputs {
+		if len(args.SelectedUTXOs) != 0 {
+			if _, ok := args.SelectedUTXOs[outPoint]; !ok {
+				continue
+			}
+		}
+		if args.Threshold != 0 && btcutil.Amount(output.TxOut.Value) >= args.Threshold {
+			continue
+		}
+		_, scheduled := scheduledOutPoints[outPoint]
+		utxos[outPoint] = maketx.UTXO{
+			TxOut: output.TxOut,
+			Configuration: account.getAddress(
+				blockchain.NewScriptHashHex(output.TxOut.PkScript)).Configuration,
+			Frozen: output.Frozen || scheduled,
+		}
+	}
+
+	txProposal, err := maketx.NewTxConsolidation(
+		account.coin,
+		utxos,
+		args.MaxInputs,
+		feeRatePerKb,
+		account.subaccounts[0].changeAddresses.GetUnused()[0],
+		account.log,
+	)
+	if err != nil {
+		return nil, err
+	}
+	account.activeTxProposal = txProposal
+
+	return &ConsolidationProposal{
+		NumInputs: len(txProposal.Transaction.TxIn),
+		Amount:    txProposal.Amount,
+		Fee:       txProposal.Fee,
+		Savings:   maketx.ConsolidationSavings(utxos, txProposal, futureFeeRatePerKb, account.log),
+	}, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// parentFee returns the absolute fee paid by a tx. For our own txs, it was already computed when
+// indexing the tx. For incoming txs, the spent outputs are fetched from the blockchain backend.
+func (account *Account) parentFee(txHash chainhash.Hash, txData *accounts.TransactionData) (
+	btcutil.Amount, error) {
+	if txData.Fee != nil {
+		fee, err := txData.Fee.Int64()
+		if err != nil {
+			return 0, err
+		}
+		return btcutil.Amount(fee), nil
+	}
+	tx, err := account.getPrevTx(txHash)
+	if err != nil {
+		return 0, err
+	}
+	var inputsSum, outputsSum btcutil.Amount
+	for _, txIn := range tx.TxIn {
+		prevTx, err := account.getPrevTx(txIn.PreviousOutPoint.Hash)
+		if err != nil {
+			return 0, err
+		}
+		if int(txIn.PreviousOutPoint.Index) >= len(prevTx.TxOut) {
+			return 0, errp.New("Invalid previous output")
+		}
+		inputsSum += btcutil.Amount(prevTx.TxOut[txIn.PreviousOutPoint.Index].Value)
+	}
+	for _, txOut := range tx.TxOut {
+		outputsSum += btcutil.Amount(txOut.Value)
+	}
+	return inputsSum - outputsSum, nil
+}
+
+// CPFP accelerates an unconfirmed transaction, incoming or outgoing, by spending its outputs
+// belonging to this account back to the wallet with a fee high enough for the parent and the child
+// together to reach the fee rate of the given fee target (child-pays-for-parent). The child is
+// signed and broadcast, and its ID is returned.
+func (account *Account) CPFP(txID string, feeTargetCode accounts.FeeTargetCode) (string, error) {
+	txHash, err := chainhash.NewHashFromStr(txID)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	parentOutputs, err := account.transactions.UnconfirmedTxOutputs(*txHash)
+	if err != nil {
+		return "", err
+	}
+	if len(parentOutputs) == 0 {
+		return "", errp.New("The transaction has no unspent outputs belonging to this account")
+	}
+	txs, err := account.Transactions()
+	if err != nil {
+		return "", err
+	}
+	var txData *accounts.TransactionData
+	for _, tx := range txs {
+		if tx.TxID == txID {
+			txData = tx
+			break
+		}
+	}
+	if txData == nil {
+		return "", errp.New("Unknown transaction")
+	}
+	parentFee, err := account.parentFee(*txHash, txData)
+	if err != nil {
+		return "", err
+	}
+	feeRatePerKb, err := account.feeRatePerKb(feeTargetCode)
+	if err != nil {
+		return "", err
+	}
+
+	utxos := make(map[wire.OutPoint]maketx.UTXO, len(parentOutputs))
+	for outPoint, output := range parentOutputs {
+		utxos[outPoint] = maketx.UTXO{
+			TxOut:         output.TxOut,
+			Configuration: account.getAddress(output.ScriptHashHex()).Configuration,
+			Frozen:        output.Frozen,
+		}
+	}
+	txProposal, err := maketx.NewTxCPFP(
+		account.coin,
+		utxos,
+		txData.VSize,
+		parentFee,
+		feeRatePerKb,
+		account.subaccounts[0].changeAddresses.GetUnused()[0],
+		account.log,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	account.log.WithField("parent", txID).Info("Signing and sending child-pays-for-parent transaction")
+	if err := account.signTransaction(txProposal, parentOutputs, account.getPrevTx); err != nil {
+		return "", errp.WithMessage(err, "Failed to sign transaction")
+	}
+	if err := account.coin.Blockchain().TransactionBroadcast(txProposal.Transaction); err != nil {
+		return "", err
+	}
+	return txProposal.Transaction.TxHash().String(), nil
+}
@@ -37,6 +37,8 @@ const (
 	bucketOutputs                = "outputs"
 	bucketAddressHistories       = "addressHistories"
 	bucketConfig                 = "config"
+	bucketUTXOMetadata           = "utxoMetadata"
+	bucketScheduledTxs           = "scheduledTxs"
 )
 
 // DB is a bbolt key/value database.
@@ -83,6 +85,14 @@ func (db *DB) Begin() (transactions.DBTxInterface, error) {
 	if err != nil {
 		return nil, errp.WithStack(err)
 	}
+	bucketUTXOMetadata, err := tx.CreateBucketIfNotExists([]byte(bucketUTXOMetadata))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	bucketScheduledTxs, err := tx.CreateBucketIfNotExists([]byte(bucketScheduledTxs))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
 	return &Tx{
 		tx:                           tx,
 		bucketTransactions:           bucketTransactions,
@@ -91,6 +101,8 @@ func (db *DB) Begin() (transactions.DBTxInterface, error) {
 		bucketOutputs:                bucketOutputs,
 		bucketAddressHistories:       bucketAddressHistories,
 		bucketConfig:                 bucketConfig,
+		bucketUTXOMetadata:           bucketUTXOMetadata,
+		bucketScheduledTxs:           bucketScheduledTxs,
 	}, nil
 }
 
@@ -109,6 +121,8 @@ type Tx struct {
 	bucketOutputs                *bbolt.Bucket
 	bucketAddressHistories       *bbolt.Bucket
 	bucketConfig                 *bbolt.Bucket
+	bucketUTXOMetadata           *bbolt.Bucket
+	bucketScheduledTxs           *bbolt.Bucket
 }
 
 // Rollback implements transactions.DBTxInterface.
@@ -246,6 +260,13 @@ func (tx *Tx) MarkTxVerified(txHash chainhash.Hash, headerTimestamp time.Time) e
 	})
 }
 
+// MarkTxReplaced implements transactions.DBTxInterface.
+func (tx *Tx) MarkTxReplaced(txHash chainhash.Hash, replacedBy chainhash.Hash) error {
+	return tx.modifyTx(txHash[:], func(walletTx *transactions.DBTxInfo) {
+		walletTx.ReplacedBy = &replacedBy
+	})
+}
+
 // PutInput implements transactions.DBTxInterface.
 func (tx *Tx) PutInput(outPoint wire.OutPoint, txHash chainhash.Hash) error {
 	return tx.bucketInputs.Put([]byte(outPoint.String()), txHash[:])
@@ -311,6 +332,51 @@ func (tx *Tx) DeleteOutput(outPoint wire.OutPoint) {
 	}
 }
 
+// PutUTXOMetadata implements transactions.DBTxInterface.
+func (tx *Tx) PutUTXOMetadata(outPoint wire.OutPoint, metadata transactions.UTXOMetadata) error {
+	if metadata == (transactions.UTXOMetadata{}) {
+		return errp.WithStack(tx.bucketUTXOMetadata.Delete([]byte(outPoint.String())))
+	}
+	return writeJSON(tx.bucketUTXOMetadata, []byte(outPoint.String()), metadata)
+}
+
+// UTXOMetadata implements transactions.DBTxInterface.
+func (tx *Tx) UTXOMetadata(outPoint wire.OutPoint) (transactions.UTXOMetadata, error) {
+	metadata := transactions.UTXOMetadata{}
+	_, err := readJSON(tx.bucketUTXOMetadata, []byte(outPoint.String()), &metadata)
+	return metadata, err
+}
+
+// PutScheduledTx implements transactions.DBTxInterface.
+func (tx *Tx) PutScheduledTx(txHash chainhash.Hash, scheduledTx *transactions.ScheduledTx) error {
+	return writeJSON(tx.bucketScheduledTxs, txHash[:], scheduledTx)
+}
+
+// DeleteScheduledTx implements transactions.DBTxInterface. It panics if called from a read-only db
+// transaction.
+func (tx *Tx) DeleteScheduledTx(txHash chainhash.Hash) {
+	if err := tx.bucketScheduledTxs.Delete(txHash[:]); err != nil {
+		panic(errp.WithStack(err))
+	}
+}
+
+// ScheduledTxs implements transactions.DBTxInterface.
+func (tx *Tx) ScheduledTxs() (map[chainhash.Hash]*transactions.ScheduledTx, error) {
+	result := map[chainhash.Hash]*transactions.ScheduledTx{}
+	txHashes, err := getTransactions(tx.bucketScheduledTxs)
+	if err != nil {
+		return nil, err
+	}
+	for _, txHash := range txHashes {
+		scheduledTx := &transactions.ScheduledTx{}
+		if _, err := readJSON(tx.bucketScheduledTxs, txHash[:], scheduledTx); err != nil {
+			return nil, err
+		}
+		result[txHash] = scheduledTx
+	}
+	return result, nil
+}
+
 // PutAddressHistory implements transactions.DBTxInterface.
 func (tx *Tx) PutAddressHistory(scriptHashHex blockchain.ScriptHashHex, history blockchain.TxHistory) error {
 	return writeJSON(tx.bucketAddressHistories, []byte(string(scriptHashHex)), history)
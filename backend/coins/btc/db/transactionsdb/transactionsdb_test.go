@@ -25,6 +25,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
 	"github.com/stretchr/testify/require"
@@ -165,6 +166,65 @@ func TestTxQuick(t *testing.T) {
 	})
 }
 
+func TestMarkTxReplaced(t *testing.T) {
+	testTx(func(tx *Tx) {
+		txHash := chainhash.HashH([]byte("original"))
+		replacedBy := chainhash.HashH([]byte("replacement"))
+		require.NoError(t, tx.PutTx(txHash, wire.NewMsgTx(wire.TxVersion), 0))
+		txInfo, err := tx.TxInfo(txHash)
+		require.NoError(t, err)
+		require.Nil(t, txInfo.ReplacedBy)
+
+		require.NoError(t, tx.MarkTxReplaced(txHash, replacedBy))
+		txInfo, err = tx.TxInfo(txHash)
+		require.NoError(t, err)
+		require.Equal(t, &replacedBy, txInfo.ReplacedBy)
+		require.Equal(t, 0, txInfo.Height)
+	})
+}
+
+func TestUTXOMetadata(t *testing.T) {
+	testTx(func(tx *Tx) {
+		outPoint := wire.OutPoint{Hash: chainhash.HashH([]byte("tx")), Index: 1}
+		metadata, err := tx.UTXOMetadata(outPoint)
+		require.NoError(t, err)
+		require.Equal(t, transactions.UTXOMetadata{}, metadata)
+
+		expected := transactions.UTXOMetadata{Frozen: true, Label: "dust"}
+		require.NoError(t, tx.PutUTXOMetadata(outPoint, expected))
+		metadata, err = tx.UTXOMetadata(outPoint)
+		require.NoError(t, err)
+		require.Equal(t, expected, metadata)
+
+		require.NoError(t, tx.PutUTXOMetadata(outPoint, transactions.UTXOMetadata{}))
+		require.Nil(t, tx.bucketUTXOMetadata.Get([]byte(outPoint.String())))
+	})
+}
+
+func TestScheduledTxs(t *testing.T) {
+	testTx(func(tx *Tx) {
+		scheduledTxs, err := tx.ScheduledTxs()
+		require.NoError(t, err)
+		require.Empty(t, scheduledTxs)
+
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, []byte{1, 2, 3}, nil))
+		msgTx.AddTxOut(wire.NewTxOut(1000, []byte{4, 5, 6}))
+		msgTx.LockTime = 650000
+		txHash := msgTx.TxHash()
+		expected := &transactions.ScheduledTx{Tx: msgTx, Error: "non-final"}
+		require.NoError(t, tx.PutScheduledTx(txHash, expected))
+		scheduledTxs, err = tx.ScheduledTxs()
+		require.NoError(t, err)
+		require.Equal(t, map[chainhash.Hash]*transactions.ScheduledTx{txHash: expected}, scheduledTxs)
+
+		tx.DeleteScheduledTx(txHash)
+		scheduledTxs, err = tx.ScheduledTxs()
+		require.NoError(t, err)
+		require.Empty(t, scheduledTxs)
+	})
+}
+
 func TestInput(t *testing.T) {
 	testTx(func(tx *Tx) {
 		outpoint1 := wire.OutPoint{
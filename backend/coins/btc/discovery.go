@@ -0,0 +1,80 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"sync"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+)
+
+// HasHistory returns true if any of the receive or change addresses of the signing configuration
+// within the default gap limits has a transaction history. This is used to discover used accounts,
+// see the account discovery section of BIP44. The signing configuration must be derived up to the
+// account level, e.g. m/84'/0'/1'.
+func HasHistory(coin *Coin, signingConfiguration *signing.Configuration) (bool, error) {
+	coin.Initialize()
+	gapLimits := defaultGapLimits(signingConfiguration)
+	var scriptHashes []blockchain.ScriptHashHex
+	for chainIndex, gapLimit := range []uint16{gapLimits.Receive, gapLimits.Change} {
+		for index := uint16(0); index < gapLimit; index++ {
+			address := addresses.NewAccountAddress(
+				signingConfiguration,
+				signing.NewEmptyRelativeKeypath().
+					Child(uint32(chainIndex), signing.NonHardened).
+					Child(uint32(index), signing.NonHardened),
+				coin.Net(),
+				coin.log,
+			)
+			scriptHashes = append(scriptHashes, address.PubkeyScriptHashHex())
+		}
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	hasHistory := false
+	var firstErr error
+	for _, scriptHash := range scriptHashes {
+		wg.Add(1)
+		coin.Blockchain().ScriptHashGetHistory(
+			scriptHash,
+			func(history blockchain.TxHistory) {
+				lock.Lock()
+				defer lock.Unlock()
+				if len(history) > 0 {
+					hasHistory = true
+				}
+			},
+			func(err error) {
+				defer wg.Done()
+				if err == nil {
+					return
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				if firstErr == nil {
+					firstErr = err
+				}
+			},
+		)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return false, firstErr
+	}
+	return hasHistory, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasHistory(t *testing.T) {
+	net := &chaincfg.TestNet3Params
+	dbFolder := test.TstTempDir("btc-dbfolder")
+	defer func() { _ = os.RemoveAll(dbFolder) }()
+
+	btcCoin := btc.NewCoin(
+		coin.CodeTBTC, "TBTC", net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
+	// Script hashes with a history, and the error returned for all script hashes.
+	var used map[blockchain.ScriptHashHex]bool
+	var historyErr error
+	mock := &blockchainMock.BlockchainMock{}
+	mock.MockScriptHashGetHistory = func(
+		scriptHash blockchain.ScriptHashHex, success func(blockchain.TxHistory), cleanup func(error)) {
+		go func() {
+			if historyErr != nil {
+				cleanup(historyErr)
+				return
+			}
+			history := blockchain.TxHistory{}
+			if used[scriptHash] {
+				history = append(history, &blockchain.TxInfo{Height: 1})
+			}
+			success(history)
+			cleanup(nil)
+		}()
+	}
+	btcCoin.TstSetMakeBlockchain(func() blockchain.Interface { return mock })
+
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/1'")
+	require.NoError(t, err)
+	xpub, err := hdkeychain.NewMaster(make([]byte, 32), net)
+	require.NoError(t, err)
+	xpub, err = xpub.Neuter()
+	require.NoError(t, err)
+	configuration := signing.NewSinglesigConfiguration(signing.ScriptTypeP2WPKH, keypath, xpub)
+	scriptHash := func(chain, index uint32) blockchain.ScriptHashHex {
+		return addresses.NewAccountAddress(
+			configuration,
+			signing.NewEmptyRelativeKeypath().
+				Child(chain, signing.NonHardened).
+				Child(index, signing.NonHardened),
+			net,
+			logging.Get().WithGroup("discovery_test"),
+		).PubkeyScriptHashHex()
+	}
+
+	// The receive (20) and change (6) addresses within the default gap limits are checked.
+	for _, testCase := range []struct {
+		chain, index uint32
+		hasHistory   bool
+	}{
+		{0, 0, true},
+		{0, 19, true},
+		{0, 20, false},
+		{1, 5, true},
+		{1, 6, false},
+	} {
+		used = map[blockchain.ScriptHashHex]bool{scriptHash(testCase.chain, testCase.index): true}
+		hasHistory, err := btc.HasHistory(btcCoin, configuration)
+		require.NoError(t, err)
+		require.Equal(t, testCase.hasHistory, hasHistory, "%d/%d", testCase.chain, testCase.index)
+	}
+
+	used = nil
+	hasHistory, err := btc.HasHistory(btcCoin, configuration)
+	require.NoError(t, err)
+	require.False(t, hasHistory)
+
+	historyErr = errors.New("connection lost")
+	_, err = btc.HasHistory(btcCoin, configuration)
+	require.Equal(t, historyErr, err)
+}
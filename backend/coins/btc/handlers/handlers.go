@@ -16,8 +16,11 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"math/big"
 	"net/http"
 	"os"
 	"path"
@@ -31,6 +34,8 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/safello"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/psbt"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
@@ -58,20 +63,37 @@ func NewHandlers(
 	handleFunc("/export", handlers.ensureAccountInitialized(handlers.postExportTransactions)).Methods("POST")
 	handleFunc("/info", handlers.ensureAccountInitialized(handlers.getAccountInfo)).Methods("GET")
 	handleFunc("/utxos", handlers.ensureAccountInitialized(handlers.getUTXOs)).Methods("GET")
+	handleFunc("/utxos/metadata", handlers.ensureAccountInitialized(handlers.postUTXOMetadata)).Methods("POST")
 	handleFunc("/balance", handlers.ensureAccountInitialized(handlers.getAccountBalance)).Methods("GET")
 	handleFunc("/sendtx", handlers.ensureAccountInitialized(handlers.postAccountSendTx)).Methods("POST")
 	handleFunc("/fee-targets", handlers.ensureAccountInitialized(handlers.getAccountFeeTargets)).Methods("GET")
 	handleFunc("/tx-proposal", handlers.ensureAccountInitialized(handlers.postAccountTxProposal)).Methods("POST")
+	handleFunc("/bump-fee", handlers.ensureAccountInitialized(handlers.postBumpFee)).Methods("POST")
+	handleFunc("/cpfp", handlers.ensureAccountInitialized(handlers.postCPFP)).Methods("POST")
+	handleFunc("/speed-up-tx", handlers.ensureAccountInitialized(handlers.postSpeedUpTx)).Methods("POST")
+	handleFunc("/cancel-tx", handlers.ensureAccountInitialized(handlers.postCancelTx)).Methods("POST")
+	handleFunc("/scheduled-txs", handlers.ensureAccountInitialized(handlers.getScheduledTxs)).Methods("GET")
+	handleFunc("/scheduled-txs/cancel", handlers.ensureAccountInitialized(handlers.postCancelScheduledTx)).Methods("POST")
+	handleFunc("/consolidation/proposal", handlers.ensureAccountInitialized(handlers.postConsolidationProposal)).Methods("POST")
+	handleFunc("/recipients/import-csv", handlers.ensureAccountInitialized(handlers.postImportRecipientsCSV)).Methods("POST")
+	handleFunc("/psbt/export", handlers.ensureAccountInitialized(handlers.postExportPSBT)).Methods("POST")
+	handleFunc("/psbt/sign", handlers.ensureAccountInitialized(handlers.postSignPSBT)).Methods("POST")
+	handleFunc("/psbt/finalize", handlers.ensureAccountInitialized(handlers.postFinalizePSBT)).Methods("POST")
+	handleFunc("/psbt/broadcast", handlers.ensureAccountInitialized(handlers.postBroadcastPSBT)).Methods("POST")
 	handleFunc("/receive-addresses", handlers.ensureAccountInitialized(handlers.getReceiveAddresses)).Methods("GET")
 	handleFunc("/verify-address", handlers.ensureAccountInitialized(handlers.postVerifyAddress)).Methods("POST")
 	handleFunc("/can-verify-extended-public-key", handlers.ensureAccountInitialized(handlers.getCanVerifyExtendedPublicKey)).Methods("GET")
 	handleFunc("/verify-extended-public-key", handlers.ensureAccountInitialized(handlers.postVerifyExtendedPublicKey)).Methods("POST")
 	handleFunc("/has-secure-output", handlers.ensureAccountInitialized(handlers.getHasSecureOutput)).Methods("GET")
+	handleFunc("/multisig/registered", handlers.ensureAccountInitialized(handlers.getMultisigRegistered)).Methods("GET")
+	handleFunc("/multisig/register", handlers.ensureAccountInitialized(handlers.postRegisterMultisig)).Methods("POST")
 	handleFunc("/exchange/safello/buy-supported", handlers.ensureAccountInitialized(handlers.getExchangeSafelloBuySupported)).Methods("GET")
 	handleFunc("/exchange/safello/buy", handlers.ensureAccountInitialized(handlers.getExchangeSafelloBuy)).Methods("GET")
 	handleFunc("/exchange/safello/process-message", handlers.ensureAccountInitialized(handlers.postExchangeSafelloProcessMessage)).Methods("POST")
 	handleFunc("/propose-tx-note", handlers.ensureAccountInitialized(handlers.postProposeTxNote)).Methods("POST")
 	handleFunc("/notes/tx", handlers.ensureAccountInitialized(handlers.postSetTxNote)).Methods("POST")
+	handleFunc("/notes/address", handlers.ensureAccountInitialized(handlers.postSetAddressLabel)).Methods("POST")
+	handleFunc("/addresses", handlers.ensureAccountInitialized(handlers.getAddresses)).Methods("GET")
 	return handlers
 }
 
@@ -122,6 +144,7 @@ type Transaction struct {
 	Fee                      FormattedAmount   `json:"fee"`
 	Time                     *string           `json:"time"`
 	Addresses                []string          `json:"addresses"`
+	AddressLabels            []string          `json:"addressLabels"`
 	Note                     string            `json:"note"`
 
 	// BTC specific fields.
@@ -131,7 +154,8 @@ type Transaction struct {
 	FeeRatePerKb FormattedAmount `json:"feeRatePerKb"`
 
 	// ETH specific fields
-	Gas uint64 `json:"gas"`
+	Gas          uint64 `json:"gas"`
+	ReplacesTxID string `json:"replacesTxID"`
 }
 
 func (handlers *Handlers) ensureAccountInitialized(h func(*http.Request) (interface{}, error)) func(*http.Request) (interface{}, error) {
@@ -160,8 +184,10 @@ func (handlers *Handlers) getAccountTransactions(_ *http.Request) (interface{},
 			formattedTime = &t
 		}
 		addresses := []string{}
+		addressLabels := []string{}
 		for _, addressAndAmount := range txInfo.Addresses {
 			addresses = append(addresses, addressAndAmount.Address)
+			addressLabels = append(addressLabels, addressAndAmount.Label)
 		}
 		txInfoJSON := Transaction{
 			TxID:                     txInfo.TxID,
@@ -173,12 +199,13 @@ func (handlers *Handlers) getAccountTransactions(_ *http.Request) (interface{},
 				accounts.TxTypeSend:     "send",
 				accounts.TxTypeSendSelf: "send_to_self",
 			}[txInfo.Type],
-			Status:    txInfo.Status,
-			Amount:    handlers.formatAmountAsJSON(txInfo.Amount, false),
-			Fee:       feeString,
-			Time:      formattedTime,
-			Addresses: addresses,
-			Note:      handlers.account.Notes().TxNote(txInfo.InternalID),
+			Status:        txInfo.Status,
+			Amount:        handlers.formatAmountAsJSON(txInfo.Amount, false),
+			Fee:           feeString,
+			Time:          formattedTime,
+			Addresses:     addresses,
+			AddressLabels: addressLabels,
+			Note:          handlers.account.Notes().TxNote(txInfo.InternalID),
 		}
 		switch handlers.account.Coin().(type) {
 		case *btc.Coin:
@@ -191,6 +218,7 @@ func (handlers *Handlers) getAccountTransactions(_ *http.Request) (interface{},
 			}
 		case *eth.Coin:
 			txInfoJSON.Gas = txInfo.Gas
+			txInfoJSON.ReplacesTxID = txInfo.ReplacesTxID
 		}
 		result = append(result, txInfoJSON)
 	}
@@ -244,12 +272,39 @@ func (handlers *Handlers) getUTXOs(_ *http.Request) (interface{}, error) {
 				"outPoint": output.OutPoint.String(),
 				"amount":   handlers.formatBTCAmountAsJSON(btcutil.Amount(output.TxOut.Value), false),
 				"address":  output.Address,
+				"frozen":   output.Frozen,
+				"label":    output.Label,
 			})
 	}
 
 	return result, nil
 }
 
+// postUTXOMetadata freezes/unfreezes and labels an unspent output. The request body is
+// `{"outPoint": "<txid>:<index>", "frozen": true, "label": "..."}`.
+func (handlers *Handlers) postUTXOMetadata(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	var input struct {
+		OutPoint string `json:"outPoint"`
+		Frozen   bool   `json:"frozen"`
+		Label    string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	outPoint, err := util.ParseOutPoint([]byte(input.OutPoint))
+	if err != nil {
+		return nil, err
+	}
+	return nil, btcAccount.SetUTXOMetadata(*outPoint, transactions.UTXOMetadata{
+		Frozen: input.Frozen,
+		Label:  input.Label,
+	})
+}
+
 func (handlers *Handlers) getAccountBalance(_ *http.Request) (interface{}, error) {
 	balance, err := handlers.account.Balance()
 	if err != nil {
@@ -266,16 +321,30 @@ type sendTxInput struct {
 	accounts.TxProposalArgs
 }
 
+// recipientJSON is a recipient of a batch payment.
+type recipientJSON struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+	SendAll bool   `json:"sendAll"`
+}
+
 func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
 	jsonBody := struct {
-		Address       string   `json:"address"`
-		SendAll       string   `json:"sendAll"`
-		FeeTarget     string   `json:"feeTarget"`
-		Amount        string   `json:"amount"`
-		SelectedUTXOS []string `json:"selectedUTXOS"`
-		Data          string   `json:"data"`
-		Note          string   `json:"note"`
-		Counter       int      `json:"counter"`
+		Address   string `json:"address"`
+		SendAll   string `json:"sendAll"`
+		FeeTarget string `json:"feeTarget"`
+		CustomFee string `json:"customFee"`
+		// AllowHighFeeRate confirms a custom fee rate rejected before with feeRateTooHigh.
+		AllowHighFeeRate bool     `json:"allowHighFeeRate"`
+		Amount           string   `json:"amount"`
+		SelectedUTXOs    []string `json:"selectedUTXOs"`
+		// Recipients replaces Address/SendAll/Amount for batch payments.
+		Recipients    []recipientJSON `json:"recipients"`
+		CoinSelection string          `json:"coinSelection"`
+		LockTime      uint32          `json:"lockTime"`
+		Data          string          `json:"data"`
+		Note          string          `json:"note"`
+		Counter       int             `json:"counter"`
 	}{}
 	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
 		return errp.WithStack(err)
@@ -286,19 +355,36 @@ func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
 	if err != nil {
 		return errp.WithMessage(err, "Failed to retrieve fee target code")
 	}
+	input.CustomFee = jsonBody.CustomFee
+	input.AllowHighFeeRate = jsonBody.AllowHighFeeRate
 	if jsonBody.SendAll == "yes" {
 		input.Amount = coin.NewSendAmountAll()
 	} else {
 		input.Amount = coin.NewSendAmount(jsonBody.Amount)
 	}
+	for _, recipient := range jsonBody.Recipients {
+		amount := coin.NewSendAmount(recipient.Amount)
+		if recipient.SendAll {
+			amount = coin.NewSendAmountAll()
+		}
+		input.Recipients = append(input.Recipients, accounts.Recipient{
+			Address: recipient.Address,
+			Amount:  amount,
+		})
+	}
 	input.SelectedUTXOs = map[wire.OutPoint]struct{}{}
-	for _, outPointString := range jsonBody.SelectedUTXOS {
+	for _, outPointString := range jsonBody.SelectedUTXOs {
 		outPoint, err := util.ParseOutPoint([]byte(outPointString))
 		if err != nil {
 			return err
 		}
 		input.SelectedUTXOs[*outPoint] = struct{}{}
 	}
+	input.CoinSelection, err = accounts.NewCoinSelectionStrategy(jsonBody.CoinSelection)
+	if err != nil {
+		return err
+	}
+	input.LockTime = jsonBody.LockTime
 	input.Data, err = hex.DecodeString(strings.TrimPrefix(jsonBody.Data, "0x"))
 	if err != nil {
 		return errp.WithStack(errors.ErrInvalidData)
@@ -312,6 +398,15 @@ func (handlers *Handlers) postAccountSendTx(r *http.Request) (interface{}, error
 	if errp.Cause(err) == keystore.ErrSigningAborted {
 		return map[string]interface{}{"success": false, "aborted": true}, nil
 	}
+	if errp.Cause(err) == btc.ErrMultisigSignaturesMissing {
+		return map[string]interface{}{"success": false, "errorCode": "multisigSignaturesMissing"}, nil
+	}
+	if errp.Cause(err) == keystore.ErrKeystoreNotConnected {
+		return map[string]interface{}{"success": false, "errorCode": "keystoreNotConnected"}, nil
+	}
+	if errp.Cause(err) == keystore.ErrMultisigNotRegistered {
+		return map[string]interface{}{"success": false, "errorCode": "multisigNotRegistered"}, nil
+	}
 	if err != nil {
 		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
 	}
@@ -337,14 +432,353 @@ func (handlers *Handlers) postAccountTxProposal(r *http.Request) (interface{}, e
 	if err != nil {
 		return txProposalError(err)
 	}
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"success": true,
 		"amount":  handlers.formatAmountAsJSON(outputAmount, false),
 		"fee":     handlers.formatAmountAsJSON(fee, true),
 		"total":   handlers.formatAmountAsJSON(total, false),
+	}
+	// For Ethereum, the fee is the expected fee, which can be lower than the max fee.
+	if ethAccount, ok := handlers.account.(*eth.Account); ok {
+		maxFee, err := ethAccount.ActiveTxProposalMaxFee()
+		if err != nil {
+			return nil, err
+		}
+		result["maxFee"] = handlers.formatAmountAsJSON(maxFee, true)
+	}
+	return result, nil
+}
+
+func (handlers *Handlers) btcAccount() (*btc.Account, error) {
+	btcAccount, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return nil, errp.New("An account must be BTC based")
+	}
+	return btcAccount, nil
+}
+
+// postImportRecipientsCSV parses a list of batch payment recipients from a CSV file, see
+// accounts.ParseRecipientsCSV. The request body is `{"csv": "..."}`. The addresses and amounts
+// are validated, so that the user can correct the file before proposing the transaction.
+func (handlers *Handlers) postImportRecipientsCSV(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	var input struct {
+		CSV string `json:"csv"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	recipients, err := accounts.ParseRecipientsCSV(strings.NewReader(input.CSV))
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(btcAccount.Coin().Decimals(false))), nil)
+	result := make([]recipientJSON, len(recipients))
+	for index, recipient := range recipients {
+		if _, err := btcAccount.Coin().(*btc.Coin).DecodeAddress(recipient.Address); err != nil {
+			return map[string]interface{}{
+				"success":      false,
+				"errorMessage": fmt.Sprintf("Invalid address %s: %v", recipient.Address, err),
+			}, nil
+		}
+		result[index] = recipientJSON{Address: recipient.Address, SendAll: recipient.Amount.SendAll()}
+		if recipient.Amount.SendAll() {
+			continue
+		}
+		allowZero := false
+		amount, err := recipient.Amount.Amount(unit, allowZero)
+		if err != nil {
+			return map[string]interface{}{
+				"success":      false,
+				"errorMessage": fmt.Sprintf("Invalid amount for %s", recipient.Address),
+			}, nil
+		}
+		result[index].Amount = btcAccount.Coin().FormatAmount(amount, false)
+	}
+	return map[string]interface{}{"success": true, "recipients": result}, nil
+}
+
+// getScheduledTxs returns the signed transactions which are broadcast once their locktime is
+// reached. Failed transactions are never broadcast; they are listed until they are cancelled.
+func (handlers *Handlers) getScheduledTxs(_ *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	scheduledTxs, err := btcAccount.ScheduledTxs()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]interface{}{}
+	for txHash, scheduledTx := range scheduledTxs {
+		var rawTx bytes.Buffer
+		if err := scheduledTx.Tx.Serialize(&rawTx); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		result = append(result, map[string]interface{}{
+			"txID":     txHash.String(),
+			"lockTime": scheduledTx.Tx.LockTime,
+			"rawTx":    hex.EncodeToString(rawTx.Bytes()),
+			"error":    scheduledTx.Error,
+			"failed":   scheduledTx.Failed,
+		})
+	}
+	return result, nil
+}
+
+// postCancelScheduledTx deletes a scheduled transaction. The request body is `{"txID": "..."}`.
+func (handlers *Handlers) postCancelScheduledTx(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	var input struct {
+		TxID string `json:"txID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return nil, btcAccount.CancelScheduledTx(input.TxID)
+}
+
+// postConsolidationProposal proposes a consolidation of unspent outputs, see
+// btc.Account.ConsolidationProposal. The proposal is sent with /sendtx. The request body is
+// `{"threshold": "0.001", "selectedUTXOs": [...], "maxInputs": 100, "feeTarget": "...",
+// "customFee": "...", "allowHighFeeRate": false, "futureFee": "..."}`, with all fields but futureFee
+// being optional.
+func (handlers *Handlers) postConsolidationProposal(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	var input struct {
+		Threshold        string   `json:"threshold"`
+		SelectedUTXOs    []string `json:"selectedUTXOs"`
+		MaxInputs        int      `json:"maxInputs"`
+		FeeTarget        string   `json:"feeTarget"`
+		CustomFee        string   `json:"customFee"`
+		AllowHighFeeRate bool     `json:"allowHighFeeRate"`
+		FutureFee        string   `json:"futureFee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	args := &btc.ConsolidationArgs{
+		SelectedUTXOs:    map[wire.OutPoint]struct{}{},
+		MaxInputs:        input.MaxInputs,
+		CustomFee:        input.CustomFee,
+		AllowHighFeeRate: input.AllowHighFeeRate,
+		FutureFee:        input.FutureFee,
+	}
+	if input.Threshold != "" {
+		unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(btcAccount.Coin().Decimals(false))), nil)
+		allowZero := false
+		threshold, err := coin.NewSendAmount(input.Threshold).Amount(unit, allowZero)
+		if err != nil {
+			return txProposalError(err)
+		}
+		thresholdInt64, err := threshold.Int64()
+		if err != nil {
+			return txProposalError(errp.WithStack(errors.ErrInvalidAmount))
+		}
+		args.Threshold = btcutil.Amount(thresholdInt64)
+	}
+	for _, outPointString := range input.SelectedUTXOs {
+		outPoint, err := util.ParseOutPoint([]byte(outPointString))
+		if err != nil {
+			return nil, err
+		}
+		args.SelectedUTXOs[*outPoint] = struct{}{}
+	}
+	if input.FeeTarget != "" {
+		args.FeeTargetCode, err = accounts.NewFeeTargetCode(input.FeeTarget)
+		if err != nil {
+			return nil, errp.WithMessage(err, "Failed to retrieve fee target code")
+		}
+	}
+	proposal, err := btcAccount.ConsolidationProposal(args)
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success":   true,
+		"numInputs": proposal.NumInputs,
+		"amount":    handlers.formatBTCAmountAsJSON(proposal.Amount, false),
+		"fee":       handlers.formatBTCAmountAsJSON(proposal.Fee, true),
+		"savings":   handlers.formatBTCAmountAsJSON(proposal.Savings, true),
 	}, nil
 }
 
+// accelerateTx decodes a request of the form `{"txID": "...", "feeTarget": "..."}` and calls the
+// given function to speed up (or cancel) the transaction.
+func (handlers *Handlers) accelerateTx(
+	r *http.Request,
+	accelerate func(string, accounts.FeeTargetCode) (string, error),
+) (interface{}, error) {
+	var input struct {
+		TxID      string `json:"txID"`
+		FeeTarget string `json:"feeTarget"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	feeTargetCode, err := accounts.NewFeeTargetCode(input.FeeTarget)
+	if err != nil {
+		return nil, errp.WithMessage(err, "Failed to retrieve fee target code")
+	}
+	txID, err := accelerate(input.TxID, feeTargetCode)
+	if errp.Cause(err) == keystore.ErrSigningAborted {
+		return map[string]interface{}{"success": false, "aborted": true}, nil
+	}
+	if errp.Cause(err) == keystore.ErrKeystoreNotConnected {
+		return map[string]interface{}{"success": false, "errorCode": "keystoreNotConnected"}, nil
+	}
+	if errp.Cause(err) == keystore.ErrMultisigNotRegistered {
+		return map[string]interface{}{"success": false, "errorCode": "multisigNotRegistered"}, nil
+	}
+	if validationErr, ok := errp.Cause(err).(errors.TxValidationError); ok {
+		return map[string]interface{}{"success": false, "errorCode": validationErr.Error()}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true, "txID": txID}, nil
+}
+
+func (handlers *Handlers) postBumpFee(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	return handlers.accelerateTx(r, btcAccount.BumpFee)
+}
+
+func (handlers *Handlers) postCPFP(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	return handlers.accelerateTx(r, btcAccount.CPFP)
+}
+
+func (handlers *Handlers) ethAccount() (*eth.Account, error) {
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return nil, errp.New("An account must be ETH based")
+	}
+	return ethAccount, nil
+}
+
+func (handlers *Handlers) postSpeedUpTx(r *http.Request) (interface{}, error) {
+	ethAccount, err := handlers.ethAccount()
+	if err != nil {
+		return nil, err
+	}
+	return handlers.accelerateTx(r, ethAccount.SpeedUpTx)
+}
+
+func (handlers *Handlers) postCancelTx(r *http.Request) (interface{}, error) {
+	ethAccount, err := handlers.ethAccount()
+	if err != nil {
+		return nil, err
+	}
+	return handlers.accelerateTx(r, ethAccount.CancelTx)
+}
+
+// decodePSBTInput decodes a request body of the form `{"psbt": "<base64>"}`.
+func decodePSBTInput(r *http.Request) (*psbt.Packet, error) {
+	var input struct {
+		PSBT string `json:"psbt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return psbt.NewFromBase64(strings.TrimSpace(input.PSBT))
+}
+
+func (handlers *Handlers) psbtResult(packet *psbt.Packet) (interface{}, error) {
+	encoded, err := packet.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"success":  true,
+		"psbt":     encoded,
+		"complete": packet.IsComplete(),
+	}, nil
+}
+
+func (handlers *Handlers) postExportPSBT(_ *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := btcAccount.TxProposalPSBT()
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return handlers.psbtResult(packet)
+}
+
+func (handlers *Handlers) postSignPSBT(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := decodePSBTInput(r)
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	err = btcAccount.SignPSBT(packet)
+	if errp.Cause(err) == keystore.ErrSigningAborted {
+		return map[string]interface{}{"success": false, "aborted": true}, nil
+	}
+	if errp.Cause(err) == keystore.ErrKeystoreNotConnected {
+		return map[string]interface{}{"success": false, "errorCode": "keystoreNotConnected"}, nil
+	}
+	if errp.Cause(err) == keystore.ErrMultisigNotRegistered {
+		return map[string]interface{}{"success": false, "errorCode": "multisigNotRegistered"}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return handlers.psbtResult(packet)
+}
+
+func (handlers *Handlers) postFinalizePSBT(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := decodePSBTInput(r)
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	if err := btcAccount.FinalizePSBT(packet); err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return handlers.psbtResult(packet)
+}
+
+func (handlers *Handlers) postBroadcastPSBT(r *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := decodePSBTInput(r)
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	txID, err := btcAccount.BroadcastPSBT(packet)
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true, "txID": txID}, nil
+}
+
 func (handlers *Handlers) getAccountFeeTargets(_ *http.Request) (interface{}, error) {
 	feeTargets, defaultFeeTarget := handlers.account.FeeTargets()
 	result := []map[string]interface{}{}
@@ -433,6 +867,38 @@ func (handlers *Handlers) postVerifyAddress(r *http.Request) (interface{}, error
 	return handlers.account.VerifyAddress(addressID)
 }
 
+func (handlers *Handlers) getMultisigRegistered(_ *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	return btcAccount.MultisigRegistered()
+}
+
+// postRegisterMultisig registers the multisig account on the connected keystores. The request body
+// is the name under which the account is registered.
+func (handlers *Handlers) postRegisterMultisig(r *http.Request) (interface{}, error) {
+	var name string
+	if err := json.NewDecoder(r.Body).Decode(&name); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	err = btcAccount.RegisterMultisig(name)
+	if errp.Cause(err) == keystore.ErrSigningAborted {
+		return map[string]interface{}{"success": false, "aborted": true}, nil
+	}
+	if errp.Cause(err) == keystore.ErrKeystoreNotConnected {
+		return map[string]interface{}{"success": false, "errorCode": "keystoreNotConnected"}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorMessage": err.Error()}, nil
+	}
+	return map[string]interface{}{"success": true}, nil
+}
+
 func (handlers *Handlers) getCanVerifyExtendedPublicKey(_ *http.Request) (interface{}, error) {
 	switch specificAccount := handlers.account.(type) {
 	case *btc.Account:
@@ -511,3 +977,36 @@ func (handlers *Handlers) postSetTxNote(r *http.Request) (interface{}, error) {
 
 	return nil, handlers.account.SetTxNote(args.InternalTxID, args.Note)
 }
+
+func (handlers *Handlers) postSetAddressLabel(r *http.Request) (interface{}, error) {
+	var args struct {
+		Address string `json:"address"`
+		Label   string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return nil, errp.WithStack(err)
+	}
+
+	return nil, handlers.account.SetAddressLabel(args.Address, args.Label)
+}
+
+// getAddresses lists all derived receive and change addresses with their label and usage.
+func (handlers *Handlers) getAddresses(_ *http.Request) (interface{}, error) {
+	btcAccount, err := handlers.btcAccount()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]interface{}{}
+	for _, info := range btcAccount.Addresses() {
+		result = append(result, map[string]interface{}{
+			"address": info.Address.EncodeForHumans(),
+			"keypath": info.Address.Configuration.AbsoluteKeypath().Encode(),
+			"change":  info.Change,
+			"label":   info.Label,
+			"used":    info.Used,
+			"balance": handlers.formatBTCAmountAsJSON(info.Balance, false),
+			"txCount": info.TxCount,
+		})
+	}
+	return result, nil
+}
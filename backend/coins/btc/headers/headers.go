@@ -56,6 +56,7 @@ type Interface interface {
 	VerifiedHeaderByHeight(int) (*wire.BlockHeader, error)
 	TipHeight() int
 	Status() (*Status, error)
+	Close() error
 }
 
 // Headers manages syncing blockchain headers.
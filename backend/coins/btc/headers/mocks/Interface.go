@@ -11,6 +11,20 @@ type Interface struct {
 	mock.Mock
 }
 
+// Close provides a mock function with given fields:
+func (_m *Interface) Close() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Initialize provides a mock function with given fields:
 func (_m *Interface) Initialize() {
 	_m.Called()
@@ -6,6 +6,8 @@
 package maketx
 
 import (
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
@@ -48,3 +50,15 @@ func isDustAmount(
 	// (output size + input size) is greater than 1/3 of the relay fee.
 	return int64(amount)*1000/(3*int64(totalSize)) < int64(relayFeePerKb)
 }
+
+// isDustOutput is like isDustAmount for an output which does not belong to the wallet, so the size
+// of the input spending it is unknown. Like in Bitcoin Core, the input is assumed to be 67 vbytes
+// if the output is a witness program, and 148 bytes otherwise.
+func isDustOutput(output *wire.TxOut, relayFeePerKb btcutil.Amount) bool {
+	inputSize := 148
+	if txscript.IsWitnessProgram(output.PkScript) {
+		inputSize = 67
+	}
+	totalSize := outputSize(len(output.PkScript)) + inputSize
+	return output.Value*1000/(3*int64(totalSize)) < int64(relayFeePerKb)
+}
@@ -0,0 +1,268 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maketx
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// CoinSelection is a strategy to select the coins spent by a new transaction. Use one of the
+// CoinSelection* values or constructors below.
+type CoinSelection struct {
+	// selectCoins selects a subset of the outputs with a sum of at least minAmount. It returns the
+	// sum and the selected outpoints, or errors.ErrInsufficientFunds.
+	selectCoins func(
+		minAmount btcutil.Amount,
+		outputs map[wire.OutPoint]UTXO,
+	) (btcutil.Amount, []wire.OutPoint, error)
+	// changeless is true if a selection which does not need a change output should be searched
+	// first, using branch and bound. selectCoins is the fallback if none is found.
+	changeless bool
+}
+
+var (
+	// CoinSelectionLargestFirst selects the largest coins first. It is the default.
+	CoinSelectionLargestFirst = &CoinSelection{selectCoins: selectLargestFirst}
+
+	// CoinSelectionBranchAndBound searches for a selection which matches the target closely enough
+	// to not need a change output, as in Bitcoin Core. If there is none, the largest coins are
+	// selected first.
+	CoinSelectionBranchAndBound = &CoinSelection{selectCoins: selectLargestFirst, changeless: true}
+
+	// CoinSelectionMinimizeLinkage prefers coins from as few addresses as possible, to avoid linking
+	// different addresses of the wallet together.
+	CoinSelectionMinimizeLinkage = &CoinSelection{selectCoins: selectMinimizeLinkage}
+)
+
+// NewCoinSelectionSingleRandomDraw returns a strategy which selects coins in random order until the
+// target is reached. The randomness source is passed in so that the result can be reproduced in
+// tests.
+func NewCoinSelectionSingleRandomDraw(random *rand.Rand) *CoinSelection {
+	seed := random.Int63()
+	return &CoinSelection{
+		selectCoins: func(
+			minAmount btcutil.Amount,
+			outputs map[wire.OutPoint]UTXO,
+		) (btcutil.Amount, []wire.OutPoint, error) {
+			// The same order is used if called again with a higher amount while computing the fee.
+			outPoints := sortedOutPoints(outputs)
+			rand.New(rand.NewSource(seed)).Shuffle(len(outPoints), func(i, j int) {
+				outPoints[i], outPoints[j] = outPoints[j], outPoints[i]
+			})
+			return selectInOrder(minAmount, outPoints, outputs)
+		},
+	}
+}
+
+type byValue struct {
+	outPoints []wire.OutPoint
+	outputs   map[wire.OutPoint]UTXO
+}
+
+func (p *byValue) Len() int { return len(p.outPoints) }
+func (p *byValue) Less(i, j int) bool {
+	if p.outputs[p.outPoints[i]].TxOut.Value == p.outputs[p.outPoints[j]].TxOut.Value {
+		// Secondary sort to make coin selection deterministic.
+		return chainhash.HashH(p.outputs[p.outPoints[i]].TxOut.PkScript).String() < chainhash.HashH(p.outputs[p.outPoints[j]].TxOut.PkScript).String()
+	}
+	return p.outputs[p.outPoints[i]].TxOut.Value < p.outputs[p.outPoints[j]].TxOut.Value
+}
+func (p *byValue) Swap(i, j int) { p.outPoints[i], p.outPoints[j] = p.outPoints[j], p.outPoints[i] }
+
+// sortedOutPoints returns the outpoints sorted by value, largest first. Ties are broken by the
+// outpoint so that the order does not depend on map iteration.
+func sortedOutPoints(outputs map[wire.OutPoint]UTXO) []wire.OutPoint {
+	outPoints := []wire.OutPoint{}
+	for outPoint := range outputs {
+		outPoints = append(outPoints, outPoint)
+	}
+	sort.Slice(outPoints, func(i, j int) bool {
+		if outPoints[i].Hash != outPoints[j].Hash {
+			return bytes.Compare(outPoints[i].Hash[:], outPoints[j].Hash[:]) < 0
+		}
+		return outPoints[i].Index < outPoints[j].Index
+	})
+	sort.Stable(sort.Reverse(&byValue{outPoints, outputs}))
+	return outPoints
+}
+
+// selectInOrder selects outputs in the given order until minAmount is reached.
+func selectInOrder(
+	minAmount btcutil.Amount,
+	outPoints []wire.OutPoint,
+	outputs map[wire.OutPoint]UTXO,
+) (btcutil.Amount, []wire.OutPoint, error) {
+	selectedOutPoints := []wire.OutPoint{}
+	outputsSum := btcutil.Amount(0)
+	for _, outPoint := range outPoints {
+		if outputsSum >= minAmount {
+			break
+		}
+		selectedOutPoints = append(selectedOutPoints, outPoint)
+		outputsSum += btcutil.Amount(outputs[outPoint].TxOut.Value)
+	}
+	if outputsSum < minAmount {
+		return 0, nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	return outputsSum, selectedOutPoints, nil
+}
+
+func selectLargestFirst(
+	minAmount btcutil.Amount,
+	outputs map[wire.OutPoint]UTXO,
+) (btcutil.Amount, []wire.OutPoint, error) {
+	outPoints := []wire.OutPoint{}
+	for outPoint := range outputs {
+		outPoints = append(outPoints, outPoint)
+	}
+	sort.Sort(sort.Reverse(&byValue{outPoints, outputs}))
+	return selectInOrder(minAmount, outPoints, outputs)
+}
+
+// selectMinimizeLinkage spends the coins of a single address if possible, choosing the address
+// whose coins exceed the target the least. Otherwise, whole addresses are added, largest first,
+// until the target is reached. All coins of an address are spent together, as they are already
+// linked by the address reuse.
+func selectMinimizeLinkage(
+	minAmount btcutil.Amount,
+	outputs map[wire.OutPoint]UTXO,
+) (btcutil.Amount, []wire.OutPoint, error) {
+	type addressGroup struct {
+		outPoints []wire.OutPoint
+		sum       btcutil.Amount
+	}
+	groupsByScript := map[string]*addressGroup{}
+	groups := []*addressGroup{}
+	for _, outPoint := range sortedOutPoints(outputs) {
+		pkScript := string(outputs[outPoint].TxOut.PkScript)
+		group, ok := groupsByScript[pkScript]
+		if !ok {
+			group = &addressGroup{}
+			groupsByScript[pkScript] = group
+			groups = append(groups, group)
+		}
+		group.outPoints = append(group.outPoints, outPoint)
+		group.sum += btcutil.Amount(outputs[outPoint].TxOut.Value)
+	}
+	// Stable sort keeps the groups in the order of their largest coin if the sums are equal.
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].sum > groups[j].sum })
+
+	// The groups are sorted largest first, so the last one covering the target is the best match.
+	var best *addressGroup
+	for _, group := range groups {
+		if group.sum >= minAmount {
+			best = group
+		}
+	}
+	if best != nil {
+		return best.sum, best.outPoints, nil
+	}
+	selectedOutPoints := []wire.OutPoint{}
+	outputsSum := btcutil.Amount(0)
+	for _, group := range groups {
+		if outputsSum >= minAmount {
+			break
+		}
+		selectedOutPoints = append(selectedOutPoints, group.outPoints...)
+		outputsSum += group.sum
+	}
+	if outputsSum < minAmount {
+		return 0, nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	return outputsSum, selectedOutPoints, nil
+}
+
+// branchAndBoundMaxTries limits the number of visited nodes in the search tree, as in Bitcoin Core.
+const branchAndBoundMaxTries = 100000
+
+// selectBranchAndBound searches for a subset of the given values whose sum is in the range
+// [target, target+costOfChange], minimizing the excess over the target. The values are the
+// effective values of the coins (value minus the fee needed to spend it) and must be sorted in
+// descending order. The indices of the selected values are returned, or nil if no such subset was
+// found. This is a port of the algorithm used in Bitcoin Core, see
+// https://murch.one/wp-content/uploads/2016/11/erhardt2016coinselection.pdf.
+func selectBranchAndBound(
+	values []btcutil.Amount,
+	target btcutil.Amount,
+	costOfChange btcutil.Amount,
+) []int {
+	var available btcutil.Amount
+	for _, value := range values {
+		available += value
+	}
+	if available < target {
+		return nil
+	}
+	var currentValue btcutil.Amount
+	currentSelection := []int{}
+	var bestSelection []int
+	var bestExcess btcutil.Amount
+
+	for tries, index := 0, 0; tries < branchAndBoundMaxTries; tries, index = tries+1, index+1 {
+		backtrack := false
+		if currentValue+available < target || currentValue > target+costOfChange {
+			// Cannot reach the target anymore, or overshot it.
+			backtrack = true
+		} else if currentValue >= target {
+			// Found a solution.
+			excess := currentValue - target
+			if bestSelection == nil || excess < bestExcess {
+				bestSelection = append([]int{}, currentSelection...)
+				bestExcess = excess
+				if excess == 0 {
+					break
+				}
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			if len(currentSelection) == 0 {
+				// Explored the whole tree.
+				break
+			}
+			last := currentSelection[len(currentSelection)-1]
+			// Add the omitted values after the last included one back to the lookahead.
+			for index--; index > last; index-- {
+				available += values[index]
+			}
+			// The last included value was explored, try the branch omitting it.
+			currentValue -= values[index]
+			currentSelection = currentSelection[:len(currentSelection)-1]
+			continue
+		}
+
+		available -= values[index]
+		if len(currentSelection) != 0 {
+			last := currentSelection[len(currentSelection)-1]
+			if last != index-1 && values[index] == values[index-1] {
+				// Including this value is equivalent to including the previous (omitted) value,
+				// which was already explored.
+				continue
+			}
+		}
+		currentSelection = append(currentSelection, index)
+		currentValue += values[index]
+	}
+	return bestSelection
+}
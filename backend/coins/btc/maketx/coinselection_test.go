@@ -0,0 +1,136 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maketx_test
+
+import (
+	"math/rand"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/stretchr/testify/require"
+)
+
+// buildUTXOOnAddresses builds an utxo set, the i-th utxo being sent to someAddresses[addressIndices[i]].
+func (s *newTxSuite) buildUTXOOnAddresses(
+	satoshis []int64, addressIndices []int) map[wire.OutPoint]maketx.UTXO {
+	utxo := map[wire.OutPoint]maketx.UTXO{}
+	for i, satoshi := range satoshis {
+		utxo[s.outpoint(i)] = maketx.UTXO{
+			TxOut:         wire.NewTxOut(satoshi, s.someAddresses[addressIndices[i]].PubkeyScript()),
+			Configuration: s.inputConfiguration,
+		}
+	}
+	return utxo
+}
+
+func (s *newTxSuite) newTxWithCoinSelection(
+	amount btcutil.Amount,
+	feePerKb btcutil.Amount,
+	utxo map[wire.OutPoint]maketx.UTXO,
+	coinSelection *maketx.CoinSelection,
+) *maketx.TxProposal {
+	txProposal, err := maketx.NewTx(
+		s.coin, utxo, []*wire.TxOut{s.output(amount)}, feePerKb, s.changeAddress, coinSelection, s.log)
+	require.NoError(s.T(), err)
+	return txProposal
+}
+
+func (s *newTxSuite) spentOutPoints(txProposal *maketx.TxProposal) map[wire.OutPoint]struct{} {
+	result := map[wire.OutPoint]struct{}{}
+	for _, txIn := range txProposal.Transaction.TxIn {
+		result[txIn.PreviousOutPoint] = struct{}{}
+	}
+	return result
+}
+
+func (s *newTxSuite) TestCoinSelectionBranchAndBound() {
+	// Without fees, an exact match is found, which the largest first strategy would miss.
+	utxo := s.buildUTXO(1000, 2000, 3000, 5000, 8000)
+	txProposal := s.newTxWithCoinSelection(10000, 0, utxo, maketx.CoinSelectionBranchAndBound)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 1)
+	require.Equal(s.T(), btcutil.Amount(0), txProposal.Fee)
+	require.Equal(s.T(),
+		map[wire.OutPoint]struct{}{s.outpoint(1): {}, s.outpoint(4): {}},
+		s.spentOutPoints(txProposal))
+	txProposal = s.newTxWithCoinSelection(10000, 0, utxo, nil)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 2)
+
+	// With fees, the effective values (value minus 148 sat for spending a P2PKH input at 1 sat/vB)
+	// are matched against the amount plus the fee of the tx without inputs (44 sat).
+	utxo = s.buildUTXO(5148, 3148, 2148, 20000)
+	txProposal = s.newTxWithCoinSelection(10000-44, 1000, utxo, maketx.CoinSelectionBranchAndBound)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 1)
+	require.Equal(s.T(),
+		map[wire.OutPoint]struct{}{s.outpoint(0): {}, s.outpoint(1): {}, s.outpoint(2): {}},
+		s.spentOutPoints(txProposal))
+	// Size of a tx with three P2PKH inputs and one output.
+	require.Equal(s.T(), btcutil.Amount(488), txProposal.Fee)
+
+	// An excess smaller than the cost of a change output (182 sat) goes to the fee.
+	txProposal = s.newTxWithCoinSelection(10000-44-100, 1000, utxo, maketx.CoinSelectionBranchAndBound)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 1)
+	require.Equal(s.T(), btcutil.Amount(588), txProposal.Fee)
+
+	// No changeless solution: falls back to the largest first.
+	utxo = s.buildUTXO(100000)
+	txProposal = s.newTxWithCoinSelection(50000, 1000, utxo, maketx.CoinSelectionBranchAndBound)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 2)
+	require.Equal(s.T(), btcutil.Amount(txSizeOneInput), txProposal.Fee)
+}
+
+func (s *newTxSuite) TestCoinSelectionMinimizeLinkage() {
+	// Address 0: 3000, 3000; address 1: 7000; address 2: 4000.
+	utxo := s.buildUTXOOnAddresses([]int64{3000, 3000, 7000, 4000}, []int{0, 0, 1, 2})
+
+	// Address 0 covers the amount with the least excess. Both coins are spent.
+	txProposal := s.newTxWithCoinSelection(5000, 0, utxo, maketx.CoinSelectionMinimizeLinkage)
+	require.Equal(s.T(),
+		map[wire.OutPoint]struct{}{s.outpoint(0): {}, s.outpoint(1): {}},
+		s.spentOutPoints(txProposal))
+	// The largest first strategy uses the coin of address 1.
+	txProposal = s.newTxWithCoinSelection(5000, 0, utxo, nil)
+	require.Equal(s.T(),
+		map[wire.OutPoint]struct{}{s.outpoint(2): {}},
+		s.spentOutPoints(txProposal))
+
+	// No single address covers the amount. The addresses with the largest sums are used.
+	txProposal = s.newTxWithCoinSelection(12000, 0, utxo, maketx.CoinSelectionMinimizeLinkage)
+	require.Equal(s.T(),
+		map[wire.OutPoint]struct{}{s.outpoint(0): {}, s.outpoint(1): {}, s.outpoint(2): {}},
+		s.spentOutPoints(txProposal))
+}
+
+func (s *newTxSuite) TestCoinSelectionSingleRandomDraw() {
+	utxo := s.buildUTXO(1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000)
+	selections := map[string]struct{}{}
+	for seed := int64(0); seed < 10; seed++ {
+		txProposal := s.newTxWithCoinSelection(
+			9000, 1000, utxo, maketx.NewCoinSelectionSingleRandomDraw(rand.New(rand.NewSource(seed))))
+		// The same seed leads to the same selection.
+		txProposal2 := s.newTxWithCoinSelection(
+			9000, 1000, utxo, maketx.NewCoinSelectionSingleRandomDraw(rand.New(rand.NewSource(seed))))
+		require.Equal(s.T(), txProposal.Transaction.TxHash(), txProposal2.Transaction.TxHash())
+
+		var inputsSum btcutil.Amount
+		for outPoint := range s.spentOutPoints(txProposal) {
+			inputsSum += btcutil.Amount(utxo[outPoint].TxOut.Value)
+		}
+		require.True(s.T(), inputsSum >= txProposal.Total())
+		selections[txProposal.Transaction.TxHash().String()] = struct{}{}
+	}
+	// Different seeds lead to different selections.
+	require.True(s.T(), len(selections) > 1)
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package maketx
+
+import (
+	"sort"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/txsort"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/sirupsen/logrus"
+)
+
+// maxStandardTxVSize is the largest transaction size in vbytes which is relayed by nodes using the
+// default policy (400000 weight units).
+const maxStandardTxVSize = 100000
+
+// NewTxConsolidation creates a transaction which spends the given unspent outputs which are not
+// frozen to a single output paying changeAddress. If maxInputs is not zero, at most that many
+// outputs are spent. The smallest outputs are preferred, as they are the most expensive to spend
+// relative to their value. Outputs are also left out if the transaction would otherwise be too big
+// to be relayed.
+func NewTxConsolidation(
+	coin coinpkg.Coin,
+	spendableOutputs map[wire.OutPoint]UTXO,
+	maxInputs int,
+	feePerKb btcutil.Amount,
+	changeAddress *addresses.AccountAddress,
+	log *logrus.Entry,
+) (*TxProposal, error) {
+	spendableOutputs = withoutFrozen(spendableOutputs)
+	outPoints := make([]wire.OutPoint, 0, len(spendableOutputs))
+	for outPoint := range spendableOutputs {
+		outPoints = append(outPoints, outPoint)
+	}
+	sort.Slice(outPoints, func(i, j int) bool {
+		valueI := spendableOutputs[outPoints[i]].TxOut.Value
+		valueJ := spendableOutputs[outPoints[j]].TxOut.Value
+		if valueI != valueJ {
+			return valueI < valueJ
+		}
+		return outPoints[i].String() < outPoints[j].String()
+	})
+	if maxInputs != 0 && len(outPoints) > maxInputs {
+		outPoints = outPoints[:maxInputs]
+	}
+	changePKScript := changeAddress.PubkeyScript()
+	for len(outPoints) > 0 && estimateTxSize(
+		toInputConfigurations(spendableOutputs, outPoints),
+		nil,
+		len(changePKScript)) > maxStandardTxVSize {
+		outPoints = outPoints[:len(outPoints)-1]
+	}
+	if len(outPoints) < 2 {
+		return nil, errp.New("At least two unspent outputs are needed for a consolidation")
+	}
+
+	inputs := make([]*wire.TxIn, len(outPoints))
+	outputsSum := btcutil.Amount(0)
+	for index, outPoint := range outPoints {
+		outPoint := outPoint // avoid reference reuse due to range loop
+		outputsSum += btcutil.Amount(spendableOutputs[outPoint].TxOut.Value)
+		inputs[index] = wire.NewTxIn(&outPoint, nil, nil)
+	}
+	txSize := estimateTxSize(
+		toInputConfigurations(spendableOutputs, outPoints),
+		nil,
+		len(changePKScript))
+	fee := feeForSerializeSize(feePerKb, txSize, log)
+	if outputsSum < fee {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	amount := outputsSum - fee
+	if isDustAmount(amount, len(changePKScript), changeAddress.Configuration, feePerKb) {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     inputs,
+		TxOut:    []*wire.TxOut{wire.NewTxOut(int64(amount), changePKScript)},
+		LockTime: 0,
+	}
+	txsort.InPlaceSort(unsignedTransaction)
+	log.WithFields(logrus.Fields{"fee": fee, "inputs": len(inputs)}).
+		Debug("Preparing consolidation transaction")
+
+	setRBF(coin, unsignedTransaction)
+	return &TxProposal{
+		Coin:          coin,
+		Amount:        amount,
+		Fee:           fee,
+		Transaction:   unsignedTransaction,
+		ChangeAddress: changeAddress,
+	}, nil
+}
+
+// ConsolidationSavings estimates the fee saved at the given future fee rate by spending the output
+// of a consolidation transaction instead of the outputs it spends. The fee of the consolidation
+// transaction itself is not deducted.
+func ConsolidationSavings(
+	spendableOutputs map[wire.OutPoint]UTXO,
+	txProposal *TxProposal,
+	futureFeePerKb btcutil.Amount,
+	log *logrus.Entry,
+) btcutil.Amount {
+	inputConfigurations := make([]*signing.Configuration, len(txProposal.Transaction.TxIn))
+	for index, txIn := range txProposal.Transaction.TxIn {
+		inputConfigurations[index] = spendableOutputs[txIn.PreviousOutPoint].Configuration
+	}
+	// The size of the future transaction apart from its inputs does not depend on the inputs
+	// (modulo the input count varint), so a single change output stands in for its outputs.
+	changePkScriptSize := len(txProposal.ChangeAddress.PubkeyScript())
+	sizeBefore := estimateTxSize(inputConfigurations, nil, changePkScriptSize)
+	sizeAfter := estimateTxSize(
+		[]*signing.Configuration{txProposal.ChangeAddress.Configuration}, nil, changePkScriptSize)
+	return feeForSerializeSize(futureFeePerKb, sizeBefore, log) -
+		feeForSerializeSize(futureFeePerKb, sizeAfter, log)
+}
@@ -17,7 +17,6 @@ package maketx
 import (
 	"sort"
 
-	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/txsort"
@@ -51,46 +50,20 @@ func (txProposal *TxProposal) Total() btcutil.Amount {
 type UTXO struct {
 	TxOut         *wire.TxOut
 	Configuration *signing.Configuration
+	// Frozen is true if the user marked the output as not to be spent. Frozen outputs are never
+	// selected.
+	Frozen bool
 }
 
-type byValue struct {
-	outPoints []wire.OutPoint
-	outputs   map[wire.OutPoint]UTXO
-}
-
-func (p *byValue) Len() int { return len(p.outPoints) }
-func (p *byValue) Less(i, j int) bool {
-	if p.outputs[p.outPoints[i]].TxOut.Value == p.outputs[p.outPoints[j]].TxOut.Value {
-		// Secondary sort to make coin selection deterministic.
-		return chainhash.HashH(p.outputs[p.outPoints[i]].TxOut.PkScript).String() < chainhash.HashH(p.outputs[p.outPoints[j]].TxOut.PkScript).String()
-	}
-	return p.outputs[p.outPoints[i]].TxOut.Value < p.outputs[p.outPoints[j]].TxOut.Value
-}
-func (p *byValue) Swap(i, j int) { p.outPoints[i], p.outPoints[j] = p.outPoints[j], p.outPoints[i] }
-
-func coinSelection(
-	minAmount btcutil.Amount,
-	outputs map[wire.OutPoint]UTXO,
-) (btcutil.Amount, []wire.OutPoint, error) {
-	outPoints := []wire.OutPoint{}
-	for outPoint := range outputs {
-		outPoints = append(outPoints, outPoint)
-	}
-	sort.Sort(sort.Reverse(&byValue{outPoints, outputs}))
-	selectedOutPoints := []wire.OutPoint{}
-	outputsSum := btcutil.Amount(0)
-
-	for _, outPoint := range outPoints {
-		if outputsSum >= minAmount {
-			break
+// withoutFrozen returns the outputs which are not frozen.
+func withoutFrozen(utxos map[wire.OutPoint]UTXO) map[wire.OutPoint]UTXO {
+	result := make(map[wire.OutPoint]UTXO, len(utxos))
+	for outPoint, utxo := range utxos {
+		if !utxo.Frozen {
+			result[outPoint] = utxo
 		}
-		selectedOutPoints = append(selectedOutPoints, outPoint)
-		outputsSum += btcutil.Amount(outputs[outPoint].TxOut.Value)
 	}
-	if outputsSum < minAmount {
-		return 0, nil, errp.WithStack(errors.ErrInsufficientFunds)
-	}
-	return outputsSum, selectedOutPoints, nil
+	return result
 }
 
 // toInputConfigurations converts selected inputs to input configurations.
@@ -115,21 +88,55 @@ func setRBF(coin coinpkg.Coin, tx *wire.MsgTx) {
 			coin.Code() == coinpkg.CodeRBTC {
 			// Enable RBF
 			// https://github.com/bitcoin/bips/blob/master/bip-0125.mediawiki#summary
-			// Locktime is also enabled by this (https://en.bitcoin.it/wiki/NLockTime), but we keep
-			// the locktime at 0, which has no effect.
+			// Locktime is also enabled by this (https://en.bitcoin.it/wiki/NLockTime). The locktime
+			// is 0, which has no effect, unless it is set with SetLockTime().
 			txIn.Sequence = wire.MaxTxInSequenceNum - 2
 		}
 	}
 }
 
-// NewTxSpendAll creates a transaction which spends all available unspent outputs.
+// SetLockTime sets the absolute locktime of the tx, a block height or a unix timestamp. The
+// locktime is only enforced if at least one input has a non-final sequence number, so final
+// sequence numbers (e.g. for coins without RBF) are lowered by one.
+func SetLockTime(tx *wire.MsgTx, lockTime uint32) {
+	tx.LockTime = lockTime
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence == wire.MaxTxInSequenceNum {
+			txIn.Sequence = wire.MaxTxInSequenceNum - 1
+		}
+	}
+}
+
+// pkScriptSizes returns the sizes of the pkScripts of the outputs.
+func pkScriptSizes(outputs []*wire.TxOut) []int {
+	sizes := make([]int, len(outputs))
+	for index, output := range outputs {
+		sizes[index] = len(output.PkScript)
+	}
+	return sizes
+}
+
+// sumOutputs returns the sum of the output values.
+func sumOutputs(outputs []*wire.TxOut) btcutil.Amount {
+	sum := btcutil.Amount(0)
+	for _, output := range outputs {
+		sum += btcutil.Amount(output.Value)
+	}
+	return sum
+}
+
+// NewTxSpendAll creates a transaction which spends all available unspent outputs which are not
+// frozen. The given outputs are paid as they are, and everything that remains after the fee is sent
+// to outputPkScript. errors.ErrInsufficientFunds is returned if the remainder is dust.
 func NewTxSpendAll(
 	coin coinpkg.Coin,
 	spendableOutputs map[wire.OutPoint]UTXO,
+	outputs []*wire.TxOut,
 	outputPkScript []byte,
 	feePerKb btcutil.Amount,
 	log *logrus.Entry,
 ) (*TxProposal, error) {
+	spendableOutputs = withoutFrozen(spendableOutputs)
 	selectedOutPoints := []wire.OutPoint{}
 	inputs := []*wire.TxIn{}
 	outputsSum := btcutil.Amount(0)
@@ -141,17 +148,22 @@ func NewTxSpendAll(
 	}
 	txSize := estimateTxSize(
 		toInputConfigurations(spendableOutputs, selectedOutPoints),
-		len(outputPkScript),
+		append(pkScriptSizes(outputs), len(outputPkScript)),
 		0)
 	maxRequiredFee := feeForSerializeSize(feePerKb, txSize, log)
-	if outputsSum < maxRequiredFee {
+	fixedAmount := sumOutputs(outputs)
+	if outputsSum < fixedAmount+maxRequiredFee {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	output := wire.NewTxOut(int64(outputsSum-fixedAmount-maxRequiredFee), outputPkScript)
+	// With fixed outputs, the remainder can be too small to be relayed.
+	if isDustOutput(output, feePerKb) {
 		return nil, errp.WithStack(errors.ErrInsufficientFunds)
 	}
-	output := wire.NewTxOut(int64(outputsSum-maxRequiredFee), outputPkScript)
 	unsignedTransaction := &wire.MsgTx{
 		Version:  wire.TxVersion,
 		TxIn:     inputs,
-		TxOut:    []*wire.TxOut{output},
+		TxOut:    append(append([]*wire.TxOut{}, outputs...), output),
 		LockTime: 0,
 	}
 	txsort.InPlaceSort(unsignedTransaction)
@@ -160,34 +172,133 @@ func NewTxSpendAll(
 	setRBF(coin, unsignedTransaction)
 	return &TxProposal{
 		Coin:        coin,
-		Amount:      btcutil.Amount(output.Value),
+		Amount:      fixedAmount + btcutil.Amount(output.Value),
 		Fee:         maxRequiredFee,
 		Transaction: unsignedTransaction,
 	}, nil
 }
 
-// NewTx creates a transaction from a set of unspent outputs, targeting an output value. A subset of
+// newTxChangeless tries to find a selection of coins which covers the output and the fee closely
+// enough that no change output is needed, using branch and bound. The excess is added to the fee.
+// Returns nil if no such selection is found.
+func newTxChangeless(
+	coin coinpkg.Coin,
+	spendableOutputs map[wire.OutPoint]UTXO,
+	outputs []*wire.TxOut,
+	feePerKb btcutil.Amount,
+	changeAddress *addresses.AccountAddress,
+	log *logrus.Entry,
+) *TxProposal {
+	targetAmount := sumOutputs(outputs)
+	outputPkScriptSizes := pkScriptSizes(outputs)
+	baseFee := feeForSerializeSize(feePerKb, estimateTxSize(nil, outputPkScriptSizes, 0), log)
+	// The cost of a change output is the fee to add it now and to spend it later.
+	changePKScriptSize := len(changeAddress.PubkeyScript())
+	changeSigScriptSize, _ := addresses.SigScriptWitnessSize(changeAddress.Configuration)
+	costOfChange := feePerKb * btcutil.Amount(
+		outputSize(changePKScriptSize)+calcInputSize(changeSigScriptSize)) / 1000
+
+	// Effective values: the value of a coin minus the fee needed to spend it.
+	type candidate struct {
+		outPoint       wire.OutPoint
+		effectiveValue btcutil.Amount
+	}
+	candidates := []candidate{}
+	for _, outPoint := range sortedOutPoints(spendableOutputs) {
+		utxo := spendableOutputs[outPoint]
+		inputSize := estimateTxSize(
+			[]*signing.Configuration{utxo.Configuration}, outputPkScriptSizes, 0)
+		inputFee := feeForSerializeSize(feePerKb, inputSize, log) - baseFee
+		effectiveValue := btcutil.Amount(utxo.TxOut.Value) - inputFee
+		if effectiveValue <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{outPoint, effectiveValue})
+	}
+	// Effective values change the order only if the coins have different input types.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].effectiveValue > candidates[j].effectiveValue
+	})
+	values := make([]btcutil.Amount, len(candidates))
+	for i, candidate := range candidates {
+		values[i] = candidate.effectiveValue
+	}
+	selection := selectBranchAndBound(values, targetAmount+baseFee, costOfChange)
+	if selection == nil {
+		return nil
+	}
+	selectedOutPoints := make([]wire.OutPoint, len(selection))
+	selectedOutputsSum := btcutil.Amount(0)
+	inputs := make([]*wire.TxIn, len(selection))
+	for i, index := range selection {
+		outPoint := candidates[index].outPoint
+		selectedOutPoints[i] = outPoint
+		selectedOutputsSum += btcutil.Amount(spendableOutputs[outPoint].TxOut.Value)
+		inputs[i] = wire.NewTxIn(&outPoint, nil, nil)
+	}
+	fee := selectedOutputsSum - targetAmount
+	requiredFee := feeForSerializeSize(feePerKb, estimateTxSize(
+		toInputConfigurations(spendableOutputs, selectedOutPoints), outputPkScriptSizes, 0), log)
+	if fee < requiredFee {
+		// Can happen due to rounding of the per-input fees.
+		return nil
+	}
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     inputs,
+		TxOut:    append([]*wire.TxOut{}, outputs...),
+		LockTime: 0,
+	}
+	txsort.InPlaceSort(unsignedTransaction)
+	log.WithField("fee", fee).Debug("Preparing changeless transaction")
+
+	setRBF(coin, unsignedTransaction)
+	return &TxProposal{
+		Coin:        coin,
+		Amount:      targetAmount,
+		Fee:         fee,
+		Transaction: unsignedTransaction,
+	}
+}
+
+// NewTx creates a transaction from a set of unspent outputs, paying the given outputs. A subset of
 // the unspent outputs is selected to cover the needed amount.
 //
 // changeAddress: a change output to this address is added if needed.
+// coinSelection: the strategy to select the coins. If nil, CoinSelectionLargestFirst is used.
 func NewTx(
 	coin coinpkg.Coin,
 	spendableOutputs map[wire.OutPoint]UTXO,
-	output *wire.TxOut,
+	outputs []*wire.TxOut,
 	feePerKb btcutil.Amount,
 	changeAddress *addresses.AccountAddress,
+	coinSelection *CoinSelection,
 	log *logrus.Entry,
 ) (*TxProposal, error) {
-	targetAmount := btcutil.Amount(output.Value)
-	if targetAmount <= 0 {
-		panic("amount must be positive")
+	if len(outputs) == 0 {
+		panic("at least one output is needed")
+	}
+	for _, output := range outputs {
+		if output.Value <= 0 {
+			panic("amount must be positive")
+		}
+	}
+	spendableOutputs = withoutFrozen(spendableOutputs)
+	targetAmount := sumOutputs(outputs)
+	if coinSelection == nil {
+		coinSelection = CoinSelectionLargestFirst
+	}
+	if coinSelection.changeless {
+		txProposal := newTxChangeless(coin, spendableOutputs, outputs, feePerKb, changeAddress, log)
+		if txProposal != nil {
+			return txProposal, nil
+		}
 	}
-	outputs := []*wire.TxOut{output}
 	changePKScript := changeAddress.PubkeyScript()
 
 	targetFee := btcutil.Amount(0)
 	for {
-		selectedOutputsSum, selectedOutPoints, err := coinSelection(
+		selectedOutputsSum, selectedOutPoints, err := coinSelection.selectCoins(
 			targetAmount+targetFee,
 			spendableOutputs,
 		)
@@ -197,7 +308,7 @@ func NewTx(
 
 		txSize := estimateTxSize(
 			toInputConfigurations(spendableOutputs, selectedOutPoints),
-			len(output.PkScript),
+			pkScriptSizes(outputs),
 			len(changePKScript))
 		maxRequiredFee := feeForSerializeSize(feePerKb, txSize, log)
 		if selectedOutputsSum-targetAmount < maxRequiredFee {
@@ -213,7 +324,7 @@ func NewTx(
 		unsignedTransaction := &wire.MsgTx{
 			Version:  wire.TxVersion,
 			TxIn:     inputs,
-			TxOut:    outputs,
+			TxOut:    append([]*wire.TxOut{}, outputs...),
 			LockTime: 0,
 		}
 		changeAmount := selectedOutputsSum - targetAmount - maxRequiredFee
@@ -243,3 +354,181 @@ func NewTx(
 		}, nil
 	}
 }
+
+// incrementalRelayFeePerKb is the minimum fee rate by which a replacement must increase the absolute
+// fee of the replaced transaction (BIP125 rule 4). This is the default of Bitcoin Core's
+// -incrementalrelayfee.
+const incrementalRelayFeePerKb btcutil.Amount = 1000
+
+// NewTxReplacement creates a transaction which replaces an unconfirmed transaction (BIP125) at a
+// higher fee rate. All inputs of the original transaction are spent again, and the payment outputs
+// are kept as they are. The higher fee is deducted from the change. If the change does not cover the
+// fee, additional confirmed coins from spendableOutputs are added.
+//
+// originalInputs: the outputs spent by the original transaction.
+// outputs: the outputs of the original transaction, excluding the change output.
+// originalFee: the absolute fee paid by the original transaction.
+// changeAddress: a change output to this address is added if needed.
+func NewTxReplacement(
+	coin coinpkg.Coin,
+	originalInputs map[wire.OutPoint]UTXO,
+	spendableOutputs map[wire.OutPoint]UTXO,
+	outputs []*wire.TxOut,
+	originalFee btcutil.Amount,
+	feePerKb btcutil.Amount,
+	changeAddress *addresses.AccountAddress,
+	log *logrus.Entry,
+) (*TxProposal, error) {
+	allInputs := map[wire.OutPoint]UTXO{}
+	selectedOutPoints := []wire.OutPoint{}
+	selectedOutputsSum := btcutil.Amount(0)
+	for outPoint, utxo := range originalInputs {
+		allInputs[outPoint] = utxo
+		selectedOutPoints = append(selectedOutPoints, outPoint)
+		selectedOutputsSum += btcutil.Amount(utxo.TxOut.Value)
+	}
+	// Additional coins, largest first.
+	candidates := []wire.OutPoint{}
+	for outPoint, utxo := range withoutFrozen(spendableOutputs) {
+		if _, ok := originalInputs[outPoint]; ok {
+			continue
+		}
+		allInputs[outPoint] = utxo
+		candidates = append(candidates, outPoint)
+	}
+	sort.Sort(sort.Reverse(&byValue{candidates, allInputs}))
+
+	targetAmount := sumOutputs(outputs)
+	outputPkScriptSizes := pkScriptSizes(outputs)
+	changePKScript := changeAddress.PubkeyScript()
+
+	for {
+		txSize := estimateTxSize(
+			toInputConfigurations(allInputs, selectedOutPoints),
+			outputPkScriptSizes,
+			len(changePKScript))
+		requiredFee := feeForSerializeSize(feePerKb, txSize, log)
+		// The replacement must pay for its own bandwidth on top of the fee of the original.
+		if minFee := originalFee + feeForSerializeSize(incrementalRelayFeePerKb, txSize, log); requiredFee < minFee {
+			requiredFee = minFee
+		}
+		if selectedOutputsSum-targetAmount < requiredFee {
+			if len(candidates) == 0 {
+				return nil, errp.WithStack(errors.ErrInsufficientFunds)
+			}
+			selectedOutPoints = append(selectedOutPoints, candidates[0])
+			selectedOutputsSum += btcutil.Amount(allInputs[candidates[0]].TxOut.Value)
+			candidates = candidates[1:]
+			continue
+		}
+
+		inputs := make([]*wire.TxIn, len(selectedOutPoints))
+		for i, outPoint := range selectedOutPoints {
+			outPoint := outPoint // avoids referencing the same variable across loop iterations
+			inputs[i] = wire.NewTxIn(&outPoint, nil, nil)
+		}
+		txOuts := make([]*wire.TxOut, len(outputs))
+		for index, output := range outputs {
+			txOuts[index] = wire.NewTxOut(output.Value, output.PkScript)
+		}
+		unsignedTransaction := &wire.MsgTx{
+			Version:  wire.TxVersion,
+			TxIn:     inputs,
+			TxOut:    txOuts,
+			LockTime: 0,
+		}
+		changeAmount := selectedOutputsSum - targetAmount - requiredFee
+		changeIsDust := isDustAmount(
+			changeAmount, len(changePKScript), changeAddress.Configuration, feePerKb)
+		finalFee := requiredFee
+		if changeIsDust {
+			log.Info("change is dust")
+			finalFee = selectedOutputsSum - targetAmount
+		}
+		if changeAmount != 0 && !changeIsDust {
+			unsignedTransaction.TxOut = append(unsignedTransaction.TxOut,
+				wire.NewTxOut(int64(changeAmount), changePKScript))
+		} else {
+			changeAddress = nil
+		}
+		txsort.InPlaceSort(unsignedTransaction)
+		log.WithFields(logrus.Fields{"fee": finalFee, "originalFee": originalFee}).
+			Debug("Preparing replacement transaction")
+
+		setRBF(coin, unsignedTransaction)
+		return &TxProposal{
+			Coin:          coin,
+			Amount:        targetAmount,
+			Fee:           finalFee,
+			Transaction:   unsignedTransaction,
+			ChangeAddress: changeAddress,
+		}, nil
+	}
+}
+
+// NewTxCPFP creates a child transaction which spends unconfirmed outputs of a parent transaction
+// back to the wallet, paying a fee high enough for the parent and the child together to reach the
+// target fee rate (child-pays-for-parent).
+//
+// parentOutputs: the outputs of the parent to spend.
+// parentVSize: the virtual size of the parent.
+// parentFee: the absolute fee paid by the parent.
+// changeAddress: the address receiving the funds.
+func NewTxCPFP(
+	coin coinpkg.Coin,
+	parentOutputs map[wire.OutPoint]UTXO,
+	parentVSize int64,
+	parentFee btcutil.Amount,
+	feePerKb btcutil.Amount,
+	changeAddress *addresses.AccountAddress,
+	log *logrus.Entry,
+) (*TxProposal, error) {
+	parentOutputs = withoutFrozen(parentOutputs)
+	if len(parentOutputs) == 0 {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	selectedOutPoints := []wire.OutPoint{}
+	inputs := []*wire.TxIn{}
+	outputsSum := btcutil.Amount(0)
+	for outPoint, output := range parentOutputs {
+		outPoint := outPoint // avoid reference reuse due to range loop
+		selectedOutPoints = append(selectedOutPoints, outPoint)
+		outputsSum += btcutil.Amount(output.TxOut.Value)
+		inputs = append(inputs, wire.NewTxIn(&outPoint, nil, nil))
+	}
+	changePKScript := changeAddress.PubkeyScript()
+	childSize := estimateTxSize(
+		toInputConfigurations(parentOutputs, selectedOutPoints),
+		nil,
+		len(changePKScript))
+	packageFee := feeForSerializeSize(feePerKb, int(parentVSize)+childSize, log)
+	childFee := packageFee - parentFee
+	if childFee < feeForSerializeSize(feePerKb, childSize, log) {
+		return nil, errp.New("The parent transaction already pays the target fee rate")
+	}
+	if outputsSum < childFee {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	changeAmount := outputsSum - childFee
+	if isDustAmount(changeAmount, len(changePKScript), changeAddress.Configuration, feePerKb) {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	output := wire.NewTxOut(int64(changeAmount), changePKScript)
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     inputs,
+		TxOut:    []*wire.TxOut{output},
+		LockTime: 0,
+	}
+	txsort.InPlaceSort(unsignedTransaction)
+	log.WithFields(logrus.Fields{"fee": childFee, "parentFee": parentFee}).
+		Debug("Preparing child-pays-for-parent transaction")
+
+	setRBF(coin, unsignedTransaction)
+	return &TxProposal{
+		Coin:        coin,
+		Amount:      changeAmount,
+		Fee:         childFee,
+		Transaction: unsignedTransaction,
+	}, nil
+}
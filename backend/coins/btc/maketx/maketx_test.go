@@ -100,9 +100,10 @@ func (s *newTxSuite) newTx(
 	return maketx.NewTx(
 		s.coin,
 		utxo,
-		s.output(amount),
+		[]*wire.TxOut{s.output(amount)},
 		feePerKb,
 		s.changeAddress,
+		nil,
 		s.log,
 	)
 }
@@ -307,3 +308,231 @@ func (s *newTxSuite) TestNewTxCoinSelection() {
 	// coins: .5, .3, .1, .1, .9, .8, .6. select .5+.3+.1+.1 to get 1BTC, take .9 to cover the fees.
 	s.check(amount, feePerKb, s.buildUTXO(500*mBTC, 300*mBTC, 100*mBTC, 100*mBTC, 90*mBTC, 80*mBTC, 70*mBTC), s.change(90*mBTC-txSizeFiveInputs), noDust, s.selectCoins(0, 1, 2, 3, 4))
 }
+
+func (s *newTxSuite) TestNewTxReplacement() {
+	originalInputs := s.buildUTXO(1000000)
+	extra := map[wire.OutPoint]maketx.UTXO{
+		{Hash: chainhash.HashH([]byte(`other-tx`)), Index: 0}: {
+			TxOut:         wire.NewTxOut(5000000, s.someAddresses[0].PubkeyScript()),
+			Configuration: s.inputConfiguration,
+		},
+	}
+	outputs := []*wire.TxOut{s.output(500000)}
+	const originalFee = btcutil.Amount(txSizeOneInput)
+
+	replace := func(feePerKb btcutil.Amount) *maketx.TxProposal {
+		txProposal, err := maketx.NewTxReplacement(
+			s.coin, originalInputs, extra, outputs, originalFee, feePerKb, s.changeAddress, s.log)
+		require.NoError(s.T(), err)
+		require.Equal(s.T(), btcutil.Amount(500000), txProposal.Amount)
+		require.Equal(s.T(), s.changeAddress, txProposal.ChangeAddress)
+		require.Len(s.T(), txProposal.Transaction.TxOut, 2)
+		return txProposal
+	}
+
+	// Higher fee rate, covered by the change.
+	txProposal := replace(10000)
+	require.Equal(s.T(), btcutil.Amount(10*txSizeOneInput), txProposal.Fee)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 1)
+	require.Equal(s.T(), s.outpoint(0), txProposal.Transaction.TxIn[0].PreviousOutPoint)
+
+	// The same fee rate as the original still needs to pay for the replacement's bandwidth.
+	txProposal = replace(1000)
+	require.Equal(s.T(), originalFee+txSizeOneInput, txProposal.Fee)
+
+	// The change can't cover the fee, an additional coin is added.
+	txProposal = replace(2500000)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 2)
+	require.Equal(s.T(), btcutil.Amount(2500*txSizeTwoInputs), txProposal.Fee)
+
+	_, err := maketx.NewTxReplacement(
+		s.coin, originalInputs, extra, outputs, originalFee, 20000000, s.changeAddress, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+}
+
+func (s *newTxSuite) TestNewTxCPFP() {
+	parentOutputs := s.buildUTXO(100000)
+	// A parent of 200 vbytes paying 1 sat/vB.
+	const parentVSize = 200
+	const parentFee = btcutil.Amount(200)
+	// Size of the child: one input, one output.
+	const childSize = 192
+
+	txProposal, err := maketx.NewTxCPFP(
+		s.coin, parentOutputs, parentVSize, parentFee, 10000, s.changeAddress, s.log)
+	require.NoError(s.T(), err)
+	expectedFee := 10*(parentVSize+childSize) - parentFee
+	require.Equal(s.T(), expectedFee, txProposal.Fee)
+	require.Equal(s.T(), 100000-expectedFee, txProposal.Amount)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 1)
+	require.Equal(s.T(), s.outpoint(0), txProposal.Transaction.TxIn[0].PreviousOutPoint)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 1)
+	require.Equal(s.T(), s.changeAddress.PubkeyScript(), txProposal.Transaction.TxOut[0].PkScript)
+
+	// The parent already pays more than the target.
+	_, err = maketx.NewTxCPFP(
+		s.coin, parentOutputs, parentVSize, 100*parentVSize, 10000, s.changeAddress, s.log)
+	require.Error(s.T(), err)
+
+	// The output can't cover the fee.
+	_, err = maketx.NewTxCPFP(
+		s.coin, parentOutputs, parentVSize, parentFee, 1000000, s.changeAddress, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+}
+
+func (s *newTxSuite) TestNewTxMultipleOutputs() {
+	feePerKb := btcutil.Amount(1000) // 1 sat / vbyte
+	// A P2PKH output adds 34 vbytes.
+	const txSize = txSizeOneInput + 34
+	outputs := []*wire.TxOut{s.output(100000), s.output(200000)}
+	txProposal, err := maketx.NewTx(
+		s.coin, s.buildUTXO(1000000), outputs, feePerKb, s.changeAddress, nil, s.log)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), btcutil.Amount(300000), txProposal.Amount)
+	require.Equal(s.T(), btcutil.Amount(txSize), txProposal.Fee)
+	require.Equal(s.T(), s.changeAddress, txProposal.ChangeAddress)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 3)
+	for _, output := range outputs {
+		require.Contains(s.T(), txProposal.Transaction.TxOut, output)
+	}
+
+	_, err = maketx.NewTx(
+		s.coin, s.buildUTXO(300000+txSize-1), outputs, feePerKb, s.changeAddress, nil, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+}
+
+func (s *newTxSuite) TestNewTxSpendAll() {
+	feePerKb := btcutil.Amount(1000) // 1 sat / vbyte
+	utxo := s.buildUTXO(600000, 400000)
+	restPkScript := s.changeAddress.PubkeyScript()
+
+	txProposal, err := maketx.NewTxSpendAll(s.coin, utxo, nil, restPkScript, feePerKb, s.log)
+	require.NoError(s.T(), err)
+	// Two inputs, one output.
+	const txSizeOneOutput = txSizeTwoInputs - 34
+	require.Equal(s.T(), btcutil.Amount(txSizeOneOutput), txProposal.Fee)
+	require.Equal(s.T(), btcutil.Amount(1000000-txSizeOneOutput), txProposal.Amount)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 2)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 1)
+
+	// With fixed outputs, the rest goes to the given pkScript.
+	fixed := []*wire.TxOut{s.output(100000)}
+	txProposal, err = maketx.NewTxSpendAll(s.coin, utxo, fixed, restPkScript, feePerKb, s.log)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), btcutil.Amount(txSizeTwoInputs), txProposal.Fee)
+	require.Equal(s.T(), btcutil.Amount(1000000-txSizeTwoInputs), txProposal.Amount)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 2)
+	require.Contains(s.T(), txProposal.Transaction.TxOut, s.output(100000))
+	require.Contains(s.T(),
+		txProposal.Transaction.TxOut, wire.NewTxOut(900000-txSizeTwoInputs, restPkScript))
+
+	_, err = maketx.NewTxSpendAll(
+		s.coin, utxo, []*wire.TxOut{s.output(1000000)}, restPkScript, feePerKb, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+
+	// The remainder must not be zero or dust.
+	for _, remainder := range []btcutil.Amount{0, 100} {
+		_, err = maketx.NewTxSpendAll(s.coin, utxo,
+			[]*wire.TxOut{s.output(1000000 - txSizeTwoInputs - remainder)},
+			restPkScript, feePerKb, s.log)
+		require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err), remainder)
+	}
+	txProposal, err = maketx.NewTxSpendAll(s.coin, utxo,
+		[]*wire.TxOut{s.output(1000000 - txSizeTwoInputs - 1000)},
+		restPkScript, feePerKb, s.log)
+	require.NoError(s.T(), err)
+	require.Contains(s.T(), txProposal.Transaction.TxOut, wire.NewTxOut(1000, restPkScript))
+}
+
+func (s *newTxSuite) TestNewTxFrozen() {
+	feePerKb := btcutil.Amount(1000) // 1 sat / vbyte
+	utxo := s.buildUTXO(1000000, 400000)
+	frozen := utxo[s.outpoint(0)]
+	frozen.Frozen = true
+	utxo[s.outpoint(0)] = frozen
+
+	txProposal, err := s.newTx(100000, feePerKb, utxo)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 1)
+	require.Equal(s.T(), s.outpoint(1), txProposal.Transaction.TxIn[0].PreviousOutPoint)
+
+	_, err = s.newTx(500000, feePerKb, utxo)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+
+	txProposal, err = maketx.NewTxSpendAll(
+		s.coin, utxo, nil, s.outputPkScript, feePerKb, s.log)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 1)
+	require.Equal(s.T(), s.outpoint(1), txProposal.Transaction.TxIn[0].PreviousOutPoint)
+}
+
+func (s *newTxSuite) TestNewTxConsolidation() {
+	feePerKb := btcutil.Amount(1000) // 1 sat / vbyte
+	utxo := s.buildUTXO(3000, 50000, 1000, 4000, 2000)
+	frozen := utxo[s.outpoint(1)]
+	frozen.Frozen = true
+	utxo[s.outpoint(1)] = frozen
+
+	// The three smallest coins which are not frozen are spent.
+	txProposal, err := maketx.NewTxConsolidation(
+		s.coin, utxo, 3, feePerKb, s.changeAddress, s.log)
+	require.NoError(s.T(), err)
+	// Three inputs, one output.
+	const txSize = 488
+	require.Equal(s.T(), btcutil.Amount(txSize), txProposal.Fee)
+	require.Equal(s.T(), btcutil.Amount(6000-txSize), txProposal.Amount)
+	require.Equal(s.T(), s.changeAddress, txProposal.ChangeAddress)
+	spent := map[wire.OutPoint]struct{}{}
+	for _, txIn := range txProposal.Transaction.TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	require.Equal(s.T(), map[wire.OutPoint]struct{}{
+		s.outpoint(0): {}, s.outpoint(2): {}, s.outpoint(4): {},
+	}, spent)
+	require.Len(s.T(), txProposal.Transaction.TxOut, 1)
+	require.Equal(s.T(), s.changeAddress.PubkeyScript(), txProposal.Transaction.TxOut[0].PkScript)
+
+	// At 10 sat/vB, spending three inputs instead of one costs 10*(488-192) more.
+	require.Equal(s.T(), btcutil.Amount(2960),
+		maketx.ConsolidationSavings(utxo, txProposal, 10000, s.log))
+
+	// Without a limit, all coins which are not frozen are spent.
+	txProposal, err = maketx.NewTxConsolidation(
+		s.coin, utxo, 0, feePerKb, s.changeAddress, s.log)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), txProposal.Transaction.TxIn, 4)
+
+	// One coin can't be consolidated.
+	_, err = maketx.NewTxConsolidation(
+		s.coin, s.buildUTXO(3000), 0, feePerKb, s.changeAddress, s.log)
+	require.Error(s.T(), err)
+
+	// The coins can't cover the fee.
+	_, err = maketx.NewTxConsolidation(
+		s.coin, s.buildUTXO(300, 300), 0, feePerKb, s.changeAddress, s.log)
+	require.Equal(s.T(), errors.ErrInsufficientFunds, errp.Cause(err))
+
+	// Without a limit, the selection stops at the largest transaction relayed by the network.
+	manyCoins := make([]int64, 1000)
+	for index := range manyCoins {
+		manyCoins[index] = 10000
+	}
+	txProposal, err = maketx.NewTxConsolidation(
+		s.coin, s.buildUTXO(manyCoins...), 0, feePerKb, s.changeAddress, s.log)
+	require.NoError(s.T(), err)
+	require.Less(s.T(), len(txProposal.Transaction.TxIn), len(manyCoins))
+	// At 1 sat/vB, the fee is the size in vbytes, which is just below the limit.
+	require.LessOrEqual(s.T(), int64(txProposal.Fee), int64(100000))
+	require.Greater(s.T(), int64(txProposal.Fee), int64(99000))
+}
+
+func (s *newTxSuite) TestSetLockTime() {
+	tx := &wire.MsgTx{TxIn: []*wire.TxIn{
+		{Sequence: wire.MaxTxInSequenceNum},
+		{Sequence: wire.MaxTxInSequenceNum - 2},
+	}}
+	maketx.SetLockTime(tx, 650000)
+	require.Equal(s.T(), uint32(650000), tx.LockTime)
+	require.Equal(s.T(), uint32(wire.MaxTxInSequenceNum-1), tx.TxIn[0].Sequence)
+	require.Equal(s.T(), uint32(wire.MaxTxInSequenceNum-2), tx.TxIn[1].Sequence)
+}
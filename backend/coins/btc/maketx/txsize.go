@@ -36,20 +36,19 @@ func outputSize(pkScriptSize int) int {
 // bytes), for the purpose of fee calculation.
 // https://en.bitcoin.it/wiki/Weight_units
 //
-// Witnesses, if present, are assumed to have the following format:
-// <serialized sig> <serialized compressed pubkey>
+// Witnesses, if present, are assumed to have the format given by addresses.WitnessSize().
 //
 // inputConfigurations defines the number of inputs and the input configurations in the tx.
-// outputPkScriptSize is the size of the output pkScript. One output is assumed (apart from change).
+// outputPkScriptSizes contains the sizes of the output pkScripts, one per output (apart from change).
 // changePkScriptSize  is the size of the change pkScript. A value of 0 means that there is no change output.
 // This function computes the virtual size of a transaction, taking segwit discount into account.
 func estimateTxSize(
 	inputConfigurations []*signing.Configuration,
-	outputPkScriptSize int,
+	outputPkScriptSizes []int,
 	changePkScriptSize int) int {
-	outputCount := 2 // 1 output + 1 change output
-	if changePkScriptSize == 0 {
-		outputCount = 1
+	outputCount := len(outputPkScriptSizes)
+	if changePkScriptSize != 0 {
+		outputCount++
 	}
 
 	const (
@@ -59,10 +58,13 @@ func estimateTxSize(
 		nonWitness = 4
 	)
 
+	outputsSize := outputSize(changePkScriptSize)
+	for _, pkScriptSize := range outputPkScriptSizes {
+		outputsSize += outputSize(pkScriptSize)
+	}
 	txWeight := nonWitness * (versionSize + lockTimeSize + wire.VarIntSerializeSize(uint64(len(inputConfigurations))) +
 		wire.VarIntSerializeSize(uint64(outputCount)) +
-		outputSize(outputPkScriptSize) +
-		outputSize(changePkScriptSize))
+		outputsSize)
 
 	isSegwitTx := false
 	for _, inputConfiguration := range inputConfigurations {
@@ -77,18 +79,8 @@ func estimateTxSize(
 		sigScriptSize, hasWitness := addresses.SigScriptWitnessSize(inputConfiguration)
 		txWeight += nonWitness * calcInputSize(sigScriptSize)
 		if isSegwitTx {
-			const (
-				// Including SIGHASH op. Assumes signatures follow the low-S requirement.
-				// See https://en.bitcoin.it/wiki/BIP_0062#DER_encoding
-				signatureSize = 72
-				pubkeySize    = 33
-			)
 			if hasWitness {
-				// For now, every input has a witness serialization of this format:
-				// <serialized sig> <serialized compressed pubkey>
-				txWeight += wire.VarIntSerializeSize(2) +
-					wire.VarIntSerializeSize(signatureSize) + signatureSize +
-					wire.VarIntSerializeSize(pubkeySize) + pubkeySize
+				txWeight += addresses.WitnessSize(inputConfiguration)
 			} else {
 				// "Empty script witnesses are encoded as a zero byte"
 				// https://github.com/bitcoin/bips/blob/d8a56c9f2b521bf4af5d588f217e7618cc44952c/bip-0144.mediawiki
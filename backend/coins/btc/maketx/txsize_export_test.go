@@ -22,6 +22,6 @@ func TstEstimateTxSize(
 	changePkScriptSize int) int {
 	return estimateTxSize(
 		inputConfigurations,
-		outputPkScriptSize,
+		[]int{outputPkScriptSize},
 		changePkScriptSize)
 }
@@ -88,7 +88,7 @@ func testEstimateTxSize(
 
 	estimatedSize := estimateTxSize(
 		inputConfigurations,
-		len(outputPkScript), changePkScriptSize)
+		[]int{len(outputPkScript)}, changePkScriptSize)
 	require.Equal(t, mempool.GetTxVirtualSize(btcutil.NewTx(tx)), int64(estimatedSize))
 
 }
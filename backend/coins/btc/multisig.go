@@ -0,0 +1,131 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"errors"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// ErrMultisigSignaturesMissing is returned when sending a multisig transaction which the connected
+// keystores cannot sign alone. The signatures of the other cosigners can be collected using a PSBT.
+var ErrMultisigSignaturesMissing = errors.New("multisig signatures missing")
+
+// multisigConfigurations returns the multisig signing configurations of the account.
+func (account *Account) multisigConfigurations() []*signing.Configuration {
+	var result []*signing.Configuration
+	for _, subacc := range account.subaccounts {
+		if subacc.signingConfiguration.Multisig() {
+			result = append(result, subacc.signingConfiguration)
+		}
+	}
+	return result
+}
+
+// multisigKeystores returns the keystores of the account which need multisig accounts to be
+// registered.
+func (account *Account) multisigKeystores() []keystore.MultisigKeystore {
+	var result []keystore.MultisigKeystore
+	if account.Config().Keystores == nil {
+		return nil
+	}
+	for _, ks := range account.Config().Keystores.Keystores() {
+		if multisigKeystore, ok := ks.(keystore.MultisigKeystore); ok {
+			result = append(result, multisigKeystore)
+		}
+	}
+	return result
+}
+
+// MultisigRegistered returns true if the multisig configurations of the account are registered on
+// all connected keystores which require a registration. Returns true for singlesig accounts.
+func (account *Account) MultisigRegistered() (bool, error) {
+	if !account.initialized {
+		return false, errp.New("account must be initialized")
+	}
+	for _, multisigKeystore := range account.multisigKeystores() {
+		for _, configuration := range account.multisigConfigurations() {
+			registered, err := multisigKeystore.MultisigRegistered(account.Coin(), configuration)
+			if err != nil {
+				return false, err
+			}
+			if !registered {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// RegisterMultisig registers the multisig configurations of the account under the given name on
+// all connected keystores which require it and where it is not registered yet. Returns
+// keystore.ErrSigningAborted if the user aborts and keystore.ErrKeystoreNotConnected if no keystore
+// is connected.
+func (account *Account) RegisterMultisig(name string) error {
+	if !account.initialized {
+		return errp.New("account must be initialized")
+	}
+	if account.Config().Keystores == nil || account.Config().Keystores.Count() == 0 {
+		return errp.WithStack(keystore.ErrKeystoreNotConnected)
+	}
+	for _, multisigKeystore := range account.multisigKeystores() {
+		for _, configuration := range account.multisigConfigurations() {
+			registered, err := multisigKeystore.MultisigRegistered(account.Coin(), configuration)
+			if err != nil {
+				return err
+			}
+			if registered {
+				continue
+			}
+			if err := multisigKeystore.RegisterMultisig(account.Coin(), configuration, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// verifyMultisigAddress outputs the multisig address on all keystores which can verify it. Returns
+// false, nil if no keystore has a secure output.
+func (account *Account) verifyMultisigAddress(
+	accountConfiguration *signing.Configuration,
+	address *addresses.AccountAddress,
+) (bool, error) {
+	verified := false
+	for _, ks := range account.Config().Keystores.Keystores() {
+		canVerifyAddress, _, err := ks.CanVerifyAddress(account.Coin())
+		if err != nil {
+			return false, err
+		}
+		if !canVerifyAddress {
+			continue
+		}
+		if multisigKeystore, ok := ks.(keystore.MultisigKeystore); ok {
+			err = multisigKeystore.VerifyMultisigAddress(
+				account.Coin(), accountConfiguration, address.Configuration.AbsoluteKeypath())
+		} else {
+			err = ks.VerifyAddress(address.Configuration, account.Coin())
+		}
+		if err != nil {
+			return false, err
+		}
+		verified = true
+	}
+	return verified, nil
+}
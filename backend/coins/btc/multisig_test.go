@@ -0,0 +1,171 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMultisigAccount returns an initialized 2-of-2 P2WSH multisig testnet account of the two
+// software keystores, signing with the given keystores, and a function to clean up.
+func newTestMultisigAccount(
+	t *testing.T,
+	cosigners []*software.Keystore,
+	keystores *keystore.Keystores,
+) (*btc.Account, func()) {
+	t.Helper()
+	net := &chaincfg.TestNet3Params
+	dbFolder := test.TstTempDir("btc-multisig")
+
+	btcCoin := btc.NewCoin(
+		coin.CodeTBTC, "TBTC", net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
+	blockchainMock := &blockchainMock.BlockchainMock{}
+	blockchainMock.MockRegisterOnConnectionStatusChangedEvent = func(func(blockchain.Status)) {}
+	blockchainMock.MockHeadersSubscribe = func(
+		setupAndTeardown func() func(error), success func(*blockchain.Header) error) {
+		go func() { _ = success(&blockchain.Header{BlockHeight: 100}) }()
+	}
+	blockchainMock.MockEstimateFee = func(
+		blocks int, success func(*btcutil.Amount), cleanup func(error)) {
+		feeRatePerKb := btcutil.Amount(10000)
+		go success(&feeRatePerKb)
+	}
+	btcCoin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
+
+	keypath, err := signing.NewAbsoluteKeypath("m/48'/1'/0'/2'")
+	require.NoError(t, err)
+	xpubs := []*hdkeychain.ExtendedKey{}
+	for _, cosigner := range cosigners {
+		xpub, err := cosigner.ExtendedPublicKey(btcCoin, keypath)
+		require.NoError(t, err)
+		xpubs = append(xpubs, xpub)
+	}
+	configurations := signing.Configurations{
+		signing.NewConfiguration(signing.ScriptTypeP2WSH, keypath, xpubs, "", len(xpubs)),
+	}
+	account := btc.NewAccount(
+		&accounts.AccountConfig{
+			Code:      "multisig",
+			Name:      "Multisig",
+			DBFolder:  dbFolder,
+			Keystores: keystores,
+			OnEvent:   func(accounts.Event) {},
+			GetSigningConfigurations: func() (signing.Configurations, error) {
+				return configurations, nil
+			},
+			GetNotifier: func(signing.Configurations) accounts.Notifier { return nil },
+		},
+		btcCoin, nil,
+		logging.Get().WithGroup("multisig_test"),
+	)
+	require.NoError(t, account.Initialize())
+	return account, func() {
+		account.Close()
+		_ = os.RemoveAll(dbFolder)
+	}
+}
+
+func newTestSoftwareKeystore(t *testing.T, cosignerIndex int, seed byte) *software.Keystore {
+	t.Helper()
+	master, err := hdkeychain.NewMaster(
+		append(make([]byte, hdkeychain.RecommendedSeedLen-1), seed), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	return software.NewKeystore(cosignerIndex, master)
+}
+
+func TestRegisterMultisigKeystoreNotConnected(t *testing.T) {
+	cosigners := []*software.Keystore{
+		newTestSoftwareKeystore(t, 0, 1),
+		newTestSoftwareKeystore(t, 1, 2),
+	}
+	for _, keystores := range []*keystore.Keystores{nil, keystore.NewKeystores()} {
+		account, cleanup := newTestMultisigAccount(t, cosigners, keystores)
+		err := account.RegisterMultisig("Multisig")
+		cleanup()
+		require.Equal(t, keystore.ErrKeystoreNotConnected, errp.Cause(err))
+	}
+
+	// The software keystore does not require a registration.
+	keystores := keystore.NewKeystores()
+	require.NoError(t, keystores.Add(cosigners[0]))
+	account, cleanup := newTestMultisigAccount(t, cosigners, keystores)
+	defer cleanup()
+	require.NoError(t, account.RegisterMultisig("Multisig"))
+	registered, err := account.MultisigRegistered()
+	require.NoError(t, err)
+	require.True(t, registered)
+}
+
+func TestSignMultisig(t *testing.T) {
+	cosigners := []*software.Keystore{
+		newTestSoftwareKeystore(t, 0, 1),
+		newTestSoftwareKeystore(t, 1, 2),
+	}
+	tests := []struct {
+		keystores   []*software.Keystore
+		expectedErr error
+	}{
+		{cosigners[:1], btc.ErrMultisigSignaturesMissing},
+		{cosigners[1:], btc.ErrMultisigSignaturesMissing},
+		{cosigners, nil},
+	}
+	for index, test := range tests {
+		keystores := keystore.NewKeystores()
+		for _, ks := range test.keystores {
+			require.NoError(t, keystores.Add(ks))
+		}
+		account, cleanup := newTestMultisigAccount(t, cosigners, keystores)
+		address := account.GetUnusedReceiveAddresses()[0][0].(*addresses.AccountAddress)
+
+		outPoint := wire.OutPoint{Hash: chainhash.HashH([]byte{byte(index)}), Index: 0}
+		previousOutputs := map[wire.OutPoint]*transactions.SpendableOutput{
+			outPoint: {TxOut: wire.NewTxOut(100000, address.PubkeyScript())},
+		}
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(90000, []byte{txscript.OP_TRUE}))
+
+		err := account.TstSignTransaction(&maketx.TxProposal{Transaction: tx}, previousOutputs)
+		cleanup()
+		require.Equal(t, test.expectedErr, errp.Cause(err), index)
+		if test.expectedErr == nil {
+			require.NoError(t, btc.TstVerifyInputScripts(tx, previousOutputs))
+		}
+	}
+}
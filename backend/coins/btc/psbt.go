@@ -0,0 +1,319 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/psbt"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// psbtBIP32Derivations returns the BIP32 derivation entries of all public keys of the given
+// address configuration.
+func psbtBIP32Derivations(configuration *signing.Configuration) []*psbt.BIP32Derivation {
+	keypath := configuration.AbsoluteKeypath().ToUInt32()
+	derivations := []*psbt.BIP32Derivation{}
+	for index, publicKey := range configuration.PublicKeys() {
+		derivations = append(derivations, &psbt.BIP32Derivation{
+			PubKey: publicKey.SerializeCompressed(),
+			// All zero is the conventional placeholder for an unknown root fingerprint.
+			Fingerprint: configuration.RootFingerprint(index),
+			Keypath:     keypath,
+		})
+	}
+	return derivations
+}
+
+// lookupChangeAddress returns the change address with the given pkScript, or nil if the pkScript
+// does not belong to a change address of this account.
+func (account *Account) lookupChangeAddress(pkScript []byte) *addresses.AccountAddress {
+	scriptHashHex := blockchain.NewScriptHashHex(pkScript)
+	for _, subacc := range account.subaccounts {
+		if address := subacc.changeAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
+			return address
+		}
+	}
+	return nil
+}
+
+// psbtPreviousOutputs returns the outputs spent by the PSBT. All inputs must spend unspent outputs
+// of this account.
+func (account *Account) psbtPreviousOutputs(
+	tx *wire.MsgTx,
+) (map[wire.OutPoint]*transactions.SpendableOutput, error) {
+	utxos := account.transactions.SpendableOutputs()
+	previousOutputs := map[wire.OutPoint]*transactions.SpendableOutput{}
+	for _, txIn := range tx.TxIn {
+		spentOutput, ok := utxos[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, errp.Newf(
+				"Input %s is not an unspent output of this account", txIn.PreviousOutPoint)
+		}
+		previousOutputs[txIn.PreviousOutPoint] = spentOutput
+	}
+	return previousOutputs, nil
+}
+
+// newPSBT creates a PSBT from an unsigned transaction spending outputs of this account. The inputs
+// are annotated with the previous transactions and the BIP32 derivations of the keys needed to
+// sign, and the change outputs are annotated so that signers can recognize them as ours.
+func (account *Account) newPSBT(unsignedTx *wire.MsgTx) (*psbt.Packet, error) {
+	unsignedTx = unsignedTx.Copy()
+	for _, txIn := range unsignedTx.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+	previousOutputs, err := account.psbtPreviousOutputs(unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.New(unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	for index, txIn := range unsignedTx.TxIn {
+		spentOutput := previousOutputs[txIn.PreviousOutPoint]
+		address := account.getAddress(spentOutput.ScriptHashHex())
+		input := packet.Inputs[index]
+		// Always included, also for segwit inputs, so that signers can verify the input amounts.
+		input.NonWitnessUTXO, err = account.getPrevTx(txIn.PreviousOutPoint.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if isSegwit, _ := address.ScriptForHashToSign(); isSegwit {
+			input.WitnessUTXO = spentOutput.TxOut
+		}
+		input.RedeemScript = address.RedeemScript()
+		input.WitnessScript = address.WitnessScript()
+		input.BIP32Derivations = psbtBIP32Derivations(address.Configuration)
+		if address.IsTaproot() {
+			// Taproot inputs are signed with the default sighash type, which is implied.
+			input.TapInternalKey = taproot.XOnlyPublicKey(address.Configuration.PublicKeys()[0])
+			continue
+		}
+		input.SighashType = uint32(txscript.SigHashAll)
+	}
+	for index, txOut := range unsignedTx.TxOut {
+		changeAddress := account.lookupChangeAddress(txOut.PkScript)
+		if changeAddress == nil {
+			continue
+		}
+		output := packet.Outputs[index]
+		output.RedeemScript = changeAddress.RedeemScript()
+		output.WitnessScript = changeAddress.WitnessScript()
+		output.BIP32Derivations = psbtBIP32Derivations(changeAddress.Configuration)
+	}
+	return packet, nil
+}
+
+// TxProposalPSBT returns the active tx proposal, set by TxProposal(), as an unsigned PSBT.
+func (account *Account) TxProposalPSBT() (*psbt.Packet, error) {
+	unlock := account.activeTxProposalLock.RLock()
+	txProposal := account.activeTxProposal
+	unlock()
+	if txProposal == nil {
+		return nil, errp.New("No active tx proposal")
+	}
+	return account.newPSBT(txProposal.Transaction)
+}
+
+// SignPSBT signs all inputs of the PSBT with the keystores of this account and adds the
+// signatures as partial signatures. Returns keystore.ErrSigningAborted if the user aborts.
+func (account *Account) SignPSBT(packet *psbt.Packet) error {
+	previousOutputs, err := account.psbtPreviousOutputs(packet.UnsignedTx)
+	if err != nil {
+		return err
+	}
+	txProposal := &maketx.TxProposal{
+		Coin:        account.coin,
+		Transaction: packet.UnsignedTx.Copy(),
+	}
+	var inputsSum btcutil.Amount
+	for _, spentOutput := range previousOutputs {
+		inputsSum += btcutil.Amount(spentOutput.Value)
+	}
+	var outputsSum btcutil.Amount
+	for _, txOut := range packet.UnsignedTx.TxOut {
+		outputsSum += btcutil.Amount(txOut.Value)
+		if changeAddress := account.lookupChangeAddress(txOut.PkScript); changeAddress != nil &&
+			txProposal.ChangeAddress == nil {
+			txProposal.ChangeAddress = changeAddress
+			continue
+		}
+		txProposal.Amount += btcutil.Amount(txOut.Value)
+	}
+	if inputsSum < outputsSum {
+		return errp.New("The PSBT outputs exceed the inputs")
+	}
+	txProposal.Fee = inputsSum - outputsSum
+
+	getPrevTx := func(txHash chainhash.Hash) (*wire.MsgTx, error) {
+		for index, txIn := range packet.UnsignedTx.TxIn {
+			prevTx := packet.Inputs[index].NonWitnessUTXO
+			if txIn.PreviousOutPoint.Hash == txHash && prevTx != nil && prevTx.TxHash() == txHash {
+				return prevTx, nil
+			}
+		}
+		return account.getPrevTx(txHash)
+	}
+	proposedTransaction, err := account.collectSignatures(txProposal, previousOutputs, getPrevTx)
+	if err != nil {
+		return err
+	}
+	for index, txIn := range packet.UnsignedTx.TxIn {
+		address := account.getAddress(previousOutputs[txIn.PreviousOutPoint].ScriptHashHex())
+		if address.IsTaproot() {
+			for _, signature := range proposedTransaction.Signatures[index] {
+				if signature != nil {
+					packet.Inputs[index].TapKeySig = taproot.SerializeSignature(signature)
+				}
+			}
+			continue
+		}
+		publicKeys := address.Configuration.PublicKeys()
+		for cosignerIndex, signature := range proposedTransaction.Signatures[index] {
+			if signature == nil || cosignerIndex >= len(publicKeys) {
+				continue
+			}
+			packet.Inputs[index].AddPartialSig(
+				publicKeys[cosignerIndex].SerializeCompressed(),
+				append(signature.Serialize(), byte(txscript.SigHashAll)),
+			)
+		}
+	}
+	return nil
+}
+
+// parsePartialSig parses a DER signature followed by the sighash type. Only SIGHASH_ALL is
+// supported.
+func parsePartialSig(signature []byte) (*btcec.Signature, error) {
+	if len(signature) == 0 {
+		return nil, errp.New("Empty signature")
+	}
+	if txscript.SigHashType(signature[len(signature)-1]) != txscript.SigHashAll {
+		return nil, errp.New("Only SIGHASH_ALL signatures are supported")
+	}
+	sig, err := btcec.ParseDERSignature(signature[:len(signature)-1], btcec.S256())
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return sig, nil
+}
+
+// FinalizePSBT builds the final scriptSig and witness for all inputs which have enough partial
+// signatures. Inputs which are already finalized are not touched. Use `packet.IsComplete()` to
+// check if all inputs could be finalized.
+func (account *Account) FinalizePSBT(packet *psbt.Packet) error {
+	previousOutputs, err := account.psbtPreviousOutputs(packet.UnsignedTx)
+	if err != nil {
+		return err
+	}
+	for index, txIn := range packet.UnsignedTx.TxIn {
+		input := packet.Inputs[index]
+		if input.IsFinalized() {
+			continue
+		}
+		address := account.getAddress(previousOutputs[txIn.PreviousOutPoint].ScriptHashHex())
+		if address.IsTaproot() {
+			if input.TapKeySig == nil {
+				account.log.Infof("PSBT input %d has no taproot signature", index)
+				continue
+			}
+			if len(input.TapKeySig) != 64 {
+				return errp.Newf("Only the default sighash type is supported in input %d", index)
+			}
+			signature, err := taproot.ParseSignature(input.TapKeySig)
+			if err != nil {
+				return errp.WithMessage(err, fmt.Sprintf("Invalid signature in input %d", index))
+			}
+			_, input.FinalScriptWitness = address.SignatureScript([]*btcec.Signature{signature})
+			continue
+		}
+		publicKeys := address.Configuration.PublicKeys()
+		signatures := make([]*btcec.Signature, len(publicKeys))
+		threshold := address.Configuration.SigningThreshold()
+		count := 0
+		for cosignerIndex, publicKey := range publicKeys {
+			if count == threshold {
+				// A multisig script must contain exactly `threshold` signatures.
+				break
+			}
+			for _, partialSig := range input.PartialSigs {
+				if !bytes.Equal(partialSig.PubKey, publicKey.SerializeCompressed()) {
+					continue
+				}
+				signature, err := parsePartialSig(partialSig.Signature)
+				if err != nil {
+					return errp.WithMessage(err, fmt.Sprintf("Invalid signature in input %d", index))
+				}
+				signatures[cosignerIndex] = signature
+				count++
+				break
+			}
+		}
+		if count < threshold {
+			account.log.Infof("PSBT input %d has %d of %d signatures", index, count, threshold)
+			continue
+		}
+		signatureScript, witness := address.SignatureScript(signatures)
+		if len(signatureScript) != 0 {
+			input.FinalScriptSig = signatureScript
+		}
+		input.FinalScriptWitness = witness
+	}
+	if !packet.IsComplete() {
+		return nil
+	}
+	tx, err := packet.Extract()
+	if err != nil {
+		return err
+	}
+	// The transaction is not necessarily BIP69 sorted if it was created by a different wallet, so
+	// only the scripts are checked.
+	return verifyInputScripts(tx, previousOutputs, txscript.NewTxSigHashes(tx))
+}
+
+// BroadcastPSBT finalizes the PSBT and broadcasts the resulting transaction, or schedules it if its
+// locktime is not reached yet. All inputs must be signed. The transaction ID is returned.
+func (account *Account) BroadcastPSBT(packet *psbt.Packet) (string, error) {
+	if err := account.FinalizePSBT(packet); err != nil {
+		return "", err
+	}
+	if !packet.IsComplete() {
+		return "", errp.New("The PSBT is missing signatures")
+	}
+	tx, err := packet.Extract()
+	if err != nil {
+		return "", err
+	}
+	account.log.Info("Broadcasting transaction from PSBT")
+	if err := account.broadcastOrSchedule(tx); err != nil {
+		return "", err
+	}
+	return tx.TxHash().String(), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psbt
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+func littleEndianUint32(b []byte) uint32 {
+	return binary.LittleEndian.Uint32(b)
+}
+
+func littleEndianPutUint32(b []byte, value uint32) {
+	binary.LittleEndian.PutUint32(b, value)
+}
+
+func writeVarBytes(w io.Writer, data []byte) error {
+	return errp.WithStack(wire.WriteVarBytes(w, 0, data))
+}
+
+func readVarBytes(r io.Reader) ([]byte, error) {
+	data, err := wire.ReadVarBytes(r, 0, maxMapEntrySize, "psbt")
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return data, nil
+}
+
+// writeEntry writes a key-value pair: <compact size keylen><key><compact size valuelen><value>.
+func writeEntry(w io.Writer, key []byte, value []byte) error {
+	if err := writeVarBytes(w, key); err != nil {
+		return err
+	}
+	return writeVarBytes(w, value)
+}
+
+// writeSeparator terminates a map.
+func writeSeparator(w io.Writer) error {
+	_, err := w.Write([]byte{0x00})
+	return errp.WithStack(err)
+}
+
+func writeUnknowns(w io.Writer, unknowns []*Unknown) error {
+	for _, unknown := range unknowns {
+		if err := writeEntry(w, unknown.Key, unknown.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMap reads key-value pairs until the separator and calls onEntry for each of them. The key is
+// guaranteed to be non-empty. Duplicate keys are rejected.
+func readMap(r io.Reader, onEntry func(key []byte, value []byte) error) error {
+	seen := map[string]struct{}{}
+	for {
+		key, err := readVarBytes(r)
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			return nil
+		}
+		if _, ok := seen[string(key)]; ok {
+			return errp.Newf("Duplicate PSBT key 0x%x", key)
+		}
+		seen[string(key)] = struct{}{}
+		value, err := readVarBytes(r)
+		if err != nil {
+			return err
+		}
+		if err := onEntry(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+// serializeKeypath encodes the value of a BIP32 derivation entry: the 4 fingerprint bytes followed
+// by each keypath element as a little endian uint32.
+func serializeKeypath(fingerprint uint32, keypath []uint32) []byte {
+	result := make([]byte, 4+4*len(keypath))
+	binary.BigEndian.PutUint32(result, fingerprint)
+	for i, element := range keypath {
+		littleEndianPutUint32(result[4+4*i:], element)
+	}
+	return result
+}
+
+func parseKeypath(value []byte) (uint32, []uint32, error) {
+	if len(value) < 4 || len(value)%4 != 0 {
+		return 0, nil, errp.New("Invalid BIP32 derivation value")
+	}
+	fingerprint := binary.BigEndian.Uint32(value)
+	keypath := make([]uint32, (len(value)-4)/4)
+	for i := range keypath {
+		keypath[i] = littleEndianUint32(value[4+4*i:])
+	}
+	return fingerprint, keypath, nil
+}
+
+// readTxOut reads an output as serialized in a transaction: the 8 byte little endian value
+// followed by the pkScript.
+func readTxOut(r io.Reader) (*wire.TxOut, error) {
+	var value [8]byte
+	if _, err := io.ReadFull(r, value[:]); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	pkScript, err := readVarBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewTxOut(int64(binary.LittleEndian.Uint64(value[:])), pkScript), nil
+}
+
+func writeWitness(w io.Writer, witness wire.TxWitness) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(witness))); err != nil {
+		return errp.WithStack(err)
+	}
+	for _, item := range witness {
+		if err := writeVarBytes(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readWitness(r io.Reader) (wire.TxWitness, error) {
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if count > maxMapEntrySize {
+		return nil, errp.New("Witness has too many items")
+	}
+	witness := make(wire.TxWitness, count)
+	for i := range witness {
+		item, err := readVarBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		witness[i] = item
+	}
+	return witness, nil
+}
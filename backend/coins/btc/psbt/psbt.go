@@ -0,0 +1,526 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package psbt implements the serialization format of partially signed bitcoin transactions as
+// specified in https://github.com/bitcoin/bips/blob/master/bip-0174.mediawiki.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// magic is the PSBT header: "psbt" followed by the 0xff separator.
+var magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// maxMapEntrySize limits the size of a single key or value to protect against malformed input.
+const maxMapEntrySize = 1 << 24
+
+const (
+	globalTypeUnsignedTx = 0x00
+	globalTypeXPub       = 0x01
+
+	inputTypeNonWitnessUTXO     = 0x00
+	inputTypeWitnessUTXO        = 0x01
+	inputTypePartialSig         = 0x02
+	inputTypeSighashType        = 0x03
+	inputTypeRedeemScript       = 0x04
+	inputTypeWitnessScript      = 0x05
+	inputTypeBIP32Derivation    = 0x06
+	inputTypeFinalScriptSig     = 0x07
+	inputTypeFinalScriptWitness = 0x08
+	inputTypeTapKeySig          = 0x13
+	inputTypeTapInternalKey     = 0x17
+
+	outputTypeRedeemScript    = 0x00
+	outputTypeWitnessScript   = 0x01
+	outputTypeBIP32Derivation = 0x02
+)
+
+// Unknown is a key-value pair of a type not interpreted by this package. It is kept so that it
+// can be passed on unchanged.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// BIP32Derivation maps a public key to the root fingerprint and keypath it was derived with.
+type BIP32Derivation struct {
+	PubKey []byte
+	// Fingerprint is the fingerprint of the master key, interpreted as a big endian number.
+	Fingerprint uint32
+	Keypath     []uint32
+}
+
+// XPub is a global extended public key entry, used e.g. to describe multisig cosigners.
+type XPub struct {
+	// ExtendedKey is the 78 byte serialized extended public key.
+	ExtendedKey []byte
+	Fingerprint uint32
+	Keypath     []uint32
+}
+
+// PartialSig is a signature for one public key of an input.
+type PartialSig struct {
+	PubKey []byte
+	// Signature is the DER encoded signature, followed by the sighash type byte.
+	Signature []byte
+}
+
+// Input holds the per input data of a PSBT.
+type Input struct {
+	NonWitnessUTXO     *wire.MsgTx
+	WitnessUTXO        *wire.TxOut
+	PartialSigs        []*PartialSig
+	SighashType        uint32
+	RedeemScript       []byte
+	WitnessScript      []byte
+	BIP32Derivations   []*BIP32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness wire.TxWitness
+	// TapKeySig is the 64 byte BIP340 signature for a taproot key path spend, optionally followed
+	// by the sighash type byte.
+	TapKeySig []byte
+	// TapInternalKey is the 32 byte x-only internal key of a taproot output.
+	TapInternalKey []byte
+	Unknowns       []*Unknown
+}
+
+// IsFinalized returns true if the input has a final scriptSig or witness.
+func (input *Input) IsFinalized() bool {
+	return input.FinalScriptSig != nil || input.FinalScriptWitness != nil
+}
+
+// AddPartialSig adds a signature for a public key, replacing an existing one for the same key.
+func (input *Input) AddPartialSig(pubKey []byte, signature []byte) {
+	for _, partialSig := range input.PartialSigs {
+		if bytes.Equal(partialSig.PubKey, pubKey) {
+			partialSig.Signature = signature
+			return
+		}
+	}
+	input.PartialSigs = append(input.PartialSigs, &PartialSig{PubKey: pubKey, Signature: signature})
+}
+
+// Output holds the per output data of a PSBT.
+type Output struct {
+	RedeemScript     []byte
+	WitnessScript    []byte
+	BIP32Derivations []*BIP32Derivation
+	Unknowns         []*Unknown
+}
+
+// Packet is a partially signed bitcoin transaction.
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	XPubs      []*XPub
+	Inputs     []*Input
+	Outputs    []*Output
+	Unknowns   []*Unknown
+}
+
+// New creates a new packet from an unsigned transaction, with empty inputs and outputs data.
+func New(unsignedTx *wire.MsgTx) (*Packet, error) {
+	for _, txIn := range unsignedTx.TxIn {
+		if len(txIn.SignatureScript) != 0 || len(txIn.Witness) != 0 {
+			return nil, errp.New("The transaction must be unsigned")
+		}
+	}
+	packet := &Packet{
+		UnsignedTx: unsignedTx,
+		Inputs:     make([]*Input, len(unsignedTx.TxIn)),
+		Outputs:    make([]*Output, len(unsignedTx.TxOut)),
+	}
+	for i := range packet.Inputs {
+		packet.Inputs[i] = &Input{}
+	}
+	for i := range packet.Outputs {
+		packet.Outputs[i] = &Output{}
+	}
+	return packet, nil
+}
+
+// IsComplete returns true if all inputs are finalized.
+func (packet *Packet) IsComplete() bool {
+	for _, input := range packet.Inputs {
+		if !input.IsFinalized() {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract returns the final network transaction. All inputs must be finalized.
+func (packet *Packet) Extract() (*wire.MsgTx, error) {
+	if !packet.IsComplete() {
+		return nil, errp.New("PSBT is not finalized")
+	}
+	tx := packet.UnsignedTx.Copy()
+	for i, txIn := range tx.TxIn {
+		input := packet.Inputs[i]
+		txIn.SignatureScript = input.FinalScriptSig
+		txIn.Witness = input.FinalScriptWitness
+	}
+	return tx, nil
+}
+
+// Serialize writes the binary representation of the packet.
+func (packet *Packet) Serialize(w io.Writer) error {
+	if _, err := w.Write(magic); err != nil {
+		return errp.WithStack(err)
+	}
+	var unsignedTx bytes.Buffer
+	if err := packet.UnsignedTx.SerializeNoWitness(&unsignedTx); err != nil {
+		return errp.WithStack(err)
+	}
+	if err := writeEntry(w, []byte{globalTypeUnsignedTx}, unsignedTx.Bytes()); err != nil {
+		return err
+	}
+	for _, xpub := range packet.XPubs {
+		err := writeEntry(w,
+			append([]byte{globalTypeXPub}, xpub.ExtendedKey...),
+			serializeKeypath(xpub.Fingerprint, xpub.Keypath))
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeUnknowns(w, packet.Unknowns); err != nil {
+		return err
+	}
+	if err := writeSeparator(w); err != nil {
+		return err
+	}
+	for _, input := range packet.Inputs {
+		if err := input.serialize(w); err != nil {
+			return err
+		}
+	}
+	for _, output := range packet.Outputs {
+		if err := output.serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bytes returns the binary representation of the packet.
+func (packet *Packet) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := packet.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// B64Encode returns the base64 encoding of the packet, which is the common format to exchange
+// PSBTs as text.
+func (packet *Packet) B64Encode() (string, error) {
+	raw, err := packet.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func (input *Input) serialize(w io.Writer) error {
+	if input.NonWitnessUTXO != nil {
+		var buf bytes.Buffer
+		if err := input.NonWitnessUTXO.Serialize(&buf); err != nil {
+			return errp.WithStack(err)
+		}
+		if err := writeEntry(w, []byte{inputTypeNonWitnessUTXO}, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if input.WitnessUTXO != nil {
+		var buf bytes.Buffer
+		if err := wire.WriteTxOut(&buf, 0, 0, input.WitnessUTXO); err != nil {
+			return errp.WithStack(err)
+		}
+		if err := writeEntry(w, []byte{inputTypeWitnessUTXO}, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if !input.IsFinalized() {
+		for _, partialSig := range input.PartialSigs {
+			err := writeEntry(w,
+				append([]byte{inputTypePartialSig}, partialSig.PubKey...),
+				partialSig.Signature)
+			if err != nil {
+				return err
+			}
+		}
+		if input.SighashType != 0 {
+			var value [4]byte
+			littleEndianPutUint32(value[:], input.SighashType)
+			if err := writeEntry(w, []byte{inputTypeSighashType}, value[:]); err != nil {
+				return err
+			}
+		}
+		if input.RedeemScript != nil {
+			if err := writeEntry(w, []byte{inputTypeRedeemScript}, input.RedeemScript); err != nil {
+				return err
+			}
+		}
+		if input.WitnessScript != nil {
+			if err := writeEntry(w, []byte{inputTypeWitnessScript}, input.WitnessScript); err != nil {
+				return err
+			}
+		}
+		for _, derivation := range input.BIP32Derivations {
+			err := writeEntry(w,
+				append([]byte{inputTypeBIP32Derivation}, derivation.PubKey...),
+				serializeKeypath(derivation.Fingerprint, derivation.Keypath))
+			if err != nil {
+				return err
+			}
+		}
+		if input.TapKeySig != nil {
+			if err := writeEntry(w, []byte{inputTypeTapKeySig}, input.TapKeySig); err != nil {
+				return err
+			}
+		}
+		if input.TapInternalKey != nil {
+			if err := writeEntry(w, []byte{inputTypeTapInternalKey}, input.TapInternalKey); err != nil {
+				return err
+			}
+		}
+	}
+	if input.FinalScriptSig != nil {
+		if err := writeEntry(w, []byte{inputTypeFinalScriptSig}, input.FinalScriptSig); err != nil {
+			return err
+		}
+	}
+	if input.FinalScriptWitness != nil {
+		var buf bytes.Buffer
+		if err := writeWitness(&buf, input.FinalScriptWitness); err != nil {
+			return err
+		}
+		if err := writeEntry(w, []byte{inputTypeFinalScriptWitness}, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := writeUnknowns(w, input.Unknowns); err != nil {
+		return err
+	}
+	return writeSeparator(w)
+}
+
+func (output *Output) serialize(w io.Writer) error {
+	if output.RedeemScript != nil {
+		if err := writeEntry(w, []byte{outputTypeRedeemScript}, output.RedeemScript); err != nil {
+			return err
+		}
+	}
+	if output.WitnessScript != nil {
+		if err := writeEntry(w, []byte{outputTypeWitnessScript}, output.WitnessScript); err != nil {
+			return err
+		}
+	}
+	for _, derivation := range output.BIP32Derivations {
+		err := writeEntry(w,
+			append([]byte{outputTypeBIP32Derivation}, derivation.PubKey...),
+			serializeKeypath(derivation.Fingerprint, derivation.Keypath))
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeUnknowns(w, output.Unknowns); err != nil {
+		return err
+	}
+	return writeSeparator(w)
+}
+
+// Parse reads a binary PSBT.
+func Parse(r io.Reader) (*Packet, error) {
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if !bytes.Equal(header, magic) {
+		return nil, errp.New("Invalid PSBT magic bytes")
+	}
+	packet := &Packet{}
+	err := readMap(r, func(key []byte, value []byte) error {
+		switch key[0] {
+		case globalTypeUnsignedTx:
+			if len(key) != 1 {
+				return errp.New("Invalid unsigned tx key")
+			}
+			if packet.UnsignedTx != nil {
+				return errp.New("Duplicate unsigned tx")
+			}
+			tx := &wire.MsgTx{}
+			if err := tx.DeserializeNoWitness(bytes.NewReader(value)); err != nil {
+				return errp.WithStack(err)
+			}
+			packet.UnsignedTx = tx
+		case globalTypeXPub:
+			fingerprint, keypath, err := parseKeypath(value)
+			if err != nil {
+				return err
+			}
+			packet.XPubs = append(packet.XPubs, &XPub{
+				ExtendedKey: key[1:],
+				Fingerprint: fingerprint,
+				Keypath:     keypath,
+			})
+		default:
+			packet.Unknowns = append(packet.Unknowns, &Unknown{Key: key, Value: value})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if packet.UnsignedTx == nil {
+		return nil, errp.New("PSBT is missing the unsigned transaction")
+	}
+	for _, txIn := range packet.UnsignedTx.TxIn {
+		if len(txIn.SignatureScript) != 0 {
+			return nil, errp.New("The PSBT transaction must be unsigned")
+		}
+	}
+	packet.Inputs = make([]*Input, len(packet.UnsignedTx.TxIn))
+	for i := range packet.Inputs {
+		input, err := parseInput(r)
+		if err != nil {
+			return nil, err
+		}
+		packet.Inputs[i] = input
+	}
+	packet.Outputs = make([]*Output, len(packet.UnsignedTx.TxOut))
+	for i := range packet.Outputs {
+		output, err := parseOutput(r)
+		if err != nil {
+			return nil, err
+		}
+		packet.Outputs[i] = output
+	}
+	return packet, nil
+}
+
+// NewFromBytes parses a binary PSBT.
+func NewFromBytes(raw []byte) (*Packet, error) {
+	return Parse(bytes.NewReader(raw))
+}
+
+// NewFromBase64 parses a base64 encoded PSBT.
+func NewFromBase64(encoded string) (*Packet, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace([]byte(encoded))))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return NewFromBytes(raw)
+}
+
+func parseInput(r io.Reader) (*Input, error) {
+	input := &Input{}
+	err := readMap(r, func(key []byte, value []byte) error {
+		switch key[0] {
+		case inputTypeNonWitnessUTXO:
+			tx := &wire.MsgTx{}
+			if err := tx.Deserialize(bytes.NewReader(value)); err != nil {
+				return errp.WithStack(err)
+			}
+			input.NonWitnessUTXO = tx
+		case inputTypeWitnessUTXO:
+			txOut, err := readTxOut(bytes.NewReader(value))
+			if err != nil {
+				return err
+			}
+			input.WitnessUTXO = txOut
+		case inputTypePartialSig:
+			input.PartialSigs = append(input.PartialSigs,
+				&PartialSig{PubKey: key[1:], Signature: value})
+		case inputTypeSighashType:
+			if len(value) != 4 {
+				return errp.New("Invalid sighash type")
+			}
+			input.SighashType = littleEndianUint32(value)
+		case inputTypeRedeemScript:
+			input.RedeemScript = value
+		case inputTypeWitnessScript:
+			input.WitnessScript = value
+		case inputTypeBIP32Derivation:
+			fingerprint, keypath, err := parseKeypath(value)
+			if err != nil {
+				return err
+			}
+			input.BIP32Derivations = append(input.BIP32Derivations, &BIP32Derivation{
+				PubKey:      key[1:],
+				Fingerprint: fingerprint,
+				Keypath:     keypath,
+			})
+		case inputTypeFinalScriptSig:
+			input.FinalScriptSig = value
+		case inputTypeFinalScriptWitness:
+			witness, err := readWitness(bytes.NewReader(value))
+			if err != nil {
+				return err
+			}
+			input.FinalScriptWitness = witness
+		case inputTypeTapKeySig:
+			if len(key) != 1 || (len(value) != 64 && len(value) != 65) {
+				return errp.New("Invalid taproot key signature")
+			}
+			input.TapKeySig = value
+		case inputTypeTapInternalKey:
+			if len(key) != 1 || len(value) != 32 {
+				return errp.New("Invalid taproot internal key")
+			}
+			input.TapInternalKey = value
+		default:
+			input.Unknowns = append(input.Unknowns, &Unknown{Key: key, Value: value})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func parseOutput(r io.Reader) (*Output, error) {
+	output := &Output{}
+	err := readMap(r, func(key []byte, value []byte) error {
+		switch key[0] {
+		case outputTypeRedeemScript:
+			output.RedeemScript = value
+		case outputTypeWitnessScript:
+			output.WitnessScript = value
+		case outputTypeBIP32Derivation:
+			fingerprint, keypath, err := parseKeypath(value)
+			if err != nil {
+				return err
+			}
+			output.BIP32Derivations = append(output.BIP32Derivations, &BIP32Derivation{
+				PubKey:      key[1:],
+				Fingerprint: fingerprint,
+				Keypath:     keypath,
+			})
+		default:
+			output.Unknowns = append(output.Unknowns, &Unknown{Key: key, Value: value})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package psbt_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/psbt"
+	"github.com/stretchr/testify/require"
+)
+
+func unsignedTx() *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.HashH([]byte("prev")), Index: 1}, nil, nil))
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.HashH([]byte("prev")), Index: 2}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x00, 0x14, 0x01, 0x02}))
+	tx.AddTxOut(wire.NewTxOut(2000, []byte{0x00, 0x14, 0x03, 0x04}))
+	return tx
+}
+
+func TestRoundtrip(t *testing.T) {
+	packet, err := psbt.New(unsignedTx())
+	require.NoError(t, err)
+	packet.XPubs = []*psbt.XPub{{ExtendedKey: []byte{1, 2, 3}, Fingerprint: 0xdeadbeef, Keypath: []uint32{48 + 0x80000000}}}
+	packet.Inputs[0].WitnessUTXO = wire.NewTxOut(5000, []byte{0x00, 0x14, 0xaa})
+	packet.Inputs[0].SighashType = 1
+	packet.Inputs[0].BIP32Derivations = []*psbt.BIP32Derivation{
+		{PubKey: []byte{0x02, 0x01}, Fingerprint: 0x01020304, Keypath: []uint32{84 + 0x80000000, 0, 5}},
+	}
+	packet.Inputs[0].AddPartialSig([]byte{0x02, 0x01}, []byte{0x30, 0x01, 0x01})
+	packet.Inputs[1].NonWitnessUTXO = unsignedTx()
+	packet.Inputs[1].RedeemScript = []byte{0x00, 0x14}
+	packet.Inputs[1].Unknowns = []*psbt.Unknown{{Key: []byte{0xfc, 0x01}, Value: []byte{0x42}}}
+	packet.Inputs[1].TapKeySig = make([]byte, 64)
+	packet.Inputs[1].TapInternalKey = make([]byte, 32)
+	packet.Outputs[1].BIP32Derivations = []*psbt.BIP32Derivation{
+		{PubKey: []byte{0x03, 0x01}, Fingerprint: 0x01020304, Keypath: []uint32{84 + 0x80000000, 1, 0}},
+	}
+
+	encoded, err := packet.B64Encode()
+	require.NoError(t, err)
+	decoded, err := psbt.NewFromBase64(encoded)
+	require.NoError(t, err)
+	require.Equal(t, packet.UnsignedTx.TxHash(), decoded.UnsignedTx.TxHash())
+	require.Equal(t, packet.XPubs, decoded.XPubs)
+	require.Equal(t, packet.Inputs[0], decoded.Inputs[0])
+	require.Equal(t, packet.Inputs[1].NonWitnessUTXO.TxHash(), decoded.Inputs[1].NonWitnessUTXO.TxHash())
+	require.Equal(t, packet.Inputs[1].RedeemScript, decoded.Inputs[1].RedeemScript)
+	require.Equal(t, packet.Inputs[1].Unknowns, decoded.Inputs[1].Unknowns)
+	require.Equal(t, packet.Inputs[1].TapKeySig, decoded.Inputs[1].TapKeySig)
+	require.Equal(t, packet.Inputs[1].TapInternalKey, decoded.Inputs[1].TapInternalKey)
+	require.Equal(t, packet.Outputs, decoded.Outputs)
+	reencoded, err := decoded.B64Encode()
+	require.NoError(t, err)
+	require.Equal(t, encoded, reencoded)
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	require.Equal(t, []byte("psbt\xff"), raw[:5])
+}
+
+func TestFinalizeAndExtract(t *testing.T) {
+	packet, err := psbt.New(unsignedTx())
+	require.NoError(t, err)
+	require.False(t, packet.IsComplete())
+	_, err = packet.Extract()
+	require.Error(t, err)
+
+	packet.Inputs[0].AddPartialSig([]byte{0x02}, []byte{0x30})
+	packet.Inputs[0].FinalScriptWitness = wire.TxWitness{{0x30}, {0x02}}
+	packet.Inputs[1].FinalScriptSig = []byte{0x01, 0x02}
+	require.True(t, packet.IsComplete())
+
+	encoded, err := packet.B64Encode()
+	require.NoError(t, err)
+	decoded, err := psbt.NewFromBase64(encoded)
+	require.NoError(t, err)
+	// Partial signatures are dropped once an input is finalized.
+	require.Empty(t, decoded.Inputs[0].PartialSigs)
+
+	tx, err := decoded.Extract()
+	require.NoError(t, err)
+	require.Equal(t, wire.TxWitness{{0x30}, {0x02}}, tx.TxIn[0].Witness)
+	require.Equal(t, []byte{0x01, 0x02}, tx.TxIn[1].SignatureScript)
+	require.Equal(t, unsignedTx().TxIn[1].PreviousOutPoint, tx.TxIn[1].PreviousOutPoint)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := psbt.NewFromBytes([]byte("psbx\xff\x00"))
+	require.Error(t, err)
+	// Missing unsigned tx.
+	_, err = psbt.NewFromBytes([]byte("psbt\xff\x00"))
+	require.Error(t, err)
+	_, err = psbt.NewFromBase64("not base64!")
+	require.Error(t, err)
+
+	// Duplicate keys.
+	packet, err := psbt.New(unsignedTx())
+	require.NoError(t, err)
+	packet.Unknowns = []*psbt.Unknown{{Key: []byte{0xfc}, Value: nil}, {Key: []byte{0xfc}, Value: nil}}
+	raw, err := packet.Bytes()
+	require.NoError(t, err)
+	_, err = psbt.NewFromBytes(raw)
+	require.Error(t, err)
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// signalsRBF returns true if the tx opts in to replace-by-fee (BIP125).
+func signalsRBF(tx *wire.MsgTx) bool {
+	for _, txIn := range tx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// BumpFee replaces an unconfirmed outgoing transaction with a transaction paying a higher fee rate
+// (BIP125), using the fee rate of the given fee target. The replacement spends the same inputs and
+// pays the same recipients; the fee is deducted from the change, and more coins are added if
+// needed. Transactions with more than one change output are rejected. The replacement is signed, broadcast, and the original is marked as replaced. The ID of
+// the replacement is returned.
+func (account *Account) BumpFee(txID string, feeTargetCode accounts.FeeTargetCode) (string, error) {
+	txHash, err := chainhash.NewHashFromStr(txID)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	originalTx, originalOutputs, err := account.transactions.UnconfirmedOutgoingTx(*txHash)
+	if err != nil {
+		return "", err
+	}
+	if !signalsRBF(originalTx) {
+		return "", errp.New("The transaction does not signal replace-by-fee")
+	}
+	feeRatePerKb, err := account.feeRatePerKb(feeTargetCode)
+	if err != nil {
+		return "", err
+	}
+
+	var originalInputsSum, originalOutputsSum btcutil.Amount
+	originalInputs := map[wire.OutPoint]maketx.UTXO{}
+	for outPoint, spentOutput := range originalOutputs {
+		originalInputsSum += btcutil.Amount(spentOutput.Value)
+		originalInputs[outPoint] = maketx.UTXO{
+			TxOut:         spentOutput.TxOut,
+			Configuration: account.getAddress(spentOutput.ScriptHashHex()).Configuration,
+		}
+	}
+	outputs := []*wire.TxOut{}
+	changeAddress := account.subaccounts[0].changeAddresses.GetUnused()[0]
+	changeOutputs := 0
+	for _, txOut := range originalTx.TxOut {
+		originalOutputsSum += btcutil.Amount(txOut.Value)
+		if address := account.lookupChangeAddress(txOut.PkScript); address != nil {
+			// The change is recomputed and sent to the same address.
+			changeAddress = address
+			changeOutputs++
+			continue
+		}
+		outputs = append(outputs, txOut)
+	}
+	// The replacement has at most one change output, so the structure of transactions with
+	// multiple change outputs can't be kept.
+	if changeOutputs > 1 {
+		return "", errp.New("Transactions with multiple change outputs can't be replaced")
+	}
+	originalFee := originalInputsSum - originalOutputsSum
+	originalFeeRatePerKb := originalFee * 1000 /
+		btcutil.Amount(mempool.GetTxVirtualSize(btcutil.NewTx(originalTx)))
+	if feeRatePerKb <= originalFeeRatePerKb {
+		return "", errp.Newf(
+			"The fee rate must be higher than the current fee rate of %d sat/vB",
+			originalFeeRatePerKb/1000)
+	}
+
+	// Additional coins must be confirmed (BIP125 rule 2). The outputs of the original can't be
+	// used, as they disappear with the replacement.
+	utxos := account.transactions.SpendableOutputs()
+	additionalUTXOs := map[wire.OutPoint]maketx.UTXO{}
+	for outPoint, spendableOutput := range utxos {
+		if !spendableOutput.Confirmed || outPoint.Hash == *txHash {
+			continue
+		}
+		additionalUTXOs[outPoint] = maketx.UTXO{
+			TxOut:         spendableOutput.TxOut,
+			Configuration: account.getAddress(spendableOutput.ScriptHashHex()).Configuration,
+			Frozen:        spendableOutput.Frozen,
+		}
+	}
+	txProposal, err := maketx.NewTxReplacement(
+		account.coin,
+		originalInputs,
+		additionalUTXOs,
+		outputs,
+		originalFee,
+		feeRatePerKb,
+		changeAddress,
+		account.log,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	previousOutputs := map[wire.OutPoint]*transactions.SpendableOutput{}
+	for outPoint, spentOutput := range originalOutputs {
+		previousOutputs[outPoint] = spentOutput
+	}
+	for outPoint, spendableOutput := range utxos {
+		previousOutputs[outPoint] = spendableOutput
+	}
+	account.log.WithField("replaces", txID).Info("Signing and sending replacement transaction")
+	if err := account.signTransaction(txProposal, previousOutputs, account.getPrevTx); err != nil {
+		return "", errp.WithMessage(err, "Failed to sign transaction")
+	}
+	if err := account.coin.Blockchain().TransactionBroadcast(txProposal.Transaction); err != nil {
+		return "", err
+	}
+	replacementHash := txProposal.Transaction.TxHash()
+	if err := account.transactions.MarkTxReplaced(*txHash, replacementHash); err != nil {
+		// Not critical, the original disappears from the history once the server drops it.
+		account.log.WithError(err).Error("Failed to mark transaction as replaced")
+	}
+	if note := account.Notes().TxNote(txID); note != "" {
+		if err := account.SetTxNote(replacementHash.String(), note); err != nil {
+			// Not critical.
+			account.log.WithError(err).Error("Failed to copy the transaction note to the replacement")
+		}
+	}
+	account.Config().OnEvent(accounts.EventStatusChanged)
+	return replacementHash.String(), nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// medianTimePastBlocks is the number of blocks whose median timestamp must have passed a time based
+// locktime (BIP113).
+const medianTimePastBlocks = 11
+
+// validateLockTime checks that a locktime entered by the user lies in the future. A zero locktime
+// means no locktime.
+func (account *Account) validateLockTime(lockTime uint32) error {
+	if lockTime == 0 {
+		return nil
+	}
+	if lockTime < txscript.LockTimeThreshold {
+		tipHeight := account.coin.Headers().TipHeight()
+		if tipHeight == 0 {
+			return errp.New("The current block height is not known yet")
+		}
+		if int64(lockTime) <= int64(tipHeight) {
+			return errp.WithStack(errors.ErrInvalidLockTime)
+		}
+		return nil
+	}
+	if !time.Unix(int64(lockTime), 0).After(time.Now()) {
+		return errp.WithStack(errors.ErrInvalidLockTime)
+	}
+	return nil
+}
+
+// medianTimePast returns the median timestamp of the last blocks, which time based locktimes are
+// compared against. false is returned if the headers are not synced yet.
+func (account *Account) medianTimePast() (time.Time, bool) {
+	theHeaders := account.coin.Headers()
+	tipHeight := theHeaders.TipHeight()
+	timestamps := []time.Time{}
+	for height := tipHeight; height > tipHeight-medianTimePastBlocks && height >= 0; height-- {
+		header, err := theHeaders.VerifiedHeaderByHeight(height)
+		if err != nil {
+			account.log.WithError(err).Error("Could not retrieve header")
+			return time.Time{}, false
+		}
+		if header == nil {
+			return time.Time{}, false
+		}
+		timestamps = append(timestamps, header.Timestamp)
+	}
+	if len(timestamps) == 0 {
+		return time.Time{}, false
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	return timestamps[len(timestamps)/2], true
+}
+
+// lockTimeReached returns true if a tx with the given locktime can be included in the next block.
+func (account *Account) lockTimeReached(lockTime uint32) bool {
+	if lockTime < txscript.LockTimeThreshold {
+		return int64(lockTime) <= int64(account.coin.Headers().TipHeight())
+	}
+	medianTimePast, ok := account.medianTimePast()
+	return ok && int64(lockTime) < medianTimePast.Unix()
+}
+
+// scheduleTx stores a signed tx whose locktime was not reached yet. It is broadcast by
+// broadcastScheduledTxs() once it is.
+func (account *Account) scheduleTx(tx *wire.MsgTx) error {
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	if err := dbTx.PutScheduledTx(tx.TxHash(), &transactions.ScheduledTx{Tx: tx}); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return err
+	}
+	account.Config().OnEvent(accounts.EventScheduledTxsChanged)
+	return nil
+}
+
+// broadcastOrSchedule broadcasts a signed tx, or schedules it if its locktime is not reached yet.
+func (account *Account) broadcastOrSchedule(tx *wire.MsgTx) error {
+	if account.lockTimeReached(tx.LockTime) {
+		return account.coin.Blockchain().TransactionBroadcast(tx)
+	}
+	account.log.WithField("lockTime", tx.LockTime).Info("Transaction is scheduled")
+	return account.scheduleTx(tx)
+}
+
+// ScheduledTxs returns the signed transactions which are broadcast once their locktime is reached.
+func (account *Account) ScheduledTxs() (map[chainhash.Hash]*transactions.ScheduledTx, error) {
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	return dbTx.ScheduledTxs()
+}
+
+// scheduledOutPoints returns the outputs spent by scheduled transactions which have not failed.
+func (account *Account) scheduledOutPoints() (map[wire.OutPoint]struct{}, error) {
+	scheduledTxs, err := account.ScheduledTxs()
+	if err != nil {
+		return nil, err
+	}
+	result := map[wire.OutPoint]struct{}{}
+	for _, scheduledTx := range scheduledTxs {
+		if scheduledTx.Failed {
+			continue
+		}
+		for _, txIn := range scheduledTx.Tx.TxIn {
+			result[txIn.PreviousOutPoint] = struct{}{}
+		}
+	}
+	return result, nil
+}
+
+// CancelScheduledTx deletes a scheduled transaction, so that it is never broadcast. Its inputs can
+// be spent again.
+func (account *Account) CancelScheduledTx(txID string) error {
+	defer account.scheduledTxsLock.Lock()()
+	txHash, err := chainhash.NewHashFromStr(txID)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	dbTx.DeleteScheduledTx(*txHash)
+	if err := dbTx.Commit(); err != nil {
+		return err
+	}
+	account.Config().OnEvent(accounts.EventScheduledTxsChanged)
+	return nil
+}
+
+// permanentBroadcastErrors are parts of the reject reasons of the node for transactions which can
+// never be included in a block, e.g. because an input was spent by another transaction.
+var permanentBroadcastErrors = []string{
+	"missingorspent",
+	"missing-inputs",
+	"mandatory-script-verify-flag-failed",
+}
+
+// isPermanentBroadcastError returns true if the broadcast failed because the tx is invalid, so that
+// retrying it is pointless.
+func isPermanentBroadcastError(err error) bool {
+	for _, reason := range permanentBroadcastErrors {
+		if strings.Contains(err.Error(), reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcastScheduledTxs broadcasts the scheduled transactions whose locktime was reached. If the
+// broadcast fails, the error is recorded and the broadcast is retried with the next block. If the
+// inputs of the tx were spent by another tx or the node rejects the tx as invalid, it is marked as
+// failed and not retried anymore.
+func (account *Account) broadcastScheduledTxs() {
+	defer account.scheduledTxsLock.Lock()()
+	if account.isClosed() {
+		return
+	}
+	scheduledTxs, err := account.ScheduledTxs()
+	if err != nil {
+		account.log.WithError(err).Error("Could not retrieve scheduled transactions")
+		return
+	}
+	var spendableOutputs map[wire.OutPoint]*transactions.SpendableOutput
+	changed := false
+	for txHash, scheduledTx := range scheduledTxs {
+		if scheduledTx.Failed || !account.lockTimeReached(scheduledTx.Tx.LockTime) {
+			continue
+		}
+		changed = true
+		log := account.log.WithField("txID", txHash.String())
+		var broadcastErr error
+		failed := false
+		// The spendable outputs are incomplete until the initial sync is done. If the inputs are
+		// not checked, the node rejects the tx if they were spent.
+		if account.Synced() {
+			if spendableOutputs == nil {
+				spendableOutputs = account.transactions.SpendableOutputs()
+			}
+			for _, txIn := range scheduledTx.Tx.TxIn {
+				if _, ok := spendableOutputs[txIn.PreviousOutPoint]; !ok {
+					broadcastErr = errp.New("The inputs were spent by another transaction")
+					failed = true
+					break
+				}
+			}
+		}
+		if !failed {
+			broadcastErr = account.coin.Blockchain().TransactionBroadcast(scheduledTx.Tx)
+			failed = broadcastErr != nil && isPermanentBroadcastError(broadcastErr)
+		}
+		if broadcastErr != nil {
+			log.WithError(broadcastErr).WithField("failed", failed).Error(
+				"Failed to broadcast scheduled transaction")
+		} else {
+			log.Info("Scheduled transaction was broadcast")
+			if err := account.notifier.Put(txHash[:]); err != nil {
+				log.WithError(err).Error("Failed notifier.Put")
+			}
+		}
+		if err := account.updateScheduledTx(txHash, scheduledTx, broadcastErr, failed); err != nil {
+			log.WithError(err).Error("Could not update scheduled transaction")
+		}
+	}
+	if changed {
+		account.Config().OnEvent(accounts.EventScheduledTxsChanged)
+	}
+}
+
+// updateScheduledTx deletes a scheduled tx after it was broadcast, or records the error if the
+// broadcast failed. failed marks the tx as failed for good.
+func (account *Account) updateScheduledTx(
+	txHash chainhash.Hash, scheduledTx *transactions.ScheduledTx, broadcastErr error, failed bool) error {
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	if broadcastErr == nil {
+		dbTx.DeleteScheduledTx(txHash)
+	} else {
+		scheduledTx.Error = broadcastErr.Error()
+		scheduledTx.Failed = failed
+		if err := dbTx.PutScheduledTx(txHash, scheduledTx); err != nil {
+			return err
+		}
+	}
+	return dbTx.Commit()
+}
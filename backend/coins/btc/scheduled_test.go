@@ -0,0 +1,313 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
+	headersMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTxs(t *testing.T) {
+	net := &chaincfg.TestNet3Params
+	dbFolder := test.TstTempDir("btc-dbfolder")
+	defer func() { _ = os.RemoveAll(dbFolder) }()
+
+	btcCoin := btc.NewCoin(
+		coin.CodeTBTC, "TBTC", net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
+
+	// Headers up to the tip, one block every 10 minutes. headersSynced=false simulates headers
+	// which are not synced up to the tip yet.
+	tipHeight := 100
+	headersSynced := true
+	timestamps := map[int]time.Time{}
+	for height := 0; height <= 200; height++ {
+		timestamps[height] = time.Unix(1600000000+int64(height)*600, 0)
+	}
+	theHeaders := &headersMock.Interface{}
+	theHeaders.On("Initialize").Return()
+	theHeaders.On("SubscribeEvent", mock.Anything).Return(func() {})
+	theHeaders.On("TipHeight").Return(func() int { return tipHeight })
+	theHeaders.On("VerifiedHeaderByHeight", mock.Anything).Return(
+		func(height int) *wire.BlockHeader {
+			if !headersSynced || height > tipHeight {
+				return nil
+			}
+			return &wire.BlockHeader{Timestamp: timestamps[height]}
+		},
+		nil)
+	btcCoin.TstSetMakeHeaders(func() headers.Interface { return theHeaders })
+
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/0'")
+	require.NoError(t, err)
+	xpub, err := hdkeychain.NewMaster(make([]byte, 32), net)
+	require.NoError(t, err)
+	xpub, err = xpub.Neuter()
+	require.NoError(t, err)
+	configuration := signing.NewSinglesigConfiguration(signing.ScriptTypeP2WPKH, keypath, xpub)
+	address := addresses.NewAccountAddress(
+		configuration,
+		signing.NewEmptyRelativeKeypath().
+			Child(0, signing.NonHardened).
+			Child(0, signing.NonHardened),
+		net,
+		logging.Get().WithGroup("scheduled_test"),
+	)
+
+	// The first receive address is funded with one confirmed coin.
+	fundingTx := wire.NewMsgTx(wire.TxVersion)
+	fundingTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0}, nil, nil))
+	fundingTx.AddTxOut(wire.NewTxOut(100000, address.PubkeyScript()))
+	coinOutPoint := wire.OutPoint{Hash: fundingTx.TxHash(), Index: 0}
+	history := blockchain.TxHistory{
+		{TXHash: blockchain.TXHash(fundingTx.TxHash()), Height: 90},
+	}
+
+	var broadcastTxs []*wire.MsgTx
+	var broadcastErr error
+	theBlockchain := &blockchainMock.BlockchainMock{}
+	theBlockchain.MockRegisterOnConnectionStatusChangedEvent = func(func(blockchain.Status)) {}
+	theBlockchain.MockRelayFee = func(success func(btcutil.Amount), cleanup func(error)) {
+		success(1000)
+		cleanup(nil)
+	}
+	theBlockchain.MockScriptHashSubscribe = func(
+		setupAndTeardown func() func(error), scriptHash blockchain.ScriptHashHex, success func(string)) {
+		if scriptHash != address.PubkeyScriptHashHex() {
+			return
+		}
+		done := setupAndTeardown()
+		go func() {
+			success(history.Status())
+			done(nil)
+		}()
+	}
+	theBlockchain.MockScriptHashGetHistory = func(
+		scriptHash blockchain.ScriptHashHex, success func(blockchain.TxHistory), cleanup func(error)) {
+		go func() {
+			success(history)
+			cleanup(nil)
+		}()
+	}
+	theBlockchain.MockTransactionGet = func(
+		txHash chainhash.Hash, success func(*wire.MsgTx), cleanup func(error)) {
+		go func() {
+			success(fundingTx)
+			cleanup(nil)
+		}()
+	}
+	theBlockchain.MockGetMerkle = func(
+		txHash chainhash.Hash, height int,
+		success func([]blockchain.TXHash, int), cleanup func(error)) {
+		go cleanup(nil)
+	}
+	theBlockchain.MockTransactionBroadcast = func(tx *wire.MsgTx) error {
+		broadcastTxs = append(broadcastTxs, tx)
+		return broadcastErr
+	}
+	btcCoin.TstSetMakeBlockchain(func() blockchain.Interface { return theBlockchain })
+
+	notifier := &accountsMocks.Notifier{}
+	notifier.On("Put", mock.Anything).Return(nil)
+
+	account := btc.NewAccount(
+		&accounts.AccountConfig{
+			Code:        "accountcode",
+			Name:        "accountname",
+			DBFolder:    dbFolder,
+			OnEvent:     func(accounts.Event) {},
+			RateUpdater: nil,
+			GetSigningConfigurations: func() (signing.Configurations, error) {
+				return signing.Configurations{configuration}, nil
+			},
+			GetNotifier: func(signing.Configurations) accounts.Notifier { return notifier },
+		},
+		btcCoin, nil,
+		logging.Get().WithGroup("scheduled_test"),
+	)
+	require.NoError(t, account.Initialize())
+	require.Eventually(t, account.Synced, time.Second, 10*time.Millisecond)
+	require.Len(t, account.SpendableOutputs(), 1)
+
+	t.Run("lockTimeReached", func(t *testing.T) {
+		// Height based locktimes are final if the tx can be included in the next block.
+		require.True(t, account.TstLockTimeReached(0))
+		require.True(t, account.TstLockTimeReached(100))
+		require.False(t, account.TstLockTimeReached(101))
+
+		// Time based locktimes are compared against the median time of the last 11 blocks (BIP113),
+		// which is the timestamp of block 95, not against the timestamp of the tip.
+		medianTimePast := uint32(timestamps[95].Unix())
+		require.True(t, account.TstLockTimeReached(medianTimePast-1))
+		require.False(t, account.TstLockTimeReached(medianTimePast))
+		tipTimestamp := timestamps[100]
+		timestamps[100] = timestamps[100].Add(24 * time.Hour)
+		require.False(t, account.TstLockTimeReached(medianTimePast+600))
+		timestamps[100] = tipTimestamp
+
+		// Without the headers, time based locktimes are never reached.
+		headersSynced = false
+		require.False(t, account.TstLockTimeReached(medianTimePast-1))
+		headersSynced = true
+	})
+
+	// newScheduledTx creates a tx spending the given output with the given locktime.
+	newScheduledTx := func(outPoint wire.OutPoint, lockTime uint32) *wire.MsgTx {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+		tx.TxIn[0].Sequence = wire.MaxTxInSequenceNum - 1
+		tx.AddTxOut(wire.NewTxOut(90000, address.PubkeyScript()))
+		tx.LockTime = lockTime
+		return tx
+	}
+	scheduledTx := func(tx *wire.MsgTx) *transactions.ScheduledTx {
+		scheduledTxs, err := account.ScheduledTxs()
+		require.NoError(t, err)
+		return scheduledTxs[tx.TxHash()]
+	}
+	txProposal := func(selectedUTXOs map[wire.OutPoint]struct{}) error {
+		_, _, _, err := account.TxProposal(&accounts.TxProposalArgs{
+			RecipientAddress: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+			Amount:           coin.NewSendAmount("0.0001"),
+			FeeTargetCode:    accounts.FeeTargetCodeCustom,
+			CustomFee:        "1",
+			SelectedUTXOs:    selectedUTXOs,
+		})
+		return err
+	}
+	require.NoError(t, txProposal(nil))
+
+	t.Run("broadcast once the locktime is reached", func(t *testing.T) {
+		broadcastTxs = nil
+		broadcastErr = nil
+		tx := newScheduledTx(coinOutPoint, 101)
+		require.NoError(t, account.TstScheduleTx(tx))
+
+		// The scheduled tx is persisted, and its coin can't be spent by another tx.
+		require.Equal(t, tx.TxHash(), scheduledTx(tx).Tx.TxHash())
+		require.Equal(t, uint32(101), scheduledTx(tx).Tx.LockTime)
+		require.Error(t, txProposal(nil))
+		err := txProposal(map[wire.OutPoint]struct{}{coinOutPoint: {}})
+		require.EqualError(t, err,
+			"The unspent output "+coinOutPoint.String()+" is spent by a scheduled transaction")
+
+		account.TstBroadcastScheduledTxs()
+		require.Empty(t, broadcastTxs)
+		require.NotNil(t, scheduledTx(tx))
+
+		tipHeight = 101
+		defer func() { tipHeight = 100 }()
+		account.TstBroadcastScheduledTxs()
+		require.Len(t, broadcastTxs, 1)
+		require.Equal(t, tx.TxHash(), broadcastTxs[0].TxHash())
+		require.Nil(t, scheduledTx(tx))
+	})
+
+	t.Run("broadcast errors are recorded and retried", func(t *testing.T) {
+		broadcastTxs = nil
+		broadcastErr = errors.New("connection lost")
+		tx := newScheduledTx(coinOutPoint, 100)
+		require.NoError(t, account.TstScheduleTx(tx))
+
+		account.TstBroadcastScheduledTxs()
+		require.Len(t, broadcastTxs, 1)
+		require.Equal(t, "connection lost", scheduledTx(tx).Error)
+		require.False(t, scheduledTx(tx).Failed)
+
+		account.TstBroadcastScheduledTxs()
+		require.Len(t, broadcastTxs, 2)
+		require.Error(t, txProposal(nil))
+
+		require.NoError(t, account.CancelScheduledTx(tx.TxHash().String()))
+	})
+
+	t.Run("invalid transactions fail for good", func(t *testing.T) {
+		broadcastTxs = nil
+		broadcastErr = errors.New(
+			"Failed to broadcast transaction: bad-txns-inputs-missingorspent")
+		tx := newScheduledTx(coinOutPoint, 100)
+		require.NoError(t, account.TstScheduleTx(tx))
+
+		account.TstBroadcastScheduledTxs()
+		require.Len(t, broadcastTxs, 1)
+		require.True(t, scheduledTx(tx).Failed)
+		require.Equal(t, broadcastErr.Error(), scheduledTx(tx).Error)
+
+		// Failed transactions are not retried, and their coins can be spent again.
+		account.TstBroadcastScheduledTxs()
+		require.Len(t, broadcastTxs, 1)
+		require.NoError(t, txProposal(nil))
+		require.NoError(t, txProposal(map[wire.OutPoint]struct{}{coinOutPoint: {}}))
+
+		require.NoError(t, account.CancelScheduledTx(tx.TxHash().String()))
+	})
+
+	t.Run("spent inputs fail without broadcast", func(t *testing.T) {
+		broadcastTxs = nil
+		broadcastErr = nil
+		tx := newScheduledTx(wire.OutPoint{Hash: fundingTx.TxHash(), Index: 1}, 100)
+		require.NoError(t, account.TstScheduleTx(tx))
+
+		account.TstBroadcastScheduledTxs()
+		require.Empty(t, broadcastTxs)
+		require.True(t, scheduledTx(tx).Failed)
+		require.Equal(t, "The inputs were spent by another transaction", scheduledTx(tx).Error)
+
+		require.NoError(t, account.CancelScheduledTx(tx.TxHash().String()))
+	})
+
+	t.Run("CancelScheduledTx", func(t *testing.T) {
+		broadcastTxs = nil
+		tx := newScheduledTx(coinOutPoint, 150)
+		require.NoError(t, account.TstScheduleTx(tx))
+		require.Error(t, txProposal(nil))
+
+		require.Error(t, account.CancelScheduledTx("invalid"))
+		require.NotNil(t, scheduledTx(tx))
+
+		require.NoError(t, account.CancelScheduledTx(tx.TxHash().String()))
+		require.Nil(t, scheduledTx(tx))
+		require.NoError(t, txProposal(nil))
+
+		// The cancelled tx is never broadcast.
+		tipHeight = 150
+		defer func() { tipHeight = 100 }()
+		account.TstBroadcastScheduledTxs()
+		require.Empty(t, broadcastTxs)
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -35,19 +36,45 @@ type ProposedTransaction struct {
 	AccountSigningConfigurations []*signing.Configuration
 	PreviousOutputs              map[wire.OutPoint]*transactions.SpendableOutput
 	GetAddress                   func(blockchain.ScriptHashHex) *addresses.AccountAddress
-	GetPrevTx                    func(chainhash.Hash) *wire.MsgTx
+	GetPrevTx                    func(chainhash.Hash) (*wire.MsgTx, error)
 	// Signatures collects the signatures (signatures[transactionInput][cosignerIndex]).
 	Signatures [][]*btcec.Signature
 	SigHashes  *txscript.TxSigHashes
 }
 
-// signTransaction signs all inputs. It assumes all outputs spent belong to this
-// wallet. previousOutputs must contain all outputs which are spent by the transaction.
-func (account *Account) signTransaction(
+// TaprootSigHash returns the BIP341 key path signature hash of the input at the given index,
+// which must spend a taproot output.
+func (p *ProposedTransaction) TaprootSigHash(inputIndex int) ([]byte, error) {
+	previousTxOuts, err := previousTxOuts(p.TXProposal.Transaction, p.PreviousOutputs)
+	if err != nil {
+		return nil, err
+	}
+	return taproot.SigHash(p.TXProposal.Transaction, inputIndex, previousTxOuts)
+}
+
+// previousTxOuts returns the outputs spent by the transaction, in the order of its inputs.
+func previousTxOuts(
+	transaction *wire.MsgTx,
+	previousOutputs map[wire.OutPoint]*transactions.SpendableOutput,
+) ([]*wire.TxOut, error) {
+	result := make([]*wire.TxOut, len(transaction.TxIn))
+	for index, txIn := range transaction.TxIn {
+		spentOutput, ok := previousOutputs[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, errp.New("There needs to be exactly one output being spent per input!")
+		}
+		result[index] = spentOutput.TxOut
+	}
+	return result, nil
+}
+
+// collectSignatures asks all keystores to sign the transaction and returns the proposed
+// transaction holding the collected signatures.
+func (account *Account) collectSignatures(
 	txProposal *maketx.TxProposal,
 	previousOutputs map[wire.OutPoint]*transactions.SpendableOutput,
-	getPrevTx func(chainhash.Hash) *wire.MsgTx,
-) error {
+	getPrevTx func(chainhash.Hash) (*wire.MsgTx, error),
+) (*ProposedTransaction, error) {
 	signingConfigs := make([]*signing.Configuration, len(account.subaccounts))
 	for i, subacc := range account.subaccounts {
 		signingConfigs[i] = subacc.signingConfiguration
@@ -62,20 +89,52 @@ func (account *Account) signTransaction(
 		SigHashes:                    txscript.NewTxSigHashes(txProposal.Transaction),
 	}
 
+	// A keystore can be one of several cosigners of a multisig account, so there is a slot for
+	// every cosigner, not just for every keystore.
+	numberOfSigners := account.Config().Keystores.Count()
+	for _, signingConfig := range signingConfigs {
+		if signingConfig.NumberOfSigners() > numberOfSigners {
+			numberOfSigners = signingConfig.NumberOfSigners()
+		}
+	}
 	for i := range proposedTransaction.Signatures {
-		// TODO: Replace count with configuration.NumberOfSigners()
-		proposedTransaction.Signatures[i] = make([]*btcec.Signature, account.Config().Keystores.Count())
+		proposedTransaction.Signatures[i] = make([]*btcec.Signature, numberOfSigners)
 	}
 
 	if err := account.Config().Keystores.SignTransaction(proposedTransaction); err != nil {
+		return nil, err
+	}
+	return proposedTransaction, nil
+}
+
+// signTransaction signs all inputs. It assumes all outputs spent belong to this
+// wallet. previousOutputs must contain all outputs which are spent by the transaction.
+func (account *Account) signTransaction(
+	txProposal *maketx.TxProposal,
+	previousOutputs map[wire.OutPoint]*transactions.SpendableOutput,
+	getPrevTx func(chainhash.Hash) (*wire.MsgTx, error),
+) error {
+	proposedTransaction, err := account.collectSignatures(txProposal, previousOutputs, getPrevTx)
+	if err != nil {
 		return err
 	}
 
 	for index, input := range txProposal.Transaction.TxIn {
 		spentOutput := previousOutputs[input.PreviousOutPoint]
 		address := proposedTransaction.GetAddress(spentOutput.ScriptHashHex())
+		if address.Configuration.Multisig() {
+			numberOfSignatures := 0
+			for _, signature := range proposedTransaction.Signatures[index] {
+				if signature != nil {
+					numberOfSignatures++
+				}
+			}
+			if numberOfSignatures < address.Configuration.SigningThreshold() {
+				return errp.WithStack(ErrMultisigSignaturesMissing)
+			}
+		}
 		input.SignatureScript, input.Witness = address.SignatureScript(
-			proposedTransaction.Signatures[index])
+			proposedTransaction.Signatures[index][:address.Configuration.NumberOfSigners()])
 	}
 
 	// Sanity check: see if the created transaction is valid.
@@ -92,11 +151,26 @@ func txValidityCheck(transaction *wire.MsgTx, previousOutputs map[wire.OutPoint]
 	if !txsort.IsSorted(transaction) {
 		return errp.New("tx not bip69 conformant")
 	}
+	return verifyInputScripts(transaction, previousOutputs, sigHashes)
+}
+
+// verifyInputScripts executes the scripts of all inputs to check that the transaction is fully and
+// correctly signed.
+func verifyInputScripts(transaction *wire.MsgTx, previousOutputs map[wire.OutPoint]*transactions.SpendableOutput,
+	sigHashes *txscript.TxSigHashes) error {
 	for index, txIn := range transaction.TxIn {
 		spentOutput, ok := previousOutputs[txIn.PreviousOutPoint]
 		if !ok {
 			return errp.New("There needs to be exactly one output being spent per input!")
 		}
+		// The script engine of our btcd version does not know about taproot, so key path spends
+		// are verified directly.
+		if taproot.IsPayToTaproot(spentOutput.PkScript) {
+			if err := verifyTaprootInput(transaction, index, previousOutputs); err != nil {
+				return err
+			}
+			continue
+		}
 		engine, err := txscript.NewEngine(spentOutput.PkScript, transaction, index,
 			txscript.StandardVerifyFlags, nil, sigHashes, spentOutput.Value)
 		if err != nil {
@@ -108,3 +182,28 @@ func txValidityCheck(transaction *wire.MsgTx, previousOutputs map[wire.OutPoint]
 	}
 	return nil
 }
+
+// verifyTaprootInput checks the BIP341 key path signature of the input at the given index.
+func verifyTaprootInput(
+	transaction *wire.MsgTx,
+	index int,
+	previousOutputs map[wire.OutPoint]*transactions.SpendableOutput,
+) error {
+	witness := transaction.TxIn[index].Witness
+	if len(witness) != 1 || len(transaction.TxIn[index].SignatureScript) != 0 {
+		return errp.New("Unexpected taproot input witness")
+	}
+	signature, err := taproot.ParseSignature(witness[0])
+	if err != nil {
+		return err
+	}
+	previousTxOuts, err := previousTxOuts(transaction, previousOutputs)
+	if err != nil {
+		return err
+	}
+	sigHash, err := taproot.SigHash(transaction, index, previousTxOuts)
+	if err != nil {
+		return err
+	}
+	return taproot.Verify(previousTxOuts[index].PkScript[2:], sigHash, signature)
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignTaproot signs a transaction spending taproot outputs with the software keystore and
+// verifies the resulting witnesses.
+func TestSignTaproot(t *testing.T) {
+	net := &chaincfg.TestNet3Params
+	master, err := hdkeychain.NewMaster(make([]byte, hdkeychain.RecommendedSeedLen), net)
+	require.NoError(t, err)
+	keystore := software.NewKeystore(0, master)
+	keypath, err := signing.NewAbsoluteKeypath("m/86'/1'/0'")
+	require.NoError(t, err)
+	xpub, err := keystore.ExtendedPublicKey(nil, keypath)
+	require.NoError(t, err)
+	configuration := signing.NewSinglesigConfiguration(signing.ScriptTypeP2TR, keypath, xpub)
+
+	log := logging.Get().WithGroup("sign_test")
+	accountAddresses := map[blockchain.ScriptHashHex]*addresses.AccountAddress{}
+	previousOutputs := map[wire.OutPoint]*transactions.SpendableOutput{}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for index := uint32(0); index < 2; index++ {
+		relativeKeypath, err := signing.NewRelativeKeypath(fmt.Sprintf("0/%d", index))
+		require.NoError(t, err)
+		address := addresses.NewAccountAddress(configuration, relativeKeypath, net, log)
+		require.True(t, address.IsTaproot())
+		accountAddresses[address.PubkeyScriptHashHex()] = address
+		outPoint := wire.OutPoint{Hash: chainhash.HashH([]byte{byte(index)}), Index: index}
+		previousOutputs[outPoint] = &transactions.SpendableOutput{
+			TxOut: wire.NewTxOut(int64(100000*(index+1)), address.PubkeyScript()),
+		}
+		tx.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+	}
+	tx.AddTxOut(wire.NewTxOut(250000, []byte{txscript.OP_TRUE}))
+
+	proposedTransaction := &btc.ProposedTransaction{
+		TXProposal:                   &maketx.TxProposal{Transaction: tx},
+		AccountSigningConfigurations: []*signing.Configuration{configuration},
+		PreviousOutputs:              previousOutputs,
+		GetAddress: func(scriptHashHex blockchain.ScriptHashHex) *addresses.AccountAddress {
+			return accountAddresses[scriptHashHex]
+		},
+		Signatures: [][]*btcec.Signature{{nil}, {nil}},
+		SigHashes:  txscript.NewTxSigHashes(tx),
+	}
+	require.NoError(t, keystore.SignTransaction(proposedTransaction))
+	for index, txIn := range tx.TxIn {
+		address := accountAddresses[previousOutputs[txIn.PreviousOutPoint].ScriptHashHex()]
+		txIn.SignatureScript, txIn.Witness = address.SignatureScript(
+			proposedTransaction.Signatures[index])
+		require.Len(t, txIn.Witness, 1)
+		require.Len(t, txIn.Witness[0], 64)
+	}
+	require.NoError(t, btc.TstVerifyInputScripts(tx, previousOutputs))
+
+	// The signatures commit to the amounts of all spent outputs.
+	for _, previousOutput := range previousOutputs {
+		previousOutput.Value++
+		break
+	}
+	require.Error(t, btc.TstVerifyInputScripts(tx, previousOutputs))
+}
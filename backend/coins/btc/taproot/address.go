@@ -0,0 +1,99 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taproot
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// AddressTaproot is a pay-to-taproot (segwit version 1) address, which btcutil does not support
+// yet.
+type AddressTaproot struct {
+	hrp            string
+	witnessProgram [32]byte
+}
+
+// NewAddressTaproot returns the address of the given output key, which is an x-only public key.
+func NewAddressTaproot(outputKey []byte, net *chaincfg.Params) (*AddressTaproot, error) {
+	if len(outputKey) != 32 {
+		return nil, errp.New("The taproot output key must be 32 bytes")
+	}
+	address := &AddressTaproot{hrp: net.Bech32HRPSegwit}
+	copy(address.witnessProgram[:], outputKey)
+	return address, nil
+}
+
+// DecodeAddress decodes a bech32m encoded taproot address of the given network.
+func DecodeAddress(address string, net *chaincfg.Params) (*AddressTaproot, error) {
+	program, err := decodeSegwitAddressV1(net.Bech32HRPSegwit, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewAddressTaproot(program, net)
+}
+
+// EncodeAddress implements btcutil.Address.
+func (address *AddressTaproot) EncodeAddress() string {
+	encoded, err := encodeSegwitAddressV1(address.hrp, address.witnessProgram[:])
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
+
+// ScriptAddress implements btcutil.Address. It returns the witness program, which is the output
+// key.
+func (address *AddressTaproot) ScriptAddress() []byte {
+	return address.witnessProgram[:]
+}
+
+// IsForNet implements btcutil.Address.
+func (address *AddressTaproot) IsForNet(net *chaincfg.Params) bool {
+	return address.hrp == net.Bech32HRPSegwit
+}
+
+// String implements btcutil.Address.
+func (address *AddressTaproot) String() string {
+	return address.EncodeAddress()
+}
+
+// PayToAddrScript is like txscript.PayToAddrScript, but also supports taproot addresses.
+func PayToAddrScript(address btcutil.Address) ([]byte, error) {
+	if taprootAddress, ok := address.(*AddressTaproot); ok {
+		script, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_1).
+			AddData(taprootAddress.witnessProgram[:]).
+			Script()
+		return script, errp.WithStack(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	return script, errp.WithStack(err)
+}
+
+// IsPayToTaproot returns true if the pkScript is a pay-to-taproot output script.
+func IsPayToTaproot(pkScript []byte) bool {
+	return len(pkScript) == 34 && pkScript[0] == txscript.OP_1 && pkScript[1] == txscript.OP_DATA_32
+}
+
+// ExtractAddress returns the address of a pay-to-taproot output script.
+func ExtractAddress(pkScript []byte, net *chaincfg.Params) (*AddressTaproot, error) {
+	if !IsPayToTaproot(pkScript) {
+		return nil, errp.New("Not a pay-to-taproot script")
+	}
+	return NewAddressTaproot(pkScript[2:], net)
+}
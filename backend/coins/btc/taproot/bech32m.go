@@ -0,0 +1,115 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taproot
+
+import (
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// The bech32 package of btcutil only supports the original bech32 checksum, which is used for
+// witness version 0. Witness version 1+ addresses use bech32m (BIP350).
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	// bech32mConstant is the constant the checksum is XORed with (BIP350).
+	bech32mConstant = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := uint(0); i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	result := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]>>5)
+	}
+	result = append(result, 0)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]&31)
+	}
+	return result
+}
+
+// encodeSegwitAddressV1 encodes a witness version 1 program as a bech32m address.
+func encodeSegwitAddressV1(hrp string, program []byte) (string, error) {
+	converted, err := bech32.ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	data := append([]byte{1}, converted...)
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32mConstant
+	var result strings.Builder
+	result.WriteString(hrp)
+	result.WriteString("1")
+	for _, value := range data {
+		result.WriteByte(bech32Charset[value])
+	}
+	for i := 0; i < 6; i++ {
+		result.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return result.String(), nil
+}
+
+// decodeSegwitAddressV1 decodes a bech32m encoded witness version 1 address with the given human
+// readable part and returns the witness program.
+func decodeSegwitAddressV1(hrp string, address string) ([]byte, error) {
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return nil, errp.New("Mixed case address")
+	}
+	address = strings.ToLower(address)
+	separator := strings.LastIndex(address, "1")
+	if separator < 1 || separator+7 > len(address) || len(address) > 90 {
+		return nil, errp.New("Invalid bech32m address length")
+	}
+	if address[:separator] != hrp {
+		return nil, errp.New("Invalid bech32m address prefix")
+	}
+	data := make([]byte, 0, len(address)-separator-1)
+	for _, char := range address[separator+1:] {
+		value := strings.IndexRune(bech32Charset, char)
+		if value < 0 {
+			return nil, errp.New("Invalid bech32m character")
+		}
+		data = append(data, byte(value))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), data...)) != bech32mConstant {
+		return nil, errp.New("Invalid bech32m checksum")
+	}
+	data = data[:len(data)-6]
+	if len(data) == 0 || data[0] != 1 {
+		return nil, errp.New("Only witness version 1 is supported")
+	}
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return program, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taproot
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Schnorr signatures according to BIP340. A signature is represented as a btcec.Signature, where R
+// is the x coordinate of the nonce point, so that it can be passed along ECDSA signatures.
+
+var curve = btcec.S256()
+
+// TaggedHash computes the tagged hash `sha256(sha256(tag)|sha256(tag)|data...)` defined in
+// BIP340.
+func TaggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	hash := sha256.New()
+	_, _ = hash.Write(tagHash[:])
+	_, _ = hash.Write(tagHash[:])
+	for _, item := range data {
+		_, _ = hash.Write(item)
+	}
+	return hash.Sum(nil)
+}
+
+// bytes32 serializes a number as 32 bytes big endian.
+func bytes32(number *big.Int) []byte {
+	result := make([]byte, 32)
+	numberBytes := number.Bytes()
+	copy(result[32-len(numberBytes):], numberBytes)
+	return result
+}
+
+func hasEvenY(y *big.Int) bool {
+	return y.Bit(0) == 0
+}
+
+// liftX returns the point with the given x coordinate and an even y coordinate.
+func liftX(x []byte) (*btcec.PublicKey, error) {
+	if len(x) != 32 {
+		return nil, errp.New("An x-only public key must be 32 bytes")
+	}
+	publicKey, err := btcec.ParsePubKey(append([]byte{0x02}, x...), curve)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return publicKey, nil
+}
+
+// XOnlyPublicKey returns the 32 bytes x-only serialization of a public key.
+func XOnlyPublicKey(publicKey *btcec.PublicKey) []byte {
+	return bytes32(publicKey.X)
+}
+
+// SerializeSignature serializes a Schnorr signature as 64 bytes.
+func SerializeSignature(signature *btcec.Signature) []byte {
+	return append(bytes32(signature.R), bytes32(signature.S)...)
+}
+
+// ParseSignature parses a 64 bytes Schnorr signature.
+func ParseSignature(signature []byte) (*btcec.Signature, error) {
+	if len(signature) != 64 {
+		return nil, errp.New("A Schnorr signature must be 64 bytes")
+	}
+	return &btcec.Signature{
+		R: new(big.Int).SetBytes(signature[:32]),
+		S: new(big.Int).SetBytes(signature[32:]),
+	}, nil
+}
+
+// Sign creates a BIP340 Schnorr signature of the 32 bytes message with the given auxiliary
+// randomness.
+func Sign(privateKey *btcec.PrivateKey, message []byte, auxRand []byte) (*btcec.Signature, error) {
+	if len(message) != 32 || len(auxRand) != 32 {
+		return nil, errp.New("The message and the auxiliary randomness must be 32 bytes")
+	}
+	d := new(big.Int).Set(privateKey.D)
+	if d.Sign() == 0 || d.Cmp(curve.N) >= 0 {
+		return nil, errp.New("Invalid private key")
+	}
+	px, py := curve.ScalarBaseMult(bytes32(d))
+	if !hasEvenY(py) {
+		d.Sub(curve.N, d)
+	}
+	t := bytes32(d)
+	auxHash := TaggedHash("BIP0340/aux", auxRand)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+	k := new(big.Int).SetBytes(TaggedHash("BIP0340/nonce", t, bytes32(px), message))
+	k.Mod(k, curve.N)
+	if k.Sign() == 0 {
+		return nil, errp.New("Invalid nonce")
+	}
+	rx, ry := curve.ScalarBaseMult(bytes32(k))
+	if !hasEvenY(ry) {
+		k.Sub(curve.N, k)
+	}
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", bytes32(rx), bytes32(px), message))
+	e.Mod(e, curve.N)
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, curve.N)
+	signature := &btcec.Signature{R: rx, S: s}
+	if err := Verify(bytes32(px), message, signature); err != nil {
+		return nil, errp.WithMessage(err, "Created an invalid signature")
+	}
+	return signature, nil
+}
+
+// Verify verifies a BIP340 Schnorr signature of the 32 bytes message by the x-only public key.
+func Verify(publicKey []byte, message []byte, signature *btcec.Signature) error {
+	if len(message) != 32 {
+		return errp.New("The message must be 32 bytes")
+	}
+	point, err := liftX(publicKey)
+	if err != nil {
+		return err
+	}
+	if signature.R.Cmp(curve.P) >= 0 || signature.S.Cmp(curve.N) >= 0 {
+		return errp.New("Invalid signature")
+	}
+	e := new(big.Int).SetBytes(
+		TaggedHash("BIP0340/challenge", bytes32(signature.R), publicKey, message))
+	e.Mod(e, curve.N)
+	// R = s*G - e*P
+	sx, sy := curve.ScalarBaseMult(bytes32(signature.S))
+	ex, ey := curve.ScalarMult(point.X, point.Y, bytes32(e))
+	ey.Sub(curve.P, ey)
+	rx, ry := curve.Add(sx, sy, ex, ey)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return errp.New("Invalid signature")
+	}
+	if !hasEvenY(ry) || rx.Cmp(signature.R) != 0 {
+		return errp.New("Invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taproot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// SigHashDefault is the BIP341 sighash type which signs the whole transaction like SIGHASH_ALL.
+// Signatures with this type are 64 bytes, without a sighash type byte.
+const SigHashDefault = 0x00
+
+// SigHash computes the BIP341 signature hash of a key path spend of the input at the given index
+// with SigHashDefault. previousOutputs are the outputs spent by the inputs, in the order of the
+// inputs.
+func SigHash(tx *wire.MsgTx, inputIndex int, previousOutputs []*wire.TxOut) ([]byte, error) {
+	if len(previousOutputs) != len(tx.TxIn) {
+		return nil, errp.New("There needs to be one previous output per input")
+	}
+	if inputIndex < 0 || inputIndex >= len(tx.TxIn) {
+		return nil, errp.New("Input index out of range")
+	}
+	var prevouts, amounts, scriptPubKeys, sequences, outputs bytes.Buffer
+	for index, txIn := range tx.TxIn {
+		_, _ = prevouts.Write(txIn.PreviousOutPoint.Hash[:])
+		_ = binary.Write(&prevouts, binary.LittleEndian, txIn.PreviousOutPoint.Index)
+		_ = binary.Write(&amounts, binary.LittleEndian, previousOutputs[index].Value)
+		if err := wire.WriteVarBytes(&scriptPubKeys, 0, previousOutputs[index].PkScript); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		_ = binary.Write(&sequences, binary.LittleEndian, txIn.Sequence)
+	}
+	for _, txOut := range tx.TxOut {
+		if err := wire.WriteTxOut(&outputs, 0, 0, txOut); err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+	single := func(buffer bytes.Buffer) []byte {
+		hash := sha256.Sum256(buffer.Bytes())
+		return hash[:]
+	}
+
+	var message bytes.Buffer
+	_ = message.WriteByte(0x00) // epoch
+	_ = message.WriteByte(SigHashDefault)
+	_ = binary.Write(&message, binary.LittleEndian, tx.Version)
+	_ = binary.Write(&message, binary.LittleEndian, tx.LockTime)
+	_, _ = message.Write(single(prevouts))
+	_, _ = message.Write(single(amounts))
+	_, _ = message.Write(single(scriptPubKeys))
+	_, _ = message.Write(single(sequences))
+	_, _ = message.Write(single(outputs))
+	_ = message.WriteByte(0x00) // spend type: key path, no annex
+	_ = binary.Write(&message, binary.LittleEndian, uint32(inputIndex))
+	return TaggedHash("TapSighash", message.Bytes()), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package taproot implements what is needed to receive on and spend from single-key taproot
+// outputs without a script tree (BIP86), which the vendored btcd does not support yet: bech32m
+// addresses (BIP350), Schnorr signatures (BIP340), the key tweak and the signature hash (BIP341).
+package taproot
+
+import (
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// tweak returns the BIP341 tweak of an internal key committing to no script tree (BIP86).
+func tweak(internalKey []byte) (*big.Int, error) {
+	t := new(big.Int).SetBytes(TaggedHash("TapTweak", internalKey))
+	if t.Cmp(curve.N) >= 0 {
+		return nil, errp.New("Invalid taproot tweak")
+	}
+	return t, nil
+}
+
+// OutputKey returns the x-only output key for the given internal key, committing to no script
+// tree.
+func OutputKey(internalKey *btcec.PublicKey) ([]byte, error) {
+	internalKeyX := XOnlyPublicKey(internalKey)
+	point, err := liftX(internalKeyX)
+	if err != nil {
+		return nil, err
+	}
+	t, err := tweak(internalKeyX)
+	if err != nil {
+		return nil, err
+	}
+	tx, ty := curve.ScalarBaseMult(bytes32(t))
+	qx, qy := curve.Add(point.X, point.Y, tx, ty)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, errp.New("Invalid taproot output key")
+	}
+	return bytes32(qx), nil
+}
+
+// TweakPrivateKey returns the private key of the output key for the given internal private key,
+// committing to no script tree. Signatures created with it spend the output with the key path.
+func TweakPrivateKey(privateKey *btcec.PrivateKey) (*btcec.PrivateKey, error) {
+	d := new(big.Int).Set(privateKey.D)
+	if !hasEvenY(privateKey.PublicKey.Y) {
+		d.Sub(curve.N, d)
+	}
+	t, err := tweak(XOnlyPublicKey(privateKey.PubKey()))
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, errp.New("Invalid tweaked private key")
+	}
+	tweaked, _ := btcec.PrivKeyFromBytes(curve, bytes32(d))
+	return tweaked, nil
+}
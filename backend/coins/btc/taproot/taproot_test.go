@@ -0,0 +1,163 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taproot
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	result, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return result
+}
+
+// Test vectors from BIP340.
+func TestSchnorr(t *testing.T) {
+	vectors := []struct {
+		privateKey, publicKey, auxRand, message, signature string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000003",
+			"f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+		},
+		{
+			"b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+			"dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+			"6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+		},
+	}
+	for _, vector := range vectors {
+		privateKey, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), unhex(t, vector.privateKey))
+		require.Equal(t, vector.publicKey, hex.EncodeToString(XOnlyPublicKey(publicKey)))
+		signature, err := Sign(privateKey, unhex(t, vector.message), unhex(t, vector.auxRand))
+		require.NoError(t, err)
+		require.Equal(t, vector.signature, hex.EncodeToString(SerializeSignature(signature)))
+
+		parsed, err := ParseSignature(unhex(t, vector.signature))
+		require.NoError(t, err)
+		require.NoError(t, Verify(unhex(t, vector.publicKey), unhex(t, vector.message), parsed))
+		otherMessage := unhex(t, vector.message)
+		otherMessage[0] ^= 1
+		require.Error(t, Verify(unhex(t, vector.publicKey), otherMessage, parsed))
+	}
+}
+
+// Test vector from BIP86.
+func TestOutputKey(t *testing.T) {
+	xpub, err := hdkeychain.NewKeyFromString("xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ")
+	require.NoError(t, err)
+	xpub, err = xpub.Child(0)
+	require.NoError(t, err)
+	xpub, err = xpub.Child(0)
+	require.NoError(t, err)
+	internalKey, err := xpub.ECPubKey()
+	require.NoError(t, err)
+	require.Equal(t,
+		"cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115",
+		hex.EncodeToString(XOnlyPublicKey(internalKey)))
+	outputKey, err := OutputKey(internalKey)
+	require.NoError(t, err)
+	require.Equal(t,
+		"a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+		hex.EncodeToString(outputKey))
+	address, err := NewAddressTaproot(outputKey, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr", address.EncodeAddress())
+	pkScript, err := PayToAddrScript(address)
+	require.NoError(t, err)
+	require.Equal(t,
+		"5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c",
+		hex.EncodeToString(pkScript))
+	require.True(t, IsPayToTaproot(pkScript))
+
+	// The tweaked private key belongs to the output key.
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(t, err)
+	tweaked, err := TweakPrivateKey(privateKey)
+	require.NoError(t, err)
+	outputKey, err = OutputKey(privateKey.PubKey())
+	require.NoError(t, err)
+	require.Equal(t, outputKey, XOnlyPublicKey(tweaked.PubKey()))
+}
+
+// Test vectors from BIP350.
+func TestDecodeAddress(t *testing.T) {
+	address, err := DecodeAddress(
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t,
+		"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		hex.EncodeToString(address.ScriptAddress()))
+	require.True(t, address.IsForNet(&chaincfg.MainNetParams))
+	require.False(t, address.IsForNet(&chaincfg.TestNet3Params))
+
+	for _, invalid := range []string{
+		// Wrong network.
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+		// bech32 instead of bech32m checksum.
+		"tb1pw508d6qejxtdg4y5r3zarqfsj6c3",
+		// Witness version 0.
+		"tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+		// Mixed case.
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vQ47zagq",
+	} {
+		_, err := DecodeAddress(invalid, &chaincfg.TestNet3Params)
+		require.Error(t, err, invalid)
+	}
+}
+
+// Test vector from BIP341 (wallet-test-vectors.json, keyPathSpending). The input at index 4 is the
+// one signed with SIGHASH_DEFAULT.
+func TestSigHash(t *testing.T) {
+	tx := wire.NewMsgTx(0)
+	require.NoError(t, tx.Deserialize(bytes.NewReader(unhex(t,
+		"02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d28eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d"))))
+	previousOutputs := []*wire.TxOut{
+		wire.NewTxOut(420000000, unhex(t, "512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343")),
+		wire.NewTxOut(462000000, unhex(t, "5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3")),
+		wire.NewTxOut(294000000, unhex(t, "76a914751e76e8199196d454941c45d1b3a323f1433bd688ac")),
+		wire.NewTxOut(504000000, unhex(t, "5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e")),
+		wire.NewTxOut(630000000, unhex(t, "512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605")),
+		wire.NewTxOut(378000000, unhex(t, "00147dd65592d0ab2fe0d0257d571abf032cd9db93dc")),
+		wire.NewTxOut(672000000, unhex(t, "512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831")),
+		wire.NewTxOut(546000000, unhex(t, "5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5")),
+		wire.NewTxOut(588000000, unhex(t, "512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220")),
+	}
+	sigHash, err := SigHash(tx, 4, previousOutputs)
+	require.NoError(t, err)
+	require.Equal(t,
+		"4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef",
+		hex.EncodeToString(sigHash))
+
+	_, err = SigHash(tx, 4, previousOutputs[:8])
+	require.Error(t, err)
+	_, err = SigHash(tx, 9, previousOutputs)
+	require.Error(t, err)
+}
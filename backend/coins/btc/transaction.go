@@ -16,16 +16,20 @@
 package btc
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
 	"math/big"
+	"math/rand"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -34,95 +38,263 @@ import (
 // unitSatoshi is 1 BTC (default unit) in Satoshi.
 const unitSatoshi = 1e8
 
-// newTx creates a new tx to the given recipient address. It also returns a set of used account
-// outputs, which contains all outputs that spent in the tx. Those are needed to be able to sign the
-// transaction. selectedUTXOs restricts the available coins; if empty, no restriction is applied and
-// all unspent coins can be used.
+// customFeeRateMaxFactor is the multiple of the highest fee estimate above which custom fee rates
+// must be confirmed by the user, to protect against typos and unit mix-ups.
+const customFeeRateMaxFactor = 10
+
+// coinSelection returns the maketx coin selection for the given strategy.
+func coinSelection(strategy accounts.CoinSelectionStrategy) (*maketx.CoinSelection, error) {
+	switch strategy {
+	case "", accounts.CoinSelectionLargestFirst:
+		return maketx.CoinSelectionLargestFirst, nil
+	case accounts.CoinSelectionBranchAndBound:
+		return maketx.CoinSelectionBranchAndBound, nil
+	case accounts.CoinSelectionMinimizeLinkage:
+		return maketx.CoinSelectionMinimizeLinkage, nil
+	case accounts.CoinSelectionSingleRandomDraw:
+		var seed int64
+		if err := binary.Read(cryptorand.Reader, binary.LittleEndian, &seed); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		return maketx.NewCoinSelectionSingleRandomDraw(rand.New(rand.NewSource(seed))), nil
+	default:
+		return nil, errp.Newf("Unrecognized coin selection strategy %s", strategy)
+	}
+}
+
+// newTx creates a new tx paying the given recipients. At most one recipient can receive all
+// remaining funds. It also returns a set of used account outputs, which contains all outputs that
+// spent in the tx. Those are needed to be able to sign the transaction. selectedUTXOs restricts the
+// available coins; if empty, no restriction is applied and all unspent coins which are not frozen
+// can be used. Frozen coins are only spent if they are selected explicitly. coinSelectionStrategy
+// determines which of the available coins are spent. customFee is the fee rate in sat/vB if
+// feeTargetCode is accounts.FeeTargetCodeCustom, and allowHighFeeRate is true if the user confirmed
+// it despite being far above the estimates. lockTime is the absolute locktime of the tx, or zero for
+// none. Coins spent by scheduled transactions are never spent, and an error is returned if one of
+// them is selected.
 func (account *Account) newTx(
-	recipientAddress string,
-	amount coin.SendAmount,
+	recipients []accounts.Recipient,
 	feeTargetCode accounts.FeeTargetCode,
+	customFee string,
+	allowHighFeeRate bool,
 	selectedUTXOs map[wire.OutPoint]struct{},
+	coinSelectionStrategy accounts.CoinSelectionStrategy,
+	lockTime uint32,
 ) (
 	map[wire.OutPoint]*transactions.SpendableOutput, *maketx.TxProposal, error) {
 
 	account.log.Debug("Prepare new transaction")
 
-	address, err := account.coin.DecodeAddress(recipientAddress)
-	if err != nil {
-		return nil, nil, err
+	if len(recipients) == 0 {
+		return nil, nil, errp.New("No recipients")
 	}
-
-	var feeTarget *FeeTarget
-	for _, target := range account.feeTargets {
-		if target.code == feeTargetCode {
-			feeTarget = target
-			break
+	outputs := []*wire.TxOut{}
+	// The output script of the recipient receiving all remaining funds, if any.
+	var sendAllPkScript []byte
+	for _, recipient := range recipients {
+		address, err := account.coin.DecodeAddress(recipient.Address)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
-	if feeTarget == nil || feeTarget.feeRatePerKb == nil {
-		return nil, nil, errp.New("Fee could not be estimated")
+		pkScript, err := taproot.PayToAddrScript(address)
+		if err != nil {
+			return nil, nil, errp.WithStack(err)
+		}
+		if recipient.Amount.SendAll() {
+			if sendAllPkScript != nil {
+				return nil, nil, errp.New("Only one recipient can receive all remaining funds")
+			}
+			sendAllPkScript = pkScript
+			continue
+		}
+		allowZero := false
+		parsedAmount, err := recipient.Amount.Amount(big.NewInt(unitSatoshi), allowZero)
+		if err != nil {
+			return nil, nil, err
+		}
+		parsedAmountInt64, err := parsedAmount.Int64()
+		if err != nil {
+			return nil, nil, errp.WithStack(errors.ErrInvalidAmount)
+		}
+		outputs = append(outputs, wire.NewTxOut(parsedAmountInt64, pkScript))
 	}
 
-	pkScript, err := txscript.PayToAddrScript(address)
+	feeRatePerKb, err := account.targetFeeRatePerKb(feeTargetCode, customFee, allowHighFeeRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := account.validateLockTime(lockTime); err != nil {
+		return nil, nil, err
+	}
+	scheduledOutPoints, err := account.scheduledOutPoints()
 	if err != nil {
-		return nil, nil, errp.WithStack(err)
+		return nil, nil, err
 	}
+	for outPoint := range selectedUTXOs {
+		if _, ok := scheduledOutPoints[outPoint]; ok {
+			return nil, nil, errp.Newf("The unspent output %s is spent by a scheduled transaction", outPoint)
+		}
+	}
+
 	utxo := account.transactions.SpendableOutputs()
 	wireUTXO := make(map[wire.OutPoint]maketx.UTXO, len(utxo))
 	for outPoint, txOut := range utxo {
 		// Apply coin control.
+		if _, ok := scheduledOutPoints[outPoint]; ok {
+			continue
+		}
+		frozen := txOut.Frozen
 		if len(selectedUTXOs) != 0 {
 			if _, ok := selectedUTXOs[outPoint]; !ok {
 				continue
 			}
+			frozen = false
 		}
 		wireUTXO[outPoint] = maketx.UTXO{
 			TxOut: txOut.TxOut,
 			Configuration: account.getAddress(
 				blockchain.NewScriptHashHex(txOut.TxOut.PkScript)).Configuration,
+			Frozen: frozen,
 		}
 	}
 	var txProposal *maketx.TxProposal
-	if amount.SendAll() {
+	if sendAllPkScript != nil {
 		txProposal, err = maketx.NewTxSpendAll(
 			account.coin,
 			wireUTXO,
-			pkScript,
-			*feeTarget.feeRatePerKb,
+			outputs,
+			sendAllPkScript,
+			feeRatePerKb,
 			account.log,
 		)
 		if err != nil {
 			return nil, nil, err
 		}
 	} else {
-		allowZero := false
-		parsedAmount, err := amount.Amount(big.NewInt(unitSatoshi), allowZero)
+		coinSelection, err := coinSelection(coinSelectionStrategy)
 		if err != nil {
 			return nil, nil, err
 		}
-		parsedAmountInt64, err := parsedAmount.Int64()
-		if err != nil {
-			return nil, nil, errp.WithStack(errors.ErrInvalidAmount)
-		}
 		txProposal, err = maketx.NewTx(
 			account.coin,
 			wireUTXO,
-			wire.NewTxOut(parsedAmountInt64, pkScript),
-			*feeTarget.feeRatePerKb,
+			outputs,
+			feeRatePerKb,
 			// Change address is of the first subaccount, always.
 			account.subaccounts[0].changeAddresses.GetUnused()[0],
+			coinSelection,
 			account.log,
 		)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
+	if lockTime != 0 {
+		maketx.SetLockTime(txProposal.Transaction, lockTime)
+	}
 	account.log.Debugf("creating tx with %d inputs, %d outputs",
 		len(txProposal.Transaction.TxIn), len(txProposal.Transaction.TxOut))
 	return utxo, txProposal, nil
 }
 
+// feeRatePerKb returns the estimated fee rate of the given fee target. Custom fee rates are not
+// supported, see targetFeeRatePerKb.
+func (account *Account) feeRatePerKb(feeTargetCode accounts.FeeTargetCode) (btcutil.Amount, error) {
+	if feeTargetCode == accounts.FeeTargetCodeCustom {
+		return 0, errp.WithStack(errors.ErrCustomFeeNotSupported)
+	}
+	defer account.RLock()()
+	for _, target := range account.feeTargets {
+		if target.code == feeTargetCode && target.feeRatePerKb != nil {
+			return *target.feeRatePerKb, nil
+		}
+	}
+	return 0, errp.New("Fee could not be estimated")
+}
+
+// targetFeeRatePerKb returns the fee rate of the given fee target, or the custom fee rate in sat/vB
+// if feeTargetCode is accounts.FeeTargetCodeCustom.
+func (account *Account) targetFeeRatePerKb(
+	feeTargetCode accounts.FeeTargetCode, customFee string, allowHighFeeRate bool) (
+	btcutil.Amount, error) {
+	if feeTargetCode == accounts.FeeTargetCodeCustom {
+		return account.customFeeRatePerKb(customFee, allowHighFeeRate)
+	}
+	return account.feeRatePerKb(feeTargetCode)
+}
+
+// relayFee returns the minimum fee rate needed for a tx to be relayed by the blockchain backend.
+func (account *Account) relayFee() (btcutil.Amount, error) {
+	feeChan := make(chan btcutil.Amount, 1)
+	errChan := make(chan error, 1)
+	account.coin.Blockchain().RelayFee(
+		func(feeRatePerKb btcutil.Amount) {
+			feeChan <- feeRatePerKb
+		},
+		func(err error) {
+			if err != nil {
+				errChan <- err
+			}
+		},
+	)
+	select {
+	case feeRatePerKb := <-feeChan:
+		return feeRatePerKb, nil
+	case err := <-errChan:
+		return 0, err
+	}
+}
+
+// parseFeeRatePerKb parses a positive fee rate in sat/vB and returns it in sat/kvB.
+func parseFeeRatePerKb(feeRate string) (btcutil.Amount, error) {
+	feeRatePerVByte, ok := new(big.Rat).SetString(feeRate)
+	if !ok || feeRatePerVByte.Sign() <= 0 {
+		return 0, errp.WithStack(errors.ErrInvalidFeeRate)
+	}
+	feeRatePerKbRat := new(big.Rat).Mul(feeRatePerVByte, big.NewRat(1000, 1))
+	// Sub-satoshi precision per kvB is dropped.
+	feeRatePerKbInt := new(big.Int).Quo(feeRatePerKbRat.Num(), feeRatePerKbRat.Denom())
+	if !feeRatePerKbInt.IsInt64() {
+		return 0, errp.WithStack(errors.ErrFeeRateTooHigh)
+	}
+	return btcutil.Amount(feeRatePerKbInt.Int64()), nil
+}
+
+// customFeeRatePerKb parses a fee rate in sat/vB entered by the user. The fee rate must be at least
+// the relay fee. Unless allowHighFeeRate is true, it must be at most customFeeRateMaxFactor times
+// the highest fee estimate.
+func (account *Account) customFeeRatePerKb(customFee string, allowHighFeeRate bool) (
+	btcutil.Amount, error) {
+	feeRatePerKb, err := parseFeeRatePerKb(customFee)
+	if err != nil {
+		return 0, err
+	}
+
+	relayFee, err := account.relayFee()
+	if err != nil {
+		return 0, err
+	}
+	if feeRatePerKb < relayFee {
+		return 0, errp.WithStack(errors.ErrFeeRateTooLow)
+	}
+	if allowHighFeeRate {
+		return feeRatePerKb, nil
+	}
+	var highestEstimate btcutil.Amount
+	unlock := account.RLock()
+	for _, target := range account.feeTargets {
+		if target.feeRatePerKb != nil && *target.feeRatePerKb > highestEstimate {
+			highestEstimate = *target.feeRatePerKb
+		}
+	}
+	unlock()
+	// Without estimates, there is nothing to compare to.
+	if highestEstimate > 0 && feeRatePerKb > customFeeRateMaxFactor*highestEstimate {
+		return 0, errp.WithStack(errors.ErrFeeRateTooHigh)
+	}
+	return feeRatePerKb, nil
+}
+
 func (account *Account) getAddress(scriptHashHex blockchain.ScriptHashHex) *addresses.AccountAddress {
 	for _, subacc := range account.subaccounts {
 		if address := subacc.receiveAddresses.LookupByScriptHashHex(scriptHashHex); address != nil {
@@ -135,6 +307,29 @@ func (account *Account) getAddress(scriptHashHex blockchain.ScriptHashHex) *addr
 	panic("address must be present")
 }
 
+// getPrevTx fetches a transaction from the blockchain backend. It is used to provide the
+// previous transactions of the inputs being spent.
+func (account *Account) getPrevTx(txHash chainhash.Hash) (*wire.MsgTx, error) {
+	txChan := make(chan *wire.MsgTx, 1)
+	errChan := make(chan error, 1)
+	account.coin.Blockchain().TransactionGet(txHash,
+		func(tx *wire.MsgTx) {
+			txChan <- tx
+		},
+		func(err error) {
+			if err != nil {
+				errChan <- err
+			}
+		},
+	)
+	select {
+	case tx := <-txChan:
+		return tx, nil
+	case err := <-errChan:
+		return nil, errp.WithMessage(err, "Failed to fetch the previous transaction")
+	}
+}
+
 // SendTx implements accounts.Interface.
 func (account *Account) SendTx() error {
 	unlock := account.activeTxProposalLock.RLock()
@@ -148,26 +343,12 @@ func (account *Account) SendTx() error {
 
 	account.log.Info("Signing and sending transaction")
 	utxos := account.transactions.SpendableOutputs()
-	getPrevTx := func(txHash chainhash.Hash) *wire.MsgTx {
-		txChan := make(chan *wire.MsgTx)
-		account.coin.Blockchain().TransactionGet(txHash,
-			func(tx *wire.MsgTx) {
-				txChan <- tx
-			},
-			func(err error) {
-				if err != nil {
-					panic(err)
-				}
-			},
-		)
-		return <-txChan
-	}
-	if err := account.signTransaction(txProposal, utxos, getPrevTx); err != nil {
+	if err := account.signTransaction(txProposal, utxos, account.getPrevTx); err != nil {
 		return errp.WithMessage(err, "Failed to sign transaction")
 	}
 
 	account.log.Info("Signed transaction is broadcasted")
-	if err := account.coin.Blockchain().TransactionBroadcast(txProposal.Transaction); err != nil {
+	if err := account.broadcastOrSchedule(txProposal.Transaction); err != nil {
 		return err
 	}
 	if err := account.SetTxNote(txProposal.Transaction.TxHash().String(), note); err != nil {
@@ -187,11 +368,18 @@ func (account *Account) TxProposal(
 	defer account.activeTxProposalLock.Lock()()
 
 	account.log.Debug("Proposing transaction")
+	recipients := args.Recipients
+	if len(recipients) == 0 {
+		recipients = []accounts.Recipient{{Address: args.RecipientAddress, Amount: args.Amount}}
+	}
 	_, txProposal, err := account.newTx(
-		args.RecipientAddress,
-		args.Amount,
+		recipients,
 		args.FeeTargetCode,
+		args.CustomFee,
+		args.AllowHighFeeRate,
 		args.SelectedUTXOs,
+		args.CoinSelection,
+		args.LockTime,
 	)
 	if err != nil {
 		return coin.Amount{}, coin.Amount{}, coin.Amount{}, err
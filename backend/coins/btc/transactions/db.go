@@ -31,6 +31,29 @@ type DBTxInfo struct {
 	Verified         *bool           `json:"Verified"`
 	HeaderTimestamp  *time.Time      `json:"ts"`
 	CreatedTimestamp *time.Time      `json:"created"`
+	// ReplacedBy is the hash of the transaction which replaced this transaction (BIP125), or nil if
+	// it was not replaced by us.
+	ReplacedBy *chainhash.Hash `json:"replacedBy"`
+}
+
+// UTXOMetadata is user defined data about an unspent output.
+type UTXOMetadata struct {
+	// Frozen is true if the output must not be spent, e.g. because it is dust sent to deanonymize
+	// the wallet.
+	Frozen bool   `json:"frozen"`
+	Label  string `json:"label"`
+}
+
+// ScheduledTx is a signed transaction with a locktime in the future. It is broadcast once it can
+// be included in the next block.
+type ScheduledTx struct {
+	Tx *wire.MsgTx `json:"tx"`
+	// Error is the error of the last broadcast attempt, or empty if it was not attempted yet.
+	Error string `json:"error"`
+	// Failed is true if the tx can never be broadcast, e.g. because its inputs were spent by
+	// another tx. It is kept so the user can see the error, but it is not retried anymore and its
+	// inputs can be spent again.
+	Failed bool `json:"failed"`
 }
 
 // DBTxInterface needs to be implemented to persist all wallet/transaction related data.
@@ -65,6 +88,9 @@ type DBTxInterface interface {
 	// MarkTxVerified marks a tx as verified. Stores timestamp of the header this tx appears in.
 	MarkTxVerified(txHash chainhash.Hash, headerTimestamp time.Time) error
 
+	// MarkTxReplaced marks a tx as replaced by another tx, e.g. after a fee bump (BIP125).
+	MarkTxReplaced(txHash chainhash.Hash, replacedBy chainhash.Hash) error
+
 	// PutInput stores a transaction input. It is referenced by the output it spends. The
 	// transaction hash of the transaction this input was found in is recorded. TODO: store slice of
 	// inputs along with the txhash they appear in. If there are more than one, a double spend is
@@ -87,6 +113,23 @@ type DBTxInterface interface {
 	// DeleteOutput deletes an output (nothing happens if not found).
 	DeleteOutput(wire.OutPoint)
 
+	// PutUTXOMetadata stores the metadata of an output. The metadata is deleted if it is the zero
+	// value.
+	PutUTXOMetadata(wire.OutPoint, UTXOMetadata) error
+
+	// UTXOMetadata retrieves the metadata of an output. If none was stored, the zero value is
+	// returned.
+	UTXOMetadata(wire.OutPoint) (UTXOMetadata, error)
+
+	// PutScheduledTx stores a scheduled transaction.
+	PutScheduledTx(chainhash.Hash, *ScheduledTx) error
+
+	// DeleteScheduledTx deletes a scheduled transaction (nothing happens if not found).
+	DeleteScheduledTx(chainhash.Hash)
+
+	// ScheduledTxs retrieves all scheduled transactions.
+	ScheduledTxs() (map[chainhash.Hash]*ScheduledTx, error)
+
 	// PutAddressHistory stores an address history.
 	PutAddressHistory(blockchain.ScriptHashHex, blockchain.TxHistory) error
 
@@ -28,7 +28,9 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/synchronizer"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
 	"github.com/sirupsen/logrus"
 )
@@ -37,6 +39,9 @@ import (
 type SpendableOutput struct {
 	*wire.TxOut
 	Address string
+	// Confirmed is true if the transaction creating this output is confirmed.
+	Confirmed bool
+	UTXOMetadata
 }
 
 // ScriptHashHex returns the hash of the PkScript of the output, in hex format.
@@ -217,19 +222,42 @@ func (transactions *Transactions) SpendableOutputs() map[wire.OutPoint]*Spendabl
 		if err != nil {
 			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
 		}
+		if transactions.isReplaced(dbTx, txInfo) {
+			continue
+		}
 		confirmed := txInfo.Height > 0
 
 		spent := transactions.isInputSpent(dbTx, outPoint)
 		if !spent && (confirmed || transactions.allInputsOurs(dbTx, txInfo.Tx)) {
+			metadata, err := dbTx.UTXOMetadata(outPoint)
+			if err != nil {
+				transactions.log.WithError(err).Panic("Failed to retrieve utxo metadata")
+			}
 			result[outPoint] = &SpendableOutput{
-				TxOut:   txOut,
-				Address: transactions.outputToAddress(txOut.PkScript),
+				TxOut:        txOut,
+				Address:      transactions.outputToAddress(txOut.PkScript),
+				Confirmed:    confirmed,
+				UTXOMetadata: metadata,
 			}
 		}
 	}
 	return result
 }
 
+// isReplaced returns true if the tx was replaced by another tx, is not confirmed and the replacement
+// was indexed. Until then, the replaced tx is kept, so that its inputs are still spent and its
+// outputs still count towards the balance.
+func (transactions *Transactions) isReplaced(dbTx DBTxInterface, txInfo *DBTxInfo) bool {
+	if txInfo.ReplacedBy == nil || txInfo.Height > 0 {
+		return false
+	}
+	replacement, err := dbTx.TxInfo(*txInfo.ReplacedBy)
+	if err != nil {
+		transactions.log.WithError(err).Panic("Failed to retrieve tx info")
+	}
+	return replacement != nil && replacement.Tx != nil
+}
+
 func (transactions *Transactions) isInputSpent(dbTx DBTxInterface, outPoint wire.OutPoint) bool {
 	input, err := dbTx.Input(outPoint)
 	if err != nil {
@@ -260,6 +288,15 @@ func (transactions *Transactions) removeTxForAddress(
 		// Tx is not touching any of our outputs anymore. Remove.
 
 		for _, txIn := range txInfo.Tx.TxIn {
+			// The input might be spent by a different tx by now, e.g. if this tx was replaced by
+			// a fee bump.
+			spentBy, err := dbTx.Input(txIn.PreviousOutPoint)
+			if err != nil {
+				transactions.log.WithError(err).Panic("Failed to retrieve input")
+			}
+			if spentBy != nil && *spentBy != txHash {
+				continue
+			}
 			transactions.log.Debug("Deleting transaction iput")
 			dbTx.DeleteInput(txIn.PreviousOutPoint)
 		}
@@ -382,6 +419,9 @@ func (transactions *Transactions) Balance() *accounts.Balance {
 		if err != nil {
 			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
 		}
+		if transactions.isReplaced(dbTx, txInfo) {
+			continue
+		}
 		confirmed := txInfo.Height > 0
 		if confirmed || transactions.allInputsOurs(dbTx, txInfo.Tx) {
 			available += txOut.Value
@@ -392,6 +432,69 @@ func (transactions *Transactions) Balance() *accounts.Balance {
 	return accounts.NewBalance(coin.NewAmountFromInt64(available), coin.NewAmountFromInt64(incoming))
 }
 
+// AddressStats holds the usage of an address.
+type AddressStats struct {
+	// Balance is the sum of the unspent outputs on the address, confirmed or not.
+	Balance btcutil.Amount
+	// TxCount is the number of transactions involving the address.
+	TxCount int
+}
+
+// AddressStats returns the balance and the number of transactions of all addresses which appear in
+// a transaction. Transactions which were replaced are ignored.
+func (transactions *Transactions) AddressStats() map[blockchain.ScriptHashHex]*AddressStats {
+	transactions.synchronizer.WaitSynchronized()
+	defer transactions.RLock()()
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		transactions.log.WithError(err).Panic("Failed to begin transaction")
+	}
+	defer dbTx.Rollback()
+	result := map[blockchain.ScriptHashHex]*AddressStats{}
+	get := func(scriptHashHex blockchain.ScriptHashHex) *AddressStats {
+		stats, ok := result[scriptHashHex]
+		if !ok {
+			stats = &AddressStats{}
+			result[scriptHashHex] = stats
+		}
+		return stats
+	}
+	txHashes, err := dbTx.Transactions()
+	if err != nil {
+		transactions.log.WithError(err).Panic("Failed to retrieve transactions")
+	}
+	for _, txHash := range txHashes {
+		txInfo, err := dbTx.TxInfo(txHash)
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
+		}
+		if transactions.isReplaced(dbTx, txInfo) {
+			continue
+		}
+		for scriptHashHex := range txInfo.Addresses {
+			get(blockchain.ScriptHashHex(scriptHashHex)).TxCount++
+		}
+	}
+	outputs, err := dbTx.Outputs()
+	if err != nil {
+		transactions.log.WithError(err).Panic("Failed to retrieve outputs")
+	}
+	for outPoint, txOut := range outputs {
+		if transactions.isInputSpent(dbTx, outPoint) {
+			continue
+		}
+		txInfo, err := dbTx.TxInfo(outPoint.Hash)
+		if err != nil {
+			transactions.log.WithError(err).Panic("Failed to retrieve tx info")
+		}
+		if transactions.isReplaced(dbTx, txInfo) {
+			continue
+		}
+		get(getScriptHashHex(txOut)).Balance += btcutil.Amount(txOut.Value)
+	}
+	return result
+}
+
 // byHeight defines the methods needed to satisify sort.Interface to sort transactions by their
 // height. Special case for unconfirmed transactions (height <=0), which come last. If the height
 // is the same for two txs, they are sorted by the created (first seen) time instead.
@@ -414,6 +517,13 @@ func (s byHeight) Less(i, j int) bool {
 func (s byHeight) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
 func (transactions *Transactions) outputToAddress(pkScript []byte) string {
+	if taproot.IsPayToTaproot(pkScript) {
+		address, err := taproot.ExtractAddress(pkScript, transactions.net)
+		if err != nil {
+			return "<unknown address>"
+		}
+		return address.String()
+	}
 	_, extractedAddresses, _, err := txscript.ExtractPkScriptAddrs(pkScript, transactions.net)
 	// unknown addresses and multisig scripts ignored.
 	if err != nil || len(extractedAddresses) != 1 {
@@ -568,8 +678,144 @@ func (transactions *Transactions) Transactions(
 			// TODO
 			panic(err)
 		}
+		if transactions.isReplaced(dbTx, txInfo) {
+			// Only the replacement is shown.
+			continue
+		}
 		txs = append(txs, transactions.txInfo(dbTx, txInfo, isChange))
 	}
 	sort.Sort(sort.Reverse(byHeight(txs)))
 	return txs
 }
+
+// UnconfirmedOutgoingTx returns an unconfirmed transaction which spends only our own outputs, along
+// with the outputs it spends. An error is returned if the tx is unknown, confirmed, not sent by us
+// or already replaced.
+func (transactions *Transactions) UnconfirmedOutgoingTx(txHash chainhash.Hash) (
+	*wire.MsgTx, map[wire.OutPoint]*SpendableOutput, error) {
+	transactions.synchronizer.WaitSynchronized()
+	defer transactions.RLock()()
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dbTx.Rollback()
+	txInfo, err := dbTx.TxInfo(txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if txInfo.Tx == nil {
+		return nil, nil, errp.New("Unknown transaction")
+	}
+	if txInfo.Height > 0 {
+		return nil, nil, errp.New("The transaction is already confirmed")
+	}
+	if txInfo.ReplacedBy != nil {
+		return nil, nil, errp.New("The transaction was already replaced")
+	}
+	spentOutputs := map[wire.OutPoint]*SpendableOutput{}
+	for _, txIn := range txInfo.Tx.TxIn {
+		txOut, err := dbTx.Output(txIn.PreviousOutPoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		if txOut == nil {
+			return nil, nil, errp.New("The transaction spends outputs not belonging to this account")
+		}
+		spentTxInfo, err := dbTx.TxInfo(txIn.PreviousOutPoint.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		spentOutputs[txIn.PreviousOutPoint] = &SpendableOutput{
+			TxOut:     txOut,
+			Address:   transactions.outputToAddress(txOut.PkScript),
+			Confirmed: spentTxInfo.Height > 0,
+		}
+	}
+	return txInfo.Tx, spentOutputs, nil
+}
+
+// UnconfirmedTxOutputs returns the unspent outputs belonging to us which were created by an
+// unconfirmed transaction. Unlike SpendableOutputs(), outputs of incoming transactions are
+// included. An error is returned if the tx is unknown, confirmed or replaced.
+func (transactions *Transactions) UnconfirmedTxOutputs(txHash chainhash.Hash) (
+	map[wire.OutPoint]*SpendableOutput, error) {
+	transactions.synchronizer.WaitSynchronized()
+	defer transactions.RLock()()
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	txInfo, err := dbTx.TxInfo(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if txInfo.Tx == nil {
+		return nil, errp.New("Unknown transaction")
+	}
+	if txInfo.Height > 0 {
+		return nil, errp.New("The transaction is already confirmed")
+	}
+	if transactions.isReplaced(dbTx, txInfo) {
+		return nil, errp.New("The transaction was replaced")
+	}
+	result := map[wire.OutPoint]*SpendableOutput{}
+	for index := range txInfo.Tx.TxOut {
+		outPoint := wire.OutPoint{Hash: txHash, Index: uint32(index)}
+		txOut, err := dbTx.Output(outPoint)
+		if err != nil {
+			return nil, err
+		}
+		if txOut == nil || transactions.isInputSpent(dbTx, outPoint) {
+			continue
+		}
+		metadata, err := dbTx.UTXOMetadata(outPoint)
+		if err != nil {
+			return nil, err
+		}
+		result[outPoint] = &SpendableOutput{
+			TxOut:        txOut,
+			Address:      transactions.outputToAddress(txOut.PkScript),
+			UTXOMetadata: metadata,
+		}
+	}
+	return result, nil
+}
+
+// MarkTxReplaced records that a tx was replaced by a different tx (BIP125). Once the replacement
+// is indexed, the replaced tx is hidden from the transactions list and its outputs are not
+// spendable anymore, unless it confirms after all.
+func (transactions *Transactions) MarkTxReplaced(txHash chainhash.Hash, replacedBy chainhash.Hash) error {
+	defer transactions.Lock()()
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	if err := dbTx.MarkTxReplaced(txHash, replacedBy); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// SetUTXOMetadata stores the metadata of an output of the wallet, e.g. to freeze it.
+func (transactions *Transactions) SetUTXOMetadata(outPoint wire.OutPoint, metadata UTXOMetadata) error {
+	defer transactions.Lock()()
+	dbTx, err := transactions.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	txOut, err := dbTx.Output(outPoint)
+	if err != nil {
+		return err
+	}
+	if txOut == nil {
+		return errp.Newf("Unknown output %s", outPoint)
+	}
+	if err := dbTx.PutUTXOMetadata(outPoint, metadata); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
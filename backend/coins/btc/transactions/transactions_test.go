@@ -173,8 +173,9 @@ func (s *transactionsSuite) TestUpdateAddressHistorySingleTxReceive() {
 		s.transactions.Balance(),
 	)
 	utxo := &transactions.SpendableOutput{
-		TxOut:   wire.NewTxOut(int64(expectedAmount), address.PubkeyScript()),
-		Address: "n4PBA1ARca4UcMBnssfFpkF7LraS58SZ4y",
+		TxOut:     wire.NewTxOut(int64(expectedAmount), address.PubkeyScript()),
+		Address:   "n4PBA1ARca4UcMBnssfFpkF7LraS58SZ4y",
+		Confirmed: true,
 	}
 	require.Equal(s.T(),
 		map[wire.OutPoint]*transactions.SpendableOutput{
@@ -187,6 +188,51 @@ func (s *transactionsSuite) TestUpdateAddressHistorySingleTxReceive() {
 	require.Equal(s.T(), expectedHeight, transactions[0].Height)
 }
 
+func (s *transactionsSuite) TestAddressStats() {
+	addresses := s.addressChain.EnsureAddresses()
+	address1 := addresses[0]
+	address2 := addresses[1]
+	tx1 := newTx(chainhash.HashH(nil), 0, address1, 1000)
+	// Spends tx1, paying 300 to address2.
+	tx2 := newTx(tx1.TxHash(), 0, address2, 300)
+	s.blockchainMock.RegisterTxs(tx1, tx2)
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(tx2.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx2.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(),
+		map[blockchainpkg.ScriptHashHex]*transactions.AddressStats{
+			address1.PubkeyScriptHashHex(): {Balance: 0, TxCount: 2},
+			address2.PubkeyScriptHashHex(): {Balance: 300, TxCount: 1},
+		},
+		s.transactions.AddressStats(),
+	)
+}
+
+func (s *transactionsSuite) TestSetUTXOMetadata() {
+	addresses := s.addressChain.EnsureAddresses()
+	address := addresses[0]
+	tx1 := newTx(chainhash.HashH(nil), 0, address, 123)
+	s.blockchainMock.RegisterTxs(tx1)
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+	})
+	outPoint := wire.OutPoint{Hash: tx1.TxHash(), Index: 0}
+	metadata := transactions.UTXOMetadata{Frozen: true, Label: "dust"}
+	require.NoError(s.T(), s.transactions.SetUTXOMetadata(outPoint, metadata))
+	require.Equal(s.T(), metadata, s.transactions.SpendableOutputs()[outPoint].UTXOMetadata)
+	// Frozen outputs still count towards the balance.
+	require.Equal(s.T(), newBalance(123, 0), s.transactions.Balance())
+
+	require.Error(s.T(), s.transactions.SetUTXOMetadata(
+		wire.OutPoint{Hash: tx1.TxHash(), Index: 1}, metadata))
+}
+
 // TestSpendableOutputs checks that the utxo set is correct. Only confirmed (or unconfirmed outputs
 // we own) outputs can be spent.
 func (s *transactionsSuite) TestSpendableOutputs() {
@@ -358,3 +404,78 @@ func (s *transactionsSuite) TestRemoveTransaction() {
 		s.transactions.Transactions(func(blockchainpkg.ScriptHashHex) bool { return false }),
 		2)
 }
+
+// TestReplaceTransaction checks that a tx replaced by a fee bump is hidden once the replacement is
+// indexed, and that removing it later does not affect the inputs spent by the replacement.
+func (s *transactionsSuite) TestReplaceTransaction() {
+	addresses := s.addressChain.EnsureAddresses()
+	address1 := addresses[0]
+	address2 := addresses[1]
+	otherAddress := addresses[2]
+	isChange := func(blockchainpkg.ScriptHashHex) bool { return false }
+	tx1 := newTx(chainhash.HashH(nil), 0, address1, 1000)
+	// Pays 100 to an external address and 800 change to address2.
+	tx1Spend := newTx(tx1.TxHash(), 0, otherAddress, 100)
+	tx1Spend.TxOut = append(tx1Spend.TxOut, wire.NewTxOut(800, address2.PubkeyScript()))
+	// The replacement pays a higher fee.
+	tx1SpendReplacement := newTx(tx1.TxHash(), 0, otherAddress, 100)
+	tx1SpendReplacement.TxOut = append(tx1SpendReplacement.TxOut,
+		wire.NewTxOut(700, address2.PubkeyScript()))
+	s.blockchainMock.RegisterTxs(tx1, tx1Spend, tx1SpendReplacement)
+	s.headersMock.On("VerifiedHeaderByHeight", 10).Return(nil, nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(tx1Spend.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1Spend.TxHash()), Height: 0},
+	})
+	unconfirmedOutputs, err := s.transactions.UnconfirmedTxOutputs(tx1Spend.TxHash())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), unconfirmedOutputs, 1)
+	require.Contains(s.T(), unconfirmedOutputs, wire.OutPoint{Hash: tx1Spend.TxHash(), Index: 1})
+
+	tx, spentOutputs, err := s.transactions.UnconfirmedOutgoingTx(tx1Spend.TxHash())
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), tx1Spend.TxHash(), tx.TxHash())
+	require.Len(s.T(), spentOutputs, 1)
+	require.True(s.T(), spentOutputs[wire.OutPoint{Hash: tx1.TxHash(), Index: 0}].Confirmed)
+	_, _, err = s.transactions.UnconfirmedOutgoingTx(tx1.TxHash())
+	require.Error(s.T(), err)
+
+	require.NoError(s.T(), s.transactions.MarkTxReplaced(tx1Spend.TxHash(), tx1SpendReplacement.TxHash()))
+	_, _, err = s.transactions.UnconfirmedOutgoingTx(tx1Spend.TxHash())
+	require.Error(s.T(), err)
+	// The original is kept until the replacement is indexed, so the change does not disappear
+	// from the balance in the meantime.
+	require.Equal(s.T(), newBalance(800, 0), s.transactions.Balance())
+	spendableOutputs := s.transactions.SpendableOutputs()
+	require.Len(s.T(), spendableOutputs, 1)
+	require.Contains(s.T(), spendableOutputs, wire.OutPoint{Hash: tx1Spend.TxHash(), Index: 1})
+	require.Len(s.T(), s.transactions.Transactions(isChange), 2)
+
+	// The replacement is indexed before the server drops the original.
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1Spend.TxHash()), Height: 0},
+		{TXHash: blockchainpkg.TXHash(tx1SpendReplacement.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(), newBalance(700, 0), s.transactions.Balance())
+	require.Len(s.T(), s.transactions.Transactions(isChange), 2)
+
+	// The server picks up the replacement and drops the original.
+	tx1SpendHash := tx1Spend.TxHash()
+	s.notifierMock.On("Delete", tx1SpendHash[:]).Return(nil).Once()
+	s.updateAddressHistory(address1, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1.TxHash()), Height: 10},
+		{TXHash: blockchainpkg.TXHash(tx1SpendReplacement.TxHash()), Height: 0},
+	})
+	s.updateAddressHistory(address2, []*blockchainpkg.TxInfo{
+		{TXHash: blockchainpkg.TXHash(tx1SpendReplacement.TxHash()), Height: 0},
+	})
+	require.Equal(s.T(), newBalance(700, 0), s.transactions.Balance())
+	require.Len(s.T(), s.transactions.Transactions(isChange), 2)
+	spendableOutputs = s.transactions.SpendableOutputs()
+	require.Len(s.T(), spendableOutputs, 1)
+	require.Contains(s.T(), spendableOutputs,
+		wire.OutPoint{Hash: tx1SpendReplacement.TxHash(), Index: 1})
+}
@@ -68,6 +68,10 @@ type Account struct {
 	balance     coin.Amount
 	blockNumber *big.Int
 
+	// feeTargets are sorted by ascending priority. Empty until the fees could be estimated.
+	feeTargets     []*FeeTarget
+	feeTargetsLock locker.Locker
+
 	// if not nil, SendTx() will sign and send this transaction. Set by TxProposal().
 	activeTxProposal     *TxProposal
 	activeTxProposalLock locker.Locker
@@ -113,6 +117,34 @@ func (account *Account) Info() *accounts.Info {
 	}
 }
 
+// firstAccountSigningConfiguration derives the signing configuration of the first address, m/0,
+// from the account's only signing configuration.
+func firstAccountSigningConfiguration(
+	signingConfigurations signing.Configurations) (*signing.Configuration, error) {
+	if len(signingConfigurations) != 1 {
+		return nil, errp.New("Ethereum only supports one signing config")
+	}
+	relKeyPath, err := signing.NewRelativeKeypath("0")
+	if err != nil {
+		return nil, err
+	}
+	return signingConfigurations[0].Derive(relKeyPath)
+}
+
+func accountIdentifier(code string, signingConfiguration *signing.Configuration) string {
+	return fmt.Sprintf("account-%s-%s", signingConfiguration.Hash(), code)
+}
+
+// AccountIdentifier returns the identifier of the account with the given code and signing
+// configurations. The account's database and notes files are named after it.
+func AccountIdentifier(code string, signingConfigurations signing.Configurations) (string, error) {
+	signingConfiguration, err := firstAccountSigningConfiguration(signingConfigurations)
+	if err != nil {
+		return "", err
+	}
+	return accountIdentifier(code, signingConfiguration), nil
+}
+
 // FilesFolder implements accounts.Interface.
 func (account *Account) FilesFolder() string {
 	if account.dbSubfolder == "" {
@@ -135,24 +167,14 @@ func (account *Account) Initialize() error {
 		return err
 	}
 
-	if len(signingConfigurations) != 1 {
-		return errp.New("Ethereum only supports one signing config")
-	}
-	signingConfiguration := signingConfigurations[0]
-
-	// Derive m/0, first account.
-	relKeyPath, err := signing.NewRelativeKeypath("0")
-	if err != nil {
-		return err
-	}
-	signingConfiguration, err = signingConfiguration.Derive(relKeyPath)
+	signingConfiguration, err := firstAccountSigningConfiguration(signingConfigurations)
 	if err != nil {
 		return err
 	}
 	account.signingConfiguration = signingConfiguration
 	account.notifier = account.Config().GetNotifier(signingConfigurations)
 
-	accountIdentifier := fmt.Sprintf("account-%s-%s", account.signingConfiguration.Hash(), account.Config().Code)
+	accountIdentifier := accountIdentifier(account.Config().Code, account.signingConfiguration)
 	account.dbSubfolder = path.Join(account.Config().DBFolder, accountIdentifier)
 	if err := os.MkdirAll(account.dbSubfolder, 0700); err != nil {
 		return errp.WithStack(err)
@@ -253,6 +275,7 @@ func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 		if tx.Height == 0 || (tipHeight-remoteTx.BlockNumber) < ethtypes.NumConfirmationsComplete || tx.Success != success {
 			tx.Height = remoteTx.BlockNumber
 			tx.GasUsed = remoteTx.GasUsed
+			tx.EffectiveGasPrice = remoteTx.EffectiveGasPrice
 			tx.Success = success
 			if err := dbTx.PutOutgoingTransaction(tx); err != nil {
 				account.log.WithError(err).Error("could not update outgoing tx")
@@ -260,12 +283,39 @@ func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 			}
 		}
 	}
+	// Once a transaction is mined, the pending transactions with the same nonce, i.e. the replaced
+	// transaction or its replacements, can't be mined anymore.
+	for _, tx := range droppedOutgoingTransactions(outgoingTransactions) {
+		account.log.Infof("dropping pending outgoing tx with used nonce: %d", tx.Transaction.Nonce())
+		if err := dbTx.DeleteOutgoingTransaction(tx.Transaction.Hash()); err != nil {
+			account.log.WithError(err).Error("could not delete outgoing tx")
+		}
+	}
 	if err := dbTx.Commit(); err != nil {
 		account.log.WithError(err).Error("could not commit db tx")
 		return
 	}
 }
 
+// droppedOutgoingTransactions returns the pending transactions for which another transaction with
+// the same nonce was mined.
+func droppedOutgoingTransactions(
+	outgoingTransactions []*ethtypes.TransactionWithMetadata) []*ethtypes.TransactionWithMetadata {
+	usedNonces := map[uint64]struct{}{}
+	for _, tx := range outgoingTransactions {
+		if tx.Height != 0 {
+			usedNonces[tx.Transaction.Nonce()] = struct{}{}
+		}
+	}
+	dropped := []*ethtypes.TransactionWithMetadata{}
+	for _, tx := range outgoingTransactions {
+		if _, ok := usedNonces[tx.Transaction.Nonce()]; ok && tx.Height == 0 {
+			dropped = append(dropped, tx)
+		}
+	}
+	return dropped
+}
+
 // outgoingTransactions gets all locally stored outgoing transactions. It filters out the ones also
 // present from the transactions source.
 func (account *Account) outgoingTransactions(allTxs []*accounts.TransactionData) (
@@ -313,9 +363,15 @@ func (account *Account) update() error {
 	var confirmedTansactions []*accounts.TransactionData
 	if transactionsSource != nil {
 		var err error
-		confirmedTansactions, err = transactionsSource.Transactions(
-			account.blockNumber,
-			account.address.Address, account.blockNumber, account.coin.erc20Token)
+		if dbTransactionsSource, ok := transactionsSource.(DBTransactionsSource); ok {
+			confirmedTansactions, err = dbTransactionsSource.TransactionsWithDB(
+				account.db, account.Config().BirthHeight, account.blockNumber,
+				account.address.Address, account.blockNumber, account.coin.erc20Token)
+		} else {
+			confirmedTansactions, err = transactionsSource.Transactions(
+				account.blockNumber,
+				account.address.Address, account.blockNumber, account.coin.erc20Token)
+		}
 		if err != nil {
 			return err
 		}
@@ -378,6 +434,8 @@ func (account *Account) update() error {
 		account.balance = coin.NewAmount(balance)
 	}
 
+	// Fee estimates change with each block.
+	account.updateFeeTargets()
 	return nil
 }
 
@@ -422,8 +480,15 @@ func (account *Account) Balance() (*accounts.Balance, error) {
 // TxProposal holds all info needed to create and sign a transacstion.
 type TxProposal struct {
 	Coin coin.Coin
-	Tx   *types.Transaction
-	Fee  *big.Int
+	// Tx is a legacy transaction (*types.Transaction) or an EIP-1559 transaction
+	// (*ethtypes.DynamicFeeTx).
+	Tx ethtypes.Transaction
+	// Fee is the expected fee. For EIP-1559 transactions, it is based on the base fee of the next
+	// block and can be lower than MaxFee.
+	Fee *big.Int
+	// MaxFee is the highest fee the transaction can pay, the gas limit times the gas price or the
+	// max fee per gas.
+	MaxFee *big.Int
 	// Value can be the same as Tx.Value(), but in case of e.g. ERC20, tx.Value() is zero, while the
 	// Token value is encoded in the contract input data.
 	Value *big.Int
@@ -433,19 +498,40 @@ type TxProposal struct {
 	Keypath signing.AbsoluteKeypath
 }
 
+// useEIP1559 returns true if the transactions of the account are EIP-1559 transactions. This needs
+// the London hard fork to be active and all keystores to be able to sign them.
+func (account *Account) useEIP1559() bool {
+	return isLondon(account.coin.Net(), account.blockNumber) &&
+		account.Config().Keystores != nil &&
+		account.Config().Keystores.SupportsEIP1559(account.coin)
+}
+
 func (account *Account) newTx(
 	recipientAddress string,
 	amount coin.SendAmount,
 	data []byte,
+	feeTargetCode accounts.FeeTargetCode,
 ) (*TxProposal, error) {
 	if !ethcommon.IsHexAddress(recipientAddress) {
 		return nil, errp.WithStack(errors.ErrInvalidAddress)
 	}
 
-	suggestedGasPrice, err := account.coin.client.SuggestGasPrice(context.TODO())
+	feeTarget, err := account.feeTarget(feeTargetCode)
 	if err != nil {
 		return nil, err
 	}
+	// If the fees could not be estimated, a legacy transaction is created with the gas price
+	// suggested by the node.
+	useEIP1559 := feeTarget != nil && account.useEIP1559()
+	var gasPrice *big.Int
+	if feeTarget != nil {
+		gasPrice = feeTarget.gasPrice()
+	} else {
+		gasPrice, err = account.coin.client.SuggestGasPrice(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var value *big.Int
 	if amount.SendAll() {
@@ -510,7 +596,11 @@ func (account *Account) newTx(
 		return nil, errp.WithStack(errors.TxValidationError(err.Error()))
 	}
 
-	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), suggestedGasPrice)
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasPrice)
+	maxFee := fee
+	if useEIP1559 {
+		maxFee = new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), feeTarget.gasFeeCap)
+	}
 
 	// Adjust amount with fee
 	if account.coin.erc20Token != nil {
@@ -521,28 +611,37 @@ func (account *Account) newTx(
 			return nil, errp.WithStack(errors.ErrInsufficientFunds)
 		}
 	} else {
+		// The balance needs to cover the max fee, as the base fee can rise until the tx is mined.
 		if amount.SendAll() {
 			// Set the value correctly and check that the fee is smaller than or equal to the balance.
-			value = new(big.Int).Sub(account.balance.BigInt(), fee)
+			value = new(big.Int).Sub(account.balance.BigInt(), maxFee)
 			message.Value = value
 			if message.Value.Sign() < 0 {
 				return nil, errp.WithStack(errors.ErrInsufficientFunds)
 			}
 		} else {
 			// Check that the entered value and the estimated fee are not greater than the balance.
-			total := new(big.Int).Add(message.Value, fee)
+			total := new(big.Int).Add(message.Value, maxFee)
 			if total.Cmp(account.balance.BigInt()) == 1 {
 				return nil, errp.WithStack(errors.ErrInsufficientFunds)
 			}
 		}
 	}
-	tx := types.NewTransaction(account.nextNonce,
-		*message.To,
-		message.Value, gasLimit, suggestedGasPrice, message.Data)
+	var tx ethtypes.Transaction
+	if useEIP1559 {
+		tx = ethtypes.NewDynamicFeeTx(account.coin.Net().ChainID, account.nextNonce,
+			*message.To,
+			message.Value, gasLimit, feeTarget.gasTipCap, feeTarget.gasFeeCap, message.Data)
+	} else {
+		tx = types.NewTransaction(account.nextNonce,
+			*message.To,
+			message.Value, gasLimit, gasPrice, message.Data)
+	}
 	return &TxProposal{
 		Coin:    account.coin,
 		Tx:      tx,
 		Fee:     fee,
+		MaxFee:  maxFee,
 		Value:   value,
 		Signer:  types.MakeSigner(account.coin.Net(), account.blockNumber),
 		Keypath: account.signingConfiguration.AbsoluteKeypath(),
@@ -550,7 +649,7 @@ func (account *Account) newTx(
 }
 
 // storePendingOutgoingTransaction puts an outgoing tx into the db with height 0 (pending).
-func (account *Account) storePendingOutgoingTransaction(transaction *types.Transaction) error {
+func (account *Account) storePendingOutgoingTransaction(transaction ethtypes.Transaction) error {
 	dbTx, err := account.db.Begin()
 	if err != nil {
 		return err
@@ -585,7 +684,11 @@ func (account *Account) SendTx() error {
 	if err := account.Config().Keystores.SignTransaction(txProposal); err != nil {
 		return err
 	}
-	if err := account.coin.client.SendTransaction(context.TODO(), txProposal.Tx); err != nil {
+	rawTx, err := ethtypes.EncodeTransaction(txProposal.Tx)
+	if err != nil {
+		return err
+	}
+	if err := account.coin.client.SendRawTransaction(context.TODO(), rawTx); err != nil {
 		return errp.WithStack(err)
 	}
 	if err := account.storePendingOutgoingTransaction(txProposal.Tx); err != nil {
@@ -599,17 +702,19 @@ func (account *Account) SendTx() error {
 	return nil
 }
 
-// FeeTargets implements accounts.Interface.
-func (account *Account) FeeTargets() ([]accounts.FeeTarget, accounts.FeeTargetCode) {
-	return nil, ""
-}
-
 // TxProposal implements accounts.Interface.
 func (account *Account) TxProposal(
 	args *accounts.TxProposalArgs,
 ) (coin.Amount, coin.Amount, coin.Amount, error) {
 	defer account.activeTxProposalLock.Lock()()
-	txProposal, err := account.newTx(args.RecipientAddress, args.Amount, args.Data)
+	if len(args.Recipients) != 0 {
+		return coin.Amount{}, coin.Amount{}, coin.Amount{}, errp.New(
+			"Sending to multiple recipients is not supported")
+	}
+	if args.LockTime != 0 {
+		return coin.Amount{}, coin.Amount{}, coin.Amount{}, errp.New("Locktimes are not supported")
+	}
+	txProposal, err := account.newTx(args.RecipientAddress, args.Amount, args.Data, args.FeeTargetCode)
 	if err != nil {
 		return coin.Amount{}, coin.Amount{}, coin.Amount{}, err
 	}
@@ -624,6 +729,16 @@ func (account *Account) TxProposal(
 	return coin.NewAmount(txProposal.Value), coin.NewAmount(txProposal.Fee), coin.NewAmount(total), nil
 }
 
+// ActiveTxProposalMaxFee returns the max fee of the transaction created by the last successful call
+// to TxProposal(). For EIP-1559 transactions, it can be higher than the expected fee.
+func (account *Account) ActiveTxProposalMaxFee() (coin.Amount, error) {
+	defer account.activeTxProposalLock.RLock()()
+	if account.activeTxProposal == nil {
+		return coin.Amount{}, errp.New("No active tx proposal")
+	}
+	return coin.NewAmount(account.activeTxProposal.MaxFee), nil
+}
+
 // GetUnusedReceiveAddresses implements accounts.Interface.
 func (account *Account) GetUnusedReceiveAddresses() []accounts.AddressList {
 	return []accounts.AddressList{
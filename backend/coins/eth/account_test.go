@@ -0,0 +1,269 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	rpcclientMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const gwei = 1000000000
+
+// testClient mocks the node of an account without any confirmed transactions. Broadcast
+// transactions are recorded.
+type testClient struct {
+	rpcclientMocks.InterfaceMock
+
+	mu      sync.Mutex
+	balance *big.Int
+	sent    []ethtypes.Transaction
+}
+
+func newTestClient() *testClient {
+	client := &testClient{balance: big.NewInt(1e18)}
+	// After the London hard fork on Ropsten.
+	client.HeaderByNumberFunc = func(context.Context, *big.Int) (*types.Header, error) {
+		return &types.Header{Number: big.NewInt(11000000)}, nil
+	}
+	client.BalanceAtFunc = func(context.Context, common.Address, *big.Int) (*big.Int, error) {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return new(big.Int).Set(client.balance), nil
+	}
+	client.PendingNonceAtFunc = func(context.Context, common.Address) (uint64, error) {
+		return 5, nil
+	}
+	client.TransactionReceiptWithBlockNumberFunc = func(
+		context.Context, common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+		return nil, nil
+	}
+	// Base fee of 10 gwei, priority fees of 1, 2 and 3 gwei for the low, normal and high targets.
+	client.FeeHistoryFunc = func(context.Context, uint64, []float64) (*rpcclient.FeeHistory, error) {
+		return &rpcclient.FeeHistory{
+			OldestBlock:   big.NewInt(10999999),
+			BaseFeePerGas: []*big.Int{big.NewInt(10 * gwei), big.NewInt(10 * gwei)},
+			GasUsedRatio:  []float64{0.5},
+			Reward:        [][]*big.Int{{big.NewInt(1 * gwei), big.NewInt(2 * gwei), big.NewInt(3 * gwei)}},
+		}, nil
+	}
+	client.EstimateGasFunc = func(context.Context, ethereum.CallMsg) (uint64, error) {
+		return params.TxGas, nil
+	}
+	client.SendRawTransactionFunc = func(ctx context.Context, rawTx []byte) error {
+		tx, err := ethtypes.DecodeTransaction(rawTx)
+		if err != nil {
+			return err
+		}
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		client.sent = append(client.sent, tx)
+		return nil
+	}
+	return client
+}
+
+func (client *testClient) setBalance(balance *big.Int) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.balance = balance
+}
+
+func (client *testClient) lastSent(t *testing.T) *ethtypes.DynamicFeeTx {
+	t.Helper()
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	require.NotEmpty(t, client.sent)
+	tx, ok := client.sent[len(client.sent)-1].(*ethtypes.DynamicFeeTx)
+	require.True(t, ok)
+	return tx
+}
+
+func (client *testClient) numSent() int {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return len(client.sent)
+}
+
+// newTestAccount creates an initialized Ropsten account of a software keystore, which signs
+// EIP-1559 transactions. The address of the account is returned as well.
+func newTestAccount(t *testing.T, client rpcclient.Interface) (*eth.Account, common.Address, func()) {
+	t.Helper()
+	dbFolder := test.TstTempDir("eth-dbfolder")
+
+	master, err := hdkeychain.NewMaster(
+		make([]byte, hdkeychain.RecommendedSeedLen), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	softwareKeystore := software.NewKeystore(0, master)
+
+	ethCoin := eth.NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "", nil, "", nil,
+		socksproxy.NewSocksProxy(false, ""))
+	ethCoin.TstSetClient(client)
+
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/1'/0'/0")
+	require.NoError(t, err)
+	xpub, err := softwareKeystore.ExtendedPublicKey(ethCoin, keypath)
+	require.NoError(t, err)
+	xprv, err := keypath.Child(0, false).Derive(master)
+	require.NoError(t, err)
+	privateKey, err := xprv.ECPrivKey()
+	require.NoError(t, err)
+
+	notifier := &accountsMocks.Notifier{}
+	notifier.On("Put", mock.Anything).Return(nil)
+	account := eth.NewAccount(
+		&accounts.AccountConfig{
+			Code:        "teth",
+			Name:        "Ethereum Ropsten",
+			DBFolder:    dbFolder,
+			NotesFolder: dbFolder,
+			Keystores:   keystore.NewKeystores(softwareKeystore),
+			OnEvent:     func(accounts.Event) {},
+			GetSigningConfigurations: func() (signing.Configurations, error) {
+				return signing.Configurations{
+					signing.NewSinglesigConfiguration(signing.ScriptTypeP2PKH, keypath, xpub),
+				}, nil
+			},
+			GetNotifier: func(signing.Configurations) accounts.Notifier { return notifier },
+		},
+		ethCoin,
+		logging.Get().WithGroup("account_test"),
+	)
+	require.NoError(t, account.Initialize())
+	require.Eventually(t, func() bool {
+		feeTargets, _ := account.FeeTargets()
+		return len(feeTargets) != 0
+	}, 10*time.Second, 10*time.Millisecond)
+	return account, crypto.PubkeyToAddress(privateKey.PublicKey), func() {
+		account.Close()
+		_ = os.RemoveAll(dbFolder)
+	}
+}
+
+// pendingTransactions waits until the account lists the given number of transactions.
+func pendingTransactions(t *testing.T, account *eth.Account, count int) []*accounts.TransactionData {
+	t.Helper()
+	var transactions []*accounts.TransactionData
+	require.Eventually(t, func() bool {
+		var err error
+		transactions, err = account.Transactions()
+		require.NoError(t, err)
+		return len(transactions) == count
+	}, 10*time.Second, 10*time.Millisecond)
+	return transactions
+}
+
+func TestReplaceTx(t *testing.T) {
+	client := newTestClient()
+	account, address, cleanup := newTestAccount(t, client)
+	defer cleanup()
+
+	recipient := common.HexToAddress("0xb94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	_, _, _, err := account.TxProposal(&accounts.TxProposalArgs{
+		RecipientAddress: recipient.Hex(),
+		Amount:           coin.NewSendAmount("0.1"),
+		FeeTargetCode:    accounts.FeeTargetCodeNormal,
+	})
+	require.NoError(t, err)
+	require.NoError(t, account.SendTx())
+	original := client.lastSent(t)
+	require.Equal(t, uint64(5), original.Nonce())
+	require.Equal(t, big.NewInt(2*gwei), original.GasTipCap())
+	require.Equal(t, big.NewInt(22*gwei), original.GasFeeCap())
+	pendingTransactions(t, account, 1)
+
+	t.Run("speedup", func(t *testing.T) {
+		txID, err := account.SpeedUpTx(original.Hash().Hex(), accounts.FeeTargetCodeNormal)
+		require.NoError(t, err)
+		speedUp := client.lastSent(t)
+		require.Equal(t, speedUp.Hash().Hex(), txID)
+		require.Equal(t, original.Nonce(), speedUp.Nonce())
+		require.Equal(t, recipient, *speedUp.To())
+		require.Equal(t, original.Value(), speedUp.Value())
+		require.Equal(t, original.Gas(), speedUp.Gas())
+		// At least 10% more than the original.
+		require.Equal(t, big.NewInt(2200000000), speedUp.GasTipCap())
+		require.Equal(t, big.NewInt(24200000000), speedUp.GasFeeCap())
+
+		// Both are pending until one of them is mined.
+		transactions := pendingTransactions(t, account, 2)
+		replacesTxID := map[string]string{}
+		for _, transaction := range transactions {
+			replacesTxID[transaction.TxID] = transaction.ReplacesTxID
+		}
+		require.Equal(t, map[string]string{
+			original.Hash().Hex(): "",
+			txID:                  original.Hash().Hex(),
+		}, replacesTxID)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		txID, err := account.CancelTx(original.Hash().Hex(), accounts.FeeTargetCodeNormal)
+		require.NoError(t, err)
+		cancel := client.lastSent(t)
+		require.Equal(t, cancel.Hash().Hex(), txID)
+		require.Equal(t, original.Nonce(), cancel.Nonce())
+		require.Equal(t, address, *cancel.To())
+		require.Zero(t, cancel.Value().Sign())
+		require.Empty(t, cancel.Data())
+		require.Equal(t, params.TxGas, cancel.Gas())
+		// At least 10% more than the speed up, the highest pending transaction with this nonce.
+		require.Equal(t, big.NewInt(2420000000), cancel.GasTipCap())
+		require.Equal(t, big.NewInt(26620000000), cancel.GasFeeCap())
+		pendingTransactions(t, account, 3)
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		numSent := client.numSent()
+		// Less than 21000 gas times the bumped max fee.
+		client.setBalance(big.NewInt(21000 * 26 * gwei))
+		_, err := account.CancelTx(original.Hash().Hex(), accounts.FeeTargetCodeNormal)
+		require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+		require.Equal(t, numSent, client.numSent())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := account.SpeedUpTx(common.Hash{}.Hex(), accounts.FeeTargetCodeNormal)
+		require.Error(t, err)
+	})
+}
@@ -16,18 +16,26 @@
 package eth
 
 import (
+	"bytes"
+	"context"
 	"math/big"
 	"strings"
 	"sync"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/db"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/nodesource"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/sirupsen/logrus"
@@ -42,16 +50,39 @@ type TransactionsSource interface {
 		[]*accounts.TransactionData, error)
 }
 
-// TransactionsSourceMaker creates a transaction source.
-type TransactionsSourceMaker func() TransactionsSource
+// DBTransactionsSource is a TransactionsSource which stores the fetched transactions in the
+// account database, so that they don't have to be fetched again in every update. Blocks before
+// birthHeight are skipped.
+type DBTransactionsSource interface {
+	TransactionsSource
+	TransactionsWithDB(
+		accountDB db.Interface,
+		birthHeight uint64,
+		blockTipHeight *big.Int,
+		address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+		[]*accounts.TransactionData, error)
+}
+
+// TransactionsSourceMaker creates a transaction source. client is the connection to the
+// configured node.
+type TransactionsSourceMaker func(client rpcclient.Interface) TransactionsSource
 
 // TransactionsSourceEtherScan creates a etherscan transactions source maker.
 func TransactionsSourceEtherScan(etherScanURL string, socksProxy socksproxy.SocksProxy) TransactionsSourceMaker {
-	return func() TransactionsSource { return etherscan.NewEtherScan(etherScanURL, socksProxy) }
+	return func(rpcclient.Interface) TransactionsSource {
+		return etherscan.NewEtherScan(etherScanURL, socksProxy)
+	}
+}
+
+// TransactionsSourceNode is used to fetch the transactions from the configured node. Ether
+// transfers are found using trace_filter if supported, otherwise by scanning the blocks since the
+// birth height of the account.
+var TransactionsSourceNode TransactionsSourceMaker = func(client rpcclient.Interface) TransactionsSource {
+	return nodesource.NewTransactionsSource(client)
 }
 
 // TransactionsSourceNone is used if no transactions source should be used.
-var TransactionsSourceNone TransactionsSourceMaker = func() TransactionsSource { return nil }
+var TransactionsSourceNone TransactionsSourceMaker = func(rpcclient.Interface) TransactionsSource { return nil }
 
 // Coin models an Ethereum coin.
 type Coin struct {
@@ -74,7 +105,8 @@ type Coin struct {
 }
 
 // NewCoin creates a new coin with the given parameters.
-// makeTransactionsSource: provide `TransactionsSourceNone` or `TransactionsSourceEtherScan()`.
+// makeTransactionsSource: provide `TransactionsSourceNone`, `TransactionsSourceNode` or
+// `TransactionsSourceEtherScan()`.
 // For erc20 tokens, provide erc20Token using NewERC20Token() (otherwise keep nil).
 func NewCoin(
 	code coin.Code,
@@ -126,10 +158,99 @@ func (coin *Coin) Initialize() {
 			coin.client = client
 		}
 
-		coin.transactionsSource = coin.makeTransactionsSource()
+		coin.transactionsSource = coin.makeTransactionsSource(coin.client)
 	})
 }
 
+// BlockNumber returns the number of the latest block.
+func (coin *Coin) BlockNumber() (*big.Int, error) {
+	coin.Initialize()
+	header, err := coin.client.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return header.Number, nil
+}
+
+// feeHistory returns the fee history of the recent blocks. If the node does not support
+// eth_feeHistory, the fees are estimated using EtherScan if it is the transactions source.
+func (coin *Coin) feeHistory(blockCount uint64, rewardPercentiles []float64) (
+	*rpcclient.FeeHistory, error) {
+	feeHistory, err := coin.client.FeeHistory(context.TODO(), blockCount, rewardPercentiles)
+	if err == nil {
+		return feeHistory, nil
+	}
+	etherScan, ok := coin.transactionsSource.(*etherscan.EtherScan)
+	if !ok || etherScan == coin.client {
+		return nil, err
+	}
+	coin.log.WithError(err).Info("Fee history not available from the node, falling back to EtherScan")
+	return etherScan.FeeHistory(context.TODO(), blockCount, rewardPercentiles)
+}
+
+// ERC20TokenInfo contains the metadata of an erc20 token, as reported by its contract.
+type ERC20TokenInfo struct {
+	Name     string
+	Symbol   string
+	Decimals uint
+}
+
+// FetchERC20TokenInfo queries the name, symbol and decimals of the erc20 token deployed at the
+// given contract address. The optional name() and symbol() functions must be implemented by the
+// contract.
+func (coin *Coin) FetchERC20TokenInfo(contractAddress common.Address) (*ERC20TokenInfo, error) {
+	coin.Initialize()
+	caller, err := erc20.NewIERC20Caller(contractAddress, coin.client)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	opts := &bind.CallOpts{Context: context.TODO()}
+	decimals, err := caller.Decimals(opts)
+	if err != nil {
+		return nil, errp.WithMessage(err, "could not fetch the token decimals")
+	}
+	symbol, err := coin.erc20TokenString(contractAddress, "symbol")
+	if err != nil {
+		return nil, errp.WithMessage(err, "could not fetch the token symbol")
+	}
+	name, err := coin.erc20TokenString(contractAddress, "name")
+	if err != nil {
+		return nil, errp.WithMessage(err, "could not fetch the token name")
+	}
+	return &ERC20TokenInfo{
+		Name:     strings.TrimSpace(name),
+		Symbol:   strings.TrimSpace(symbol),
+		Decimals: uint(decimals),
+	}, nil
+}
+
+// erc20TokenString calls the given method of the erc20 token contract, which returns a string.
+// Some early tokens, e.g. MKR, return a bytes32 padded with zeros instead.
+func (coin *Coin) erc20TokenString(contractAddress common.Address, method string) (string, error) {
+	parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
+	if err != nil {
+		panic(errp.WithStack(err))
+	}
+	input, err := parsed.Pack(method)
+	if err != nil {
+		panic(errp.WithStack(err))
+	}
+	output, err := coin.client.CallContract(
+		context.TODO(), ethereum.CallMsg{To: &contractAddress, Data: input}, nil)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	// An encoded string is at least 64 bytes long: the offset and the length of its content.
+	if len(output) == 32 {
+		return string(bytes.TrimRight(output, "\x00")), nil
+	}
+	var result string
+	if err := parsed.Unpack(&result, method, output); err != nil {
+		return "", errp.WithStack(err)
+	}
+	return result, nil
+}
+
 // Code implements coin.Coin.
 func (coin *Coin) Code() coin.Code {
 	return coin.code
@@ -0,0 +1,24 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+
+// TstSetClient makes the coin use the given client instead of connecting to its node in
+// Initialize().
+func (coin *Coin) TstSetClient(client rpcclient.Interface) {
+	coin.initOnce.Do(func() {})
+	coin.client = client
+}
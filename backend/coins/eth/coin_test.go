@@ -0,0 +1,111 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth_test
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	rpcclientMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// abiString returns the ABI encoding of a string return value.
+func abiString(value string) []byte {
+	encoded := common.LeftPadBytes(big.NewInt(32).Bytes(), 32)
+	encoded = append(encoded, common.LeftPadBytes(big.NewInt(int64(len(value))).Bytes(), 32)...)
+	return append(encoded, common.RightPadBytes([]byte(value), (len(value)+31)/32*32)...)
+}
+
+func TestFetchERC20TokenInfo(t *testing.T) {
+	contractAddress := common.HexToAddress("0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2")
+	tests := []struct {
+		name           string
+		nameOutput     []byte
+		symbolOutput   []byte
+		expectedName   string
+		expectedSymbol string
+	}{
+		{
+			name:           "string",
+			nameOutput:     abiString(" Some Token "),
+			symbolOutput:   abiString("SOME"),
+			expectedName:   "Some Token",
+			expectedSymbol: "SOME",
+		},
+		{
+			// E.g. MKR.
+			name:           "bytes32",
+			nameOutput:     common.RightPadBytes([]byte("Maker"), 32),
+			symbolOutput:   common.RightPadBytes([]byte("MKR"), 32),
+			expectedName:   "Maker",
+			expectedSymbol: "MKR",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			client := &rpcclientMocks.InterfaceMock{
+				CallContractFunc: func(
+					ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+					require.Equal(t, contractAddress, *call.To)
+					switch hex.EncodeToString(call.Data) {
+					case "313ce567": // decimals()
+						return common.LeftPadBytes([]byte{18}, 32), nil
+					case "06fdde03": // name()
+						return test.nameOutput, nil
+					case "95d89b41": // symbol()
+						return test.symbolOutput, nil
+					default:
+						return nil, ethereum.NotFound
+					}
+				},
+			}
+			ethCoin := eth.NewCoin("eth", "ETH", "ETH", params.MainnetChainConfig, "", nil, "", nil,
+				socksproxy.NewSocksProxy(false, ""))
+			ethCoin.TstSetClient(client)
+			info, err := ethCoin.FetchERC20TokenInfo(contractAddress)
+			require.NoError(t, err)
+			require.Equal(t, &eth.ERC20TokenInfo{
+				Name:     test.expectedName,
+				Symbol:   test.expectedSymbol,
+				Decimals: 18,
+			}, info)
+		})
+	}
+}
+
+func TestBlockNumber(t *testing.T) {
+	ethCoin := eth.NewCoin("eth", "ETH", "ETH", params.MainnetChainConfig, "", nil, "", nil,
+		socksproxy.NewSocksProxy(false, ""))
+	ethCoin.TstSetClient(&rpcclientMocks.InterfaceMock{
+		HeaderByNumberFunc: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			// The latest block.
+			require.Nil(t, number)
+			return &types.Header{Number: big.NewInt(12000000)}, nil
+		},
+	})
+	blockNumber, err := ethCoin.BlockNumber()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(12000000), blockNumber)
+}
@@ -16,17 +16,27 @@ package db
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"sort"
 
 	bbolt "github.com/coreos/bbolt"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/jsonp"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 const (
 	bucketOutgoingTransactions = "pendingTransactions"
+	// bucketTransfers contains a sub bucket for ether and for each ERC20 token, see transfersKey,
+	// containing the transfers keyed by <8 bytes big endian height><tx hash><4 bytes big endian
+	// index>.
+	bucketTransfers = "transfers"
+	// bucketTransfersScannedRange maps the transfersKey to the scanned block range, as two 8 bytes
+	// big endian heights.
+	bucketTransfersScannedRange = "transfersScannedRange"
 )
 
 // DB is a bbolt key/value database.
@@ -53,9 +63,19 @@ func (db *DB) Begin() (TxInterface, error) {
 	if err != nil {
 		return nil, err
 	}
+	bucketTransfers, err := tx.CreateBucketIfNotExists([]byte(bucketTransfers))
+	if err != nil {
+		return nil, err
+	}
+	bucketTransfersScannedRange, err := tx.CreateBucketIfNotExists([]byte(bucketTransfersScannedRange))
+	if err != nil {
+		return nil, err
+	}
 	return &Tx{
-		tx:                         tx,
-		bucketOutgoingTransactions: bucketOutgoingTransactions,
+		tx:                          tx,
+		bucketOutgoingTransactions:  bucketOutgoingTransactions,
+		bucketTransfers:             bucketTransfers,
+		bucketTransfersScannedRange: bucketTransfersScannedRange,
 	}, nil
 }
 
@@ -68,7 +88,9 @@ func (db *DB) Close() error {
 type Tx struct {
 	tx *bbolt.Tx
 
-	bucketOutgoingTransactions *bbolt.Bucket
+	bucketOutgoingTransactions  *bbolt.Bucket
+	bucketTransfers             *bbolt.Bucket
+	bucketTransfersScannedRange *bbolt.Bucket
 }
 
 // Rollback implements DBTxInterface.
@@ -89,6 +111,11 @@ func (tx *Tx) PutOutgoingTransaction(transaction *types.TransactionWithMetadata)
 		jsonp.MustMarshal(transaction))
 }
 
+// DeleteOutgoingTransaction implements DBTxInterface.
+func (tx *Tx) DeleteOutgoingTransaction(txHash common.Hash) error {
+	return tx.bucketOutgoingTransactions.Delete(txHash.Bytes())
+}
+
 type byNonce []*types.TransactionWithMetadata
 
 func (txs byNonce) Len() int      { return len(txs) }
@@ -114,3 +141,79 @@ func (tx *Tx) OutgoingTransactions() ([]*types.TransactionWithMetadata, error) {
 	sort.Sort(sort.Reverse(byNonce(transactions)))
 	return transactions, nil
 }
+
+// transfersKey is the key of the transfers of the given ERC20 token, or of ether if nil.
+func transfersKey(erc20Token *erc20.Token) []byte {
+	if erc20Token == nil {
+		return []byte("eth")
+	}
+	return erc20Token.ContractAddress().Bytes()
+}
+
+// PutTransfer implements DBTxInterface.
+func (tx *Tx) PutTransfer(erc20Token *erc20.Token, transfer *types.Transfer) error {
+	bucket, err := tx.bucketTransfers.CreateBucketIfNotExists(transfersKey(erc20Token))
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	key := make([]byte, 8+common.HashLength+4)
+	binary.BigEndian.PutUint64(key, transfer.BlockNumber)
+	copy(key[8:], transfer.TxHash.Bytes())
+	binary.BigEndian.PutUint32(key[8+common.HashLength:], uint32(transfer.Index))
+	return bucket.Put(key, jsonp.MustMarshal(transfer))
+}
+
+// DeleteTransfersFrom implements DBTxInterface.
+func (tx *Tx) DeleteTransfersFrom(erc20Token *erc20.Token, height uint64) error {
+	bucket := tx.bucketTransfers.Bucket(transfersKey(erc20Token))
+	if bucket == nil {
+		return nil
+	}
+	seek := make([]byte, 8)
+	binary.BigEndian.PutUint64(seek, height)
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(seek); key != nil; key, _ = cursor.Seek(seek) {
+		if err := bucket.Delete(key); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Transfers implements DBTxInterface.
+func (tx *Tx) Transfers(erc20Token *erc20.Token) ([]*types.Transfer, error) {
+	transfers := []*types.Transfer{}
+	bucket := tx.bucketTransfers.Bucket(transfersKey(erc20Token))
+	if bucket == nil {
+		return transfers, nil
+	}
+	cursor := bucket.Cursor()
+	for key, transferSerialized := cursor.First(); key != nil; key, transferSerialized = cursor.Next() {
+		transfer := new(types.Transfer)
+		if err := json.Unmarshal(transferSerialized, transfer); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+// TransfersScannedRange implements DBTxInterface.
+func (tx *Tx) TransfersScannedRange(erc20Token *erc20.Token) (uint64, uint64, error) {
+	value := tx.bucketTransfersScannedRange.Get(transfersKey(erc20Token))
+	if value == nil {
+		return 0, 0, nil
+	}
+	if len(value) != 16 {
+		return 0, 0, errp.New("invalid scanned range")
+	}
+	return binary.BigEndian.Uint64(value[:8]), binary.BigEndian.Uint64(value[8:]), nil
+}
+
+// SetTransfersScannedRange implements DBTxInterface.
+func (tx *Tx) SetTransfersScannedRange(erc20Token *erc20.Token, from uint64, to uint64) error {
+	value := make([]byte, 16)
+	binary.BigEndian.PutUint64(value[:8], from)
+	binary.BigEndian.PutUint64(value[8:], to)
+	return tx.bucketTransfersScannedRange.Put(transfersKey(erc20Token), value)
+}
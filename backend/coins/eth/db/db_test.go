@@ -0,0 +1,106 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfers(t *testing.T) {
+	token := erc20.NewToken("0xdac17f958d2ee523a2206206994597c13d831ec7", 6)
+	otherToken := erc20.NewToken("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", 6)
+	sender := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	recipient := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	accountDB, err := NewDB(test.TstTempFile("eth-db"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, accountDB.Close()) }()
+
+	dbTx, err := accountDB.Begin()
+	require.NoError(t, err)
+	defer dbTx.Rollback()
+
+	from, to, err := dbTx.TransfersScannedRange(token)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), from)
+	require.Equal(t, uint64(0), to)
+	transfers, err := dbTx.Transfers(token)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+
+	for _, blockNumber := range []uint64{300, 10, 200} {
+		require.NoError(t, dbTx.PutTransfer(token, &ethtypes.Transfer{
+			TxHash:      common.BigToHash(new(big.Int).SetUint64(blockNumber)),
+			BlockNumber: blockNumber,
+			From:        sender,
+			To:          recipient,
+			Value:       big.NewInt(1),
+		}))
+	}
+	require.NoError(t, dbTx.SetTransfersScannedRange(token, 5, 300))
+
+	from, to, err = dbTx.TransfersScannedRange(token)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), from)
+	require.Equal(t, uint64(300), to)
+	transfers, err = dbTx.Transfers(token)
+	require.NoError(t, err)
+	require.Len(t, transfers, 3)
+	// Sorted by height.
+	require.Equal(t, uint64(10), transfers[0].BlockNumber)
+	require.Equal(t, uint64(200), transfers[1].BlockNumber)
+	require.Equal(t, uint64(300), transfers[2].BlockNumber)
+	require.Equal(t, "1", transfers[0].Value.String())
+
+	// Other tokens and ether are stored separately.
+	transfers, err = dbTx.Transfers(otherToken)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+	transfers, err = dbTx.Transfers(nil)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+	_, to, err = dbTx.TransfersScannedRange(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), to)
+
+	require.NoError(t, dbTx.PutTransfer(nil, &ethtypes.Transfer{
+		TxHash:      common.HexToHash("0x01"),
+		BlockNumber: 50,
+		From:        sender,
+		To:          recipient,
+		Value:       big.NewInt(2),
+		Failed:      true,
+	}))
+	transfers, err = dbTx.Transfers(nil)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.True(t, transfers[0].Failed)
+
+	require.NoError(t, dbTx.DeleteTransfersFrom(token, 200))
+	transfers, err = dbTx.Transfers(token)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, uint64(10), transfers[0].BlockNumber)
+	transfers, err = dbTx.Transfers(nil)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+}
@@ -14,7 +14,11 @@
 
 package db
 
-import "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+import (
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/ethereum/go-ethereum/common"
+)
 
 // TxInterface needs to be implemented to persist all wallet/transaction related data.
 type TxInterface interface {
@@ -27,9 +31,33 @@ type TxInterface interface {
 	// PutOutgoingTransaction stores the transaction in the collection of outgoing transactions.
 	PutOutgoingTransaction(*types.TransactionWithMetadata) error
 
+	// DeleteOutgoingTransaction removes the transaction with the given hash from the collection of
+	// outgoing transactions, e.g. after another transaction with the same nonce was mined.
+	DeleteOutgoingTransaction(common.Hash) error
+
 	// OutgoingTransactions returns the stored list of outgoing transactions, sorted descending by
 	// the transaction nonce.
 	OutgoingTransactions() ([]*types.TransactionWithMetadata, error)
+
+	// PutTransfer stores a transfer of the given ERC20 token, or an ether transfer if erc20Token
+	// is nil.
+	PutTransfer(erc20Token *erc20.Token, transfer *types.Transfer) error
+
+	// DeleteTransfersFrom removes the stored transfers of the given ERC20 token (ether if nil) at
+	// or above the given block height, e.g. to rescan blocks which might be reorged.
+	DeleteTransfersFrom(erc20Token *erc20.Token, height uint64) error
+
+	// Transfers returns the stored transfers of the given ERC20 token (ether if nil), sorted
+	// ascending by height.
+	Transfers(erc20Token *erc20.Token) ([]*types.Transfer, error)
+
+	// TransfersScannedRange returns the block range (inclusive) which was scanned for transfers of
+	// the given ERC20 token (ether if nil). 0, 0 is returned if nothing was scanned yet.
+	TransfersScannedRange(erc20Token *erc20.Token) (uint64, uint64, error)
+
+	// SetTransfersScannedRange stores the block range (inclusive) which was scanned for transfers
+	// of the given ERC20 token (ether if nil).
+	SetTransfersScannedRange(erc20Token *erc20.Token, from uint64, to uint64) error
 }
 
 // Interface can be implemented by database backends to open database transactions.
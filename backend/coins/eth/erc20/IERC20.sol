@@ -4,8 +4,8 @@ From https://github.com/OpenZeppelin/openzeppelin-solidity/blob/79346123eade98fb
 pragma solidity ^0.5.0;
 
 /**
- * @dev Interface of the ERC20 standard as defined in the EIP. Does not include
- * the optional functions; to access them see `ERC20Detailed`.
+ * @dev Interface of the ERC20 standard as defined in the EIP, including the
+ * optional functions of `ERC20Detailed`.
  */
 interface IERC20 {
     /**
@@ -63,6 +63,22 @@ interface IERC20 {
      */
     function transferFrom(address sender, address recipient, uint256 amount) external returns (bool);
 
+    /**
+     * @dev Returns the name of the token. Optional, from `ERC20Detailed`.
+     */
+    function name() external view returns (string memory);
+
+    /**
+     * @dev Returns the symbol of the token. Optional, from `ERC20Detailed`.
+     */
+    function symbol() external view returns (string memory);
+
+    /**
+     * @dev Returns the number of decimals used to get its user representation. Optional, from
+     * `ERC20Detailed`.
+     */
+    function decimals() external view returns (uint8);
+
     /**
      * @dev Emitted when `value` tokens are moved from one account (`from`) to
      * another (`to`).
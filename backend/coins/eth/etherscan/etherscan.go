@@ -499,7 +499,7 @@ func (etherScan *EtherScan) EstimateGas(ctx context.Context, msg ethereum.CallMs
 
 // FilterLogs implements rpc.Interface.
 func (etherScan *EtherScan) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
-	panic("not implemented")
+	return nil, errp.WithStack(rpcclient.ErrMethodNotFound)
 }
 
 // PendingCodeAt implements rpc.Interface.
@@ -526,13 +526,40 @@ func (etherScan *EtherScan) SendTransaction(ctx context.Context, tx *types.Trans
 	if err != nil {
 		return errp.WithStack(err)
 	}
+	return etherScan.SendRawTransaction(ctx, encodedTx)
+}
 
+// SendRawTransaction implements rpc.Interface.
+func (etherScan *EtherScan) SendRawTransaction(ctx context.Context, rawTx []byte) error {
 	params := url.Values{}
 	params.Set("action", "eth_sendRawTransaction")
-	params.Set("hex", hexutil.Encode(encodedTx))
+	params.Set("hex", hexutil.Encode(rawTx))
 	return etherScan.rpcCall(params, nil)
 }
 
+// TraceFilter implements rpc.Interface.
+func (etherScan *EtherScan) TraceFilter(ctx context.Context, fromBlock, toBlock uint64,
+	fromAddresses, toAddresses []common.Address) ([]*rpcclient.Trace, error) {
+	return nil, errp.WithStack(rpcclient.ErrMethodNotFound)
+}
+
+// BlockTransactions implements rpc.Interface.
+func (etherScan *EtherScan) BlockTransactions(ctx context.Context, number uint64) (
+	*rpcclient.Block, error) {
+	params := url.Values{}
+	params.Set("action", "eth_getBlockByNumber")
+	params.Set("tag", hexutil.EncodeUint64(number))
+	params.Set("boolean", "true")
+	var result *rpcclient.Block
+	if err := etherScan.rpcCall(params, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, errp.Newf("block %d not found", number)
+	}
+	return result, nil
+}
+
 // SubscribeFilterLogs implements rpc.Interface.
 func (etherScan *EtherScan) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
 	panic("not implemented")
@@ -548,3 +575,75 @@ func (etherScan *EtherScan) SuggestGasPrice(ctx context.Context) (*big.Int, erro
 	}
 	return (*big.Int)(&result), nil
 }
+
+// gweiToWei parses a decimal gwei amount as returned by the gas tracker.
+func gweiToWei(gwei string) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(gwei)
+	if !ok {
+		return nil, errp.Newf("failed to parse %s", gwei)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt64(1e9))
+	return new(big.Int).Quo(amount.Num(), amount.Denom()), nil
+}
+
+// FeeHistory implements rpc.Interface. EtherScan does not proxy eth_feeHistory, so the history is
+// approximated with the gas tracker oracle: a single block with the suggested base fee of the
+// next block and the safe/proposed/fast gas prices as the low, median and high priority fees.
+// Only mainnet has a gas tracker.
+func (etherScan *EtherScan) FeeHistory(
+	ctx context.Context, blockCount uint64, rewardPercentiles []float64) (*rpcclient.FeeHistory, error) {
+	var result struct {
+		Status  string
+		Message string
+		Result  struct {
+			LastBlock       jsonBigInt
+			SafeGasPrice    string
+			ProposeGasPrice string
+			FastGasPrice    string
+			SuggestBaseFee  string `json:"suggestBaseFee"`
+		}
+	}
+	params := url.Values{}
+	params.Set("module", "gastracker")
+	params.Set("action", "gasoracle")
+	if err := etherScan.call(params, &result); err != nil {
+		return nil, err
+	}
+	if result.Status != "1" {
+		return nil, errp.Newf("unexpected response: %s", result.Message)
+	}
+	baseFee, err := gweiToWei(result.Result.SuggestBaseFee)
+	if err != nil {
+		return nil, err
+	}
+	var prices [3]*big.Int
+	for index, gasPrice := range []string{
+		result.Result.SafeGasPrice, result.Result.ProposeGasPrice, result.Result.FastGasPrice} {
+		price, err := gweiToWei(gasPrice)
+		if err != nil {
+			return nil, err
+		}
+		priorityFee := new(big.Int).Sub(price, baseFee)
+		if priorityFee.Sign() < 0 {
+			priorityFee.SetInt64(0)
+		}
+		prices[index] = priorityFee
+	}
+	rewards := make([]*big.Int, len(rewardPercentiles))
+	for index, percentile := range rewardPercentiles {
+		switch {
+		case percentile < 25:
+			rewards[index] = prices[0]
+		case percentile < 75:
+			rewards[index] = prices[1]
+		default:
+			rewards[index] = prices[2]
+		}
+	}
+	return &rpcclient.FeeHistory{
+		OldestBlock:   result.Result.LastBlock.BigInt(),
+		BaseFeePerGas: []*big.Int{baseFee, baseFee},
+		GasUsedRatio:  []float64{0.5},
+		Reward:        [][]*big.Int{rewards},
+	}, nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+// feeHistoryBlockCount is the number of recent blocks used to estimate the priority fees.
+const feeHistoryBlockCount = 20
+
+// londonBlocks contains the activation height of the London hard fork (EIP-1559) by chain ID. The
+// vendored go-ethereum chain configs predate London.
+var londonBlocks = map[uint64]*big.Int{
+	1: big.NewInt(12965000), // mainnet
+	3: big.NewInt(10499401), // ropsten
+	4: big.NewInt(8897988),  // rinkeby
+	5: big.NewInt(5062605),  // goerli
+}
+
+// isLondon returns true if EIP-1559 transactions are valid at the given block number.
+func isLondon(net *params.ChainConfig, blockNumber *big.Int) bool {
+	if net.ChainID == nil || blockNumber == nil {
+		return false
+	}
+	londonBlock, ok := londonBlocks[net.ChainID.Uint64()]
+	return ok && blockNumber.Cmp(londonBlock) >= 0
+}
+
+// FeeTarget contains the fees per gas for a specific fee target.
+type FeeTarget struct {
+	// code is the identifier for the UI.
+	code accounts.FeeTargetCode
+	// percentile is the priority fee percentile of recent blocks used for this target.
+	percentile float64
+	// baseFee is the expected base fee per gas of the next block.
+	baseFee *big.Int
+	// gasTipCap is the max priority fee per gas paid to the miner.
+	gasTipCap *big.Int
+	// gasFeeCap is the max fee per gas. It leaves room for the base fee to double.
+	gasFeeCap *big.Int
+}
+
+// Code returns the eth fee target.
+func (feeTarget *FeeTarget) Code() accounts.FeeTargetCode {
+	return feeTarget.code
+}
+
+// gasPrice returns the expected fee per gas, the base fee plus the priority fee. It is used as the
+// gas price of legacy transactions.
+func (feeTarget *FeeTarget) gasPrice() *big.Int {
+	return new(big.Int).Add(feeTarget.baseFee, feeTarget.gasTipCap)
+}
+
+// newFeeTargets returns the fee targets, sorted by ascending priority, without fees.
+func newFeeTargets() []*FeeTarget {
+	return []*FeeTarget{
+		{code: accounts.FeeTargetCodeLow, percentile: 10},
+		{code: accounts.FeeTargetCodeNormal, percentile: 50},
+		{code: accounts.FeeTargetCodeHigh, percentile: 90},
+	}
+}
+
+// feeTargetsFromHistory computes the fees of the fee targets from the fee history, which was
+// requested with the percentiles of the fee targets. The priority fee of a target is the median of
+// the priority fees at its percentile over the non-empty blocks.
+func feeTargetsFromHistory(feeHistory *rpcclient.FeeHistory) ([]*FeeTarget, error) {
+	baseFee := feeHistory.NextBaseFee()
+	if baseFee == nil {
+		return nil, errp.New("fee history without base fees")
+	}
+	feeTargets := newFeeTargets()
+	for index, feeTarget := range feeTargets {
+		tips := []*big.Int{}
+		for blockIndex, rewards := range feeHistory.Reward {
+			if blockIndex < len(feeHistory.GasUsedRatio) && feeHistory.GasUsedRatio[blockIndex] == 0 {
+				// Empty blocks have no priority fees.
+				continue
+			}
+			if len(rewards) != len(feeTargets) {
+				return nil, errp.New("unexpected number of priority fees in the fee history")
+			}
+			tips = append(tips, rewards[index])
+		}
+		if len(tips) == 0 {
+			return nil, errp.New("fee history without priority fees")
+		}
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		feeTarget.baseFee = new(big.Int).Set(baseFee)
+		feeTarget.gasTipCap = new(big.Int).Set(tips[len(tips)/2])
+		feeTarget.gasFeeCap = new(big.Int).Add(
+			new(big.Int).Mul(baseFee, big.NewInt(2)), feeTarget.gasTipCap)
+	}
+	return feeTargets, nil
+}
+
+// updateFeeTargets fetches the recent fee history and updates the fee targets. Failures are only
+// logged, as transactions can still be priced with the gas price suggested by the node.
+func (account *Account) updateFeeTargets() {
+	percentiles := []float64{}
+	for _, feeTarget := range newFeeTargets() {
+		percentiles = append(percentiles, feeTarget.percentile)
+	}
+	feeHistory, err := account.coin.feeHistory(feeHistoryBlockCount, percentiles)
+	if err != nil {
+		account.log.WithError(err).Warning("Could not fetch the fee history")
+		return
+	}
+	feeTargets, err := feeTargetsFromHistory(feeHistory)
+	if err != nil {
+		account.log.WithError(err).Warning("Could not estimate the fees")
+		return
+	}
+	func() {
+		defer account.feeTargetsLock.Lock()()
+		account.feeTargets = feeTargets
+	}()
+	for _, feeTarget := range feeTargets {
+		account.log.WithFields(logrus.Fields{
+			"code":         feeTarget.code,
+			"base-fee":     feeTarget.baseFee,
+			"priority-fee": feeTarget.gasTipCap,
+		}).Debug("Fee estimate per gas")
+	}
+	account.Config().OnEvent(accounts.EventFeeTargetsChanged)
+}
+
+// feeTarget returns the fee target with the given code, or nil if the fees are not known.
+func (account *Account) feeTarget(code accounts.FeeTargetCode) (*FeeTarget, error) {
+	if code == accounts.FeeTargetCodeCustom {
+		return nil, errp.WithStack(errors.ErrCustomFeeNotSupported)
+	}
+	defer account.feeTargetsLock.RLock()()
+	if len(account.feeTargets) == 0 {
+		return nil, nil
+	}
+	var defaultFeeTarget *FeeTarget
+	for _, feeTarget := range account.feeTargets {
+		if feeTarget.code == code {
+			return feeTarget, nil
+		}
+		if feeTarget.code == accounts.DefaultFeeTarget {
+			defaultFeeTarget = feeTarget
+		}
+	}
+	// Codes of other coins, e.g. the Bitcoin economy target, fall back to the default target.
+	if defaultFeeTarget == nil {
+		return nil, errp.Newf("Could not find fee target %s", code)
+	}
+	account.log.Debugf("Unknown fee target %s, using the default fee target", code)
+	return defaultFeeTarget, nil
+}
+
+// FeeTargets implements accounts.Interface.
+func (account *Account) FeeTargets() ([]accounts.FeeTarget, accounts.FeeTargetCode) {
+	defer account.feeTargetsLock.RLock()()
+	if len(account.feeTargets) == 0 {
+		return nil, ""
+	}
+	// Like for Bitcoin, the targets are returned by descending priority.
+	feeTargets := []accounts.FeeTarget{}
+	for i := len(account.feeTargets) - 1; i >= 0; i-- {
+		feeTargets = append(feeTargets, account.feeTargets[i])
+	}
+	return feeTargets, accounts.DefaultFeeTarget
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeTargetsFromHistory(t *testing.T) {
+	var feeHistory rpcclient.FeeHistory
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "oldestBlock": "0xc5d5a0",
+  "baseFeePerGas": ["0x3b9aca00", "0x3b9aca00", "0x3b9aca00", "0x3b9aca00", "0x77359400"],
+  "gasUsedRatio": [0.5, 0, 0.9, 0.2],
+  "reward": [
+    ["0x1", "0x2", "0x3"],
+    ["0x0", "0x0", "0x0"],
+    ["0x10", "0x20", "0x30"],
+    ["0x5", "0x6", "0x7"]
+  ]
+}`), &feeHistory))
+	require.Equal(t, big.NewInt(0xc5d5a0), feeHistory.OldestBlock)
+	require.Equal(t, big.NewInt(2000000000), feeHistory.NextBaseFee())
+
+	feeTargets, err := feeTargetsFromHistory(&feeHistory)
+	require.NoError(t, err)
+	require.Len(t, feeTargets, 3)
+	// The empty block is skipped, the median of the remaining three blocks is used.
+	for index, expected := range []struct {
+		code      accounts.FeeTargetCode
+		gasTipCap int64
+	}{
+		{accounts.FeeTargetCodeLow, 0x5},
+		{accounts.FeeTargetCodeNormal, 0x6},
+		{accounts.FeeTargetCodeHigh, 0x7},
+	} {
+		feeTarget := feeTargets[index]
+		require.Equal(t, expected.code, feeTarget.Code())
+		require.Equal(t, big.NewInt(expected.gasTipCap), feeTarget.gasTipCap)
+		require.Equal(t, big.NewInt(4000000000+expected.gasTipCap), feeTarget.gasFeeCap)
+		require.Equal(t, big.NewInt(2000000000+expected.gasTipCap), feeTarget.gasPrice())
+	}
+
+	_, err = feeTargetsFromHistory(&rpcclient.FeeHistory{})
+	require.Error(t, err)
+}
+
+func TestIsLondon(t *testing.T) {
+	require.True(t, isLondon(params.MainnetChainConfig, big.NewInt(12965000)))
+	require.False(t, isLondon(params.MainnetChainConfig, big.NewInt(12964999)))
+	require.True(t, isLondon(params.TestnetChainConfig, big.NewInt(10499401)))
+	require.False(t, isLondon(params.AllEthashProtocolChanges, big.NewInt(100000000)))
+}
+
+func TestFeeTargetCustom(t *testing.T) {
+	account := &Account{}
+	_, err := account.feeTarget(accounts.FeeTargetCodeCustom)
+	require.Equal(t, errors.ErrCustomFeeNotSupported, errp.Cause(err))
+	// Without fee estimates, the fee target is unknown.
+	feeTarget, err := account.feeTarget(accounts.FeeTargetCodeNormal)
+	require.NoError(t, err)
+	require.Nil(t, feeTarget)
+}
+
+func TestFeeTargetFallback(t *testing.T) {
+	account := &Account{log: logging.Get().WithGroup("eth")}
+	account.feeTargets = newFeeTargets()
+	for _, code := range []accounts.FeeTargetCode{
+		accounts.FeeTargetCodeLow, accounts.FeeTargetCodeNormal, accounts.FeeTargetCodeHigh,
+	} {
+		feeTarget, err := account.feeTarget(code)
+		require.NoError(t, err)
+		require.Equal(t, code, feeTarget.Code())
+	}
+	// Unknown codes fall back to the default fee target.
+	feeTarget, err := account.feeTarget(accounts.FeeTargetCodeEconomy)
+	require.NoError(t, err)
+	require.Equal(t, accounts.DefaultFeeTarget, feeTarget.Code())
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodesource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// erc20Transfers returns the token transfers from and to the address in the given block range.
+func (source *TransactionsSource) erc20Transfers(
+	filterer *erc20.IERC20Filterer,
+	address common.Address,
+	from uint64,
+	to uint64,
+) ([]*ethtypes.Transfer, error) {
+	opts := &bind.FilterOpts{Start: from, End: &to, Context: context.TODO()}
+	events := []*erc20.IERC20Transfer{}
+	seen := map[string]struct{}{}
+	for _, fromTo := range [][2][]common.Address{{{address}, nil}, {nil, {address}}} {
+		iterator, err := filterer.FilterTransfer(opts, fromTo[0], fromTo[1])
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		for iterator.Next() {
+			event := iterator.Event
+			if event.Raw.Removed {
+				continue
+			}
+			// Transfers to self are found by both queries.
+			id := fmt.Sprintf("%s-%d", event.Raw.TxHash.Hex(), event.Raw.Index)
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			events = append(events, event)
+		}
+		err = iterator.Error()
+		_ = iterator.Close()
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+
+	transfers := make([]*ethtypes.Transfer, len(events))
+	timestamps := map[uint64]uint64{}
+	receipts := map[common.Hash]*rpcclient.RPCTransactionReceipt{}
+	for i, event := range events {
+		timestamp, err := source.blockTimestamp(timestamps, event.Raw.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		transfer := &ethtypes.Transfer{
+			TxHash:      event.Raw.TxHash,
+			Index:       event.Raw.Index,
+			BlockNumber: event.Raw.BlockNumber,
+			Timestamp:   timestamp,
+			From:        event.From,
+			To:          event.To,
+			Value:       event.Value,
+		}
+		receipt, ok := receipts[event.Raw.TxHash]
+		if !ok {
+			receipt, err = source.receipt(event.Raw.TxHash)
+			if err != nil {
+				return nil, err
+			}
+			receipts[event.Raw.TxHash] = receipt
+		}
+		// The fee is paid by the sender of the transaction, who is not necessarily the sender of
+		// the tokens, e.g. in case of transferFrom() or tokens received from a contract we called.
+		if receipt.From == address {
+			setFee(transfer, receipt)
+		}
+		transfers[i] = transfer
+	}
+	return transfers, nil
+}
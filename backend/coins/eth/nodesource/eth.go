@@ -0,0 +1,180 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodesource
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// tracedETHTransfers returns the ether transfers from and to the address in the given block range,
+// including internal transactions, using trace_filter.
+func (source *TransactionsSource) tracedETHTransfers(
+	address common.Address,
+	from uint64,
+	to uint64,
+) ([]*ethtypes.Transfer, error) {
+	traces := []*rpcclient.Trace{}
+	seen := map[string]struct{}{}
+	for _, fromTo := range [][2][]common.Address{{{address}, nil}, {nil, {address}}} {
+		result, err := source.client.TraceFilter(context.TODO(), from, to, fromTo[0], fromTo[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range result {
+			if trace.Type != "call" && trace.Type != "create" {
+				continue
+			}
+			// Transfers to self are found by both queries.
+			id := fmt.Sprintf("%s-%v", trace.TransactionHash.Hex(), trace.TraceAddress)
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			traces = append(traces, trace)
+		}
+	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		if traces[i].BlockNumber != traces[j].BlockNumber {
+			return traces[i].BlockNumber < traces[j].BlockNumber
+		}
+		if traces[i].TransactionPosition != traces[j].TransactionPosition {
+			return traces[i].TransactionPosition < traces[j].TransactionPosition
+		}
+		return lessTraceAddress(traces[i].TraceAddress, traces[j].TraceAddress)
+	})
+
+	transfers := []*ethtypes.Transfer{}
+	timestamps := map[uint64]uint64{}
+	// Number of internal transfers per transaction so far, to index them.
+	internalTransfers := map[common.Hash]uint{}
+	for _, trace := range traces {
+		value := big.NewInt(0)
+		if trace.Action.Value != nil {
+			value = trace.Action.Value.ToInt()
+		}
+		isTransaction := len(trace.TraceAddress) == 0
+		// Failed internal calls and internal calls without value do not change the balance.
+		// Transactions are always shown, as the fee is paid.
+		if !isTransaction && (trace.Error != "" || value.Sign() == 0) {
+			continue
+		}
+		var index uint
+		if !isTransaction {
+			internalTransfers[trace.TransactionHash]++
+			index = internalTransfers[trace.TransactionHash]
+		}
+		var recipient common.Address
+		switch {
+		case trace.Action.To != nil:
+			recipient = *trace.Action.To
+		case trace.Result != nil && trace.Result.Address != nil:
+			recipient = *trace.Result.Address
+		}
+		timestamp, err := source.blockTimestamp(timestamps, trace.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		transfer := &ethtypes.Transfer{
+			TxHash:      trace.TransactionHash,
+			Index:       index,
+			BlockNumber: trace.BlockNumber,
+			Timestamp:   timestamp,
+			From:        trace.Action.From,
+			To:          recipient,
+			Value:       value,
+			Failed:      isTransaction && trace.Error != "",
+		}
+		if isTransaction && trace.Action.From == address {
+			// We paid the fee.
+			receipt, err := source.receipt(trace.TransactionHash)
+			if err != nil {
+				return nil, err
+			}
+			setFee(transfer, receipt)
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, nil
+}
+
+// lessTraceAddress orders the calls of a transaction in the order they were made.
+func lessTraceAddress(a, b []uint) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// blockETHTransfers returns the ether transfers from and to the address in the given block range by
+// fetching every block. Internal transactions are not found.
+func (source *TransactionsSource) blockETHTransfers(
+	address common.Address,
+	from uint64,
+	to uint64,
+) ([]*ethtypes.Transfer, error) {
+	transfers := []*ethtypes.Transfer{}
+	for blockNumber := from; blockNumber <= to; blockNumber++ {
+		block, err := source.client.BlockTransactions(context.TODO(), blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, errp.Newf("block %d not found", blockNumber)
+		}
+		for _, tx := range block.Transactions {
+			if tx.From != address && (tx.To == nil || *tx.To != address) {
+				continue
+			}
+			receipt, err := source.receipt(tx.Hash)
+			if err != nil {
+				return nil, err
+			}
+			value := big.NewInt(0)
+			if tx.Value != nil {
+				value = tx.Value.ToInt()
+			}
+			recipient := receipt.ContractAddress
+			if tx.To != nil {
+				recipient = *tx.To
+			}
+			transfer := &ethtypes.Transfer{
+				TxHash:      tx.Hash,
+				BlockNumber: blockNumber,
+				Timestamp:   uint64(block.Timestamp),
+				From:        tx.From,
+				To:          recipient,
+				Value:       value,
+				Failed:      receipt.Status == types.ReceiptStatusFailed,
+			}
+			if tx.From == address {
+				// We paid the fee.
+				setFee(transfer, receipt)
+			}
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
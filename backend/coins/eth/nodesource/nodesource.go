@@ -0,0 +1,373 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nodesource reconstructs the transaction history of an account from an Ethereum node, so
+// that no third party API like EtherScan is needed.
+package nodesource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/db"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBlockRange is the number of blocks initially queried at once using eth_getLogs or
+// trace_filter. Nodes limit the number of results or the block range of a query, so the range is
+// reduced if a query fails.
+const maxBlockRange = 10000
+
+// blockScanRange is the number of blocks fetched one by one before the progress is stored, if ether
+// transfers are found by scanning the blocks.
+const blockScanRange = 100
+
+// ErrBirthHeightRequired is returned if the node does not support trace_filter and the account has
+// no birth height, as scanning all blocks since the genesis block would take days.
+var ErrBirthHeightRequired = errors.New("the birth height of the account is required to scan the blocks")
+
+// TransactionsSource fetches the transactions of an account from the node. ERC20 token transfers
+// are found using the Transfer event logs of the token contract. Ether transfers, including
+// internal transactions, are found using trace_filter if the node supports it. Otherwise, the
+// transactions of all blocks since the birth height of the account are scanned, which does not
+// find internal transactions.
+type TransactionsSource struct {
+	client rpcclient.Interface
+
+	// traceFilterUnsupported is true if the node does not support trace_filter.
+	traceFilterUnsupported     bool
+	traceFilterUnsupportedLock locker.Locker
+
+	log *logrus.Entry
+}
+
+// NewTransactionsSource creates a new transactions source querying the given node.
+func NewTransactionsSource(client rpcclient.Interface) *TransactionsSource {
+	return &TransactionsSource{
+		client: client,
+		log:    logging.Get().WithGroup("nodesource"),
+	}
+}
+
+// Transactions implements eth.TransactionsSource. All blocks up to endBlock are scanned, as
+// nothing is cached. Use TransactionsWithDB to only scan the blocks not scanned before.
+func (source *TransactionsSource) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	transfers := []*ethtypes.Transfer{}
+	err := source.scan(address, erc20Token, 0, endBlock.Uint64(),
+		func(newTransfers []*ethtypes.Transfer, _ uint64) error {
+			transfers = append(transfers, newTransfers...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return transactionsData(blockTipHeight, address, transfers), nil
+}
+
+// TransactionsWithDB implements eth.DBTransactionsSource. The transfers are stored in the account
+// database, so that only the blocks since the last update are scanned. The most recent blocks are
+// scanned again in case they were reorganized. Blocks before birthHeight are not scanned.
+func (source *TransactionsSource) TransactionsWithDB(
+	accountDB db.Interface,
+	birthHeight uint64,
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	scannedFrom, scannedTo, err := transfersScannedRange(accountDB, erc20Token)
+	if err != nil {
+		return nil, err
+	}
+	// If nothing was scanned yet or the birth height was lowered, all stored transfers are
+	// replaced.
+	resume := scannedTo > 0 && scannedFrom <= birthHeight
+	rangeFrom, startBlock, deleteFrom := birthHeight, birthHeight, uint64(0)
+	if resume {
+		rangeFrom, startBlock = scannedFrom, scannedFrom
+		if scannedTo+1 >= scannedFrom+ethtypes.NumConfirmationsComplete {
+			startBlock = scannedTo + 1 - ethtypes.NumConfirmationsComplete
+		}
+		deleteFrom = startBlock
+	}
+	firstPage := true
+	err = source.scan(address, erc20Token, startBlock, endBlock.Uint64(),
+		func(transfers []*ethtypes.Transfer, height uint64) error {
+			dbTx, err := accountDB.Begin()
+			if err != nil {
+				return err
+			}
+			defer dbTx.Rollback()
+			if firstPage {
+				if err := dbTx.DeleteTransfersFrom(erc20Token, deleteFrom); err != nil {
+					return err
+				}
+			}
+			for _, transfer := range transfers {
+				if err := dbTx.PutTransfer(erc20Token, transfer); err != nil {
+					return err
+				}
+			}
+			if err := dbTx.SetTransfersScannedRange(erc20Token, rangeFrom, height); err != nil {
+				return err
+			}
+			if err := dbTx.Commit(); err != nil {
+				return errp.WithStack(err)
+			}
+			firstPage = false
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	dbTx, err := accountDB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	transfers, err := dbTx.Transfers(erc20Token)
+	if err != nil {
+		return nil, err
+	}
+	return transactionsData(blockTipHeight, address, transfers), nil
+}
+
+func transfersScannedRange(accountDB db.Interface, erc20Token *erc20.Token) (uint64, uint64, error) {
+	dbTx, err := accountDB.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer dbTx.Rollback()
+	return dbTx.TransfersScannedRange(erc20Token)
+}
+
+// scan fetches the transfers of the given ERC20 token, or the ether transfers if erc20Token is
+// nil, from and to the address between startBlock and endBlock (inclusive), one block range at a
+// time. onPage is called with the transfers of each range and the last block of the range.
+func (source *TransactionsSource) scan(
+	address common.Address,
+	erc20Token *erc20.Token,
+	startBlock uint64,
+	endBlock uint64,
+	onPage func(transfers []*ethtypes.Transfer, height uint64) error,
+) error {
+	if erc20Token != nil {
+		filterer, err := erc20.NewIERC20Filterer(erc20Token.ContractAddress(), source.client)
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		return source.scanPaged(startBlock, endBlock, maxBlockRange,
+			func(from, to uint64) ([]*ethtypes.Transfer, error) {
+				return source.erc20Transfers(filterer, address, from, to)
+			}, onPage)
+	}
+	if !source.isTraceFilterUnsupported() {
+		err := source.scanPaged(startBlock, endBlock, maxBlockRange,
+			func(from, to uint64) ([]*ethtypes.Transfer, error) {
+				return source.tracedETHTransfers(address, from, to)
+			}, onPage)
+		if !rpcclient.IsMethodNotFound(err) {
+			return err
+		}
+		source.log.Info("trace_filter is not supported by the node, scanning the blocks instead")
+		func() {
+			defer source.traceFilterUnsupportedLock.Lock()()
+			source.traceFilterUnsupported = true
+		}()
+	}
+	if startBlock == 0 {
+		source.log.Warning("Not scanning the blocks since the genesis block, the account needs a birth height")
+		return errp.WithStack(ErrBirthHeightRequired)
+	}
+	return source.scanPaged(startBlock, endBlock, blockScanRange,
+		func(from, to uint64) ([]*ethtypes.Transfer, error) {
+			return source.blockETHTransfers(address, from, to)
+		}, onPage)
+}
+
+func (source *TransactionsSource) isTraceFilterUnsupported() bool {
+	defer source.traceFilterUnsupportedLock.RLock()()
+	return source.traceFilterUnsupported
+}
+
+// scanPaged calls fetch for consecutive block ranges between startBlock and endBlock (inclusive),
+// and onPage with the result and the last block of each range. The block range is halved if fetch
+// fails, unless the method is not supported by the node.
+func (source *TransactionsSource) scanPaged(
+	startBlock uint64,
+	endBlock uint64,
+	blockRange uint64,
+	fetch func(from, to uint64) ([]*ethtypes.Transfer, error),
+	onPage func(transfers []*ethtypes.Transfer, height uint64) error,
+) error {
+	for from := startBlock; from <= endBlock; {
+		to := from + blockRange - 1
+		if to > endBlock {
+			to = endBlock
+		}
+		transfers, err := fetch(from, to)
+		if err != nil {
+			if blockRange == 1 || rpcclient.IsMethodNotFound(err) {
+				return err
+			}
+			blockRange /= 2
+			source.log.WithError(err).Debugf("Reducing the block range to %d", blockRange)
+			continue
+		}
+		source.log.Debugf("Scanned blocks %d to %d: %d transfers", from, to, len(transfers))
+		sortTransfers(transfers)
+		if err := onPage(transfers, to); err != nil {
+			return err
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// blockTimestamp returns the timestamp of the block with the given number. timestamps caches the
+// timestamps by block number.
+func (source *TransactionsSource) blockTimestamp(
+	timestamps map[uint64]uint64, blockNumber uint64) (uint64, error) {
+	if timestamp, ok := timestamps[blockNumber]; ok {
+		return timestamp, nil
+	}
+	header, err := source.client.HeaderByNumber(
+		context.TODO(), new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return 0, errp.WithStack(err)
+	}
+	timestamps[blockNumber] = header.Time
+	return header.Time, nil
+}
+
+// receipt returns the receipt of the transaction with the given hash.
+func (source *TransactionsSource) receipt(txHash common.Hash) (
+	*rpcclient.RPCTransactionReceipt, error) {
+	receipt, err := source.client.TransactionReceiptWithBlockNumber(context.TODO(), txHash)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if receipt == nil {
+		return nil, errp.Newf("receipt of %s not found", txHash.Hex())
+	}
+	return receipt, nil
+}
+
+// setFee sets the gas used and the fee of the transfer from the receipt of its transaction.
+func setFee(transfer *ethtypes.Transfer, receipt *rpcclient.RPCTransactionReceipt) {
+	transfer.GasUsed = receipt.GasUsed
+	if receipt.EffectiveGasPrice != nil {
+		transfer.Fee = new(big.Int).Mul(
+			new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+	}
+}
+
+// sortTransfers sorts the transfers ascending by height and index.
+func sortTransfers(transfers []*ethtypes.Transfer) {
+	sort.SliceStable(transfers, func(i, j int) bool {
+		if transfers[i].BlockNumber != transfers[j].BlockNumber {
+			return transfers[i].BlockNumber < transfers[j].BlockNumber
+		}
+		return transfers[i].Index < transfers[j].Index
+	})
+}
+
+// transactionsData converts the transfers to the tx data to be shown to the user. If a transaction
+// contains multiple transfers of the account, each is shown with a distinct internal ID.
+func transactionsData(
+	blockTipHeight *big.Int,
+	address common.Address,
+	transfers []*ethtypes.Transfer) []*accounts.TransactionData {
+	transfersPerTx := map[common.Hash]int{}
+	for _, transfer := range transfers {
+		transfersPerTx[transfer.TxHash]++
+	}
+	result := make([]*accounts.TransactionData, len(transfers))
+	for i, transfer := range transfers {
+		internalID := transfer.TxHash.Hex()
+		if transfersPerTx[transfer.TxHash] > 1 {
+			internalID = fmt.Sprintf("%s-%d", internalID, transfer.Index)
+		}
+		result[i] = transactionData(blockTipHeight, address, transfer, internalID)
+	}
+	return result
+}
+
+func transactionData(
+	blockTipHeight *big.Int,
+	address common.Address,
+	transfer *ethtypes.Transfer,
+	internalID string) *accounts.TransactionData {
+	var txType accounts.TxType
+	switch {
+	case transfer.From == address && transfer.To == address:
+		txType = accounts.TxTypeSendSelf
+	case transfer.From == address:
+		txType = accounts.TxTypeSend
+	default:
+		txType = accounts.TxTypeReceive
+	}
+	numConfirmations := 0
+	if tipHeight := blockTipHeight.Uint64(); tipHeight >= transfer.BlockNumber {
+		numConfirmations = int(tipHeight - transfer.BlockNumber + 1)
+	}
+	var status accounts.TxStatus
+	switch {
+	case transfer.Failed:
+		status = accounts.TxStatusFailed
+	case numConfirmations >= ethtypes.NumConfirmationsComplete:
+		status = accounts.TxStatusComplete
+	default:
+		status = accounts.TxStatusPending
+	}
+	var fee *coin.Amount
+	if transfer.Fee != nil {
+		amount := coin.NewAmount(transfer.Fee)
+		fee = &amount
+	}
+	timestamp := time.Unix(int64(transfer.Timestamp), 0)
+	amount := coin.NewAmount(transfer.Value)
+	return &accounts.TransactionData{
+		Fee:                      fee,
+		Timestamp:                &timestamp,
+		TxID:                     transfer.TxHash.Hex(),
+		InternalID:               internalID,
+		Height:                   int(transfer.BlockNumber),
+		NumConfirmations:         numConfirmations,
+		NumConfirmationsComplete: ethtypes.NumConfirmationsComplete,
+		Status:                   status,
+		Type:                     txType,
+		Amount:                   amount,
+		Addresses: []accounts.AddressAndAmount{{
+			Address: transfer.To.Hex(),
+			Amount:  amount,
+		}},
+		Gas: transfer.GasUsed,
+	}
+}
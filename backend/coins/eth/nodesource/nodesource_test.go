@@ -0,0 +1,520 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodesource
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/db"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	rpcclientMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ours     = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	other    = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	contract = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+// transferEventID is the topic of the ERC20 Transfer event.
+var transferEventID = common.HexToHash(
+	"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// matchesTopic returns true if the topic is one of the queried topics. An empty query matches all.
+func matchesTopic(queried []common.Hash, topic common.Hash) bool {
+	for _, queriedTopic := range queried {
+		if queriedTopic == topic {
+			return true
+		}
+	}
+	return len(queried) == 0
+}
+
+func TestTransactionsData(t *testing.T) {
+	txHash := common.HexToHash("0x01")
+	transfers := []*ethtypes.Transfer{
+		{
+			TxHash:      common.HexToHash("0x02"),
+			Index:       3,
+			BlockNumber: 100,
+			Timestamp:   1600000000,
+			From:        other,
+			To:          ours,
+			Value:       big.NewInt(50),
+		},
+		{
+			TxHash:      txHash,
+			Index:       1,
+			BlockNumber: 105,
+			Timestamp:   1600000100,
+			From:        ours,
+			To:          other,
+			Value:       big.NewInt(20),
+			GasUsed:     50000,
+			Fee:         big.NewInt(1000000),
+		},
+		{
+			TxHash:      txHash,
+			Index:       2,
+			BlockNumber: 105,
+			Timestamp:   1600000100,
+			From:        ours,
+			To:          ours,
+			Value:       big.NewInt(5),
+			GasUsed:     50000,
+			Fee:         big.NewInt(1000000),
+		},
+		{
+			TxHash:      common.HexToHash("0x03"),
+			BlockNumber: 106,
+			Timestamp:   1600000200,
+			From:        ours,
+			To:          other,
+			Value:       big.NewInt(7),
+			GasUsed:     21000,
+			Fee:         big.NewInt(21000),
+			Failed:      true,
+		},
+	}
+	txs := transactionsData(big.NewInt(111), ours, transfers)
+	require.Len(t, txs, 4)
+
+	require.Equal(t, accounts.TxTypeReceive, txs[0].Type)
+	require.Equal(t, transfers[0].TxHash.Hex(), txs[0].InternalID)
+	require.Equal(t, 12, txs[0].NumConfirmations)
+	require.Equal(t, accounts.TxStatusComplete, txs[0].Status)
+	require.Nil(t, txs[0].Fee)
+	require.Equal(t, int64(1600000000), txs[0].Timestamp.Unix())
+	require.Equal(t, "50", txs[0].Amount.BigInt().String())
+	require.Equal(t, ours.Hex(), txs[0].Addresses[0].Address)
+
+	// Multiple transfers in the same transaction have distinct internal IDs.
+	require.Equal(t, accounts.TxTypeSend, txs[1].Type)
+	require.Equal(t, txHash.Hex(), txs[1].TxID)
+	require.Equal(t, txHash.Hex()+"-1", txs[1].InternalID)
+	require.Equal(t, 7, txs[1].NumConfirmations)
+	require.Equal(t, accounts.TxStatusPending, txs[1].Status)
+	require.Equal(t, "1000000", txs[1].Fee.BigInt().String())
+	require.Equal(t, uint64(50000), txs[1].Gas)
+	require.Equal(t, other.Hex(), txs[1].Addresses[0].Address)
+
+	require.Equal(t, accounts.TxTypeSendSelf, txs[2].Type)
+	require.Equal(t, txHash.Hex()+"-2", txs[2].InternalID)
+
+	// A failed transaction only paid the fee.
+	require.Equal(t, accounts.TxStatusFailed, txs[3].Status)
+	require.Equal(t, transfers[3].TxHash.Hex(), txs[3].InternalID)
+	require.Equal(t, "21000", txs[3].Fee.BigInt().String())
+}
+
+func TestERC20TransfersFee(t *testing.T) {
+	type transferTx struct {
+		from, to, sender common.Address
+		expectFee        bool
+	}
+	txs := []transferTx{
+		// We send tokens.
+		{from: ours, to: other, sender: ours, expectFee: true},
+		// The tokens are sent by someone else using our approval (transferFrom).
+		{from: ours, to: other, sender: other, expectFee: false},
+		// We call a contract which sends us tokens.
+		{from: other, to: ours, sender: ours, expectFee: true},
+		// We receive tokens.
+		{from: other, to: ours, sender: other, expectFee: false},
+	}
+	logs := []types.Log{}
+	receipts := map[common.Hash]*rpcclient.RPCTransactionReceipt{}
+	for i, tx := range txs {
+		txHash := common.BigToHash(big.NewInt(int64(i + 1)))
+		logs = append(logs, types.Log{
+			Topics: []common.Hash{
+				transferEventID, common.BytesToHash(tx.from[:]), common.BytesToHash(tx.to[:]),
+			},
+			Data:        common.LeftPadBytes(big.NewInt(int64(100+i)).Bytes(), 32),
+			BlockNumber: uint64(10 + i),
+			TxHash:      txHash,
+			Index:       uint(i),
+		})
+		receipts[txHash] = &rpcclient.RPCTransactionReceipt{
+			Receipt:           types.Receipt{GasUsed: 50000},
+			BlockNumber:       uint64(10 + i),
+			From:              tx.sender,
+			EffectiveGasPrice: big.NewInt(20),
+		}
+	}
+	client := &rpcclientMocks.InterfaceMock{
+		FilterLogsFunc: func(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+			result := []types.Log{}
+			for _, log := range logs {
+				if matchesTopic(query.Topics[1], log.Topics[1]) &&
+					matchesTopic(query.Topics[2], log.Topics[2]) {
+					result = append(result, log)
+				}
+			}
+			return result, nil
+		},
+		HeaderByNumberFunc: func(ctx context.Context, number *big.Int) (*types.Header, error) {
+			return &types.Header{Number: number, Time: 1600000000 + number.Uint64()}, nil
+		},
+		TransactionReceiptWithBlockNumberFunc: func(
+			ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+			return receipts[hash], nil
+		},
+	}
+	source := NewTransactionsSource(client)
+	filterer, err := erc20.NewIERC20Filterer(common.Address{}, client)
+	require.NoError(t, err)
+	transfers, err := source.erc20Transfers(filterer, ours, 0, 100)
+	require.NoError(t, err)
+	require.Len(t, transfers, len(txs))
+	sortTransfers(transfers)
+	for i, tx := range txs {
+		transfer := transfers[i]
+		require.Equal(t, tx.from, transfer.From)
+		require.Equal(t, tx.to, transfer.To)
+		require.Equal(t, big.NewInt(int64(100+i)), transfer.Value)
+		require.Equal(t, uint64(1600000000+10+i), transfer.Timestamp)
+		if tx.expectFee {
+			require.Equal(t, big.NewInt(50000*20), transfer.Fee, i)
+			require.Equal(t, uint64(50000), transfer.GasUsed)
+		} else {
+			require.Nil(t, transfer.Fee, i)
+			require.Zero(t, transfer.GasUsed)
+		}
+	}
+}
+
+// testTrace describes a trace as returned by trace_filter.
+type testTrace struct {
+	traceType    string
+	blockNumber  uint64
+	position     uint
+	txHash       common.Hash
+	traceAddress []uint
+	from         common.Address
+	to           *common.Address
+	created      *common.Address
+	value        int64
+	err          string
+}
+
+// decode returns the trace decoded from the JSON response of the node.
+func (testTrace testTrace) decode(t *testing.T) *rpcclient.Trace {
+	t.Helper()
+	action := map[string]interface{}{
+		"from":  testTrace.from,
+		"value": hexutil.EncodeBig(big.NewInt(testTrace.value)),
+	}
+	if testTrace.to != nil {
+		action["to"] = testTrace.to
+	}
+	encoded := map[string]interface{}{
+		"type":                testTrace.traceType,
+		"blockNumber":         testTrace.blockNumber,
+		"transactionHash":     testTrace.txHash,
+		"transactionPosition": testTrace.position,
+		"traceAddress":        append([]uint{}, testTrace.traceAddress...),
+		"action":              action,
+	}
+	if testTrace.created != nil {
+		encoded["result"] = map[string]interface{}{"address": testTrace.created}
+	}
+	if testTrace.err != "" {
+		encoded["error"] = testTrace.err
+	}
+	serialized, err := json.Marshal(encoded)
+	require.NoError(t, err)
+	trace := new(rpcclient.Trace)
+	require.NoError(t, json.Unmarshal(serialized, trace))
+	return trace
+}
+
+// testNode is a node returning the given traces using trace_filter. The timestamp of a block is
+// 1600000000 plus its number. All transactions paid a fee of 21000*10.
+type testNode struct {
+	rpcclientMocks.InterfaceMock
+	traces []*rpcclient.Trace
+	// traceFilterCalls are the block ranges queried using trace_filter.
+	traceFilterCalls [][2]uint64
+}
+
+func newTestNode(t *testing.T, traces ...testTrace) *testNode {
+	t.Helper()
+	node := &testNode{}
+	for _, trace := range traces {
+		node.traces = append(node.traces, trace.decode(t))
+	}
+	node.TraceFilterFunc = node.traceFilter
+	node.HeaderByNumberFunc = func(ctx context.Context, number *big.Int) (*types.Header, error) {
+		return &types.Header{Number: number, Time: 1600000000 + number.Uint64()}, nil
+	}
+	node.TransactionReceiptWithBlockNumberFunc = func(
+		ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+		return &rpcclient.RPCTransactionReceipt{
+			Receipt:           types.Receipt{GasUsed: 21000},
+			EffectiveGasPrice: big.NewInt(10),
+		}, nil
+	}
+	return node
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, candidate := range addresses {
+		if candidate == address {
+			return true
+		}
+	}
+	return false
+}
+
+func (node *testNode) traceFilter(ctx context.Context, fromBlock, toBlock uint64,
+	fromAddresses, toAddresses []common.Address) ([]*rpcclient.Trace, error) {
+	node.traceFilterCalls = append(node.traceFilterCalls, [2]uint64{fromBlock, toBlock})
+	result := []*rpcclient.Trace{}
+	for _, trace := range node.traces {
+		if trace.BlockNumber < fromBlock || trace.BlockNumber > toBlock {
+			continue
+		}
+		if containsAddress(fromAddresses, trace.Action.From) ||
+			(trace.Action.To != nil && containsAddress(toAddresses, *trace.Action.To)) {
+			result = append(result, trace)
+		}
+	}
+	return result, nil
+}
+
+func TestTracedETHTransfers(t *testing.T) {
+	txHash := func(i int64) common.Hash { return common.BigToHash(big.NewInt(i)) }
+	node := newTestNode(t,
+		// Returned by the node in a different order than executed.
+		testTrace{traceType: "call", blockNumber: 11, position: 1, txHash: txHash(2),
+			traceAddress: []uint{3}, from: contract, to: &ours, value: 4},
+		// We send ether.
+		testTrace{traceType: "call", blockNumber: 10, txHash: txHash(1),
+			from: ours, to: &other, value: 5},
+		// A contract called by someone else sends us ether in internal transactions. Failed internal
+		// calls and internal calls without value are skipped.
+		testTrace{traceType: "call", blockNumber: 11, position: 1, txHash: txHash(2),
+			traceAddress: []uint{0}, from: contract, to: &ours, value: 7},
+		testTrace{traceType: "call", blockNumber: 11, position: 1, txHash: txHash(2),
+			traceAddress: []uint{1}, from: contract, to: &ours, value: 3, err: "Reverted"},
+		testTrace{traceType: "call", blockNumber: 11, position: 1, txHash: txHash(2),
+			traceAddress: []uint{2}, from: contract, to: &ours},
+		// We create a contract.
+		testTrace{traceType: "create", blockNumber: 12, txHash: txHash(3),
+			from: ours, created: &contract, value: 1},
+		// A failed transaction sent to us by someone else.
+		testTrace{traceType: "call", blockNumber: 13, txHash: txHash(4),
+			from: other, to: &ours, value: 2, err: "Out of gas"},
+		// A transaction to self is found by both queries.
+		testTrace{traceType: "call", blockNumber: 14, txHash: txHash(5),
+			from: ours, to: &ours, value: 6},
+		// Other trace types are skipped.
+		testTrace{traceType: "suicide", blockNumber: 15, txHash: txHash(6),
+			from: contract, to: &ours, value: 8},
+	)
+	source := NewTransactionsSource(node)
+	transfers, err := source.tracedETHTransfers(ours, 0, 100)
+	require.NoError(t, err)
+
+	fee := big.NewInt(21000 * 10)
+	expected := []*ethtypes.Transfer{
+		{TxHash: txHash(1), BlockNumber: 10, From: ours, To: other, Value: big.NewInt(5),
+			GasUsed: 21000, Fee: fee},
+		{TxHash: txHash(2), Index: 1, BlockNumber: 11, From: contract, To: ours, Value: big.NewInt(7)},
+		{TxHash: txHash(2), Index: 2, BlockNumber: 11, From: contract, To: ours, Value: big.NewInt(4)},
+		{TxHash: txHash(3), BlockNumber: 12, From: ours, To: contract, Value: big.NewInt(1),
+			GasUsed: 21000, Fee: fee},
+		{TxHash: txHash(4), BlockNumber: 13, From: other, To: ours, Value: big.NewInt(2),
+			Failed: true},
+		{TxHash: txHash(5), BlockNumber: 14, From: ours, To: ours, Value: big.NewInt(6),
+			GasUsed: 21000, Fee: fee},
+	}
+	for _, transfer := range expected {
+		transfer.Timestamp = 1600000000 + transfer.BlockNumber
+	}
+	require.Equal(t, expected, transfers)
+}
+
+func TestScanPaged(t *testing.T) {
+	source := NewTransactionsSource(&rpcclientMocks.InterfaceMock{})
+	// The node fails queries of more than 10 blocks.
+	fetched := [][2]uint64{}
+	fetch := func(from, to uint64) ([]*ethtypes.Transfer, error) {
+		if to-from+1 > 10 {
+			return nil, errp.New("query returned more than 10000 results")
+		}
+		fetched = append(fetched, [2]uint64{from, to})
+		return []*ethtypes.Transfer{{BlockNumber: to}, {BlockNumber: from}}, nil
+	}
+	heights := []uint64{}
+	onPage := func(transfers []*ethtypes.Transfer, height uint64) error {
+		require.Equal(t, []*ethtypes.Transfer{{BlockNumber: fetched[len(fetched)-1][0]},
+			{BlockNumber: height}}, transfers)
+		heights = append(heights, height)
+		return nil
+	}
+	require.NoError(t, source.scanPaged(5, 39, 40, fetch, onPage))
+	// The range is halved until the query succeeds, and stays reduced.
+	require.Equal(t, [][2]uint64{{5, 14}, {15, 24}, {25, 34}, {35, 39}}, fetched)
+	require.Equal(t, []uint64{14, 24, 34, 39}, heights)
+
+	// The range is not reduced below one block.
+	fetchError := errp.New("failure")
+	err := source.scanPaged(0, 10, 4,
+		func(from, to uint64) ([]*ethtypes.Transfer, error) { return nil, fetchError }, onPage)
+	require.Equal(t, fetchError, errp.Cause(err))
+
+	// The range is not reduced if the method is not supported.
+	calls := 0
+	err = source.scanPaged(0, 10, 4,
+		func(from, to uint64) ([]*ethtypes.Transfer, error) {
+			calls++
+			return nil, errp.WithStack(rpcclient.ErrMethodNotFound)
+		}, onPage)
+	require.True(t, rpcclient.IsMethodNotFound(err))
+	require.Equal(t, 1, calls)
+}
+
+func TestTransactionsWithDB(t *testing.T) {
+	txHash := func(i int64) common.Hash { return common.BigToHash(big.NewInt(i)) }
+	node := newTestNode(t,
+		testTrace{traceType: "call", blockNumber: 60, txHash: txHash(1),
+			from: other, to: &ours, value: 1},
+		testTrace{traceType: "call", blockNumber: 120, txHash: txHash(2),
+			from: other, to: &ours, value: 2},
+		testTrace{traceType: "call", blockNumber: 145, txHash: txHash(3),
+			from: ours, to: &other, value: 3},
+	)
+	source := NewTransactionsSource(node)
+	accountDB, err := db.NewDB(test.TstTempFile("eth-db"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, accountDB.Close()) }()
+
+	transactions := func(birthHeight uint64, endBlock int64) []uint64 {
+		node.traceFilterCalls = nil
+		txs, err := source.TransactionsWithDB(
+			accountDB, birthHeight, big.NewInt(endBlock), ours, big.NewInt(endBlock), nil)
+		require.NoError(t, err)
+		heights := []uint64{}
+		for _, tx := range txs {
+			heights = append(heights, uint64(tx.Height))
+		}
+		return heights
+	}
+
+	// The blocks before the birth height are not scanned.
+	require.Equal(t, []uint64{120, 145}, transactions(100, 150))
+	require.Equal(t, [][2]uint64{{100, 150}, {100, 150}}, node.traceFilterCalls)
+
+	// The transaction in block 145 is reorganized into block 152. The most recent blocks are
+	// scanned again, and the transfers stored for them replaced.
+	node.traces[2].BlockNumber = 152
+	node.traces = append(node.traces, testTrace{traceType: "call", blockNumber: 160,
+		txHash: txHash(4), from: other, to: &ours, value: 4}.decode(t))
+	require.Equal(t, []uint64{120, 152, 160}, transactions(100, 200))
+	resumeFrom := uint64(150 + 1 - ethtypes.NumConfirmationsComplete)
+	require.Equal(t, [][2]uint64{{resumeFrom, 200}, {resumeFrom, 200}}, node.traceFilterCalls)
+
+	// Nothing new.
+	require.Equal(t, []uint64{120, 152, 160}, transactions(100, 200))
+
+	// Lowering the birth height rescans all blocks.
+	require.Equal(t, []uint64{60, 120, 152, 160}, transactions(50, 200))
+	require.Equal(t, [][2]uint64{{50, 200}, {50, 200}}, node.traceFilterCalls)
+
+	// Raising the birth height only scans the new blocks.
+	require.Equal(t, []uint64{60, 120, 152, 160}, transactions(100, 210))
+	resumeFrom = uint64(200 + 1 - ethtypes.NumConfirmationsComplete)
+	require.Equal(t, [][2]uint64{{resumeFrom, 210}, {resumeFrom, 210}}, node.traceFilterCalls)
+}
+
+func TestBlockScanFallback(t *testing.T) {
+	txHash := func(i int64) common.Hash { return common.BigToHash(big.NewInt(i)) }
+	traceFilterCalls := 0
+	blocksFetched := []uint64{}
+	client := &rpcclientMocks.InterfaceMock{
+		TraceFilterFunc: func(ctx context.Context, fromBlock, toBlock uint64,
+			fromAddresses, toAddresses []common.Address) ([]*rpcclient.Trace, error) {
+			traceFilterCalls++
+			return nil, errp.WithStack(rpcclient.ErrMethodNotFound)
+		},
+		BlockTransactionsFunc: func(ctx context.Context, number uint64) (*rpcclient.Block, error) {
+			blocksFetched = append(blocksFetched, number)
+			block := &rpcclient.Block{
+				Number:    hexutil.Uint64(number),
+				Timestamp: hexutil.Uint64(1600000000 + number),
+			}
+			switch number {
+			case 12:
+				block.Transactions = []*rpcclient.BlockTransaction{
+					{Hash: txHash(1), From: other, To: &contract, Value: (*hexutil.Big)(big.NewInt(1))},
+					{Hash: txHash(2), From: other, To: &ours, Value: (*hexutil.Big)(big.NewInt(2))},
+				}
+			case 15:
+				block.Transactions = []*rpcclient.BlockTransaction{
+					{Hash: txHash(3), From: ours, To: &other, Value: (*hexutil.Big)(big.NewInt(3))},
+				}
+			}
+			return block, nil
+		},
+		TransactionReceiptWithBlockNumberFunc: func(
+			ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+			return &rpcclient.RPCTransactionReceipt{
+				Receipt:           types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: 21000},
+				EffectiveGasPrice: big.NewInt(10),
+			}, nil
+		},
+	}
+	source := NewTransactionsSource(client)
+	accountDB, err := db.NewDB(test.TstTempFile("eth-db"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, accountDB.Close()) }()
+
+	// Without a birth height, the blocks are not scanned from the genesis block.
+	_, err = source.TransactionsWithDB(accountDB, 0, big.NewInt(20), ours, big.NewInt(20), nil)
+	require.Equal(t, ErrBirthHeightRequired, errp.Cause(err))
+	_, err = source.Transactions(big.NewInt(20), ours, big.NewInt(20), nil)
+	require.Equal(t, ErrBirthHeightRequired, errp.Cause(err))
+	require.Empty(t, blocksFetched)
+	// trace_filter is not tried again once it is known to be unsupported.
+	require.Equal(t, 1, traceFilterCalls)
+
+	txs, err := source.TransactionsWithDB(accountDB, 10, big.NewInt(20), ours, big.NewInt(20), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, traceFilterCalls)
+	require.Equal(t, []uint64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, blocksFetched)
+	require.Len(t, txs, 2)
+	require.Equal(t, txHash(2).Hex(), txs[0].TxID)
+	require.Equal(t, accounts.TxTypeReceive, txs[0].Type)
+	require.Nil(t, txs[0].Fee)
+	require.Equal(t, txHash(3).Hex(), txs[1].TxID)
+	require.Equal(t, accounts.TxTypeSend, txs[1].Type)
+	require.Equal(t, "210000", txs[1].Fee.BigInt().String())
+	require.Equal(t, 15, txs[1].Height)
+}
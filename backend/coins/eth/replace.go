@@ -0,0 +1,234 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// bumpGasPrice returns the gas price increased by 10%, rounded up. Nodes only accept a replacement
+// of a pending transaction if the gas price (for EIP-1559 transactions, both the max priority fee
+// and the max fee) is at least 10% higher.
+func bumpGasPrice(gasPrice *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(gasPrice, big.NewInt(11))
+	bumped.Add(bumped, big.NewInt(9))
+	return bumped.Quo(bumped, big.NewInt(10))
+}
+
+// maxBigInt returns the larger of the two numbers.
+func maxBigInt(x, y *big.Int) *big.Int {
+	if x.Cmp(y) >= 0 {
+		return new(big.Int).Set(x)
+	}
+	return new(big.Int).Set(y)
+}
+
+// pendingOutgoingTransaction returns the stored outgoing transaction with the given ID and all
+// pending outgoing transactions with the same nonce, including itself, e.g. earlier replacements.
+// An error is returned if it is unknown or already confirmed.
+func (account *Account) pendingOutgoingTransaction(txID string) (
+	*ethtypes.TransactionWithMetadata, []*ethtypes.TransactionWithMetadata, error) {
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dbTx.Rollback()
+	outgoingTransactions, err := dbTx.OutgoingTransactions()
+	if err != nil {
+		return nil, nil, err
+	}
+	var pendingTx *ethtypes.TransactionWithMetadata
+	for _, tx := range outgoingTransactions {
+		if tx.TxID() != txID {
+			continue
+		}
+		if tx.Height != 0 {
+			return nil, nil, errp.New("The transaction is already confirmed")
+		}
+		pendingTx = tx
+	}
+	if pendingTx == nil {
+		return nil, nil, errp.New("Unknown pending transaction")
+	}
+	sameNonce := []*ethtypes.TransactionWithMetadata{}
+	for _, tx := range outgoingTransactions {
+		if tx.Height == 0 && tx.Transaction.Nonce() == pendingTx.Transaction.Nonce() {
+			sameNonce = append(sameNonce, tx)
+		}
+	}
+	return pendingTx, sameNonce, nil
+}
+
+// replaceTx signs and broadcasts a transaction with the same nonce as the given pending outgoing
+// transaction, paying the fees of the given fee target, but at least 10% more than the original.
+// If cancel is false, the replacement has the same recipient, value and data as the original. If
+// cancel is true, it sends zero ether to our own address. Both are kept in the database until one
+// of them is mined, see updateOutgoingTransactions. The ID of the replacement is returned.
+func (account *Account) replaceTx(
+	txID string, feeTargetCode accounts.FeeTargetCode, cancel bool) (string, error) {
+	if !account.initialized {
+		return "", errp.New("account must be initialized")
+	}
+	original, sameNonce, err := account.pendingOutgoingTransaction(txID)
+	if err != nil {
+		return "", err
+	}
+	originalTx := original.Transaction
+	if originalTx.To() == nil {
+		return "", errp.New("contract creation not supported")
+	}
+	recipient, value, data, gasLimit :=
+		*originalTx.To(), originalTx.Value(), originalTx.Data(), originalTx.Gas()
+	if cancel {
+		recipient, value, data, gasLimit = account.address.Address, big.NewInt(0), nil, params.TxGas
+	}
+
+	feeTarget, err := account.feeTarget(feeTargetCode)
+	if err != nil {
+		return "", err
+	}
+	// The highest max priority fee and max fee of the pending transactions with this nonce, which
+	// the replacement needs to beat. Both are the gas price for legacy transactions.
+	originalGasTipCap, originalGasFeeCap := big.NewInt(0), big.NewInt(0)
+	for _, tx := range sameNonce {
+		gasTipCap := tx.Transaction.GasPrice()
+		if dynamicFeeTx, ok := tx.Transaction.(*ethtypes.DynamicFeeTx); ok {
+			gasTipCap = dynamicFeeTx.GasTipCap()
+		}
+		originalGasTipCap = maxBigInt(originalGasTipCap, gasTipCap)
+		originalGasFeeCap = maxBigInt(originalGasFeeCap, tx.Transaction.GasPrice())
+	}
+
+	var tx ethtypes.Transaction
+	var fee, maxFee *big.Int
+	gas := new(big.Int).SetUint64(gasLimit)
+	if feeTarget != nil && account.useEIP1559() {
+		gasTipCap := maxBigInt(feeTarget.gasTipCap, bumpGasPrice(originalGasTipCap))
+		gasFeeCap := maxBigInt(
+			new(big.Int).Add(feeTarget.gasFeeCap, new(big.Int).Sub(gasTipCap, feeTarget.gasTipCap)),
+			bumpGasPrice(originalGasFeeCap))
+		tx = ethtypes.NewDynamicFeeTx(account.coin.Net().ChainID, originalTx.Nonce(),
+			recipient, value, gasLimit, gasTipCap, gasFeeCap, data)
+		expectedGasPrice := new(big.Int).Add(feeTarget.baseFee, gasTipCap)
+		if expectedGasPrice.Cmp(gasFeeCap) > 0 {
+			expectedGasPrice = gasFeeCap
+		}
+		fee = new(big.Int).Mul(gas, expectedGasPrice)
+		maxFee = new(big.Int).Mul(gas, gasFeeCap)
+	} else {
+		var gasPrice *big.Int
+		if feeTarget != nil {
+			gasPrice = feeTarget.gasPrice()
+		} else {
+			gasPrice, err = account.coin.client.SuggestGasPrice(context.TODO())
+			if err != nil {
+				return "", err
+			}
+		}
+		// A legacy replacement of an EIP-1559 transaction needs to beat its max fee.
+		gasPrice = maxBigInt(gasPrice, bumpGasPrice(originalGasFeeCap))
+		tx = types.NewTransaction(originalTx.Nonce(), recipient, value, gasLimit, gasPrice, data)
+		fee = new(big.Int).Mul(gas, gasPrice)
+		maxFee = fee
+	}
+	// The balance needs to cover the value and the max fee of the replacement. The fee is paid in
+	// ether, also for ERC20 transfers, whose value is zero.
+	balance, err := account.coin.client.BalanceAt(context.TODO(), account.address.Address, nil)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	if new(big.Int).Add(tx.Value(), maxFee).Cmp(balance) > 0 {
+		return "", errp.WithStack(errors.ErrInsufficientFunds)
+	}
+	txProposal := &TxProposal{
+		Coin:    account.coin,
+		Tx:      tx,
+		Fee:     fee,
+		MaxFee:  maxFee,
+		Value:   value,
+		Signer:  types.MakeSigner(account.coin.Net(), account.blockNumber),
+		Keypath: account.signingConfiguration.AbsoluteKeypath(),
+	}
+
+	account.log.WithField("replaces", txID).Info("Signing and sending replacement transaction")
+	if err := account.Config().Keystores.SignTransaction(txProposal); err != nil {
+		return "", err
+	}
+	rawTx, err := ethtypes.EncodeTransaction(txProposal.Tx)
+	if err != nil {
+		return "", err
+	}
+	if err := account.coin.client.SendRawTransaction(context.TODO(), rawTx); err != nil {
+		return "", errp.WithStack(err)
+	}
+	replacementTxID := txProposal.Tx.Hash().Hex()
+	if err := account.storeReplacementTransaction(original, txProposal.Tx); err != nil {
+		return "", err
+	}
+	if note := account.Notes().TxNote(txID); note != "" {
+		if err := account.SetTxNote(replacementTxID, note); err != nil {
+			// Not critical.
+			account.log.WithError(err).Error("Failed to copy the transaction note to the replacement")
+		}
+	}
+	account.enqueueUpdateCh <- struct{}{}
+	return replacementTxID, nil
+}
+
+// storeReplacementTransaction stores the replacement of a pending outgoing transaction in the db.
+// The original is kept, as it can still be mined instead of the replacement.
+func (account *Account) storeReplacementTransaction(
+	original *ethtypes.TransactionWithMetadata, replacement ethtypes.Transaction) error {
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	replaces := original.Transaction.Hash()
+	if err := dbTx.PutOutgoingTransaction(
+		&ethtypes.TransactionWithMetadata{
+			Transaction: replacement,
+			Height:      0,
+			Replaces:    &replaces,
+		}); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return err
+	}
+	account.log.Infof("stored replacement of pending outgoing tx with nonce: %d", replacement.Nonce())
+	return nil
+}
+
+// SpeedUpTx replaces a pending outgoing transaction with the same transaction paying a higher fee,
+// using the fees of the given fee target. The ID of the replacement is returned.
+func (account *Account) SpeedUpTx(txID string, feeTargetCode accounts.FeeTargetCode) (string, error) {
+	return account.replaceTx(txID, feeTargetCode, false)
+}
+
+// CancelTx replaces a pending outgoing transaction with a transaction sending zero ether to our
+// own address, paying a higher fee using the fees of the given fee target. If the replacement
+// confirms first, the original transaction is dropped. The ID of the replacement is returned.
+func (account *Account) CancelTx(txID string, feeTargetCode accounts.FeeTargetCode) (string, error) {
+	return account.replaceTx(txID, feeTargetCode, true)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpGasPrice(t *testing.T) {
+	require.Equal(t, big.NewInt(11000000000), bumpGasPrice(big.NewInt(10000000000)))
+	// Rounded up.
+	require.Equal(t, big.NewInt(2), bumpGasPrice(big.NewInt(1)))
+	require.Equal(t, big.NewInt(14), bumpGasPrice(big.NewInt(12)))
+	require.Zero(t, bumpGasPrice(big.NewInt(0)).Sign())
+}
+
+func TestDroppedOutgoingTransactions(t *testing.T) {
+	newTx := func(nonce uint64, gasPrice int64, height uint64) *ethtypes.TransactionWithMetadata {
+		return &ethtypes.TransactionWithMetadata{
+			Transaction: types.NewTransaction(
+				nonce, common.Address{}, big.NewInt(0), 21000, big.NewInt(gasPrice), nil),
+			Height: height,
+		}
+	}
+	// Nonce 1: the replacement was mined. Nonce 2: the original was mined. Nonce 3: both pending.
+	original1, replacement1 := newTx(1, 10, 0), newTx(1, 11, 100)
+	original2, replacement2 := newTx(2, 10, 101), newTx(2, 11, 0)
+	original3, replacement3 := newTx(3, 10, 0), newTx(3, 11, 0)
+	dropped := droppedOutgoingTransactions([]*ethtypes.TransactionWithMetadata{
+		original3, replacement3, original2, replacement2, original1, replacement1,
+	})
+	require.Equal(t, []*ethtypes.TransactionWithMetadata{replacement2, original1}, dropped)
+	require.Empty(t, droppedOutgoingTransactions([]*ethtypes.TransactionWithMetadata{
+		original3, replacement3,
+	}))
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Ensure, that InterfaceMock does implement rpcclient.Interface.
+var _ rpcclient.Interface = &InterfaceMock{}
+
+// InterfaceMock is a mock implementation of rpcclient.Interface. Calling a method whose function
+// is not set panics.
+type InterfaceMock struct {
+	// TransactionReceiptWithBlockNumberFunc mocks the TransactionReceiptWithBlockNumber method.
+	TransactionReceiptWithBlockNumberFunc func(ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error)
+
+	// HeaderByNumberFunc mocks the HeaderByNumber method.
+	HeaderByNumberFunc func(ctx context.Context, number *big.Int) (*types.Header, error)
+
+	// BalanceAtFunc mocks the BalanceAt method.
+	BalanceAtFunc func(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+
+	// FeeHistoryFunc mocks the FeeHistory method.
+	FeeHistoryFunc func(ctx context.Context, blockCount uint64, rewardPercentiles []float64) (*rpcclient.FeeHistory, error)
+
+	// SendRawTransactionFunc mocks the SendRawTransaction method.
+	SendRawTransactionFunc func(ctx context.Context, rawTx []byte) error
+
+	// TraceFilterFunc mocks the TraceFilter method.
+	TraceFilterFunc func(ctx context.Context, fromBlock uint64, toBlock uint64, fromAddresses []common.Address, toAddresses []common.Address) ([]*rpcclient.Trace, error)
+
+	// BlockTransactionsFunc mocks the BlockTransactions method.
+	BlockTransactionsFunc func(ctx context.Context, number uint64) (*rpcclient.Block, error)
+
+	// CodeAtFunc mocks the CodeAt method.
+	CodeAtFunc func(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error)
+
+	// CallContractFunc mocks the CallContract method.
+	CallContractFunc func(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+
+	// PendingCodeAtFunc mocks the PendingCodeAt method.
+	PendingCodeAtFunc func(ctx context.Context, account common.Address) ([]byte, error)
+
+	// PendingNonceAtFunc mocks the PendingNonceAt method.
+	PendingNonceAtFunc func(ctx context.Context, account common.Address) (uint64, error)
+
+	// SuggestGasPriceFunc mocks the SuggestGasPrice method.
+	SuggestGasPriceFunc func(ctx context.Context) (*big.Int, error)
+
+	// EstimateGasFunc mocks the EstimateGas method.
+	EstimateGasFunc func(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+
+	// SendTransactionFunc mocks the SendTransaction method.
+	SendTransactionFunc func(ctx context.Context, tx *types.Transaction) error
+
+	// FilterLogsFunc mocks the FilterLogs method.
+	FilterLogsFunc func(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+
+	// SubscribeFilterLogsFunc mocks the SubscribeFilterLogs method.
+	SubscribeFilterLogsFunc func(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+}
+
+// TransactionReceiptWithBlockNumber calls TransactionReceiptWithBlockNumberFunc.
+func (mock *InterfaceMock) TransactionReceiptWithBlockNumber(ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+	if mock.TransactionReceiptWithBlockNumberFunc == nil {
+		panic("InterfaceMock.TransactionReceiptWithBlockNumberFunc: method is nil but Interface.TransactionReceiptWithBlockNumber was just called")
+	}
+	return mock.TransactionReceiptWithBlockNumberFunc(ctx, hash)
+}
+
+// HeaderByNumber calls HeaderByNumberFunc.
+func (mock *InterfaceMock) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if mock.HeaderByNumberFunc == nil {
+		panic("InterfaceMock.HeaderByNumberFunc: method is nil but Interface.HeaderByNumber was just called")
+	}
+	return mock.HeaderByNumberFunc(ctx, number)
+}
+
+// BalanceAt calls BalanceAtFunc.
+func (mock *InterfaceMock) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if mock.BalanceAtFunc == nil {
+		panic("InterfaceMock.BalanceAtFunc: method is nil but Interface.BalanceAt was just called")
+	}
+	return mock.BalanceAtFunc(ctx, account, blockNumber)
+}
+
+// FeeHistory calls FeeHistoryFunc.
+func (mock *InterfaceMock) FeeHistory(ctx context.Context, blockCount uint64, rewardPercentiles []float64) (*rpcclient.FeeHistory, error) {
+	if mock.FeeHistoryFunc == nil {
+		panic("InterfaceMock.FeeHistoryFunc: method is nil but Interface.FeeHistory was just called")
+	}
+	return mock.FeeHistoryFunc(ctx, blockCount, rewardPercentiles)
+}
+
+// SendRawTransaction calls SendRawTransactionFunc.
+func (mock *InterfaceMock) SendRawTransaction(ctx context.Context, rawTx []byte) error {
+	if mock.SendRawTransactionFunc == nil {
+		panic("InterfaceMock.SendRawTransactionFunc: method is nil but Interface.SendRawTransaction was just called")
+	}
+	return mock.SendRawTransactionFunc(ctx, rawTx)
+}
+
+// TraceFilter calls TraceFilterFunc.
+func (mock *InterfaceMock) TraceFilter(ctx context.Context, fromBlock uint64, toBlock uint64, fromAddresses []common.Address, toAddresses []common.Address) ([]*rpcclient.Trace, error) {
+	if mock.TraceFilterFunc == nil {
+		panic("InterfaceMock.TraceFilterFunc: method is nil but Interface.TraceFilter was just called")
+	}
+	return mock.TraceFilterFunc(ctx, fromBlock, toBlock, fromAddresses, toAddresses)
+}
+
+// BlockTransactions calls BlockTransactionsFunc.
+func (mock *InterfaceMock) BlockTransactions(ctx context.Context, number uint64) (*rpcclient.Block, error) {
+	if mock.BlockTransactionsFunc == nil {
+		panic("InterfaceMock.BlockTransactionsFunc: method is nil but Interface.BlockTransactions was just called")
+	}
+	return mock.BlockTransactionsFunc(ctx, number)
+}
+
+// CodeAt calls CodeAtFunc.
+func (mock *InterfaceMock) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if mock.CodeAtFunc == nil {
+		panic("InterfaceMock.CodeAtFunc: method is nil but Interface.CodeAt was just called")
+	}
+	return mock.CodeAtFunc(ctx, contract, blockNumber)
+}
+
+// CallContract calls CallContractFunc.
+func (mock *InterfaceMock) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if mock.CallContractFunc == nil {
+		panic("InterfaceMock.CallContractFunc: method is nil but Interface.CallContract was just called")
+	}
+	return mock.CallContractFunc(ctx, call, blockNumber)
+}
+
+// PendingCodeAt calls PendingCodeAtFunc.
+func (mock *InterfaceMock) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	if mock.PendingCodeAtFunc == nil {
+		panic("InterfaceMock.PendingCodeAtFunc: method is nil but Interface.PendingCodeAt was just called")
+	}
+	return mock.PendingCodeAtFunc(ctx, account)
+}
+
+// PendingNonceAt calls PendingNonceAtFunc.
+func (mock *InterfaceMock) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if mock.PendingNonceAtFunc == nil {
+		panic("InterfaceMock.PendingNonceAtFunc: method is nil but Interface.PendingNonceAt was just called")
+	}
+	return mock.PendingNonceAtFunc(ctx, account)
+}
+
+// SuggestGasPrice calls SuggestGasPriceFunc.
+func (mock *InterfaceMock) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if mock.SuggestGasPriceFunc == nil {
+		panic("InterfaceMock.SuggestGasPriceFunc: method is nil but Interface.SuggestGasPrice was just called")
+	}
+	return mock.SuggestGasPriceFunc(ctx)
+}
+
+// EstimateGas calls EstimateGasFunc.
+func (mock *InterfaceMock) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	if mock.EstimateGasFunc == nil {
+		panic("InterfaceMock.EstimateGasFunc: method is nil but Interface.EstimateGas was just called")
+	}
+	return mock.EstimateGasFunc(ctx, call)
+}
+
+// SendTransaction calls SendTransactionFunc.
+func (mock *InterfaceMock) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if mock.SendTransactionFunc == nil {
+		panic("InterfaceMock.SendTransactionFunc: method is nil but Interface.SendTransaction was just called")
+	}
+	return mock.SendTransactionFunc(ctx, tx)
+}
+
+// FilterLogs calls FilterLogsFunc.
+func (mock *InterfaceMock) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if mock.FilterLogsFunc == nil {
+		panic("InterfaceMock.FilterLogsFunc: method is nil but Interface.FilterLogs was just called")
+	}
+	return mock.FilterLogsFunc(ctx, query)
+}
+
+// SubscribeFilterLogs calls SubscribeFilterLogsFunc.
+func (mock *InterfaceMock) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if mock.SubscribeFilterLogsFunc == nil {
+		panic("InterfaceMock.SubscribeFilterLogsFunc: method is nil but Interface.SubscribeFilterLogs was just called")
+	}
+	return mock.SubscribeFilterLogsFunc(ctx, query, ch)
+}
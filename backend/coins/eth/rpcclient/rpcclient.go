@@ -17,6 +17,7 @@ package rpcclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"math/big"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -34,9 +35,124 @@ type Interface interface {
 		ctx context.Context, hash common.Hash) (*RPCTransactionReceipt, error)
 	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
 	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	// FeeHistory returns the base fees and the priority fees at the given percentiles of the last
+	// `blockCount` blocks, see eth_feeHistory.
+	FeeHistory(ctx context.Context, blockCount uint64, rewardPercentiles []float64) (*FeeHistory, error)
+	// SendRawTransaction broadcasts a serialized signed transaction. Unlike SendTransaction, it
+	// supports typed transactions.
+	SendRawTransaction(ctx context.Context, rawTx []byte) error
+	// TraceFilter returns the call traces in the given block range (inclusive) from any of the
+	// fromAddresses and to any of the toAddresses, see trace_filter. Not all nodes support it.
+	TraceFilter(ctx context.Context, fromBlock, toBlock uint64,
+		fromAddresses, toAddresses []common.Address) ([]*Trace, error)
+	// BlockTransactions returns the block with the given number, including its transactions.
+	BlockTransactions(ctx context.Context, number uint64) (*Block, error)
 	bind.ContractBackend
 }
 
+// ErrMethodNotFound is returned by clients which do not support a method of Interface.
+var ErrMethodNotFound = errors.New("method not supported")
+
+// IsMethodNotFound returns true if the error is ErrMethodNotFound or the node's response to an
+// unsupported method.
+func IsMethodNotFound(err error) bool {
+	if errp.Cause(err) == ErrMethodNotFound {
+		return true
+	}
+	rpcErr, ok := errp.Cause(err).(rpc.Error)
+	return ok && rpcErr.ErrorCode() == -32601
+}
+
+// Trace is a call trace returned by trace_filter. Only calls and contract creations are decoded.
+type Trace struct {
+	Type            string      `json:"type"`
+	BlockNumber     uint64      `json:"blockNumber"`
+	TransactionHash common.Hash `json:"transactionHash"`
+	// TransactionPosition is the index of the transaction in the block.
+	TransactionPosition uint `json:"transactionPosition"`
+	// TraceAddress is the position of the call in the call tree of the transaction. It is empty for
+	// the transaction itself.
+	TraceAddress []uint `json:"traceAddress"`
+	Action       struct {
+		From  common.Address  `json:"from"`
+		To    *common.Address `json:"to"`
+		Value *hexutil.Big    `json:"value"`
+	} `json:"action"`
+	Result *struct {
+		// Address is the address of the created contract.
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+	// Error is not empty if the call failed, in which case no value was transferred.
+	Error string `json:"error"`
+}
+
+// Block is a block returned by eth_getBlockByNumber. Only the fields needed to find the transfers
+// of an address are decoded. Unlike ethclient.BlockByNumber, it supports blocks with typed
+// transactions.
+type Block struct {
+	Number       hexutil.Uint64      `json:"number"`
+	Timestamp    hexutil.Uint64      `json:"timestamp"`
+	Transactions []*BlockTransaction `json:"transactions"`
+}
+
+// BlockTransaction is a transaction of a Block.
+type BlockTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+}
+
+// FeeHistory is the result of eth_feeHistory.
+type FeeHistory struct {
+	OldestBlock *big.Int
+	// BaseFeePerGas contains one more entry than the number of blocks, which is the base fee of the
+	// next block.
+	BaseFeePerGas []*big.Int
+	GasUsedRatio  []float64
+	// Reward contains, for each block, the priority fee per gas at the requested percentiles.
+	Reward [][]*big.Int
+}
+
+// NextBaseFee returns the base fee of the next block, or nil if unknown.
+func (feeHistory *FeeHistory) NextBaseFee() *big.Int {
+	if len(feeHistory.BaseFeePerGas) == 0 {
+		return nil
+	}
+	return feeHistory.BaseFeePerGas[len(feeHistory.BaseFeePerGas)-1]
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (feeHistory *FeeHistory) UnmarshalJSON(msg []byte) error {
+	result := struct {
+		OldestBlock   *hexutil.Big     `json:"oldestBlock"`
+		BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+		GasUsedRatio  []float64        `json:"gasUsedRatio"`
+		Reward        [][]*hexutil.Big `json:"reward"`
+	}{}
+	if err := json.Unmarshal(msg, &result); err != nil {
+		return errp.WithStack(err)
+	}
+	if result.OldestBlock == nil {
+		return errp.New("fee history: missing oldestBlock")
+	}
+	feeHistory.OldestBlock = result.OldestBlock.ToInt()
+	feeHistory.BaseFeePerGas = make([]*big.Int, len(result.BaseFeePerGas))
+	for index, baseFee := range result.BaseFeePerGas {
+		feeHistory.BaseFeePerGas[index] = baseFee.ToInt()
+	}
+	feeHistory.GasUsedRatio = result.GasUsedRatio
+	feeHistory.Reward = make([][]*big.Int, len(result.Reward))
+	for blockIndex, rewards := range result.Reward {
+		feeHistory.Reward[blockIndex] = make([]*big.Int, len(rewards))
+		for index, reward := range rewards {
+			feeHistory.Reward[blockIndex][index] = reward.ToInt()
+		}
+	}
+	return nil
+}
+
 // RPCClient wraps the high level ethclient, extending it with more functions. Implements Interface.
 type RPCClient struct {
 	*ethclient.Client
@@ -60,6 +176,11 @@ func RPCDial(url string) (*RPCClient, error) {
 type RPCTransactionReceipt struct {
 	types.Receipt
 	BlockNumber uint64
+	// From is the sender of the transaction, who paid the fee.
+	From common.Address
+	// EffectiveGasPrice is the gas price paid by the transaction. nil if the node does not provide
+	// it.
+	EffectiveGasPrice *big.Int
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -68,12 +189,16 @@ func (rpcTR *RPCTransactionReceipt) UnmarshalJSON(msg []byte) error {
 		return err
 	}
 	bn := struct {
-		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+		BlockNumber       hexutil.Uint64 `json:"blockNumber"`
+		From              common.Address `json:"from"`
+		EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
 	}{}
 	if err := json.Unmarshal(msg, &bn); err != nil {
 		return err
 	}
 	rpcTR.BlockNumber = uint64(bn.BlockNumber)
+	rpcTR.From = bn.From
+	rpcTR.EffectiveGasPrice = (*big.Int)(bn.EffectiveGasPrice)
 	return nil
 }
 
@@ -85,3 +210,53 @@ func (rpc *RPCClient) TransactionReceiptWithBlockNumber(
 	err := rpc.c.CallContext(ctx, &r, "eth_getTransactionReceipt", hash)
 	return r, err
 }
+
+// FeeHistory implements Interface.
+func (rpc *RPCClient) FeeHistory(
+	ctx context.Context, blockCount uint64, rewardPercentiles []float64) (*FeeHistory, error) {
+	var result FeeHistory
+	err := rpc.c.CallContext(
+		ctx, &result, "eth_feeHistory", hexutil.Uint64(blockCount), "latest", rewardPercentiles)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &result, nil
+}
+
+// SendRawTransaction implements Interface.
+func (rpc *RPCClient) SendRawTransaction(ctx context.Context, rawTx []byte) error {
+	return rpc.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(rawTx))
+}
+
+// TraceFilter implements Interface.
+func (rpc *RPCClient) TraceFilter(ctx context.Context, fromBlock, toBlock uint64,
+	fromAddresses, toAddresses []common.Address) ([]*Trace, error) {
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.Uint64(fromBlock),
+		"toBlock":   hexutil.Uint64(toBlock),
+	}
+	if len(fromAddresses) > 0 {
+		filter["fromAddress"] = fromAddresses
+	}
+	if len(toAddresses) > 0 {
+		filter["toAddress"] = toAddresses
+	}
+	var result []*Trace
+	if err := rpc.c.CallContext(ctx, &result, "trace_filter", filter); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result, nil
+}
+
+// BlockTransactions implements Interface.
+func (rpc *RPCClient) BlockTransactions(ctx context.Context, number uint64) (*Block, error) {
+	var result *Block
+	err := rpc.c.CallContext(ctx, &result, "eth_getBlockByNumber", hexutil.Uint64(number), true)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if result == nil {
+		return nil, errp.Newf("block %d not found", number)
+	}
+	return result, nil
+}
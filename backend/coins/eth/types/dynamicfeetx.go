@@ -0,0 +1,263 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// DynamicFeeTxType is the EIP-2718 transaction type of EIP-1559 transactions.
+const DynamicFeeTxType = 0x02
+
+// Transaction is an outgoing transaction. It is either a legacy transaction (*types.Transaction)
+// or an EIP-1559 transaction (*DynamicFeeTx).
+type Transaction interface {
+	Nonce() uint64
+	Gas() uint64
+	// GasPrice is the gas price of a legacy transaction and the max fee per gas of an EIP-1559
+	// transaction.
+	GasPrice() *big.Int
+	Value() *big.Int
+	To() *common.Address
+	Data() []byte
+	Hash() common.Hash
+}
+
+// DynamicFeeTx is an EIP-1559 transaction. The vendored go-ethereum predates typed transactions
+// (EIP-2718), so the encoding and the signature hash are implemented here. Access lists are not
+// supported and always empty.
+type DynamicFeeTx struct {
+	chainID   *big.Int
+	nonce     uint64
+	gasTipCap *big.Int
+	gasFeeCap *big.Int
+	gas       uint64
+	to        common.Address
+	value     *big.Int
+	data      []byte
+	// v is the y-parity of the signature (0 or 1). v, r and s are nil if the tx is not signed.
+	v, r, s *big.Int
+}
+
+// accessTuple is an element of the access list, see EIP-2930.
+type accessTuple struct {
+	Address     common.Address
+	StorageKeys []common.Hash
+}
+
+// dynamicFeeTxRLP is the RLP payload of a signed EIP-1559 transaction.
+type dynamicFeeTxRLP struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         common.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList []accessTuple
+	V, R, S    *big.Int
+}
+
+// NewDynamicFeeTx creates a new unsigned EIP-1559 transaction. gasTipCap is the max priority fee
+// per gas paid to the miner, gasFeeCap the max total fee per gas (base fee plus priority fee).
+func NewDynamicFeeTx(
+	chainID *big.Int,
+	nonce uint64,
+	to common.Address,
+	value *big.Int,
+	gas uint64,
+	gasTipCap *big.Int,
+	gasFeeCap *big.Int,
+	data []byte,
+) *DynamicFeeTx {
+	return &DynamicFeeTx{
+		chainID:   new(big.Int).Set(chainID),
+		nonce:     nonce,
+		gasTipCap: new(big.Int).Set(gasTipCap),
+		gasFeeCap: new(big.Int).Set(gasFeeCap),
+		gas:       gas,
+		to:        to,
+		value:     new(big.Int).Set(value),
+		data:      common.CopyBytes(data),
+	}
+}
+
+// ChainID returns the chain ID the transaction is signed for.
+func (tx *DynamicFeeTx) ChainID() *big.Int { return new(big.Int).Set(tx.chainID) }
+
+// Nonce implements Transaction.
+func (tx *DynamicFeeTx) Nonce() uint64 { return tx.nonce }
+
+// Gas implements Transaction.
+func (tx *DynamicFeeTx) Gas() uint64 { return tx.gas }
+
+// GasPrice implements Transaction. Returns the max fee per gas.
+func (tx *DynamicFeeTx) GasPrice() *big.Int { return tx.GasFeeCap() }
+
+// GasTipCap returns the max priority fee per gas.
+func (tx *DynamicFeeTx) GasTipCap() *big.Int { return new(big.Int).Set(tx.gasTipCap) }
+
+// GasFeeCap returns the max fee per gas.
+func (tx *DynamicFeeTx) GasFeeCap() *big.Int { return new(big.Int).Set(tx.gasFeeCap) }
+
+// Value implements Transaction.
+func (tx *DynamicFeeTx) Value() *big.Int { return new(big.Int).Set(tx.value) }
+
+// To implements Transaction.
+func (tx *DynamicFeeTx) To() *common.Address {
+	to := tx.to
+	return &to
+}
+
+// Data implements Transaction.
+func (tx *DynamicFeeTx) Data() []byte { return common.CopyBytes(tx.data) }
+
+// SigHash returns the hash to be signed, keccak256(0x02 || rlp(payload without signature)).
+func (tx *DynamicFeeTx) SigHash() common.Hash {
+	payload, err := rlp.EncodeToBytes([]interface{}{
+		tx.chainID,
+		tx.nonce,
+		tx.gasTipCap,
+		tx.gasFeeCap,
+		tx.gas,
+		tx.to,
+		tx.value,
+		tx.data,
+		[]accessTuple{},
+	})
+	if err != nil {
+		panic(errp.WithStack(err))
+	}
+	return crypto.Keccak256Hash([]byte{DynamicFeeTxType}, payload)
+}
+
+// WithSignature returns a copy of the transaction signed with the given 65 byte signature in the
+// [R || S || V] format, where V is the recovery id (0 or 1).
+func (tx *DynamicFeeTx) WithSignature(signature []byte) (*DynamicFeeTx, error) {
+	if len(signature) != crypto.SignatureLength {
+		return nil, errp.Newf("wrong size for signature: got %d, want %d",
+			len(signature), crypto.SignatureLength)
+	}
+	if signature[64] > 1 {
+		return nil, errp.New("invalid signature recovery id")
+	}
+	signed := *tx
+	signed.r = new(big.Int).SetBytes(signature[:32])
+	signed.s = new(big.Int).SetBytes(signature[32:64])
+	signed.v = big.NewInt(int64(signature[64]))
+	return &signed, nil
+}
+
+// MarshalBinary returns the EIP-2718 encoding of the transaction, 0x02 || rlp(payload), which is
+// broadcast using eth_sendRawTransaction.
+func (tx *DynamicFeeTx) MarshalBinary() ([]byte, error) {
+	if tx.v == nil {
+		return nil, errp.New("the transaction is not signed")
+	}
+	payload, err := rlp.EncodeToBytes(&dynamicFeeTxRLP{
+		ChainID:    tx.chainID,
+		Nonce:      tx.nonce,
+		GasTipCap:  tx.gasTipCap,
+		GasFeeCap:  tx.gasFeeCap,
+		Gas:        tx.gas,
+		To:         tx.to,
+		Value:      tx.value,
+		Data:       tx.data,
+		AccessList: []accessTuple{},
+		V:          tx.v,
+		R:          tx.r,
+		S:          tx.s,
+	})
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return append([]byte{DynamicFeeTxType}, payload...), nil
+}
+
+// Hash implements Transaction. The transaction ID is the hash of the signed encoding. The hash of
+// an unsigned transaction is the signature hash.
+func (tx *DynamicFeeTx) Hash() common.Hash {
+	if tx.v == nil {
+		return tx.SigHash()
+	}
+	encoded, err := tx.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	return crypto.Keccak256Hash(encoded)
+}
+
+// unmarshalDynamicFeeTx decodes a signed transaction encoded with MarshalBinary.
+func unmarshalDynamicFeeTx(encoded []byte) (*DynamicFeeTx, error) {
+	if len(encoded) == 0 || encoded[0] != DynamicFeeTxType {
+		return nil, errp.New("not an EIP-1559 transaction")
+	}
+	var decoded dynamicFeeTxRLP
+	if err := rlp.DecodeBytes(encoded[1:], &decoded); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if len(decoded.AccessList) != 0 {
+		return nil, errp.New("access lists are not supported")
+	}
+	return &DynamicFeeTx{
+		chainID:   decoded.ChainID,
+		nonce:     decoded.Nonce,
+		gasTipCap: decoded.GasTipCap,
+		gasFeeCap: decoded.GasFeeCap,
+		gas:       decoded.Gas,
+		to:        decoded.To,
+		value:     decoded.Value,
+		data:      decoded.Data,
+		v:         decoded.V,
+		r:         decoded.R,
+		s:         decoded.S,
+	}, nil
+}
+
+// EncodeTransaction serializes a signed transaction in the format broadcast to the network: the
+// RLP encoding of legacy transactions and the EIP-2718 encoding of typed transactions.
+func EncodeTransaction(tx Transaction) ([]byte, error) {
+	switch specificTx := tx.(type) {
+	case *types.Transaction:
+		encoded, err := rlp.EncodeToBytes(specificTx)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		return encoded, nil
+	case *DynamicFeeTx:
+		return specificTx.MarshalBinary()
+	default:
+		return nil, errp.New("unknown transaction type")
+	}
+}
+
+// DecodeTransaction is the inverse of EncodeTransaction.
+func DecodeTransaction(encoded []byte) (Transaction, error) {
+	if len(encoded) > 0 && encoded[0] == DynamicFeeTxType {
+		return unmarshalDynamicFeeTx(encoded)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encoded, tx); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return tx, nil
+}
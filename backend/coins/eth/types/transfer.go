@@ -0,0 +1,47 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Transfer is a transfer of ether or ERC20 tokens from or to the account, as found by scanning the
+// chain. ERC20 token transfers are emitted as Transfer events by the token contract. Ether
+// transfers are transactions or, if the node supports tracing, internal transactions. Used for DB
+// serialization.
+type Transfer struct {
+	TxHash common.Hash `json:"txHash"`
+	// Index orders the transfers within a block and distinguishes multiple transfers of the same
+	// transaction. It is the index of the event log in the block for ERC20 token transfers. For
+	// ether transfers, it is the position of the call in the transaction, 0 being the transaction
+	// itself.
+	Index       uint   `json:"index"`
+	BlockNumber uint64 `json:"blockNumber"`
+	// Timestamp is the unix timestamp of the block.
+	Timestamp uint64         `json:"timestamp"`
+	From      common.Address `json:"from"`
+	To        common.Address `json:"to"`
+	Value     *big.Int       `json:"value"`
+	// GasUsed and Fee are the gas used by and the fee paid for the transaction. They are only
+	// fetched for transactions sent by the account. Fee is nil if unknown.
+	GasUsed uint64   `json:"gasUsed"`
+	Fee     *big.Int `json:"fee"`
+	// Failed is true if the transaction failed, in which case no value was transferred, but the fee
+	// was paid. Only applies to ether transfers.
+	Failed bool `json:"failed"`
+}
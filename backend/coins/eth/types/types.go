@@ -24,8 +24,6 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // NumConfirmationsComplete indicates after how many confs the tx is considered complete.
@@ -33,11 +31,17 @@ const NumConfirmationsComplete = 12
 
 // TransactionWithMetadata wraps an outgoing transaction and implements accounts.Transaction.
 type TransactionWithMetadata struct {
-	Transaction *types.Transaction
+	Transaction Transaction
 	// Height is 0 for pending tx.
 	Height uint64
 	// Only applies if Height > 0
 	GasUsed uint64
+	// Only applies if Height > 0. The gas price actually paid, which can be lower than the max fee
+	// per gas of EIP-1559 transactions. nil if unknown.
+	EffectiveGasPrice *big.Int
+	// Replaces is the hash of the pending transaction with the same nonce which was replaced by this
+	// transaction to speed it up or to cancel it. nil if it is not a replacement.
+	Replaces *common.Hash
 	// Only applies if Height > 0.
 	// false if contract execution failed, otherwise true.
 	Success bool
@@ -45,36 +49,48 @@ type TransactionWithMetadata struct {
 
 // MarshalJSON implements json.Marshaler. Used for DB serialization.
 func (txh *TransactionWithMetadata) MarshalJSON() ([]byte, error) {
-	txSerialized, err := rlp.EncodeToBytes(txh.Transaction)
+	txSerialized, err := EncodeTransaction(txh.Transaction)
 	if err != nil {
 		return nil, err
 	}
-	return json.Marshal(map[string]interface{}{
+	result := map[string]interface{}{
 		"tx":      txSerialized,
 		"height":  txh.Height,
 		"gasUsed": hexutil.Uint64(txh.GasUsed),
 		"success": txh.Success,
-	})
+	}
+	if txh.EffectiveGasPrice != nil {
+		result["effectiveGasPrice"] = (*hexutil.Big)(txh.EffectiveGasPrice)
+	}
+	if txh.Replaces != nil {
+		result["replaces"] = txh.Replaces
+	}
+	return json.Marshal(result)
 }
 
 // UnmarshalJSON implements json.Unmarshaler. Used for DB serialization.
 func (txh *TransactionWithMetadata) UnmarshalJSON(input []byte) error {
 	m := struct {
-		TransactionRLP []byte         `json:"tx"`
-		Height         uint64         `json:"height"`
-		GasUsed        hexutil.Uint64 `json:"gasUsed"`
-		Success        bool           `json:"success"`
+		TransactionRLP    []byte         `json:"tx"`
+		Height            uint64         `json:"height"`
+		GasUsed           hexutil.Uint64 `json:"gasUsed"`
+		Success           bool           `json:"success"`
+		EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
+		Replaces          *common.Hash   `json:"replaces"`
 	}{}
 	if err := json.Unmarshal(input, &m); err != nil {
 		return err
 	}
-	txh.Transaction = new(types.Transaction)
-	if err := rlp.DecodeBytes(m.TransactionRLP, txh.Transaction); err != nil {
+	transaction, err := DecodeTransaction(m.TransactionRLP)
+	if err != nil {
 		return err
 	}
+	txh.Transaction = transaction
 	txh.Height = m.Height
 	txh.GasUsed = uint64(m.GasUsed)
 	txh.Success = m.Success
+	txh.EffectiveGasPrice = (*big.Int)(m.EffectiveGasPrice)
+	txh.Replaces = m.Replaces
 	return nil
 }
 
@@ -89,7 +105,8 @@ func (txh *TransactionWithMetadata) TransactionData(
 	amount := coin.NewAmount(txh.Transaction.Value())
 	address := txh.Transaction.To().Hex()
 
-	if erc20Token != nil {
+	// A cancellation of a pending ERC20 transfer is a zero value transaction without data.
+	if erc20Token != nil && len(data) > 0 {
 		// ERC20 transfer.
 
 		// An ERC20-Token transfer looks like this:
@@ -106,6 +123,11 @@ func (txh *TransactionWithMetadata) TransactionData(
 		address = common.BytesToAddress(data[4+32-common.AddressLength : 4+32]).Hex()
 	}
 
+	var replacesTxID string
+	if txh.Replaces != nil {
+		replacesTxID = txh.Replaces.Hex()
+	}
+
 	numConfirmations := txh.numConfirmations(tipHeight)
 	return &accounts.TransactionData{
 		Fee:                      txh.fee(),
@@ -122,12 +144,17 @@ func (txh *TransactionWithMetadata) TransactionData(
 			Address: address,
 			Amount:  amount,
 		}},
-		Gas: txh.gas(),
+		Gas:          txh.gas(),
+		ReplacesTxID: replacesTxID,
 	}
 }
 
 func (txh *TransactionWithMetadata) fee() *coin.Amount {
-	fee := new(big.Int).Mul(big.NewInt(int64(txh.Transaction.Gas())), txh.Transaction.GasPrice())
+	gasPrice := txh.Transaction.GasPrice()
+	if txh.Height > 0 && txh.EffectiveGasPrice != nil {
+		gasPrice = txh.EffectiveGasPrice
+	}
+	fee := new(big.Int).Mul(big.NewInt(int64(txh.Transaction.Gas())), gasPrice)
 	amount := coin.NewAmount(fee)
 	return &amount
 }
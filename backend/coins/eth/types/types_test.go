@@ -1,6 +1,8 @@
 package types_test
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/util/jsonp"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -33,3 +36,115 @@ func TestTransactionWithHeightJSON(t *testing.T) {
 	require.Equal(t, tx.Success, tx2.Success)
 	require.Equal(t, tx.Transaction.Hash(), tx2.Transaction.Hash())
 }
+
+func TestDynamicFeeTransaction(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chainID := big.NewInt(3)
+	tx := ethtypes.NewDynamicFeeTx(
+		chainID,
+		123,
+		common.BytesToAddress([]byte("12345678901234567890")),
+		big.NewInt(123456),
+		45678,
+		big.NewInt(2000000000),
+		big.NewInt(52000000000),
+		[]byte("contract data"),
+	)
+	_, err = ethtypes.EncodeTransaction(tx)
+	require.Error(t, err, "unsigned transactions can't be encoded")
+
+	signature, err := crypto.Sign(tx.SigHash().Bytes(), privateKey)
+	require.NoError(t, err)
+	signedTx, err := tx.WithSignature(signature)
+	require.NoError(t, err)
+	require.NotEqual(t, tx.Hash(), signedTx.Hash())
+
+	publicKey, err := crypto.SigToPub(signedTx.SigHash().Bytes(), signature)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), crypto.PubkeyToAddress(*publicKey))
+
+	encoded, err := ethtypes.EncodeTransaction(signedTx)
+	require.NoError(t, err)
+	require.Equal(t, byte(ethtypes.DynamicFeeTxType), encoded[0])
+	decoded, err := ethtypes.DecodeTransaction(encoded)
+	require.NoError(t, err)
+	require.Equal(t, signedTx.Hash(), decoded.Hash())
+	decodedDynamicFeeTx, ok := decoded.(*ethtypes.DynamicFeeTx)
+	require.True(t, ok)
+	require.Equal(t, chainID, decodedDynamicFeeTx.ChainID())
+	require.Equal(t, big.NewInt(2000000000), decodedDynamicFeeTx.GasTipCap())
+	require.Equal(t, big.NewInt(52000000000), decodedDynamicFeeTx.GasPrice())
+
+	txWithMetadata := &ethtypes.TransactionWithMetadata{
+		Transaction:       signedTx,
+		Height:            352,
+		GasUsed:           21000,
+		Success:           true,
+		EffectiveGasPrice: big.NewInt(30000000000),
+	}
+	txWithMetadata2 := new(ethtypes.TransactionWithMetadata)
+	require.NoError(t, json.Unmarshal(jsonp.MustMarshal(txWithMetadata), txWithMetadata2))
+	require.Equal(t, signedTx.Hash(), txWithMetadata2.Transaction.Hash())
+	require.Equal(t, txWithMetadata.EffectiveGasPrice, txWithMetadata2.EffectiveGasPrice)
+}
+
+// TestDynamicFeeTransactionEncoding checks the signature hash and the encoding against vectors
+// computed independently following EIP-1559 and EIP-2718.
+func TestDynamicFeeTransactionEncoding(t *testing.T) {
+	tx := ethtypes.NewDynamicFeeTx(
+		big.NewInt(1),
+		3,
+		common.HexToAddress("0xb94f5374fce5edbc8e2a8697c15331677e6ebf0b"),
+		big.NewInt(10),
+		25000,
+		big.NewInt(1000000000),
+		big.NewInt(100000000000),
+		common.FromHex("0x5544"),
+	)
+	// keccak256(0x02 || rlp([1, 3, 1000000000, 100000000000, 25000, to, 10, 0x5544, []]))
+	require.Equal(t,
+		common.HexToHash("0x64d1d9bf589cd56427be27cdbc40e37149c609375821058738aad5799176005e"),
+		tx.SigHash())
+
+	signature := append(
+		append(bytes.Repeat([]byte{0x11}, 32), bytes.Repeat([]byte{0x22}, 32)...),
+		1)
+	signedTx, err := tx.WithSignature(signature)
+	require.NoError(t, err)
+	encoded, err := ethtypes.EncodeTransaction(signedTx)
+	require.NoError(t, err)
+	require.Equal(t,
+		"02f86d0103843b9aca0085174876e8008261a894b94f5374fce5edbc8e2a8697c15331677e6ebf0b0a825544c0"+
+			"01a01111111111111111111111111111111111111111111111111111111111111111"+
+			"a02222222222222222222222222222222222222222222222222222222222222222",
+		hex.EncodeToString(encoded))
+	require.Equal(t,
+		common.HexToHash("0x127bdd0afd5ae68d08f884f7182fff49f09c77e81d149ced117d5aa112ec0a6b"),
+		signedTx.Hash())
+
+	_, err = tx.WithSignature(append(signature[:64], 27))
+	require.Error(t, err, "the recovery id must be 0 or 1")
+}
+
+func TestTransactionWithMetadataReplaces(t *testing.T) {
+	original := types.NewTransaction(
+		5, common.BytesToAddress([]byte("12345678901234567890")), big.NewInt(1), 21000,
+		big.NewInt(1000000000), nil)
+	replaces := original.Hash()
+	tx := &ethtypes.TransactionWithMetadata{
+		Transaction: types.NewTransaction(
+			5, common.BytesToAddress([]byte("12345678901234567890")), big.NewInt(1), 21000,
+			big.NewInt(1100000000), nil),
+		Replaces: &replaces,
+	}
+	tx2 := new(ethtypes.TransactionWithMetadata)
+	require.NoError(t, json.Unmarshal(jsonp.MustMarshal(tx), tx2))
+	require.Equal(t, &replaces, tx2.Replaces)
+	require.Equal(t, replaces.Hex(), tx2.TransactionData(100, nil).ReplacesTxID)
+
+	notReplacing := &ethtypes.TransactionWithMetadata{Transaction: original}
+	require.NoError(t, json.Unmarshal(jsonp.MustMarshal(notReplacing), tx2))
+	require.Nil(t, tx2.Replaces)
+	require.Equal(t, "", tx2.TransactionData(100, nil).ReplacesTxID)
+}
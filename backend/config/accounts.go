@@ -22,20 +22,78 @@ import (
 
 // Account holds information related to an account.
 type Account struct {
-	CoinCode      coin.Code              `json:"coinCode"`
-	Name          string                 `json:"name"`
-	Code          string                 `json:"code"`
-	Configuration *signing.Configuration `json:"configuration"`
+	CoinCode coin.Code `json:"coinCode"`
+	Name     string    `json:"name"`
+	Code     string    `json:"code"`
+	// Configuration is the signing configuration of accounts persisted before an account could
+	// have multiple signing configurations. It is migrated to Configurations when loading.
+	Configuration *signing.Configuration `json:"configuration,omitempty"`
+	// Configurations are the signing configurations of the account. Bitcoin-like accounts can
+	// combine multiple configurations, e.g. one per script type, in one account.
+	Configurations signing.Configurations `json:"configurations"`
+	// Hidden accounts are kept in the config, but not loaded.
+	Hidden bool `json:"hidden,omitempty"`
+	// RootFingerprint is the hex encoded root fingerprint of the keystore the account was derived
+	// from, and empty for watch-only accounts. Accounts of a keystore are only loaded while the
+	// keystore is registered.
+	RootFingerprint string `json:"rootFingerprint,omitempty"`
+	// BirthHeight is the block height at which the account was created. Ethereum accounts using
+	// the node transactions source do not scan the blocks before it for transactions.
+	BirthHeight uint64 `json:"birthHeight,omitempty"`
 }
 
-// AccountsConfig persists the list of accounts added to the app.
+// Keystore holds information about a keystore which was registered before.
+type Keystore struct {
+	// RootFingerprint is the hex encoded root fingerprint of the keystore.
+	RootFingerprint string `json:"rootFingerprint"`
+	// Testing is true if the default accounts were created in testing mode (testnet or regtest).
+	// Mainnet and testnet accounts are created separately.
+	Testing bool `json:"testing"`
+	// Coins are the coins and ERC20 tokens whose default accounts were created. The default
+	// accounts of coins added later, e.g. new ERC20 tokens, are created the next time the keystore
+	// is registered. It is nil for keystores registered before the coins were recorded.
+	Coins []coin.Code `json:"coins"`
+}
+
+// AccountsConfig persists the list of accounts added to the app. The order of the accounts is the
+// order in which they are shown.
 type AccountsConfig struct {
 	Accounts []Account `json:"accounts"`
+	// Keystores are the keystores for which the default accounts were already created. The
+	// default accounts of a coin are only created once per keystore, so that the user can rename,
+	// hide or remove them.
+	Keystores []Keystore `json:"keystores"`
+	// BirthHeights are the birth heights of the accounts which are not persisted, like the default
+	// accounts of keystores which can't return their root fingerprint, see Account.BirthHeight.
+	// They are keyed by the identifier the files of the account are named after, as the codes of
+	// these accounts are the same for all keystores.
+	BirthHeights map[string]uint64 `json:"birthHeights,omitempty"`
 }
 
 // newDefaultAccountsonfig returns the default accounts config.
 func newDefaultAccountsonfig() AccountsConfig {
 	return AccountsConfig{
-		Accounts: []Account{},
+		Accounts:  []Account{},
+		Keystores: []Keystore{},
+	}
+}
+
+// migrateAccountConfigurations moves the single signing configuration of accounts persisted before
+// accounts could have multiple signing configurations to the list of configurations. Returns true
+// if an account was migrated.
+func migrateAccountConfigurations(accountsConfig AccountsConfig) (AccountsConfig, bool) {
+	migrated := false
+	accounts := make([]Account, len(accountsConfig.Accounts))
+	for index, account := range accountsConfig.Accounts {
+		if account.Configuration != nil {
+			if len(account.Configurations) == 0 {
+				account.Configurations = signing.Configurations{account.Configuration}
+			}
+			account.Configuration = nil
+			migrated = true
+		}
+		accounts[index] = account
 	}
+	accountsConfig.Accounts = accounts
+	return accountsConfig, migrated
 }
@@ -0,0 +1,99 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfiguration(t *testing.T, scriptType signing.ScriptType) *signing.Configuration {
+	t.Helper()
+	xpub, err := hdkeychain.NewMaster(make([]byte, 32), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	xpub, err = xpub.Neuter()
+	require.NoError(t, err)
+	return signing.NewSinglesigConfiguration(scriptType, signing.NewEmptyAbsoluteKeypath(), xpub)
+}
+
+func TestMigrateAccountConfigurations(t *testing.T) {
+	legacy := newTestConfiguration(t, signing.ScriptTypeP2WPKH)
+	other := newTestConfiguration(t, signing.ScriptTypeP2PKH)
+
+	accountsConfig, migrated := migrateAccountConfigurations(AccountsConfig{
+		Accounts: []Account{
+			{Code: "legacy", Configuration: legacy},
+			{Code: "both", Configuration: legacy, Configurations: signing.Configurations{other}},
+			{Code: "current", Configurations: signing.Configurations{other}},
+		},
+	})
+	require.True(t, migrated)
+	require.Len(t, accountsConfig.Accounts, 3)
+	for _, account := range accountsConfig.Accounts {
+		require.Nil(t, account.Configuration)
+		require.Len(t, account.Configurations, 1)
+	}
+	require.Equal(t, legacy.Hash(), accountsConfig.Accounts[0].Configurations[0].Hash())
+	// Existing configurations take precedence.
+	require.Equal(t, other.Hash(), accountsConfig.Accounts[1].Configurations[0].Hash())
+	require.Equal(t, other.Hash(), accountsConfig.Accounts[2].Configurations[0].Hash())
+
+	_, migrated = migrateAccountConfigurations(accountsConfig)
+	require.False(t, migrated)
+}
+
+func TestNewConfigMigratesAccountConfigurations(t *testing.T) {
+	legacy := newTestConfiguration(t, signing.ScriptTypeP2WPKH)
+	accountsConfigFilename := test.TstTempFile("accounts.json")
+	defer func() { _ = os.Remove(accountsConfigFilename) }()
+	appConfigFilename := test.TstTempFile("config.json")
+	defer func() { _ = os.Remove(appConfigFilename) }()
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"accounts": []interface{}{
+			map[string]interface{}{"coinCode": "tbtc", "name": "legacy", "code": "legacy", "configuration": legacy},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(accountsConfigFilename, encoded, 0600))
+	require.NoError(t, ioutil.WriteFile(appConfigFilename, []byte("{}"), 0600))
+
+	config, err := NewConfig(appConfigFilename, accountsConfigFilename)
+	require.NoError(t, err)
+	accounts := config.AccountsConfig().Accounts
+	require.Len(t, accounts, 1)
+	require.Nil(t, accounts[0].Configuration)
+	require.Len(t, accounts[0].Configurations, 1)
+	require.Equal(t, legacy.Hash(), accounts[0].Configurations[0].Hash())
+
+	// The migration is persisted.
+	var persisted struct {
+		Accounts []map[string]json.RawMessage `json:"accounts"`
+	}
+	contents, err := ioutil.ReadFile(accountsConfigFilename)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(contents, &persisted))
+	require.Len(t, persisted.Accounts, 1)
+	require.NotContains(t, persisted.Accounts[0], "configuration")
+	require.Contains(t, persisted.Accounts[0], "configurations")
+}
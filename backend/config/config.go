@@ -49,6 +49,12 @@ const (
 	ETHTransactionsSourceNone ETHTransactionsSource = "none"
 	// ETHTransactionsSourceEtherScan configures to get transactions from EtherScan.
 	ETHTransactionsSourceEtherScan ETHTransactionsSource = "etherScan"
+	// ETHTransactionsSourceNode configures to get transactions from the configured node, without
+	// a third party API. ERC20 token transfers are found using the Transfer event logs of the token
+	// contracts. Ether transfers are found using trace_filter if the node supports it, otherwise by
+	// scanning all blocks since the birth height of the account. The node must not be the
+	// EtherScan proxy.
+	ETHTransactionsSourceNode ETHTransactionsSource = "node"
 )
 
 // ethCoinConfig holds configurations for ethereum coins.
@@ -57,6 +63,19 @@ type ethCoinConfig struct {
 
 	TransactionsSource ETHTransactionsSource `json:"transactionsSource"`
 	ActiveERC20Tokens  []string              `json:"activeERC20Tokens"`
+	// CustomERC20Tokens are the ERC20 tokens added by the user, in addition to the built-in ones.
+	CustomERC20Tokens []ERC20Token `json:"customERC20Tokens"`
+}
+
+// ERC20Token is an ERC20 token added by the user. The token details are fetched from the contract
+// when adding the token.
+type ERC20Token struct {
+	// Code is the token id, as used in ActiveERC20Tokens. It is the lowercase contract address.
+	Code            string `json:"code"`
+	ContractAddress string `json:"contractAddress"`
+	Name            string `json:"name"`
+	Unit            string `json:"unit"`
+	Decimals        uint   `json:"decimals"`
 }
 
 // ERC20TokenActive returns true if this token is configured to be active.
@@ -255,16 +274,19 @@ func NewDefaultAppConfig() AppConfig {
 				NodeURL:            "etherscan+https://api.etherscan.io/api",
 				TransactionsSource: ETHTransactionsSourceEtherScan,
 				ActiveERC20Tokens:  []string{},
+				CustomERC20Tokens:  []ERC20Token{},
 			},
 			TETH: ethCoinConfig{
 				NodeURL:            "etherscan+https://api-ropsten.etherscan.io/api",
 				TransactionsSource: ETHTransactionsSourceEtherScan,
 				ActiveERC20Tokens:  []string{},
+				CustomERC20Tokens:  []ERC20Token{},
 			},
 			RETH: ethCoinConfig{
 				NodeURL:            "etherscan+https://api-rinkeby.etherscan.io/api",
 				TransactionsSource: ETHTransactionsSourceEtherScan,
 				ActiveERC20Tokens:  []string{},
+				CustomERC20Tokens:  []ERC20Token{},
 			},
 		},
 	}
@@ -296,6 +318,12 @@ func NewConfig(appConfigFilename string, accountsConfigFilename string) (*Config
 	if err := config.SetAppConfig(appConfig); err != nil {
 		return nil, errp.WithStack(err)
 	}
+	accountsConfig, migrated := migrateAccountConfigurations(config.accountsConfig)
+	if migrated {
+		if err := config.SetAccountsConfig(accountsConfig); err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
 	return config, nil
 }
 
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
@@ -27,6 +28,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/sirupsen/logrus"
 )
 
@@ -52,7 +54,9 @@ func (keystore *keystore) SupportsAccount(
 	coin coin.Coin, multisig bool, meta interface{}) bool {
 	switch coin.(type) {
 	case *btc.Coin:
-		return !multisig
+		// Taproot is not supported by the BitBox01 firmware.
+		scriptType, ok := meta.(signing.ScriptType)
+		return !multisig && (!ok || scriptType != signing.ScriptTypeP2TR)
 	default:
 		return false
 	}
@@ -98,6 +102,19 @@ func (keystore *keystore) VerifyExtendedPublicKey(coin coin.Coin, configuration
 	return nil
 }
 
+// RootFingerprint implements keystore.Keystore.
+func (keystore *keystore) RootFingerprint() ([]byte, error) {
+	xpub, err := keystore.dbb.xpub(signing.NewEmptyAbsoluteKeypath().Encode())
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := xpub.ECPubKey()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return btcutil.Hash160(publicKey.SerializeCompressed())[:4], nil
+}
+
 // ExtendedPublicKey implements keystore.Keystore.
 func (keystore *keystore) ExtendedPublicKey(
 	coin coin.Coin, keyPath signing.AbsoluteKeypath) (*hdkeychain.ExtendedKey, error) {
@@ -160,8 +177,12 @@ func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransact
 }
 
 func (keystore *keystore) signETHTransaction(txProposal *eth.TxProposal) error {
+	tx, ok := txProposal.Tx.(*types.Transaction)
+	if !ok {
+		return errp.New("only legacy transactions are supported")
+	}
 	signatureHashes := [][]byte{
-		txProposal.Signer.Hash(txProposal.Tx).Bytes(),
+		txProposal.Signer.Hash(tx).Bytes(),
 	}
 	signatures, err := keystore.dbb.Sign(nil, signatureHashes, []string{txProposal.Keypath.Encode()})
 	if isErrorAbort(err) {
@@ -181,7 +202,7 @@ func (keystore *keystore) signETHTransaction(txProposal *eth.TxProposal) error {
 	copy(sig[:32], math.PaddedBigBytes(signature.R, 32))
 	copy(sig[32:64], math.PaddedBigBytes(signature.S, 32))
 	sig[64] = byte(signature.RecID)
-	signedTx, err := txProposal.Tx.WithSignature(txProposal.Signer, sig)
+	signedTx, err := tx.WithSignature(txProposal.Signer, sig)
 	if err != nil {
 		return err
 	}
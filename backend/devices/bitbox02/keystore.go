@@ -33,6 +33,8 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox02-api-go/api/firmware"
 	"github.com/digitalbitbox/bitbox02-api-go/api/firmware/messages"
+	"github.com/digitalbitbox/bitbox02-api-go/util/semver"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/sirupsen/logrus"
 )
 
@@ -62,7 +64,10 @@ func (keystore *keystore) SupportsAccount(
 			return false
 		}
 		scriptType := meta.(signing.ScriptType)
-		return !multisig && scriptType != signing.ScriptTypeP2PKH
+		if multisig {
+			return keystore.supportsMultisig(coin, scriptType)
+		}
+		return scriptType != signing.ScriptTypeP2PKH && scriptType != signing.ScriptTypeP2TR
 	case *eth.Coin:
 		if specificCoin.ERC20Token() != nil {
 			return keystore.device.SupportsERC20(specificCoin.ERC20Token().ContractAddress().String())
@@ -195,6 +200,25 @@ func (keystore *keystore) VerifyExtendedPublicKey(
 	return nil
 }
 
+// rootFingerprintMinFirmwareVersion is the first firmware version which returns the root
+// fingerprint of the keystore.
+var rootFingerprintMinFirmwareVersion = semver.NewSemVer(9, 2, 0)
+
+// RootFingerprint implements keystore.Keystore. Querying the root fingerprint requires a newer
+// version of the bitbox02-api-go library, which is used as soon as it is vendored.
+func (keystore *keystore) RootFingerprint() ([]byte, error) {
+	if !keystore.device.Version().AtLeast(rootFingerprintMinFirmwareVersion) {
+		return nil, errp.New("The firmware does not support querying the root fingerprint")
+	}
+	device, ok := interface{}(keystore.device).(interface {
+		RootFingerprint() ([]byte, error)
+	})
+	if !ok {
+		return nil, errp.New("The device library does not support querying the root fingerprint")
+	}
+	return device.RootFingerprint()
+}
+
 // ExtendedPublicKey implements keystore.Keystore.
 func (keystore *keystore) ExtendedPublicKey(
 	coin coinpkg.Coin, keyPath signing.AbsoluteKeypath) (*hdkeychain.ExtendedKey, error) {
@@ -230,8 +254,36 @@ func (keystore *keystore) ExtendedPublicKey(
 func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransaction) error {
 	tx := btcProposedTx.TXProposal.Transaction
 
+	coin := btcProposedTx.TXProposal.Coin.(*btc.Coin)
+	msgCoin, ok := btcMsgCoinMap[coin.Code()]
+	if !ok {
+		return errp.Newf("coin not supported: %s", coin.Code())
+	}
+
 	scriptConfigs := make([]*messages.BTCScriptConfigWithKeypath, len(btcProposedTx.AccountSigningConfigurations))
+	// The index at which the device signs, per script config. For multisig, this is the position
+	// of the xpub of the device among the cosigners.
+	cosignerIndices := make([]int, len(btcProposedTx.AccountSigningConfigurations))
 	for i, cfg := range btcProposedTx.AccountSigningConfigurations {
+		if cfg.Multisig() {
+			scriptConfig, ourXPubIndex, err := keystore.multisigScriptConfig(coin, cfg)
+			if err != nil {
+				return err
+			}
+			registered, err := keystore.scriptConfigRegistered(coin, cfg, scriptConfig)
+			if err != nil {
+				return err
+			}
+			if !registered {
+				return errp.WithStack(keystorePkg.ErrMultisigNotRegistered)
+			}
+			scriptConfigs[i] = &messages.BTCScriptConfigWithKeypath{
+				ScriptConfig: scriptConfig,
+				Keypath:      cfg.AbsoluteKeypath().ToUInt32(),
+			}
+			cosignerIndices[i] = ourXPubIndex
+			continue
+		}
 		msgScriptType, ok := btcMsgScriptTypeMap[cfg.ScriptType()]
 		if !ok {
 			return errp.Newf("Unsupported script type %s", cfg.ScriptType())
@@ -240,18 +292,18 @@ func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransact
 			ScriptConfig: firmware.NewBTCScriptConfigSimple(msgScriptType),
 			Keypath:      cfg.AbsoluteKeypath().ToUInt32(),
 		}
-	}
-	coin := btcProposedTx.TXProposal.Coin.(*btc.Coin)
-	msgCoin, ok := btcMsgCoinMap[coin.Code()]
-	if !ok {
-		return errp.Newf("coin not supported: %s", coin.Code())
+		cosignerIndices[i] = keystore.CosignerIndex()
 	}
 
 	inputs := make([]*firmware.BTCTxInput, len(tx.TxIn))
+	inputScriptConfigIndices := make([]uint32, len(tx.TxIn))
 	for inputIndex, txIn := range tx.TxIn {
 		prevOut := btcProposedTx.PreviousOutputs[txIn.PreviousOutPoint]
 
-		prevTx := btcProposedTx.GetPrevTx(txIn.PreviousOutPoint.Hash)
+		prevTx, err := btcProposedTx.GetPrevTx(txIn.PreviousOutPoint.Hash)
+		if err != nil {
+			return err
+		}
 
 		prevTxInputs := make([]*messages.BTCPrevTxInputRequest, len(prevTx.TxIn))
 		for prevInputIndex, prevTxIn := range prevTx.TxIn {
@@ -271,15 +323,12 @@ func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransact
 		}
 		inputAddress := btcProposedTx.GetAddress(prevOut.ScriptHashHex())
 
-		// Find the script config index. Assumption: there is only one entry per script type, so we
-		// don't have to check that the keypath prefix matches.
-		var scriptConfigIndex uint32
-		for i, cfg := range btcProposedTx.AccountSigningConfigurations {
-			if cfg.ScriptType() == inputAddress.Configuration.ScriptType() {
-				scriptConfigIndex = uint32(i)
-				break
-			}
+		inputScriptConfigIndex, err := scriptConfigIndex(
+			btcProposedTx.AccountSigningConfigurations, inputAddress.Configuration)
+		if err != nil {
+			return err
 		}
+		inputScriptConfigIndices[inputIndex] = inputScriptConfigIndex
 
 		inputs[inputIndex] = &firmware.BTCTxInput{
 			Input: &messages.BTCSignInputRequest{
@@ -288,7 +337,7 @@ func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransact
 				PrevOutValue:      uint64(prevOut.Value),
 				Sequence:          txIn.Sequence,
 				Keypath:           inputAddress.Configuration.AbsoluteKeypath().ToUInt32(),
-				ScriptConfigIndex: scriptConfigIndex,
+				ScriptConfigIndex: inputScriptConfigIndex,
 			},
 			PrevTx: &firmware.BTCPrevTx{
 				Version:  uint32(prevTx.Version),
@@ -317,15 +366,22 @@ func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransact
 			txOut.PkScript,
 		)
 		var keypath []uint32
+		var changeScriptConfigIndex uint32
 		if isChange {
 			keypath = changeAddress.Configuration.AbsoluteKeypath().ToUInt32()
+			changeScriptConfigIndex, err = scriptConfigIndex(
+				btcProposedTx.AccountSigningConfigurations, changeAddress.Configuration)
+			if err != nil {
+				return err
+			}
 		}
 		outputs[index] = &messages.BTCSignOutputRequest{
-			Ours:    isChange,
-			Type:    msgOutputType,
-			Value:   uint64(txOut.Value),
-			Hash:    addresses[0].ScriptAddress(),
-			Keypath: keypath,
+			Ours:              isChange,
+			Type:              msgOutputType,
+			Value:             uint64(txOut.Value),
+			Hash:              addresses[0].ScriptAddress(),
+			Keypath:           keypath,
+			ScriptConfigIndex: changeScriptConfigIndex,
 		}
 	}
 
@@ -346,7 +402,8 @@ func (keystore *keystore) signBTCTransaction(btcProposedTx *btc.ProposedTransact
 		return err
 	}
 	for index, signature := range signatures {
-		btcProposedTx.Signatures[index][keystore.CosignerIndex()] = &btcec.Signature{
+		cosignerIndex := cosignerIndices[inputScriptConfigIndices[index]]
+		btcProposedTx.Signatures[index][cosignerIndex] = &btcec.Signature{
 			R: big.NewInt(0).SetBytes(signature[:32]),
 			S: big.NewInt(0).SetBytes(signature[32:]),
 		}
@@ -359,7 +416,11 @@ func (keystore *keystore) signETHTransaction(txProposal *eth.TxProposal) error {
 	if !ok {
 		return errp.New("unsupported coin")
 	}
-	tx := txProposal.Tx
+	// The device does not support EIP-1559 transactions yet, see keystorePkg.EIP1559Keystore.
+	tx, ok := txProposal.Tx.(*types.Transaction)
+	if !ok {
+		return errp.New("only legacy transactions are supported")
+	}
 	recipient := tx.To()
 	if recipient == nil {
 		return errp.New("contract creation not supported")
@@ -380,7 +441,7 @@ func (keystore *keystore) signETHTransaction(txProposal *eth.TxProposal) error {
 	if err != nil {
 		return err
 	}
-	signedTx, err := txProposal.Tx.WithSignature(txProposal.Signer, signature)
+	signedTx, err := tx.WithSignature(txProposal.Signer, signature)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,205 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitbox02
+
+import (
+	"bytes"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	keystorePkg "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox02-api-go/api/firmware"
+	"github.com/digitalbitbox/bitbox02-api-go/api/firmware/messages"
+	"github.com/digitalbitbox/bitbox02-api-go/util/semver"
+)
+
+// multisigMinFirmwareVersion is the first firmware version supporting P2WSH multisig.
+var multisigMinFirmwareVersion = semver.NewSemVer(9, 2, 0)
+
+// supportsMultisig returns true if the device can be a cosigner of multisig accounts of the coin.
+// Only P2WSH multisig for Bitcoin is supported by the device.
+func (keystore *keystore) supportsMultisig(coin coinpkg.Coin, scriptType signing.ScriptType) bool {
+	if coin.Code() != coinpkg.CodeBTC && coin.Code() != coinpkg.CodeTBTC {
+		return false
+	}
+	return scriptType == signing.ScriptTypeP2WSH &&
+		keystore.device.Version().AtLeast(multisigMinFirmwareVersion)
+}
+
+// sameXPub returns true if the two extended public keys have the same public key and chain code.
+// The version bytes are ignored, as the device always returns xpubs.
+func sameXPub(xpub1, xpub2 string) (bool, error) {
+	decoded1, err := firmware.NewXPub(xpub1)
+	if err != nil {
+		return false, errp.WithStack(err)
+	}
+	decoded2, err := firmware.NewXPub(xpub2)
+	if err != nil {
+		return false, errp.WithStack(err)
+	}
+	return bytes.Equal(decoded1.PublicKey, decoded2.PublicKey) &&
+		bytes.Equal(decoded1.ChainCode, decoded2.ChainCode), nil
+}
+
+// ourXPubIndex returns the index of the extended public key of the device in the multisig
+// configuration.
+func (keystore *keystore) ourXPubIndex(
+	coin coinpkg.Coin, configuration *signing.Configuration) (int, error) {
+	ourXPub, err := keystore.ExtendedPublicKey(coin, configuration.AbsoluteKeypath())
+	if err != nil {
+		return 0, err
+	}
+	for index, xpub := range configuration.ExtendedPublicKeys() {
+		same, err := sameXPub(ourXPub.String(), xpub.String())
+		if err != nil {
+			return 0, err
+		}
+		if same {
+			return index, nil
+		}
+	}
+	return 0, errp.New("The BitBox02 is not a cosigner of the multisig account")
+}
+
+// multisigScriptConfig returns the script config of the multisig configuration and the index of
+// the device among the cosigners.
+func (keystore *keystore) multisigScriptConfig(
+	coin coinpkg.Coin, configuration *signing.Configuration) (*messages.BTCScriptConfig, int, error) {
+	// The device only supports sorted multisig scripts.
+	if !configuration.Multisig() || !configuration.SortedMultisig() ||
+		!keystore.supportsMultisig(coin, configuration.MultisigScriptType()) {
+		return nil, 0, errp.New("Unsupported multisig configuration")
+	}
+	ourXPubIndex, err := keystore.ourXPubIndex(coin, configuration)
+	if err != nil {
+		return nil, 0, err
+	}
+	xpubs := make([]string, len(configuration.ExtendedPublicKeys()))
+	for index, xpub := range configuration.ExtendedPublicKeys() {
+		xpubs[index] = xpub.String()
+	}
+	scriptConfig, err := firmware.NewBTCScriptConfigMultisig(
+		uint32(configuration.SigningThreshold()), xpubs, uint32(ourXPubIndex))
+	if err != nil {
+		return nil, 0, errp.WithStack(err)
+	}
+	return scriptConfig, ourXPubIndex, nil
+}
+
+// scriptConfigRegistered returns true if the multisig script config is registered on the device.
+func (keystore *keystore) scriptConfigRegistered(
+	coin coinpkg.Coin,
+	configuration *signing.Configuration,
+	scriptConfig *messages.BTCScriptConfig,
+) (bool, error) {
+	return keystore.device.BTCIsScriptConfigRegistered(
+		btcMsgCoinMap[coin.Code()],
+		scriptConfig,
+		configuration.AbsoluteKeypath().ToUInt32(),
+	)
+}
+
+// MultisigRegistered implements keystore.MultisigKeystore.
+func (keystore *keystore) MultisigRegistered(
+	coin coinpkg.Coin, configuration *signing.Configuration) (bool, error) {
+	scriptConfig, _, err := keystore.multisigScriptConfig(coin, configuration)
+	if err != nil {
+		return false, err
+	}
+	return keystore.scriptConfigRegistered(coin, configuration, scriptConfig)
+}
+
+// RegisterMultisig implements keystore.MultisigKeystore.
+func (keystore *keystore) RegisterMultisig(
+	coin coinpkg.Coin, configuration *signing.Configuration, name string) error {
+	scriptConfig, _, err := keystore.multisigScriptConfig(coin, configuration)
+	if err != nil {
+		return err
+	}
+	err = keystore.device.BTCRegisterScriptConfig(
+		btcMsgCoinMap[coin.Code()],
+		scriptConfig,
+		configuration.AbsoluteKeypath().ToUInt32(),
+		name,
+	)
+	if firmware.IsErrorAbort(err) {
+		return errp.WithStack(keystorePkg.ErrSigningAborted)
+	}
+	return err
+}
+
+// VerifyMultisigAddress implements keystore.MultisigKeystore.
+func (keystore *keystore) VerifyMultisigAddress(
+	coin coinpkg.Coin, configuration *signing.Configuration, keypath signing.AbsoluteKeypath) error {
+	if _, ok := coin.(*btc.Coin); !ok {
+		return errp.New("unsupported coin")
+	}
+	scriptConfig, _, err := keystore.multisigScriptConfig(coin, configuration)
+	if err != nil {
+		return err
+	}
+	registered, err := keystore.scriptConfigRegistered(coin, configuration, scriptConfig)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		return errp.WithStack(keystorePkg.ErrMultisigNotRegistered)
+	}
+	_, err = keystore.device.BTCAddress(
+		btcMsgCoinMap[coin.Code()],
+		keypath.ToUInt32(),
+		scriptConfig,
+		true,
+	)
+	if firmware.IsErrorAbort(err) {
+		// No special action on user abort.
+		return nil
+	}
+	return err
+}
+
+// scriptConfigIndex returns the index of the account configuration from which the address
+// configuration was derived, i.e. whose keypath is the address keypath without the last two
+// elements (change and address index).
+func scriptConfigIndex(
+	accountConfigurations []*signing.Configuration,
+	addressConfiguration *signing.Configuration,
+) (uint32, error) {
+	addressKeypath := addressConfiguration.AbsoluteKeypath().ToUInt32()
+	for index, configuration := range accountConfigurations {
+		accountKeypath := configuration.AbsoluteKeypath().ToUInt32()
+		if len(addressKeypath) != len(accountKeypath)+2 ||
+			configuration.Multisig() != addressConfiguration.Multisig() {
+			continue
+		}
+		if !configuration.Multisig() &&
+			configuration.ScriptType() != addressConfiguration.ScriptType() {
+			continue
+		}
+		matches := true
+		for i := range accountKeypath {
+			if accountKeypath[i] != addressKeypath[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return uint32(index), nil
+		}
+	}
+	return 0, errp.New("Could not find the account configuration of an address")
+}
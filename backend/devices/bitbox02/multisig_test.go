@@ -0,0 +1,178 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitbox02
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/ltc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	bitbox02common "github.com/digitalbitbox/bitbox02-api-go/api/common"
+	"github.com/digitalbitbox/bitbox02-api-go/util/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeystore(version *semver.SemVer) *keystore {
+	return &keystore{
+		device: NewDevice(
+			"device-id", version, bitbox02common.ProductBitBox02Multi, nil, nil),
+		log: logging.Get().WithGroup("multisig_test"),
+	}
+}
+
+func newTestXPub(t *testing.T, seed byte, net *chaincfg.Params) *hdkeychain.ExtendedKey {
+	t.Helper()
+	master, err := hdkeychain.NewMaster(append(make([]byte, 31), seed), net)
+	require.NoError(t, err)
+	xpub, err := master.Neuter()
+	require.NoError(t, err)
+	return xpub
+}
+
+func TestSupportsMultisig(t *testing.T) {
+	proxy := socksproxy.NewSocksProxy(false, "")
+	coins := map[coinpkg.Code]*btc.Coin{
+		coinpkg.CodeBTC:  btc.NewCoin(coinpkg.CodeBTC, "BTC", &chaincfg.MainNetParams, "", nil, "", proxy),
+		coinpkg.CodeTBTC: btc.NewCoin(coinpkg.CodeTBTC, "TBTC", &chaincfg.TestNet3Params, "", nil, "", proxy),
+		coinpkg.CodeLTC:  btc.NewCoin(coinpkg.CodeLTC, "LTC", &ltc.MainNetParams, "", nil, "", proxy),
+	}
+	tests := []struct {
+		version    *semver.SemVer
+		coinCode   coinpkg.Code
+		scriptType signing.ScriptType
+		expected   bool
+	}{
+		{semver.NewSemVer(9, 1, 0), coinpkg.CodeBTC, signing.ScriptTypeP2WSH, false},
+		{semver.NewSemVer(9, 1, 9), coinpkg.CodeTBTC, signing.ScriptTypeP2WSH, false},
+		{semver.NewSemVer(9, 2, 0), coinpkg.CodeBTC, signing.ScriptTypeP2WSH, true},
+		{semver.NewSemVer(9, 2, 0), coinpkg.CodeTBTC, signing.ScriptTypeP2WSH, true},
+		{semver.NewSemVer(9, 3, 1), coinpkg.CodeBTC, signing.ScriptTypeP2WSH, true},
+		{semver.NewSemVer(9, 2, 0), coinpkg.CodeBTC, signing.ScriptTypeP2WSHP2SH, false},
+		{semver.NewSemVer(9, 2, 0), coinpkg.CodeBTC, signing.ScriptTypeP2PKH, false},
+		{semver.NewSemVer(9, 2, 0), coinpkg.CodeLTC, signing.ScriptTypeP2WSH, false},
+	}
+	for _, test := range tests {
+		keystore := newTestKeystore(test.version)
+		coin := coins[test.coinCode]
+		require.Equal(t, test.expected, keystore.supportsMultisig(coin, test.scriptType),
+			"%s %s %s", test.version, test.coinCode, test.scriptType)
+		require.Equal(t, test.expected, keystore.SupportsAccount(coin, true, test.scriptType),
+			"%s %s %s", test.version, test.coinCode, test.scriptType)
+	}
+}
+
+func TestRootFingerprintUnsupported(t *testing.T) {
+	// Too old firmware.
+	_, err := newTestKeystore(semver.NewSemVer(9, 1, 0)).RootFingerprint()
+	require.Error(t, err)
+	// The vendored device library does not query the root fingerprint yet.
+	_, err = newTestKeystore(semver.NewSemVer(9, 2, 0)).RootFingerprint()
+	require.Error(t, err)
+}
+
+func TestSameXPub(t *testing.T) {
+	xpub := newTestXPub(t, 1, &chaincfg.MainNetParams)
+	tpub := newTestXPub(t, 1, &chaincfg.TestNet3Params)
+	otherXPub := newTestXPub(t, 2, &chaincfg.MainNetParams)
+	tests := []struct {
+		xpub1    string
+		xpub2    string
+		expected bool
+	}{
+		{xpub.String(), xpub.String(), true},
+		// The version bytes are ignored.
+		{xpub.String(), tpub.String(), true},
+		{tpub.String(), xpub.String(), true},
+		{xpub.String(), otherXPub.String(), false},
+		{tpub.String(), otherXPub.String(), false},
+	}
+	for index, test := range tests {
+		same, err := sameXPub(test.xpub1, test.xpub2)
+		require.NoError(t, err, index)
+		require.Equal(t, test.expected, same, index)
+	}
+
+	_, err := sameXPub("invalid", xpub.String())
+	require.Error(t, err)
+	_, err = sameXPub(xpub.String(), "invalid")
+	require.Error(t, err)
+}
+
+func TestScriptConfigIndex(t *testing.T) {
+	net := &chaincfg.TestNet3Params
+	newKeypath := func(keypath string) signing.AbsoluteKeypath {
+		absoluteKeypath, err := signing.NewAbsoluteKeypath(keypath)
+		require.NoError(t, err)
+		return absoluteKeypath
+	}
+	p2wpkh := signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKH, newKeypath("m/84'/1'/0'"), newTestXPub(t, 1, net))
+	p2wpkhP2SH := signing.NewSinglesigConfiguration(
+		signing.ScriptTypeP2WPKHP2SH, newKeypath("m/49'/1'/0'"), newTestXPub(t, 2, net))
+	multisig := signing.NewConfiguration(
+		signing.ScriptTypeP2WSH,
+		newKeypath("m/48'/1'/0'/2'"),
+		[]*hdkeychain.ExtendedKey{newTestXPub(t, 3, net), newTestXPub(t, 4, net)},
+		"",
+		1,
+	)
+	accountConfigurations := []*signing.Configuration{p2wpkh, p2wpkhP2SH, multisig}
+	addressConfiguration := func(configuration *signing.Configuration) *signing.Configuration {
+		relativeKeypath, err := signing.NewRelativeKeypath("1/5")
+		require.NoError(t, err)
+		derived, err := configuration.Derive(relativeKeypath)
+		require.NoError(t, err)
+		return derived
+	}
+
+	tests := []struct {
+		addressConfiguration *signing.Configuration
+		expectedIndex        uint32
+	}{
+		{addressConfiguration(p2wpkh), 0},
+		{addressConfiguration(p2wpkhP2SH), 1},
+		{addressConfiguration(multisig), 2},
+	}
+	for _, test := range tests {
+		index, err := scriptConfigIndex(accountConfigurations, test.addressConfiguration)
+		require.NoError(t, err)
+		require.Equal(t, test.expectedIndex, index)
+	}
+
+	invalid := []*signing.Configuration{
+		// Another script type at the same keypath.
+		signing.NewSinglesigConfiguration(
+			signing.ScriptTypeP2TR, newKeypath("m/84'/1'/0'/1/5"), newTestXPub(t, 1, net)),
+		// Another account keypath.
+		signing.NewSinglesigConfiguration(
+			signing.ScriptTypeP2WPKH, newKeypath("m/84'/1'/1'/1/5"), newTestXPub(t, 1, net)),
+		// Not an address keypath of the account.
+		signing.NewSinglesigConfiguration(
+			signing.ScriptTypeP2WPKH, newKeypath("m/84'/1'/0'/5"), newTestXPub(t, 1, net)),
+		// A singlesig configuration at the multisig keypath.
+		signing.NewSinglesigConfiguration(
+			signing.ScriptTypeP2WPKH, newKeypath("m/48'/1'/0'/2'/1/5"), newTestXPub(t, 1, net)),
+	}
+	for index, configuration := range invalid {
+		_, err := scriptConfigIndex(accountConfigurations, configuration)
+		require.Error(t, err, index)
+	}
+}
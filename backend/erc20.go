@@ -16,10 +16,40 @@
 package backend
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
 )
 
+// ErrERC20TokenAlreadyExists is returned when adding an ERC20 token which is already available.
+var ErrERC20TokenAlreadyExists = errors.New("erc20 token already exists")
+
+// ErrERC20TokenInvalidAddress is returned when adding an ERC20 token with an invalid contract
+// address.
+var ErrERC20TokenInvalidAddress = errors.New("invalid erc20 contract address")
+
+// ErrERC20TokenNotSupported is returned when adding an ERC20 token which no connected keystore
+// supports.
+var ErrERC20TokenNotSupported = errors.New("erc20 token not supported")
+
+// ErrERC20TokenUnitReserved is returned when adding an ERC20 token whose symbol is the unit of a
+// coin with fiat rates, e.g. a token impersonating USDT.
+var ErrERC20TokenUnitReserved = errors.New("erc20 token unit reserved")
+
+// ErrERC20TokenNotFound is returned when removing an ERC20 token which was not added by the user.
+var ErrERC20TokenNotFound = errors.New("erc20 token not found")
+
+// erc20Keypath is the keypath of the ERC20 token accounts, the same as of the Ethereum account.
+const erc20Keypath = "m/44'/60'/0'/0"
+
 type erc20Token struct {
 	code  coin.Code
 	name  string
@@ -80,8 +110,38 @@ var erc20Tokens = []erc20Token{
 	},
 }
 
-func erc20TokenByCode(code coin.Code) *erc20Token {
-	for _, token := range erc20Tokens {
+// CustomERC20CoinCode returns the coin code of the ERC20 token added by the user.
+func CustomERC20CoinCode(token config.ERC20Token) coin.Code {
+	return coin.Code(erc20CoinCodePrefix + token.Code)
+}
+
+// customERC20Tokens returns the ERC20 tokens added by the user.
+func (backend *Backend) customERC20Tokens() []erc20Token {
+	tokens := []erc20Token{}
+	for _, token := range backend.config.AppConfig().Backend.ETH.CustomERC20Tokens {
+		if !common.IsHexAddress(token.ContractAddress) {
+			backend.log.WithField("code", token.Code).Error("Skipping custom ERC20 token with invalid address")
+			continue
+		}
+		tokens = append(tokens, erc20Token{
+			code:  CustomERC20CoinCode(token),
+			name:  token.Name,
+			unit:  token.Unit,
+			token: erc20.NewToken(token.ContractAddress, token.Decimals),
+		})
+	}
+	return tokens
+}
+
+// allERC20Tokens returns the built-in and the custom ERC20 tokens.
+func (backend *Backend) allERC20Tokens() []erc20Token {
+	return append(append([]erc20Token{}, erc20Tokens...), backend.customERC20Tokens()...)
+}
+
+// erc20TokenByCode returns the built-in or custom ERC20 token with the given coin code, or nil if
+// there is no such token.
+func (backend *Backend) erc20TokenByCode(code coin.Code) *erc20Token {
+	for _, token := range backend.allERC20Tokens() {
 		if code == token.code {
 			token := token
 			return &token
@@ -89,3 +149,172 @@ func erc20TokenByCode(code coin.Code) *erc20Token {
 	}
 	return nil
 }
+
+// erc20UnitRated returns true if amounts of the given unit are converted to fiat. Rates are looked
+// up by unit, also for testnet coins using the mainnet rates, see coin.Conversions.
+func erc20UnitRated(unit string) bool {
+	unit = strings.ToUpper(unit)
+	if len(unit) == 4 && strings.HasPrefix(unit, "T") || unit == "RETH" {
+		if rates.IsSupportedCoin(unit[1:]) {
+			return true
+		}
+	}
+	return rates.IsSupportedCoin(unit)
+}
+
+// erc20TokenSupported returns true if a connected keystore supports the ERC20 token deployed at the
+// given contract address.
+func (backend *Backend) erc20TokenSupported(contractAddress common.Address) bool {
+	// The decimals are not needed to check if the token is supported.
+	tokenCoin := eth.NewCoin("", "", "ETH", params.MainnetChainConfig, "", nil, "",
+		erc20.NewToken(contractAddress.Hex(), 0), backend.socksProxy)
+	for _, keystore := range backend.keystores.Keystores() {
+		if keystore.SupportsAccount(tokenCoin, false, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomERC20Tokens returns the ERC20 tokens added by the user.
+func (backend *Backend) CustomERC20Tokens() []config.ERC20Token {
+	return backend.config.AppConfig().Backend.ETH.CustomERC20Tokens
+}
+
+// AddERC20Token adds the ERC20 token deployed at the given contract address. The name, unit and
+// decimals are fetched from the contract using the configured Ethereum node. A connected keystore
+// must support the token. The token is persisted and activated, and its account is created for
+// the registered keystore. Keystores which are registered for the first time later get the
+// account with their default accounts. Custom tokens have no fiat value. Returns the coin code of
+// the token.
+func (backend *Backend) AddERC20Token(contractAddress string) (coin.Code, error) {
+	if backend.Testing() {
+		return "", errp.New("Custom ERC20 tokens are only supported on mainnet")
+	}
+	contractAddress = strings.TrimSpace(contractAddress)
+	if !common.IsHexAddress(contractAddress) {
+		return "", errp.WithStack(ErrERC20TokenInvalidAddress)
+	}
+	address := common.HexToAddress(contractAddress)
+	for _, token := range backend.allERC20Tokens() {
+		if token.token.ContractAddress() == address {
+			return "", errp.WithStack(ErrERC20TokenAlreadyExists)
+		}
+	}
+	if !backend.erc20TokenSupported(address) {
+		return "", errp.WithStack(ErrERC20TokenNotSupported)
+	}
+	ethCoin, err := backend.Coin(coin.CodeETH)
+	if err != nil {
+		return "", err
+	}
+	info, err := ethCoin.(*eth.Coin).FetchERC20TokenInfo(address)
+	if err != nil {
+		return "", err
+	}
+	if info.Symbol == "" {
+		return "", errp.New("The contract does not define a token symbol")
+	}
+	// Custom tokens have no fiat value. A token using the unit of a coin with rates would be
+	// valued like that coin.
+	if erc20UnitRated(info.Symbol) {
+		return "", errp.WithStack(ErrERC20TokenUnitReserved)
+	}
+	if info.Name == "" {
+		info.Name = info.Symbol
+	}
+
+	token := config.ERC20Token{
+		Code:            strings.ToLower(address.Hex()),
+		ContractAddress: address.Hex(),
+		Name:            info.Name,
+		Unit:            info.Symbol,
+		Decimals:        info.Decimals,
+	}
+	code := CustomERC20CoinCode(token)
+	appConfig := backend.config.AppConfig()
+	ethConfig := &appConfig.Backend.ETH
+	ethConfig.CustomERC20Tokens = append(
+		append([]config.ERC20Token{}, ethConfig.CustomERC20Tokens...), token)
+	if !ethConfig.ERC20TokenActive(token.Code) {
+		ethConfig.ActiveERC20Tokens = append(
+			append([]string{}, ethConfig.ActiveERC20Tokens...), token.Code)
+	}
+	if err := backend.config.SetAppConfig(appConfig); err != nil {
+		return "", err
+	}
+	backend.log.WithField("code", code).WithField("unit", info.Symbol).Info("Added custom ERC20 token")
+
+	if backend.keystores.Count() == 1 {
+		keystore := backend.keystores.Keystores()[0]
+		rootFingerprint, err := keystore.RootFingerprint()
+		if err != nil {
+			return "", err
+		}
+		tokenCoin, err := backend.Coin(code)
+		if err != nil {
+			return "", err
+		}
+		accounts, err := backend.defaultETHAccounts(
+			keystore, tokenCoin, string(code), info.Name, erc20Keypath)
+		if err != nil {
+			return "", err
+		}
+		for _, account := range accounts {
+			// The token transfers are found without scanning the blocks, so the birth height is
+			// not needed.
+			if err := backend.persistDefaultAccount(keystore, rootFingerprint, account, 0); err != nil {
+				return "", err
+			}
+		}
+	}
+	backend.ReinitializeAccounts()
+	return code, nil
+}
+
+// RemoveERC20Token removes the custom ERC20 token with the given coin code, as well as its
+// persisted accounts.
+func (backend *Backend) RemoveERC20Token(code coin.Code) error {
+	tokenCode := strings.TrimPrefix(string(code), erc20CoinCodePrefix)
+	appConfig := backend.config.AppConfig()
+	ethConfig := &appConfig.Backend.ETH
+	customTokens := []config.ERC20Token{}
+	for _, token := range ethConfig.CustomERC20Tokens {
+		if token.Code != tokenCode {
+			customTokens = append(customTokens, token)
+		}
+	}
+	if !strings.HasPrefix(string(code), erc20CoinCodePrefix) ||
+		len(customTokens) == len(ethConfig.CustomERC20Tokens) {
+		return errp.WithStack(ErrERC20TokenNotFound)
+	}
+	ethConfig.CustomERC20Tokens = customTokens
+	activeTokens := []string{}
+	for _, activeToken := range ethConfig.ActiveERC20Tokens {
+		if activeToken != tokenCode {
+			activeTokens = append(activeTokens, activeToken)
+		}
+	}
+	ethConfig.ActiveERC20Tokens = activeTokens
+	if err := backend.config.SetAppConfig(appConfig); err != nil {
+		return err
+	}
+	func() {
+		defer backend.coinsLock.Lock()()
+		delete(backend.coins, code)
+	}()
+	backend.log.WithField("code", code).Info("Removed custom ERC20 token")
+
+	err := backend.removeAccounts(func(account *config.Account) bool {
+		return account.CoinCode == code
+	})
+	if err != nil && errp.Cause(err) != ErrAccountNotPersisted {
+		return err
+	}
+	if err := backend.removeKeystoresCoin(code); err != nil {
+		return err
+	}
+	// The token's coin is gone, so also the accounts which are not persisted must be reloaded.
+	backend.ReinitializeAccounts()
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/arguments"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestERC20UnitRated(t *testing.T) {
+	for _, unit := range []string{"ETH", "USDT", "usdt", "MKR", "TETH", "RETH", "TBTC"} {
+		require.True(t, erc20UnitRated(unit), unit)
+	}
+	for _, unit := range []string{"", "SAI", "UNI", "TUSD", "USDTT", "TSOME"} {
+		require.False(t, erc20UnitRated(unit), unit)
+	}
+}
+
+func TestAddERC20TokenNotSupported(t *testing.T) {
+	mainDirectoryPath, err := ioutil.TempDir("", "backend")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(mainDirectoryPath) }()
+	backend, err := NewBackend(arguments.NewArguments(
+		mainDirectoryPath,
+		false, // testing
+		false, // regtest
+		false, // devmode
+		false, // devservers
+		nil,   // gap limits
+	), testEnvironment{})
+	require.NoError(t, err)
+	defer func() { _ = backend.Close() }()
+	backend.OnAccountInit(func(accounts.Interface) {})
+	backend.OnAccountUninit(func(accounts.Interface) {})
+
+	const contractAddress = "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984"
+	// No keystore connected.
+	_, err = backend.AddERC20Token(contractAddress)
+	require.Equal(t, ErrERC20TokenNotSupported, errp.Cause(err))
+
+	// The software keystore does not support Ethereum accounts.
+	master, err := hdkeychain.NewMaster(make([]byte, 32), &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.NoError(t, backend.keystores.Add(software.NewKeystore(0, master)))
+	_, err = backend.AddERC20Token(contractAddress)
+	require.Equal(t, ErrERC20TokenNotSupported, errp.Cause(err))
+
+	require.Empty(t, backend.CustomERC20Tokens())
+}
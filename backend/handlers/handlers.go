@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -107,6 +108,22 @@ type Backend interface {
 	NotifyUser(string)
 	SystemOpen(string) error
 	ReinitializeAccounts()
+	KeystoreConnectedAccounts() map[string]bool
+	DiscoverAccounts() error
+	RenameAccount(code string, name string) error
+	SetAccountHidden(code string, hidden bool) error
+	SetAccountBirthHeight(code string, birthHeight uint64) error
+	ReorderAccounts(codes []string) error
+	RemoveAccount(code string) error
+	CustomERC20Tokens() []config.ERC20Token
+	AddERC20Token(contractAddress string) (coinpkg.Code, error)
+	RemoveERC20Token(code coinpkg.Code) error
+	CreateAndAddKeystoreAccount(
+		coin coinpkg.Coin,
+		name string,
+		scriptType signing.ScriptType,
+		keypath signing.AbsoluteKeypath,
+	) (string, error)
 	CheckForUpdateIgnoringErrors() *backend.UpdateFile
 	Banners() *banners.Banners
 	Environment() backend.Environment
@@ -187,9 +204,20 @@ func NewHandlers(
 	getAPIRouter(apiRouter)("/version", handlers.getVersionHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/testing", handlers.getTestingHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/account-add", handlers.postAddAccountHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/account-add-keystore", handlers.postAddKeystoreAccountHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/keystores", handlers.getKeystoresHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/accounts", handlers.getAccountsHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/accounts/reinitialize", handlers.postAccountsReinitializeHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/discover", handlers.postAccountsDiscoverHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/persisted", handlers.getPersistedAccountsHandler).Methods("GET")
+	getAPIRouter(apiRouter)("/accounts/rename", handlers.postAccountsRenameHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/set-hidden", handlers.postAccountsSetHiddenHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/set-birth-height", handlers.postAccountsSetBirthHeightHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/reorder", handlers.postAccountsReorderHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/accounts/remove", handlers.postAccountsRemoveHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/erc20-tokens", handlers.getERC20TokensHandler).Methods("GET")
+	getAPIRouter(apiRouter)("/erc20-tokens/add", handlers.postERC20TokensAddHandler).Methods("POST")
+	getAPIRouter(apiRouter)("/erc20-tokens/remove", handlers.postERC20TokensRemoveHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/export-account-summary", handlers.postExportAccountSummary).Methods("POST")
 	getAPIRouter(apiRouter)("/account-summary", handlers.getAccountSummary).Methods("GET")
 	getAPIRouter(apiRouter)("/test/register", handlers.postRegisterTestKeystoreHandler).Methods("POST")
@@ -403,76 +431,209 @@ func (handlers *Handlers) getTestingHandler(_ *http.Request) (interface{}, error
 	return handlers.backend.Testing(), nil
 }
 
+// singlesigConfiguration parses a watch-only singlesig extended public key. If the key is invalid,
+// the error code for the frontend is returned. wrongNet is true if the version bytes of the key
+// do not match the script type.
+func singlesigConfiguration(
+	coin coinpkg.Coin,
+	scriptType signing.ScriptType,
+	jsonExtendedPublicKey string,
+) (configuration *signing.Configuration, errorCode string, wrongNet bool) {
+	extendedPublicKey, err := hdkeychain.NewKeyFromString(strings.TrimSpace(jsonExtendedPublicKey))
+	if err != nil {
+		return nil, "xpubInvalid", false
+	}
+	if extendedPublicKey.IsPrivate() {
+		return nil, "xprivEntered", false
+	}
+	if btcCoin, ok := coin.(*btc.Coin); ok {
+		expectedNet := &chaincfg.Params{
+			HDPublicKeyID: btc.XPubVersionForScriptType(btcCoin, scriptType),
+		}
+		wrongNet = !extendedPublicKey.IsForNet(expectedNet)
+	}
+	return signing.NewSinglesigConfiguration(
+		scriptType, signing.NewEmptyAbsoluteKeypath(), extendedPublicKey), "", wrongNet
+}
+
 func (handlers *Handlers) postAddAccountHandler(r *http.Request) (interface{}, error) {
-	jsonBody := map[string]string{}
+	// The following parameters only work for watch-only accounts at the moment. Descriptors replace
+	// the script type and the extended public keys. Multiple extended public keys and a threshold
+	// make a multisig account, with a multisig script type and the keypath of the xpubs, which
+	// defaults to the first BIP48 account. Subaccounts or multiple descriptors make
+	// an account combining multiple script types (bitcoin-like coins only).
+	var jsonBody struct {
+		CoinCode           coinpkg.Code `json:"coinCode"`
+		ScriptType         string       `json:"scriptType"`
+		AccountName        string       `json:"accountName"`
+		ExtendedPublicKey  string       `json:"extendedPublicKey"`
+		ExtendedPublicKeys []string     `json:"extendedPublicKeys"`
+		Threshold          int          `json:"threshold"`
+		Keypath            string       `json:"keypath"`
+		Address            string       `json:"address"`
+		Descriptor         string       `json:"descriptor"`
+		Descriptors        []string     `json:"descriptors"`
+		Subaccounts        []struct {
+			ScriptType        string `json:"scriptType"`
+			ExtendedPublicKey string `json:"extendedPublicKey"`
+		} `json:"subaccounts"`
+	}
 	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
 		return nil, errp.WithStack(err)
 	}
-	// The following parameters only work for watch-only singlesig accounts at the moment.
-	jsonCoinCode := coinpkg.Code(jsonBody["coinCode"])
-	jsonScriptType := jsonBody["scriptType"]
-	jsonAccountName := jsonBody["accountName"]
-	jsonExtendedPublicKey := jsonBody["extendedPublicKey"]
-	jsonAddress := jsonBody["address"]
+	if jsonBody.Descriptor != "" {
+		jsonBody.Descriptors = append([]string{jsonBody.Descriptor}, jsonBody.Descriptors...)
+	}
+	multisig := len(jsonBody.ExtendedPublicKeys) != 0
 
-	coin, err := handlers.backend.Coin(jsonCoinCode)
+	coin, err := handlers.backend.Coin(jsonBody.CoinCode)
 	if err != nil {
 		return nil, err
 	}
+	btcCoin, isBTCCoin := coin.(*btc.Coin)
 
-	scriptType, err := signing.DecodeScriptType(jsonScriptType)
-	if err != nil {
-		return nil, err
+	var scriptType signing.ScriptType
+	switch {
+	case len(jsonBody.Descriptors) != 0, len(jsonBody.Subaccounts) != 0:
+	case multisig:
+		scriptType, err = signing.DecodeMultisigScriptType(jsonBody.ScriptType)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		scriptType, err = signing.DecodeScriptType(jsonBody.ScriptType)
+		if err != nil {
+			return nil, err
+		}
 	}
 	keypath := signing.NewEmptyAbsoluteKeypath()
 
-	var configuration *signing.Configuration
+	var configurations signing.Configurations
 	var warningCode string
 
-	if jsonAddress != "" {
-		switch jsonCoinCode {
+	switch {
+	case len(jsonBody.Descriptors) != 0:
+		if !isBTCCoin {
+			return map[string]interface{}{"success": false, "errorCode": "descriptorInvalid"}, nil
+		}
+		for _, descriptor := range jsonBody.Descriptors {
+			configuration, err := signing.ParseDescriptor(descriptor, btcCoin.Net())
+			if err != nil {
+				return map[string]interface{}{
+					"success":      false,
+					"errorCode":    "descriptorInvalid",
+					"errorMessage": err.Error(),
+				}, nil
+			}
+			configurations = append(configurations, configuration)
+		}
+	case len(jsonBody.Subaccounts) != 0:
+		if !isBTCCoin && len(jsonBody.Subaccounts) != 1 {
+			return map[string]interface{}{"success": false, "errorCode": "xpubInvalid"}, nil
+		}
+		for _, subaccount := range jsonBody.Subaccounts {
+			scriptType, err := signing.DecodeScriptType(subaccount.ScriptType)
+			if err != nil {
+				return map[string]interface{}{"success": false, "errorCode": "scriptTypeInvalid"}, nil
+			}
+			configuration, errorCode, wrongNet := singlesigConfiguration(
+				coin, scriptType, subaccount.ExtendedPublicKey)
+			if errorCode != "" {
+				return map[string]interface{}{"success": false, "errorCode": errorCode}, nil
+			}
+			if wrongNet {
+				warningCode = "xpubWrongNet"
+			}
+			configurations = append(configurations, configuration)
+		}
+	case multisig:
+		if !isBTCCoin {
+			return map[string]interface{}{"success": false, "errorCode": "multisigInvalid"}, nil
+		}
+		numberOfSigners := len(jsonBody.ExtendedPublicKeys)
+		if numberOfSigners < 2 || jsonBody.Threshold < 1 || jsonBody.Threshold > numberOfSigners {
+			return map[string]interface{}{"success": false, "errorCode": "multisigInvalid"}, nil
+		}
+		extendedPublicKeys := make([]*hdkeychain.ExtendedKey, numberOfSigners)
+		seen := map[string]struct{}{}
+		for index, jsonExtendedPublicKey := range jsonBody.ExtendedPublicKeys {
+			extendedPublicKey, err := hdkeychain.NewKeyFromString(strings.TrimSpace(jsonExtendedPublicKey))
+			if err != nil {
+				return map[string]interface{}{"success": false, "errorCode": "xpubInvalid"}, nil
+			}
+			if extendedPublicKey.IsPrivate() {
+				return map[string]interface{}{"success": false, "errorCode": "xprivEntered"}, nil
+			}
+			if !extendedPublicKey.IsForNet(btcCoin.Net()) {
+				warningCode = "xpubWrongNet"
+			}
+			// The version bytes do not matter, so they are ignored when looking for duplicates.
+			extendedPublicKey.SetNet(btcCoin.Net())
+			if _, ok := seen[extendedPublicKey.String()]; ok {
+				return map[string]interface{}{"success": false, "errorCode": "xpubDuplicate"}, nil
+			}
+			seen[extendedPublicKey.String()] = struct{}{}
+			extendedPublicKeys[index] = extendedPublicKey
+		}
+		// The keypath of the xpubs is needed by the cosigning keystores. It defaults to the first
+		// BIP48 account.
+		multisigKeypath, err := signing.NewBIP48Keypath(btcCoin.Net().HDCoinType, 0, scriptType)
+		if err != nil {
+			return nil, err
+		}
+		if jsonBody.Keypath != "" {
+			multisigKeypath, err = signing.NewAbsoluteKeypath(jsonBody.Keypath)
+			if err != nil || len(multisigKeypath) == 0 {
+				return map[string]interface{}{"success": false, "errorCode": "keypathInvalid"}, nil
+			}
+		}
+		configurations = signing.Configurations{signing.NewConfiguration(
+			scriptType, multisigKeypath, extendedPublicKeys, "", jsonBody.Threshold)}
+	case jsonBody.Address != "":
+		switch jsonBody.CoinCode {
 		case coinpkg.CodeBTC, coinpkg.CodeLTC, coinpkg.CodeTBTC, coinpkg.CodeTLTC:
-			btcCoin, ok := coin.(*btc.Coin)
-			if !ok {
+			if !isBTCCoin {
 				panic("unexpected type, expected: *btc.Coin")
 			}
-			_, err := btcCoin.DecodeAddress(jsonAddress)
+			_, err := btcCoin.DecodeAddress(jsonBody.Address)
 			if err != nil {
 				return map[string]interface{}{"success": false, "errorCode": "invalidAddress"}, nil
 			}
-			configuration = signing.NewAddressConfiguration(scriptType, keypath, jsonAddress)
 		case coinpkg.CodeETH, coinpkg.CodeTETH:
-			if !common.IsHexAddress(jsonAddress) {
+			if !common.IsHexAddress(jsonBody.Address) {
 				return map[string]interface{}{"success": false, "errorCode": "invalidAddress"}, nil
 			}
-			configuration = signing.NewAddressConfiguration(scriptType, keypath, jsonAddress)
 		}
-
-	} else {
-		extendedPublicKey, err := hdkeychain.NewKeyFromString(jsonExtendedPublicKey)
-		if err != nil {
-			return map[string]interface{}{"success": false, "errorCode": "xpubInvalid"}, nil
+		configurations = signing.Configurations{
+			signing.NewAddressConfiguration(scriptType, keypath, jsonBody.Address)}
+	default:
+		configuration, errorCode, wrongNet := singlesigConfiguration(
+			coin, scriptType, jsonBody.ExtendedPublicKey)
+		if errorCode != "" {
+			return map[string]interface{}{"success": false, "errorCode": errorCode}, nil
 		}
-		if extendedPublicKey.IsPrivate() {
-			return map[string]interface{}{"success": false, "errorCode": "xprivEntered"}, nil
+		if wrongNet {
+			warningCode = "xpubWrongNet"
 		}
-		if btcCoin, ok := coin.(*btc.Coin); ok {
-			expectedNet := &chaincfg.Params{
-				HDPublicKeyID: btc.XPubVersionForScriptType(btcCoin, scriptType),
-			}
-			if !extendedPublicKey.IsForNet(expectedNet) {
-				warningCode = "xpubWrongNet"
+		configurations = signing.Configurations{configuration}
+	}
+	seenConfigurations := map[string]struct{}{}
+	for _, configuration := range configurations {
+		if _, ok := seenConfigurations[configuration.Hash()]; ok {
+			if len(jsonBody.Descriptors) != 0 {
+				return map[string]interface{}{"success": false, "errorCode": "descriptorDuplicate"}, nil
 			}
+			return map[string]interface{}{"success": false, "errorCode": "xpubDuplicate"}, nil
 		}
-		configuration = signing.NewSinglesigConfiguration(scriptType, keypath, extendedPublicKey)
+		seenConfigurations[configuration.Hash()] = struct{}{}
 	}
 
 	getSigningConfigurations := func() (signing.Configurations, error) {
-		return signing.Configurations{configuration}, nil
+		return configurations, nil
 	}
-	accountCode := fmt.Sprintf("%s-%s", configuration.Hash(), coin.Code())
+	accountCode := fmt.Sprintf("%s-%s", configurations.Hash(), coin.Code())
 	err = handlers.backend.CreateAndAddAccount(
-		coin, accountCode, jsonAccountName, getSigningConfigurations, true, true)
+		coin, accountCode, jsonBody.AccountName, getSigningConfigurations, true, true)
 	if errp.Cause(err) == backend.ErrAccountAlreadyExists {
 		return map[string]interface{}{"success": false, "errorCode": "alreadyExists"}, nil
 	}
@@ -490,6 +651,50 @@ func (handlers *Handlers) postAddAccountHandler(r *http.Request) (interface{}, e
 	}, nil
 }
 
+// postAddKeystoreAccountHandler adds an account of the registered keystore at a custom keypath.
+func (handlers *Handlers) postAddKeystoreAccountHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		CoinCode    coinpkg.Code `json:"coinCode"`
+		ScriptType  string       `json:"scriptType"`
+		Keypath     string       `json:"keypath"`
+		AccountName string       `json:"accountName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	coin, err := handlers.backend.Coin(jsonBody.CoinCode)
+	if err != nil {
+		return nil, err
+	}
+	var scriptType signing.ScriptType
+	if _, isBTCCoin := coin.(*btc.Coin); isBTCCoin {
+		scriptType, err = signing.DecodeScriptType(jsonBody.ScriptType)
+		if err != nil {
+			return map[string]interface{}{"success": false, "errorCode": "scriptTypeInvalid"}, nil
+		}
+	}
+	keypath, err := signing.NewAbsoluteKeypath(strings.TrimSpace(jsonBody.Keypath))
+	if err != nil {
+		return map[string]interface{}{"success": false, "errorCode": "keypathInvalid"}, nil
+	}
+	accountCode, err := handlers.backend.CreateAndAddKeystoreAccount(
+		coin, jsonBody.AccountName, scriptType, keypath)
+	switch errp.Cause(err) {
+	case nil:
+		return map[string]interface{}{"success": true, "accountCode": accountCode}, nil
+	case backend.ErrAccountAlreadyExists:
+		return map[string]interface{}{"success": false, "errorCode": "alreadyExists"}, nil
+	case backend.ErrAccountNotSupported:
+		return map[string]interface{}{"success": false, "errorCode": "unsupported"}, nil
+	default:
+		return map[string]interface{}{
+			"success":      false,
+			"errorCode":    "unknown",
+			"errorMessage": err.Error(),
+		}, nil
+	}
+}
+
 func (handlers *Handlers) getKeystoresHandler(_ *http.Request) (interface{}, error) {
 	type json struct {
 		Type keystore.Type `json:"type"`
@@ -510,15 +715,27 @@ func (handlers *Handlers) getAccountsHandler(_ *http.Request) (interface{}, erro
 		Code                  string       `json:"code"`
 		Name                  string       `json:"name"`
 		BlockExplorerTxPrefix string       `json:"blockExplorerTxPrefix"`
+		KeystoreConnected     bool         `json:"keystoreConnected"`
+	}
+	// Accounts are shown in the stored order. Accounts which are not persisted come first.
+	order := map[string]int{}
+	for index, account := range handlers.backend.Config().AccountsConfig().Accounts {
+		order[account.Code] = index + 1
 	}
+	backendAccounts := append([]accounts.Interface{}, handlers.backend.Accounts()...)
+	sort.SliceStable(backendAccounts, func(i, j int) bool {
+		return order[backendAccounts[i].Config().Code] < order[backendAccounts[j].Config().Code]
+	})
+	keystoreConnected := handlers.backend.KeystoreConnectedAccounts()
 	accounts := []*accountJSON{}
-	for _, account := range handlers.backend.Accounts() {
+	for _, account := range backendAccounts {
 		accounts = append(accounts, &accountJSON{
 			CoinCode:              account.Coin().Code(),
 			CoinUnit:              account.Coin().Unit(false),
 			Code:                  account.Config().Code,
 			Name:                  account.Config().Name,
 			BlockExplorerTxPrefix: account.Coin().BlockExplorerTransactionURLPrefix(),
+			KeystoreConnected:     keystoreConnected[account.Config().Code],
 		})
 	}
 	return accounts, nil
@@ -529,6 +746,178 @@ func (handlers *Handlers) postAccountsReinitializeHandler(_ *http.Request) (inte
 	return nil, nil
 }
 
+func (handlers *Handlers) getPersistedAccountsHandler(_ *http.Request) (interface{}, error) {
+	type accountJSON struct {
+		CoinCode coinpkg.Code `json:"coinCode"`
+		Code     string       `json:"code"`
+		Name     string       `json:"name"`
+		Hidden   bool         `json:"hidden"`
+	}
+	accounts := []*accountJSON{}
+	for _, account := range handlers.backend.Config().AccountsConfig().Accounts {
+		accounts = append(accounts, &accountJSON{
+			CoinCode: account.CoinCode,
+			Code:     account.Code,
+			Name:     account.Name,
+			Hidden:   account.Hidden,
+		})
+	}
+	return accounts, nil
+}
+
+// accountsConfigResult converts the result of changing the persisted accounts to the API response.
+func accountsConfigResult(err error) (interface{}, error) {
+	if errp.Cause(err) == backend.ErrAccountNotPersisted {
+		return map[string]interface{}{"success": false, "errorCode": "accountNotFound"}, nil
+	}
+	if err != nil {
+		return map[string]interface{}{
+			"success":      false,
+			"errorCode":    "unknown",
+			"errorMessage": err.Error(),
+		}, nil
+	}
+	return map[string]interface{}{"success": true}, nil
+}
+
+func (handlers *Handlers) postAccountsRenameHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return accountsConfigResult(handlers.backend.RenameAccount(jsonBody.Code, jsonBody.Name))
+}
+
+func (handlers *Handlers) postAccountsSetHiddenHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		Code   string `json:"code"`
+		Hidden bool   `json:"hidden"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return accountsConfigResult(handlers.backend.SetAccountHidden(jsonBody.Code, jsonBody.Hidden))
+}
+
+func (handlers *Handlers) postAccountsSetBirthHeightHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		Code        string `json:"code"`
+		BirthHeight uint64 `json:"birthHeight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return accountsConfigResult(
+		handlers.backend.SetAccountBirthHeight(jsonBody.Code, jsonBody.BirthHeight))
+}
+
+func (handlers *Handlers) postAccountsReorderHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		Codes []string `json:"codes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return accountsConfigResult(handlers.backend.ReorderAccounts(jsonBody.Codes))
+}
+
+func (handlers *Handlers) postAccountsRemoveHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return accountsConfigResult(handlers.backend.RemoveAccount(jsonBody.Code))
+}
+
+// getERC20TokensHandler returns the ERC20 tokens added by the user.
+func (handlers *Handlers) getERC20TokensHandler(_ *http.Request) (interface{}, error) {
+	type tokenJSON struct {
+		CoinCode        coinpkg.Code `json:"coinCode"`
+		ContractAddress string       `json:"contractAddress"`
+		Name            string       `json:"name"`
+		Unit            string       `json:"unit"`
+		Decimals        uint         `json:"decimals"`
+	}
+	result := []tokenJSON{}
+	for _, token := range handlers.backend.CustomERC20Tokens() {
+		result = append(result, tokenJSON{
+			CoinCode:        backend.CustomERC20CoinCode(token),
+			ContractAddress: token.ContractAddress,
+			Name:            token.Name,
+			Unit:            token.Unit,
+			Decimals:        token.Decimals,
+		})
+	}
+	return result, nil
+}
+
+func (handlers *Handlers) postERC20TokensAddHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		ContractAddress string `json:"contractAddress"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	coinCode, err := handlers.backend.AddERC20Token(jsonBody.ContractAddress)
+	switch errp.Cause(err) {
+	case nil:
+		return map[string]interface{}{"success": true, "coinCode": coinCode}, nil
+	case backend.ErrERC20TokenAlreadyExists:
+		return map[string]interface{}{"success": false, "errorCode": "alreadyExists"}, nil
+	case backend.ErrERC20TokenInvalidAddress:
+		return map[string]interface{}{"success": false, "errorCode": "invalidAddress"}, nil
+	case backend.ErrERC20TokenNotSupported:
+		return map[string]interface{}{"success": false, "errorCode": "notSupported"}, nil
+	case backend.ErrERC20TokenUnitReserved:
+		return map[string]interface{}{"success": false, "errorCode": "unitReserved"}, nil
+	default:
+		handlers.log.WithError(err).Error("Could not add the ERC20 token")
+		return map[string]interface{}{
+			"success":      false,
+			"errorCode":    "unknown",
+			"errorMessage": err.Error(),
+		}, nil
+	}
+}
+
+func (handlers *Handlers) postERC20TokensRemoveHandler(r *http.Request) (interface{}, error) {
+	var jsonBody struct {
+		CoinCode coinpkg.Code `json:"coinCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	err := handlers.backend.RemoveERC20Token(jsonBody.CoinCode)
+	switch errp.Cause(err) {
+	case nil:
+		return map[string]interface{}{"success": true}, nil
+	case backend.ErrERC20TokenNotFound:
+		return map[string]interface{}{"success": false, "errorCode": "tokenNotFound"}, nil
+	default:
+		return map[string]interface{}{
+			"success":      false,
+			"errorCode":    "unknown",
+			"errorMessage": err.Error(),
+		}, nil
+	}
+}
+
+// postAccountsDiscoverHandler starts scanning for used accounts in the background. The progress
+// is reported with "accountDiscovery" events.
+func (handlers *Handlers) postAccountsDiscoverHandler(_ *http.Request) (interface{}, error) {
+	go func() {
+		if err := handlers.backend.DiscoverAccounts(); err != nil {
+			handlers.log.WithError(err).Error("Account discovery failed")
+		}
+	}()
+	return nil, nil
+}
+
 func (handlers *Handlers) getDevicesRegisteredHandler(_ *http.Request) (interface{}, error) {
 	jsonDevices := map[string]string{}
 	for deviceID, device := range handlers.backend.DevicesRegistered() {
@@ -868,6 +1257,7 @@ func (handlers *Handlers) postExportAccountSummary(_ *http.Request) (interface{}
 		"Type",
 		"Xpubs",
 		"Address",
+		"Descriptors",
 	})
 	if err != nil {
 		return nil, errp.WithStack(err)
@@ -891,17 +1281,17 @@ func (handlers *Handlers) postExportAccountSummary(_ *http.Request) (interface{}
 		var accountType string
 		var xpubs []string
 		var address string
-		signingConfigurations := account.Info().SigningConfigurations
+		info := account.Info()
+		signingConfigurations := info.SigningConfigurations
 		if len(signingConfigurations) == 1 && signingConfigurations[0].IsAddressBased() {
 			accountType = "address"
 			address = signingConfigurations[0].Address()
 		} else {
 			accountType = "xpubs"
 			for _, signingConfiguration := range signingConfigurations {
-				if len(signingConfiguration.ExtendedPublicKeys()) != 1 {
-					return nil, errp.New("multisig not supported in the export yet")
+				for _, xpub := range signingConfiguration.ExtendedPublicKeys() {
+					xpubs = append(xpubs, xpub.String())
 				}
-				xpubs = append(xpubs, signingConfiguration.ExtendedPublicKeys()[0].String())
 			}
 
 			if _, ok := account.(*eth.Account); ok {
@@ -917,6 +1307,7 @@ func (handlers *Handlers) postExportAccountSummary(_ *http.Request) (interface{}
 			accountType,
 			strings.Join(xpubs, "; "),
 			address,
+			strings.Join(info.Descriptors, "; "),
 		})
 		if err != nil {
 			return nil, errp.WithStack(err)
@@ -37,6 +37,14 @@ const (
 // ErrSigningAborted is used when the user aborts a signing in process (e.g. abort on HW wallet).
 var ErrSigningAborted = errors.New("signing aborted by user")
 
+// ErrKeystoreNotConnected is returned when signing with an account whose keystore is not
+// connected, e.g. a watch-only account of an unplugged device.
+var ErrKeystoreNotConnected = errors.New("keystore not connected")
+
+// ErrMultisigNotRegistered is returned when using a multisig account which has not been registered
+// on the keystore yet, see MultisigKeystore.
+var ErrMultisigNotRegistered = errors.New("multisig account not registered")
+
 // Keystore supports hardened key derivation according to BIP32 and signing of transactions.
 type Keystore interface {
 	// Type denotes the type of the keystore.
@@ -71,6 +79,10 @@ type Keystore interface {
 	// VerifyExtendedPublicKey displays the public key on the device for verification
 	VerifyExtendedPublicKey(coin.Coin, *signing.Configuration) error
 
+	// RootFingerprint returns the fingerprint of the root key, i.e. the first four bytes of the
+	// hash160 of the public key at m/, see BIP32. It identifies the keystore.
+	RootFingerprint() ([]byte, error)
+
 	// ExtendedPublicKey returns the extended public key at the given absolute keypath.
 	ExtendedPublicKey(coin.Coin, signing.AbsoluteKeypath) (*hdkeychain.ExtendedKey, error)
 
@@ -80,3 +92,27 @@ type Keystore interface {
 	// aborts.
 	SignTransaction(interface{}) error
 }
+
+// MultisigKeystore is implemented by keystores which can be a cosigner of a multisig account, but
+// need the account to be registered before they can show its addresses or sign its transactions.
+// The given configurations are account-level multisig configurations.
+type MultisigKeystore interface {
+	// MultisigRegistered returns true if the multisig account is registered on the keystore.
+	MultisigRegistered(coin.Coin, *signing.Configuration) (bool, error)
+
+	// RegisterMultisig registers the multisig account on the keystore under the given name. The
+	// user has to confirm the cosigners on the device. Returns ErrSigningAborted if the user aborts.
+	RegisterMultisig(coin coin.Coin, configuration *signing.Configuration, name string) error
+
+	// VerifyMultisigAddress outputs the address at the given keypath, which is derived from the
+	// multisig account configuration.
+	VerifyMultisigAddress(coin.Coin, *signing.Configuration, signing.AbsoluteKeypath) error
+}
+
+// EIP1559Keystore is implemented by keystores which can sign EIP-1559 (dynamic fee) Ethereum
+// transactions. Ethereum transactions are signed as legacy transactions if a keystore does not
+// implement it.
+type EIP1559Keystore interface {
+	// SupportsEIP1559 returns true if the keystore can sign EIP-1559 transactions of the coin.
+	SupportsEIP1559(coin.Coin) bool
+}
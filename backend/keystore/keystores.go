@@ -115,9 +115,27 @@ func (keystores *Keystores) CanVerifyExtendedPublicKeys() []int {
 	return canVerifyExtendedPublicKey
 }
 
+// SupportsEIP1559 returns true if there is at least one keystore and all keystores can sign
+// EIP-1559 transactions of the coin, see EIP1559Keystore.
+func (keystores *Keystores) SupportsEIP1559(coin coinpkg.Coin) bool {
+	if len(keystores.keystores) == 0 {
+		return false
+	}
+	for _, keystore := range keystores.keystores {
+		eip1559Keystore, ok := keystore.(EIP1559Keystore)
+		if !ok || !eip1559Keystore.SupportsEIP1559(coin) {
+			return false
+		}
+	}
+	return true
+}
+
 // SignTransaction signs the given proposed transaction on all keystores. Returns ErrSigningAborted
-// if the user aborts.
+// if the user aborts and ErrKeystoreNotConnected if there are no keystores.
 func (keystores *Keystores) SignTransaction(proposedTransaction interface{}) error {
+	if len(keystores.keystores) == 0 {
+		return errp.WithStack(ErrKeystoreNotConnected)
+	}
 	for _, keystore := range keystores.keystores {
 		if err := keystore.SignTransaction(proposedTransaction); err != nil {
 			return err
@@ -16,19 +16,26 @@
 package software
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
 	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/taproot"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
 	keystorePkg "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/pbkdf2"
 )
@@ -93,6 +100,12 @@ func (keystore *Keystore) SupportsAccount(
 	}
 }
 
+// SupportsEIP1559 implements keystore.EIP1559Keystore.
+func (keystore *Keystore) SupportsEIP1559(coin coin.Coin) bool {
+	_, ok := coin.(*eth.Coin)
+	return ok
+}
+
 // SupportsUnifiedAccounts implements keystore.Keystore.
 func (keystore *Keystore) SupportsUnifiedAccounts() bool {
 	return true
@@ -123,6 +136,15 @@ func (keystore *Keystore) VerifyExtendedPublicKey(coin coin.Coin, configuration
 	return errp.New("The software-based keystore has no secure output to display the public key.")
 }
 
+// RootFingerprint implements keystore.Keystore.
+func (keystore *Keystore) RootFingerprint() ([]byte, error) {
+	publicKey, err := keystore.master.ECPubKey()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return btcutil.Hash160(publicKey.SerializeCompressed())[:4], nil
+}
+
 // ExtendedPublicKey implements keystore.Keystore.
 func (keystore *Keystore) ExtendedPublicKey(
 	coin coin.Coin, absoluteKeypath signing.AbsoluteKeypath,
@@ -137,8 +159,9 @@ func (keystore *Keystore) ExtendedPublicKey(
 func (keystore *Keystore) sign(
 	signatureHashes [][]byte,
 	keyPaths []signing.AbsoluteKeypath,
+	taprootInputs []bool,
 ) ([]btcec.Signature, error) {
-	if len(signatureHashes) != len(keyPaths) {
+	if len(signatureHashes) != len(keyPaths) || len(taprootInputs) != len(keyPaths) {
 		return nil, errp.New("The number of hashes to sign has to be equal to the number of paths.")
 	}
 	signatures := make([]btcec.Signature, len(keyPaths))
@@ -151,6 +174,14 @@ func (keystore *Keystore) sign(
 		if err != nil {
 			return nil, err
 		}
+		if taprootInputs[i] {
+			signature, err := keystore.signTaproot(prv, signatureHashes[i])
+			if err != nil {
+				return nil, err
+			}
+			signatures[i] = *signature
+			continue
+		}
 		signature, err := prv.Sign(signatureHashes[i])
 		if err != nil {
 			return nil, err
@@ -160,17 +191,68 @@ func (keystore *Keystore) sign(
 	return signatures, nil
 }
 
+// signTaproot creates a BIP340 Schnorr signature with the BIP86 tweaked private key.
+func (keystore *Keystore) signTaproot(prv *btcec.PrivateKey, signatureHash []byte) (*btcec.Signature, error) {
+	tweakedPrv, err := taproot.TweakPrivateKey(prv)
+	if err != nil {
+		return nil, err
+	}
+	auxRand := make([]byte, 32)
+	if _, err := rand.Read(auxRand); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return taproot.Sign(tweakedPrv, signatureHash, auxRand)
+}
+
+// signETHTransaction signs legacy and EIP-1559 transactions.
+func (keystore *Keystore) signETHTransaction(txProposal *eth.TxProposal) error {
+	xprv, err := txProposal.Keypath.Derive(keystore.master)
+	if err != nil {
+		return err
+	}
+	prv, err := xprv.ECPrivKey()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	switch tx := txProposal.Tx.(type) {
+	case *types.Transaction:
+		signedTx, err := types.SignTx(tx, txProposal.Signer, prv.ToECDSA())
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		txProposal.Tx = signedTx
+	case *ethtypes.DynamicFeeTx:
+		signature, err := crypto.Sign(tx.SigHash().Bytes(), prv.ToECDSA())
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		signedTx, err := tx.WithSignature(signature)
+		if err != nil {
+			return err
+		}
+		txProposal.Tx = signedTx
+	default:
+		return errp.New("unknown transaction type")
+	}
+	return nil
+}
+
 // SignTransaction implements keystore.Keystore.
 func (keystore *Keystore) SignTransaction(
 	proposedTransaction interface{},
 ) error {
+	if ethTxProposal, ok := proposedTransaction.(*eth.TxProposal); ok {
+		keystore.log.Info("Sign transaction.")
+		return keystore.signETHTransaction(ethTxProposal)
+	}
 	btcProposedTx, ok := proposedTransaction.(*btc.ProposedTransaction)
 	if !ok {
-		panic("Only BTC supported for now.")
+		panic("Only BTC and ETH supported for now.")
 	}
 	keystore.log.Info("Sign transaction.")
 	signatureHashes := [][]byte{}
 	keyPaths := []signing.AbsoluteKeypath{}
+	taprootInputs := []bool{}
 	transaction := btcProposedTx.TXProposal.Transaction
 	for index, txIn := range transaction.TxIn {
 		spentOutput, ok := btcProposedTx.PreviousOutputs[txIn.PreviousOutPoint]
@@ -178,9 +260,17 @@ func (keystore *Keystore) SignTransaction(
 			keystore.log.Panic("There needs to be exactly one output being spent per input!")
 		}
 		address := btcProposedTx.GetAddress(spentOutput.ScriptHashHex())
+		isTaproot := address.IsTaproot()
 		isSegwit, subScript := address.ScriptForHashToSign()
 		var signatureHash []byte
-		if isSegwit {
+		if isTaproot {
+			var err error
+			signatureHash, err = btcProposedTx.TaprootSigHash(index)
+			if err != nil {
+				return errp.WithMessage(err, "Failed to calculate taproot signature hash")
+			}
+			keystore.log.Debug("Calculated taproot signature hash")
+		} else if isSegwit {
 			var err error
 			signatureHash, err = txscript.CalcWitnessSigHash(subScript, btcProposedTx.SigHashes,
 				txscript.SigHashAll, transaction, index, spentOutput.Value)
@@ -200,9 +290,10 @@ func (keystore *Keystore) SignTransaction(
 
 		signatureHashes = append(signatureHashes, signatureHash)
 		keyPaths = append(keyPaths, address.Configuration.AbsoluteKeypath())
+		taprootInputs = append(taprootInputs, isTaproot)
 	}
 
-	signatures, err := keystore.sign(signatureHashes, keyPaths)
+	signatures, err := keystore.sign(signatureHashes, keyPaths, taprootInputs)
 	if err != nil {
 		return errp.WithMessage(err, "Failed to sign signature hash")
 	}
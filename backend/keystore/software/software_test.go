@@ -0,0 +1,101 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package software_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeystore(t *testing.T) (*software.Keystore, *hdkeychain.ExtendedKey) {
+	t.Helper()
+	master, err := hdkeychain.NewMaster(
+		make([]byte, hdkeychain.RecommendedSeedLen), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	return software.NewKeystore(0, master), master
+}
+
+func TestSupportsEIP1559(t *testing.T) {
+	ks, _ := newTestKeystore(t)
+	var _ keystore.EIP1559Keystore = ks
+	ethCoin := eth.NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "", nil, "", nil, socksproxy.NewSocksProxy(false, ""))
+	btcCoin := btc.NewCoin("tbtc", "TBTC", &chaincfg.TestNet3Params, "", nil, "", socksproxy.NewSocksProxy(false, ""))
+	require.True(t, ks.SupportsEIP1559(ethCoin))
+	require.False(t, ks.SupportsEIP1559(btcCoin))
+}
+
+func TestSignETHTransaction(t *testing.T) {
+	ks, master := newTestKeystore(t)
+	ethCoin := eth.NewCoin("teth", "TETH", "TETH", params.TestnetChainConfig, "", nil, "", nil, socksproxy.NewSocksProxy(false, ""))
+	keypath, err := signing.NewAbsoluteKeypath("m/44'/1'/0'/0/0")
+	require.NoError(t, err)
+	xprv, err := keypath.Derive(master)
+	require.NoError(t, err)
+	privateKey, err := xprv.ECPrivKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	recipient := common.HexToAddress("0xb94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	chainID := params.TestnetChainConfig.ChainID
+	signer := types.NewEIP155Signer(chainID)
+
+	t.Run("eip1559", func(t *testing.T) {
+		tx := ethtypes.NewDynamicFeeTx(
+			chainID, 3, recipient, big.NewInt(10), 21000,
+			big.NewInt(1000000000), big.NewInt(100000000000), nil)
+		txProposal := &eth.TxProposal{Coin: ethCoin, Tx: tx, Signer: signer, Keypath: keypath}
+		require.NoError(t, ks.SignTransaction(txProposal))
+		signedTx, ok := txProposal.Tx.(*ethtypes.DynamicFeeTx)
+		require.True(t, ok)
+		encoded, err := ethtypes.EncodeTransaction(signedTx)
+		require.NoError(t, err)
+		require.Equal(t, byte(ethtypes.DynamicFeeTxType), encoded[0])
+
+		signature, err := crypto.Sign(tx.SigHash().Bytes(), privateKey.ToECDSA())
+		require.NoError(t, err)
+		recoveredKey, err := crypto.SigToPub(signedTx.SigHash().Bytes(), signature)
+		require.NoError(t, err)
+		require.Equal(t, address, crypto.PubkeyToAddress(*recoveredKey))
+		expectedTx, err := tx.WithSignature(signature)
+		require.NoError(t, err)
+		require.Equal(t, expectedTx.Hash(), signedTx.Hash())
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		tx := types.NewTransaction(3, recipient, big.NewInt(10), 21000, big.NewInt(1000000000), nil)
+		txProposal := &eth.TxProposal{Coin: ethCoin, Tx: tx, Signer: signer, Keypath: keypath}
+		require.NoError(t, ks.SignTransaction(txProposal))
+		signedTx, ok := txProposal.Tx.(*types.Transaction)
+		require.True(t, ok)
+		sender, err := types.Sender(signer, signedTx)
+		require.NoError(t, err)
+		require.Equal(t, address, sender)
+	})
+}
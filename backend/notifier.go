@@ -159,3 +159,44 @@ func (notifier *notifierForAccount) MarkAllNotified() error {
 		return nil
 	})
 }
+
+// MoveAccount moves the notification state of the account with the code `from` to the account
+// with the code `to`, e.g. when the code of an account changes. Nothing is moved if there is no
+// state for `from` or if there already is state for `to`.
+func (notifier *Notifier) MoveAccount(from string, to string) error {
+	tx, err := notifier.db.Begin(true)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	fromKey := []byte(fmt.Sprintf("account-%s", from))
+	toKey := []byte(fmt.Sprintf("account-%s", to))
+	bucketFrom := tx.Bucket(fromKey)
+	if bucketFrom == nil || tx.Bucket(toKey) != nil {
+		return nil
+	}
+	bucketTo, err := tx.CreateBucket(toKey)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, key := range []string{bucketUnnotifiedKey, bucketSeenKey} {
+		bucket := bucketFrom.Bucket([]byte(key))
+		if bucket == nil {
+			continue
+		}
+		copiedBucket, err := bucketTo.CreateBucket([]byte(key))
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		err = bucket.ForEach(func(id, value []byte) error {
+			return copiedBucket.Put(id, value)
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	if err := tx.DeleteBucket(fromKey); err != nil {
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(tx.Commit())
+}
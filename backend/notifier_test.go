@@ -0,0 +1,60 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifierMoveAccount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifier")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	notifier, err := NewNotifier(path.Join(dir, "notifier.db"))
+	require.NoError(t, err)
+	defer func() { _ = notifier.Close() }()
+
+	from := notifier.ForAccount("hash-btc")
+	require.NoError(t, from.Put([]byte("tx1")))
+	require.NoError(t, from.MarkAllNotified())
+	require.NoError(t, from.Put([]byte("tx2")))
+
+	require.NoError(t, notifier.MoveAccount("hash-btc", "hash-d34db33f-btc"))
+	count, err := from.UnnotifiedCount()
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	to := notifier.ForAccount("hash-d34db33f-btc")
+	count, err = to.UnnotifiedCount()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	// tx1 was seen before, so it is not unnotified again.
+	require.NoError(t, to.Put([]byte("tx1")))
+	count, err = to.UnnotifiedCount()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// Existing state is not overwritten, and moving a missing account does nothing.
+	require.NoError(t, notifier.ForAccount("other").Put([]byte("tx3")))
+	require.NoError(t, notifier.MoveAccount("other", "hash-d34db33f-btc"))
+	count, err = to.UnnotifiedCount()
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.NoError(t, notifier.MoveAccount("missing", "hash-d34db33f-btc"))
+}
@@ -37,6 +37,16 @@ var fiats = []string{"USD", "EUR", "CHF", "GBP", "JPY", "KRW", "CNY", "RUB", "CA
 const interval = time.Minute
 const cryptoCompareURL = "https://min-api.cryptocompare.com/data/pricemulti?fsyms=%s&tsyms=%s"
 
+// IsSupportedCoin returns true if the rates of the coin with the given unit are fetched.
+func IsSupportedCoin(unit string) bool {
+	for _, coin := range coins {
+		if coin == unit {
+			return true
+		}
+	}
+	return false
+}
+
 // RateUpdater implements coin.RateUpdater.
 type RateUpdater struct {
 	observable.Implementation
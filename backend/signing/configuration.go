@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcutil/hdkeychain"
@@ -36,11 +37,19 @@ type Configuration struct {
 	extendedPublicKeys []*hdkeychain.ExtendedKey // Should be empty for address based watch only accounts
 	signingThreshold   int                       // TODO Multisig Only
 	address            string                    // For address based accounts only
+	// rootFingerprints are the fingerprints of the root keys of the extended public keys, in the
+	// same order. Empty if unknown.
+	rootFingerprints []uint32
+	// unsortedMultisig is true if the public keys appear in the multisig script in the order of the
+	// extended public keys instead of sorted (BIP67).
+	unsortedMultisig bool
 }
 
-// NewConfiguration creates a new configuration. At the moment, multisig is a predefined
-// multisig-P2SH script, and is active if there are more than one xpubs. Otherwise, it's single sig
-// and `scriptType` defines the type of script.
+// NewConfiguration creates a new configuration. Multisig is a sorted multisig script, and is active
+// if there are more than one xpubs. Use WithUnsortedMultisig() for a multisig script which keeps
+// the order of the xpubs. Its `scriptType` is ScriptTypeP2WSH or ScriptTypeP2WSHP2SH, or
+// ScriptTypeP2PKH or empty for a legacy P2SH script. Otherwise, it's single sig and `scriptType`
+// defines the type of script.
 func NewConfiguration(
 	scriptType ScriptType,
 	absoluteKeypath AbsoluteKeypath,
@@ -56,6 +65,9 @@ func NewConfiguration(
 			panic("An extended key is private! Only extended public keys are accepted.")
 		}
 	}
+	if len(extendedPublicKeys) > 1 && !validMultisigScriptType(scriptType) {
+		panic(fmt.Sprintf("Unknown multisig script type %s", scriptType))
+	}
 	return &Configuration{
 		scriptType:         scriptType,
 		absoluteKeypath:    absoluteKeypath,
@@ -85,6 +97,43 @@ func NewAddressConfiguration(
 		scriptType, absoluteKeypath, []*hdkeychain.ExtendedKey{}, address, 1)
 }
 
+// WithRootFingerprints returns a copy of the configuration with the given root key fingerprints,
+// one per extended public key. The fingerprints are metadata and do not change the hash.
+func (configuration *Configuration) WithRootFingerprints(rootFingerprints []uint32) *Configuration {
+	if len(rootFingerprints) != len(configuration.extendedPublicKeys) {
+		panic("There must be one root fingerprint per extended public key")
+	}
+	result := *configuration
+	result.rootFingerprints = append([]uint32{}, rootFingerprints...)
+	return &result
+}
+
+// WithUnsortedMultisig returns a copy of the multisig configuration in which the public keys appear
+// in the script in the order of the extended public keys, instead of sorted.
+func (configuration *Configuration) WithUnsortedMultisig() *Configuration {
+	if !configuration.Multisig() {
+		panic("Only multisig configurations can be unsorted")
+	}
+	result := *configuration
+	result.unsortedMultisig = true
+	return &result
+}
+
+// SortedMultisig returns true if the public keys of a multisig configuration are sorted in the
+// script (BIP67).
+func (configuration *Configuration) SortedMultisig() bool {
+	return !configuration.unsortedMultisig
+}
+
+// RootFingerprint returns the fingerprint of the root key of the extended public key at the given
+// index, or zero if it is unknown.
+func (configuration *Configuration) RootFingerprint(index int) uint32 {
+	if index >= len(configuration.rootFingerprints) {
+		return 0
+	}
+	return configuration.rootFingerprints[index]
+}
+
 // ScriptType returns the configuration's keypath.
 func (configuration *Configuration) ScriptType() ScriptType {
 	if configuration.Multisig() {
@@ -93,6 +142,26 @@ func (configuration *Configuration) ScriptType() ScriptType {
 	return configuration.scriptType
 }
 
+// validMultisigScriptType returns true if the script type is one of the multisig script types. A
+// legacy P2SH multisig configuration has ScriptTypeP2PKH or an empty script type.
+func validMultisigScriptType(scriptType ScriptType) bool {
+	switch scriptType {
+	case "", ScriptTypeP2PKH, ScriptTypeP2WSH, ScriptTypeP2WSHP2SH:
+		return true
+	default:
+		return false
+	}
+}
+
+// MultisigScriptType returns the script type of a multisig configuration. ScriptTypeP2PKH and the
+// empty script type mean legacy P2SH multisig.
+func (configuration *Configuration) MultisigScriptType() ScriptType {
+	if !configuration.Multisig() {
+		panic("MultisigScriptType is only defined for multisig")
+	}
+	return configuration.scriptType
+}
+
 // AbsoluteKeypath returns the configuration's keypath.
 func (configuration *Configuration) AbsoluteKeypath() AbsoluteKeypath {
 	return configuration.absoluteKeypath
@@ -138,6 +207,15 @@ func (configuration *Configuration) SortedPublicKeys() []*btcec.PublicKey {
 	return publicKeys
 }
 
+// MultisigPublicKeys returns the public keys in the order in which they appear in the multisig
+// script.
+func (configuration *Configuration) MultisigPublicKeys() []*btcec.PublicKey {
+	if configuration.unsortedMultisig {
+		return configuration.PublicKeys()
+	}
+	return configuration.SortedPublicKeys()
+}
+
 // SigningThreshold returns the signing threshold in case of a multisig config.
 func (configuration *Configuration) SigningThreshold() int {
 	return configuration.signingThreshold
@@ -178,15 +256,19 @@ func (configuration *Configuration) Derive(relativeKeypath RelativeKeypath) (*Co
 		absoluteKeypath:    configuration.absoluteKeypath.Append(relativeKeypath),
 		extendedPublicKeys: derivedPublicKeys,
 		signingThreshold:   configuration.signingThreshold,
+		rootFingerprints:   configuration.rootFingerprints,
+		unsortedMultisig:   configuration.unsortedMultisig,
 	}, nil
 }
 
 type configurationEncoding struct {
-	ScriptType string          `json:"scriptType"`
-	Keypath    AbsoluteKeypath `json:"keypath"`
-	Threshold  int             `json:"threshold"`
-	Xpubs      []string        `json:"xpubs"`
-	Address    string          `json:"address"`
+	ScriptType       string          `json:"scriptType"`
+	Keypath          AbsoluteKeypath `json:"keypath"`
+	Threshold        int             `json:"threshold"`
+	Xpubs            []string        `json:"xpubs"`
+	Address          string          `json:"address"`
+	RootFingerprints []string        `json:"rootFingerprints,omitempty"`
+	Unsorted         bool            `json:"unsorted,omitempty"`
 }
 
 // MarshalJSON implements json.Marshaler.
@@ -196,12 +278,18 @@ func (configuration Configuration) MarshalJSON() ([]byte, error) {
 	for i := 0; i < length; i++ {
 		xpubs[i] = configuration.extendedPublicKeys[i].String()
 	}
+	var rootFingerprints []string
+	for _, rootFingerprint := range configuration.rootFingerprints {
+		rootFingerprints = append(rootFingerprints, fmt.Sprintf("%08x", rootFingerprint))
+	}
 	return json.Marshal(&configurationEncoding{
-		ScriptType: string(configuration.scriptType),
-		Keypath:    configuration.absoluteKeypath,
-		Threshold:  configuration.signingThreshold,
-		Xpubs:      xpubs,
-		Address:    configuration.address,
+		ScriptType:       string(configuration.scriptType),
+		Keypath:          configuration.absoluteKeypath,
+		Threshold:        configuration.signingThreshold,
+		Xpubs:            xpubs,
+		Address:          configuration.address,
+		RootFingerprints: rootFingerprints,
+		Unsorted:         configuration.unsortedMultisig,
 	})
 }
 
@@ -217,6 +305,7 @@ func (configuration *Configuration) UnmarshalJSON(bytes []byte) error {
 	length := len(encoding.Xpubs)
 	configuration.extendedPublicKeys = make([]*hdkeychain.ExtendedKey, length)
 	configuration.address = encoding.Address
+	configuration.unsortedMultisig = encoding.Unsorted
 	for i := 0; i < length; i++ {
 		var err error
 		configuration.extendedPublicKeys[i], err = hdkeychain.NewKeyFromString(encoding.Xpubs[i])
@@ -224,18 +313,36 @@ func (configuration *Configuration) UnmarshalJSON(bytes []byte) error {
 			return errp.Wrap(err, "Could not read an extended public key.")
 		}
 	}
+	if length > 1 && !validMultisigScriptType(configuration.scriptType) {
+		return errp.Newf("Unknown multisig script type %s", configuration.scriptType)
+	}
+	configuration.rootFingerprints = nil
+	for _, rootFingerprint := range encoding.RootFingerprints {
+		parsed, err := strconv.ParseUint(rootFingerprint, 16, 32)
+		if err != nil {
+			return errp.Wrap(err, "Could not read a root fingerprint.")
+		}
+		configuration.rootFingerprints = append(configuration.rootFingerprints, uint32(parsed))
+	}
 	return nil
 }
 
-// Hash returns a hash of the configuration in hex format.
+// Hash returns a hash of the configuration in hex format. The root fingerprints are not included, as
+// they do not change the scripts.
 func (configuration *Configuration) Hash() string {
-	hash := sha256.Sum256(jsonp.MustMarshal(configuration))
+	withoutRootFingerprints := *configuration
+	withoutRootFingerprints.rootFingerprints = nil
+	hash := sha256.Sum256(jsonp.MustMarshal(withoutRootFingerprints))
 	return hex.EncodeToString(hash[:])
 }
 
 // String returns a short summary of the configuration to be used in logs, etc.
 func (configuration *Configuration) String() string {
 	if configuration.Multisig() {
+		if configuration.scriptType == ScriptTypeP2WSH || configuration.scriptType == ScriptTypeP2WSHP2SH {
+			return fmt.Sprintf("multisig, %d/%d, scriptType: %s", configuration.SigningThreshold(),
+				configuration.NumberOfSigners(), configuration.scriptType)
+		}
 		return fmt.Sprintf("multisig, %d/%d",
 			configuration.SigningThreshold(), configuration.NumberOfSigners())
 	}
@@ -15,6 +15,8 @@
 package signing
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -40,3 +42,35 @@ func TestConfigurationsHash(t *testing.T) {
 		(Configurations{cfg2, cfg1}).Hash(),
 	)
 }
+
+func TestMultisigScriptType(t *testing.T) {
+	newXPub := func(seed byte) *hdkeychain.ExtendedKey {
+		xpub, err := hdkeychain.NewMaster(append(make([]byte, 31), seed), &chaincfg.TestNet3Params)
+		require.NoError(t, err)
+		xpub, err = xpub.Neuter()
+		require.NoError(t, err)
+		return xpub
+	}
+	xpubs := []*hdkeychain.ExtendedKey{newXPub(1), newXPub(2)}
+	keypath := NewEmptyAbsoluteKeypath()
+
+	for _, scriptType := range []ScriptType{"", ScriptTypeP2PKH, ScriptTypeP2WSH, ScriptTypeP2WSHP2SH} {
+		configuration := NewConfiguration(scriptType, keypath, xpubs, "", 1)
+		require.Equal(t, scriptType, configuration.MultisigScriptType())
+		encoded, err := json.Marshal(configuration)
+		require.NoError(t, err)
+		var decoded Configuration
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		require.Equal(t, configuration.Hash(), decoded.Hash())
+	}
+
+	// Unknown multisig script types are rejected instead of being treated as legacy P2SH.
+	for _, scriptType := range []ScriptType{ScriptTypeP2WPKH, ScriptTypeP2TR, "p2wsh-unknown"} {
+		require.Panics(t, func() { NewConfiguration(scriptType, keypath, xpubs, "", 1) })
+	}
+	encoded, err := json.Marshal(NewConfiguration(ScriptTypeP2WSH, keypath, xpubs, "", 1))
+	require.NoError(t, err)
+	var decoded Configuration
+	require.Error(t, json.Unmarshal(
+		[]byte(strings.Replace(string(encoded), `"p2wsh"`, `"p2wpkh"`, 1)), &decoded))
+}
@@ -0,0 +1,335 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signing
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// See https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki#checksum.
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// descriptorKeySuffix is appended to the keys in a descriptor. The receive (0) and change (1)
+// addresses are derived from the keys of a configuration (BIP389).
+const descriptorKeySuffix = "/<0;1>/*"
+
+func descriptorPolymod(symbols []uint64) uint64 {
+	generator := [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+	checksum := uint64(1)
+	for _, value := range symbols {
+		top := checksum >> 35
+		checksum = (checksum&0x7ffffffff)<<5 ^ value
+		for i := uint(0); i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+// descriptorChecksum computes the 8 character checksum of a descriptor.
+func descriptorChecksum(descriptor string) (string, error) {
+	symbols := []uint64{}
+	groups := []uint64{}
+	for _, char := range descriptor {
+		position := strings.IndexRune(descriptorInputCharset, char)
+		if position < 0 {
+			return "", errp.Newf("Invalid character in descriptor: %q", char)
+		}
+		symbols = append(symbols, uint64(position&31))
+		groups = append(groups, uint64(position>>5))
+		if len(groups) == 3 {
+			symbols = append(symbols, groups[0]*9+groups[1]*3+groups[2])
+			groups = groups[:0]
+		}
+	}
+	switch len(groups) {
+	case 1:
+		symbols = append(symbols, groups[0])
+	case 2:
+		symbols = append(symbols, groups[0]*3+groups[1])
+	}
+	checksum := descriptorPolymod(append(symbols, 0, 0, 0, 0, 0, 0, 0, 0)) ^ 1
+	result := make([]byte, 8)
+	for i := range result {
+		result[i] = descriptorChecksumCharset[(checksum>>(5*(7-uint(i))))&31]
+	}
+	return string(result), nil
+}
+
+// unwrapDescriptor returns the argument of a descriptor function like `wpkh(<argument>)`.
+func unwrapDescriptor(descriptor string, function string) (string, bool) {
+	if !strings.HasPrefix(descriptor, function+"(") || !strings.HasSuffix(descriptor, ")") {
+		return "", false
+	}
+	return descriptor[len(function)+1 : len(descriptor)-1], true
+}
+
+// descriptorKey is a parsed key expression of a descriptor.
+type descriptorKey struct {
+	// hasOrigin is false if the key origin was omitted, in which case the root fingerprint and
+	// the keypath are unknown.
+	hasOrigin       bool
+	rootFingerprint uint32
+	keypath         AbsoluteKeypath
+	xpub            *hdkeychain.ExtendedKey
+}
+
+// parseDescriptorKey parses a key expression like `[d34db33f/84h/0h/0h]xpub.../<0;1>/*`. The key
+// origin is optional.
+func parseDescriptorKey(key string, net *chaincfg.Params) (*descriptorKey, error) {
+	result := &descriptorKey{keypath: NewEmptyAbsoluteKeypath()}
+	if strings.HasPrefix(key, "[") {
+		end := strings.Index(key, "]")
+		if end < 0 {
+			return nil, errp.New("Unterminated key origin in descriptor")
+		}
+		origin := strings.Split(key[1:end], "/")
+		rootFingerprint, err := hex.DecodeString(origin[0])
+		if err != nil || len(rootFingerprint) != 4 {
+			return nil, errp.Newf("Invalid root fingerprint in descriptor: %s", origin[0])
+		}
+		result.hasOrigin = true
+		result.rootFingerprint = binary.BigEndian.Uint32(rootFingerprint)
+		keypath := strings.NewReplacer("h", hardenedKeySymbol, "H", hardenedKeySymbol).Replace(
+			strings.Join(origin[1:], "/"))
+		result.keypath, err = NewAbsoluteKeypath("m/" + keypath)
+		if err != nil {
+			return nil, err
+		}
+		key = key[end+1:]
+	}
+	if !strings.HasSuffix(key, descriptorKeySuffix) {
+		return nil, errp.Newf(
+			"Only keys deriving receive and change addresses (%s) are supported", descriptorKeySuffix)
+	}
+	xpub := strings.TrimSuffix(key, descriptorKeySuffix)
+	var err error
+	result.xpub, err = hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if result.xpub.IsPrivate() {
+		return nil, errp.New("Descriptors with private keys are not accepted")
+	}
+	if !result.xpub.IsForNet(net) {
+		return nil, errp.New("The extended public key does not belong to this network")
+	}
+	return result, nil
+}
+
+// parseMulti parses the arguments of `sortedmulti(<threshold>,<key>,...)` or, if sorted is false,
+// `multi(<threshold>,<key>,...)`.
+func parseMulti(arguments string, sorted bool, scriptType ScriptType, net *chaincfg.Params) (
+	*Configuration, error) {
+	splits := strings.Split(arguments, ",")
+	threshold, err := strconv.Atoi(splits[0])
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	keys := splits[1:]
+	if len(keys) < 2 || threshold < 1 || threshold > len(keys) {
+		return nil, errp.Newf("Invalid multisig threshold %d of %d", threshold, len(keys))
+	}
+	// The keypath is taken from the keys with a key origin. Keys without one, e.g. of cosigners
+	// whose root fingerprint is unknown, are assumed to use the same keypath.
+	keypath := NewEmptyAbsoluteKeypath()
+	hasKeypath := false
+	xpubs := make([]*hdkeychain.ExtendedKey, len(keys))
+	rootFingerprints := make([]uint32, len(keys))
+	for index, key := range keys {
+		parsedKey, err := parseDescriptorKey(key, net)
+		if err != nil {
+			return nil, err
+		}
+		if parsedKey.hasOrigin {
+			if !hasKeypath {
+				keypath = parsedKey.keypath
+				hasKeypath = true
+			} else if parsedKey.keypath.Encode() != keypath.Encode() {
+				return nil, errp.New("All cosigners must use the same keypath")
+			}
+		}
+		xpubs[index] = parsedKey.xpub
+		rootFingerprints[index] = parsedKey.rootFingerprint
+	}
+	configuration := NewConfiguration(scriptType, keypath, xpubs, "", threshold).
+		WithRootFingerprints(rootFingerprints)
+	if !sorted {
+		configuration = configuration.WithUnsortedMultisig()
+	}
+	return configuration, nil
+}
+
+// unwrapMulti parses `sortedmulti(...)` or `multi(...)`. The second result is false if the
+// descriptor is neither.
+func unwrapMulti(descriptor string, scriptType ScriptType, net *chaincfg.Params) (
+	*Configuration, bool, error) {
+	if arguments, ok := unwrapDescriptor(descriptor, "sortedmulti"); ok {
+		configuration, err := parseMulti(arguments, true, scriptType, net)
+		return configuration, true, err
+	}
+	if arguments, ok := unwrapDescriptor(descriptor, "multi"); ok {
+		configuration, err := parseMulti(arguments, false, scriptType, net)
+		return configuration, true, err
+	}
+	return nil, false, nil
+}
+
+// ParseDescriptor parses an output script descriptor (BIP380) into a configuration. Supported are
+// `pkh(KEY)`, `wpkh(KEY)`, `sh(wpkh(KEY))`, `tr(KEY)`, `sh(MULTI)`, `wsh(MULTI)` and
+// `sh(wsh(MULTI))`, where MULTI is `sortedmulti(k,KEY,...)` or `multi(k,KEY,...)` and KEY is an
+// extended public key of the given network with an optional key origin, followed by `/<0;1>/*`.
+// Keys deriving only one chain (e.g. `/0/*`) are rejected, as accounts always use a receive and a
+// change chain. The checksum is optional, but verified if present.
+func ParseDescriptor(descriptor string, net *chaincfg.Params) (*Configuration, error) {
+	descriptor = strings.TrimSpace(descriptor)
+	if index := strings.LastIndex(descriptor, "#"); index >= 0 {
+		checksum, err := descriptorChecksum(descriptor[:index])
+		if err != nil {
+			return nil, err
+		}
+		if descriptor[index+1:] != checksum {
+			return nil, errp.New("Invalid descriptor checksum")
+		}
+		descriptor = descriptor[:index]
+	}
+
+	singlesig := func(scriptType ScriptType, key string) (*Configuration, error) {
+		parsedKey, err := parseDescriptorKey(key, net)
+		if err != nil {
+			return nil, err
+		}
+		return NewSinglesigConfiguration(scriptType, parsedKey.keypath, parsedKey.xpub).
+			WithRootFingerprints([]uint32{parsedKey.rootFingerprint}), nil
+	}
+	if inner, ok := unwrapDescriptor(descriptor, "sh"); ok {
+		if key, ok := unwrapDescriptor(inner, "wpkh"); ok {
+			return singlesig(ScriptTypeP2WPKHP2SH, key)
+		}
+		if configuration, ok, err := unwrapMulti(inner, "", net); ok {
+			return configuration, err
+		}
+		if witnessScript, ok := unwrapDescriptor(inner, "wsh"); ok {
+			if configuration, ok, err := unwrapMulti(witnessScript, ScriptTypeP2WSHP2SH, net); ok {
+				return configuration, err
+			}
+		}
+		return nil, errp.Newf("Unsupported descriptor: %s", descriptor)
+	}
+	if witnessScript, ok := unwrapDescriptor(descriptor, "wsh"); ok {
+		if configuration, ok, err := unwrapMulti(witnessScript, ScriptTypeP2WSH, net); ok {
+			return configuration, err
+		}
+		return nil, errp.Newf("Unsupported descriptor: %s", descriptor)
+	}
+	if key, ok := unwrapDescriptor(descriptor, "wpkh"); ok {
+		return singlesig(ScriptTypeP2WPKH, key)
+	}
+	if key, ok := unwrapDescriptor(descriptor, "pkh"); ok {
+		return singlesig(ScriptTypeP2PKH, key)
+	}
+	if key, ok := unwrapDescriptor(descriptor, "tr"); ok {
+		return singlesig(ScriptTypeP2TR, key)
+	}
+	return nil, errp.Newf("Unsupported descriptor: %s", descriptor)
+}
+
+// descriptorKey returns the key expression of the extended public key at the given index. The key
+// origin is omitted if the root fingerprint is unknown, as a made up fingerprint would keep other
+// wallets from finding the signer. The keypath is not exported in this case.
+func (configuration *Configuration) descriptorKey(index int, net *chaincfg.Params) (string, error) {
+	xpub, err := hdkeychain.NewKeyFromString(configuration.extendedPublicKeys[index].String())
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	xpub.SetNet(net)
+	rootFingerprint := configuration.RootFingerprint(index)
+	if rootFingerprint == 0 {
+		return xpub.String() + descriptorKeySuffix, nil
+	}
+	origin := fmt.Sprintf("%08x", rootFingerprint)
+	for _, node := range configuration.absoluteKeypath {
+		origin += fmt.Sprintf("/%d", node.index)
+		if node.hardened {
+			origin += "h"
+		}
+	}
+	return "[" + origin + "]" + xpub.String() + descriptorKeySuffix, nil
+}
+
+// Descriptor returns the output script descriptor (BIP380) of the configuration including the
+// checksum, see ParseDescriptor(). The extended public keys are encoded for the given network
+// (xpub/tpub). Address based configurations have no descriptor.
+func (configuration *Configuration) Descriptor(net *chaincfg.Params) (string, error) {
+	if configuration.IsAddressBased() {
+		return "", errp.New("Address based configurations have no descriptor")
+	}
+	keys := make([]string, configuration.NumberOfSigners())
+	for index := range keys {
+		key, err := configuration.descriptorKey(index, net)
+		if err != nil {
+			return "", err
+		}
+		keys[index] = key
+	}
+	var descriptor string
+	if configuration.Multisig() {
+		function := "sortedmulti"
+		if !configuration.SortedMultisig() {
+			function = "multi"
+		}
+		descriptor = fmt.Sprintf("%s(%d,%s)",
+			function, configuration.signingThreshold, strings.Join(keys, ","))
+		switch configuration.scriptType {
+		case ScriptTypeP2WSH:
+			descriptor = fmt.Sprintf("wsh(%s)", descriptor)
+		case ScriptTypeP2WSHP2SH:
+			descriptor = fmt.Sprintf("sh(wsh(%s))", descriptor)
+		default:
+			descriptor = fmt.Sprintf("sh(%s)", descriptor)
+		}
+	} else {
+		switch configuration.scriptType {
+		case ScriptTypeP2PKH:
+			descriptor = fmt.Sprintf("pkh(%s)", keys[0])
+		case ScriptTypeP2WPKHP2SH:
+			descriptor = fmt.Sprintf("sh(wpkh(%s))", keys[0])
+		case ScriptTypeP2WPKH:
+			descriptor = fmt.Sprintf("wpkh(%s)", keys[0])
+		case ScriptTypeP2TR:
+			descriptor = fmt.Sprintf("tr(%s)", keys[0])
+		default:
+			return "", errp.Newf("Unsupported script type %s", configuration.scriptType)
+		}
+	}
+	checksum, err := descriptorChecksum(descriptor)
+	if err != nil {
+		return "", err
+	}
+	return descriptor + "#" + checksum, nil
+}
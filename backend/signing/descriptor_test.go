@@ -0,0 +1,154 @@
+// Copyright 2020 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescriptorChecksum(t *testing.T) {
+	// Test vector from Bitcoin Core.
+	checksum, err := descriptorChecksum(
+		"pkh([d34db33f/44'/0'/0']xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL/1/*)")
+	require.NoError(t, err)
+	require.Equal(t, "ml40v0wf", checksum)
+
+	_, err = descriptorChecksum("wpkh(ä)")
+	require.Error(t, err)
+}
+
+func TestDescriptor(t *testing.T) {
+	net := &chaincfg.TestNet3Params
+	newXPub := func(seed byte) *hdkeychain.ExtendedKey {
+		xpub, err := hdkeychain.NewMaster(append(make([]byte, 31), seed), net)
+		require.NoError(t, err)
+		xpub, err = xpub.Neuter()
+		require.NoError(t, err)
+		return xpub
+	}
+	keypath, err := NewAbsoluteKeypath("m/84'/1'/0'")
+	require.NoError(t, err)
+
+	singlesig := NewSinglesigConfiguration(ScriptTypeP2WPKH, keypath, newXPub(1)).
+		WithRootFingerprints([]uint32{0xd34db33f})
+	descriptor, err := singlesig.Descriptor(net)
+	require.NoError(t, err)
+	require.Regexp(t, `^wpkh\(\[d34db33f/84h/1h/0h\]tpub\w+/<0;1>/\*\)#\w{8}$`, descriptor)
+	parsed, err := ParseDescriptor(descriptor, net)
+	require.NoError(t, err)
+	require.Equal(t, singlesig.String(), parsed.String())
+	require.Equal(t, uint32(0xd34db33f), parsed.RootFingerprint(0))
+
+	taproot := NewSinglesigConfiguration(ScriptTypeP2TR, keypath, newXPub(1)).
+		WithRootFingerprints([]uint32{0xd34db33f})
+	descriptor, err = taproot.Descriptor(net)
+	require.NoError(t, err)
+	require.Regexp(t, `^tr\(\[d34db33f/84h/1h/0h\]tpub`, descriptor)
+	parsed, err = ParseDescriptor(descriptor, net)
+	require.NoError(t, err)
+	require.Equal(t, taproot.Hash(), parsed.Hash())
+
+	// Without the root fingerprint, the key origin including the keypath is omitted.
+	descriptor, err = NewSinglesigConfiguration(ScriptTypeP2TR, keypath, newXPub(1)).Descriptor(net)
+	require.NoError(t, err)
+	require.Regexp(t, `^tr\(tpub\w+/<0;1>/\*\)#\w{8}$`, descriptor)
+	parsed, err = ParseDescriptor(descriptor, net)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), parsed.RootFingerprint(0))
+	require.Empty(t, parsed.AbsoluteKeypath())
+	require.Equal(t, newXPub(1).String(), parsed.ExtendedPublicKeys()[0].String())
+	descriptor, err = singlesig.Descriptor(net)
+	require.NoError(t, err)
+
+	// The checksum is optional, but must match if present.
+	_, err = ParseDescriptor(descriptor[:len(descriptor)-9], net)
+	require.NoError(t, err)
+	_, err = ParseDescriptor(descriptor[:len(descriptor)-1]+"x", net)
+	require.Error(t, err)
+	// Keys of other networks are rejected.
+	_, err = ParseDescriptor(descriptor, &chaincfg.MainNetParams)
+	require.Error(t, err)
+
+	// Only the key origins of cosigners with a known root fingerprint are exported. The keypath
+	// of the others is assumed to be the same.
+	multisig := NewConfiguration(
+		"", keypath, []*hdkeychain.ExtendedKey{newXPub(1), newXPub(2), newXPub(3)}, "", 2).
+		WithRootFingerprints([]uint32{0, 0xd34db33f, 0})
+	descriptor, err = multisig.Descriptor(net)
+	require.NoError(t, err)
+	require.Regexp(t, `^sh\(sortedmulti\(2,tpub\w+/<0;1>/\*,\[d34db33f/84h/1h/0h\]tpub\w+/<0;1>/\*,tpub`,
+		descriptor)
+	parsed, err = ParseDescriptor(descriptor, net)
+	require.NoError(t, err)
+	require.True(t, parsed.Multisig())
+	require.Equal(t, multisig.String(), parsed.String())
+	require.Equal(t, multisig.Hash(), parsed.Hash())
+	require.Equal(t, []uint32{0, 0xd34db33f, 0}, []uint32{
+		parsed.RootFingerprint(0), parsed.RootFingerprint(1), parsed.RootFingerprint(2)})
+
+	for _, scriptType := range []ScriptType{ScriptTypeP2WSH, ScriptTypeP2WSHP2SH} {
+		multisig := NewConfiguration(
+			scriptType, keypath, []*hdkeychain.ExtendedKey{newXPub(1), newXPub(2)}, "", 1).
+			WithRootFingerprints([]uint32{1, 2})
+		descriptor, err := multisig.Descriptor(net)
+		require.NoError(t, err)
+		parsed, err := ParseDescriptor(descriptor, net)
+		require.NoError(t, err)
+		require.Equal(t, scriptType, parsed.MultisigScriptType())
+		require.Equal(t, multisig.Hash(), parsed.Hash())
+	}
+
+	// multi() keeps the order of the keys.
+	unsorted := NewConfiguration(
+		ScriptTypeP2WSH, keypath, []*hdkeychain.ExtendedKey{newXPub(2), newXPub(1)}, "", 1).
+		WithRootFingerprints([]uint32{1, 2}).
+		WithUnsortedMultisig()
+	descriptor, err = unsorted.Descriptor(net)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(
+		descriptor, "wsh(multi(1,[00000001/84h/1h/0h]"+newXPub(2).String()+"/<0;1>/*,"))
+	parsed, err = ParseDescriptor(descriptor, net)
+	require.NoError(t, err)
+	require.False(t, parsed.SortedMultisig())
+	require.Equal(t, unsorted.Hash(), parsed.Hash())
+	require.NotEqual(t, unsorted.Hash(), NewConfiguration(
+		ScriptTypeP2WSH, keypath, []*hdkeychain.ExtendedKey{newXPub(2), newXPub(1)}, "", 1).Hash())
+	require.Equal(t, newXPub(2).String(), parsed.ExtendedPublicKeys()[0].String())
+	parsed, err = ParseDescriptor(
+		"sh(wsh(multi(1,"+newXPub(1).String()+"/<0;1>/*,"+newXPub(2).String()+"/<0;1>/*)))", net)
+	require.NoError(t, err)
+	require.False(t, parsed.SortedMultisig())
+	require.Equal(t, ScriptTypeP2WSHP2SH, parsed.MultisigScriptType())
+
+	for _, invalid := range []string{
+		"wpkh(" + newXPub(1).String() + "/1/*)",
+		// Keys deriving only the receive chain are not accepted.
+		"wpkh(" + newXPub(1).String() + "/0/*)",
+		"wsh(multi(1," + newXPub(1).String() + "/0/*," + newXPub(2).String() + "/0/*))",
+		"multi(1," + newXPub(1).String() + "/0/*)",
+		// Cosigners with different keypaths.
+		"wsh(multi(1,[00000001/48h]" + newXPub(1).String() + "/<0;1>/*,[00000002/84h]" +
+			newXPub(2).String() + "/<0;1>/*))",
+		"sh(sortedmulti(3," + newXPub(1).String() + "/0/*," + newXPub(2).String() + "/0/*))",
+	} {
+		_, err := ParseDescriptor(invalid, net)
+		require.Error(t, err, invalid)
+	}
+}
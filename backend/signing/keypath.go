@@ -181,6 +181,25 @@ func NewAbsoluteKeypath(input string) (AbsoluteKeypath, error) {
 	return AbsoluteKeypath(path), nil
 }
 
+// NewBIP48Keypath returns the keypath `m/48'/<coinType>'/<account>'/<script>'` of a multisig
+// account (BIP48), where script is 2 for P2WSH and 1 for P2SH-P2WSH.
+func NewBIP48Keypath(coinType uint32, account uint32, scriptType ScriptType) (AbsoluteKeypath, error) {
+	var script uint32
+	switch scriptType {
+	case ScriptTypeP2WSH:
+		script = 2
+	case ScriptTypeP2WSHP2SH:
+		script = 1
+	default:
+		return nil, errp.Newf("BIP48 does not define the script type %s", scriptType)
+	}
+	return NewEmptyAbsoluteKeypath().
+		Child(48, true).
+		Child(coinType, true).
+		Child(account, true).
+		Child(script, true), nil
+}
+
 // Encode encodes the absolute keypath as a string.
 func (absoluteKeypath AbsoluteKeypath) Encode() string {
 	return "m/" + keypath(absoluteKeypath).encode()
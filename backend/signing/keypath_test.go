@@ -39,3 +39,14 @@ func TestKeypath(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, absoluteKeypath.Encode(), decodedKeypath.Encode())
 }
+
+func TestBIP48Keypath(t *testing.T) {
+	keypath, err := signing.NewBIP48Keypath(0, 0, signing.ScriptTypeP2WSH)
+	assert.NoError(t, err)
+	assert.Equal(t, "m/48'/0'/0'/2'", keypath.Encode())
+	keypath, err = signing.NewBIP48Keypath(1, 3, signing.ScriptTypeP2WSHP2SH)
+	assert.NoError(t, err)
+	assert.Equal(t, "m/48'/1'/3'/1'", keypath.Encode())
+	_, err = signing.NewBIP48Keypath(0, 0, signing.ScriptTypeP2WPKH)
+	assert.Error(t, err)
+}
@@ -16,7 +16,8 @@ package signing
 
 import "github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 
-// ScriptType indicates which type of output should be produced in case of singlesig.
+// ScriptType indicates which type of output should be produced. The singlesig and the multisig
+// script types are distinct.
 type ScriptType string
 
 const (
@@ -28,6 +29,15 @@ const (
 
 	// ScriptTypeP2WPKH is a segwit PayToPubKeyHash output.
 	ScriptTypeP2WPKH ScriptType = "p2wpkh"
+
+	// ScriptTypeP2TR is a taproot output spendable only with the key path (BIP86).
+	ScriptTypeP2TR ScriptType = "p2tr"
+
+	// ScriptTypeP2WSH is a segwit PayToScriptHash multisig output.
+	ScriptTypeP2WSH ScriptType = "p2wsh"
+
+	// ScriptTypeP2WSHP2SH is a segwit PayToScriptHash multisig output wrapped in p2sh.
+	ScriptTypeP2WSHP2SH ScriptType = "p2wsh-p2sh"
 )
 
 // DecodeScriptType decodes the given script type or returns an error.
@@ -39,7 +49,22 @@ func DecodeScriptType(scriptType string) (ScriptType, error) {
 		return ScriptTypeP2WPKHP2SH, nil
 	case "p2wpkh":
 		return ScriptTypeP2WPKH, nil
+	case "p2tr":
+		return ScriptTypeP2TR, nil
 	default:
 		return "", errp.Newf("The given script type %s is unknown.", scriptType)
 	}
 }
+
+// DecodeMultisigScriptType decodes the given multisig script type or returns an error. Legacy P2SH
+// multisig is not offered for new accounts.
+func DecodeMultisigScriptType(scriptType string) (ScriptType, error) {
+	switch scriptType {
+	case "p2wsh":
+		return ScriptTypeP2WSH, nil
+	case "p2wsh-p2sh":
+		return ScriptTypeP2WSHP2SH, nil
+	default:
+		return "", errp.Newf("The given multisig script type %s is unknown.", scriptType)
+	}
+}